@@ -0,0 +1,149 @@
+// Command mockats is a local stand-in for a Greenhouse-style ATS, for
+// exercising the ATS connector end to end without a vendor account.
+//
+// It serves a resume file, records scores posted back to
+// /applications/<id>/scores (dropping repeats of an Idempotency-Key), and can
+// fire a signed "new_candidate_application" webhook at the backend:
+//
+//	ATS_WEBHOOK_SECRET=dev ATS_API_URL=http://localhost:9090 go run .
+//	go run ./cmd/mockats -secret dev -resume resume.pdf -send -fail 2
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"interviewme/utils"
+)
+
+type receivedScore struct {
+	IdempotencyKey string          `json:"idempotency_key"`
+	ApplicationID  string          `json:"application_id"`
+	SignatureOK    bool            `json:"signature_ok"`
+	Body           json.RawMessage `json:"body"`
+	ReceivedAt     time.Time       `json:"received_at"`
+}
+
+func main() {
+	addr := flag.String("addr", "localhost:9090", "address to listen on")
+	secret := flag.String("secret", os.Getenv("ATS_WEBHOOK_SECRET"), "shared webhook secret")
+	resume := flag.String("resume", "", "resume file offered as the application attachment")
+	target := flag.String("target", "http://localhost:8080/integrations/ats/webhook", "connector webhook URL")
+	send := flag.Bool("send", false, "send an application webhook once listening")
+	applicationID := flag.String("application", "1001", "application ID for -send")
+	jobID := flag.String("job", "4001", "ATS job ID for -send")
+	failures := flag.Int("fail", 0, "answer the first N score posts with 503 to exercise retries")
+	flag.Parse()
+
+	var (
+		mu     sync.Mutex
+		scores []receivedScore
+		seen   = make(map[string]bool)
+		posts  int
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /files/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if *resume == "" || r.PathValue("name") != filepath.Base(*resume) {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeFile(w, r, *resume)
+	})
+	mux.HandleFunc("POST /applications/{id}/scores", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		key := r.Header.Get("Idempotency-Key")
+
+		mu.Lock()
+		defer mu.Unlock()
+		posts++
+		if posts <= *failures {
+			log.Printf("Failing score post %d of %d on purpose", posts, *failures)
+			http.Error(w, "temporarily unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		if key != "" && seen[key] {
+			log.Printf("Duplicate score for %s ignored (Idempotency-Key %s)", r.PathValue("id"), key)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		seen[key] = true
+		score := receivedScore{
+			IdempotencyKey: key,
+			ApplicationID:  r.PathValue("id"),
+			SignatureOK:    utils.VerifySignature(*secret, body, r.Header.Get("X-Signature")),
+			Body:           body,
+			ReceivedAt:     time.Now(),
+		}
+		scores = append(scores, score)
+		log.Printf("Score for application %s: %s", score.ApplicationID, body)
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /scores", func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(scores)
+	})
+
+	if *send {
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			if err := sendApplication(*target, *secret, *addr, *applicationID, *jobID, *resume); err != nil {
+				log.Printf("Sending webhook failed: %v", err)
+			}
+		}()
+	}
+	log.Printf("Mock ATS listening on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// sendApplication posts a signed Greenhouse-style application webhook
+func sendApplication(target, secret, addr, applicationID, jobID, resume string) error {
+	if resume == "" {
+		return fmt.Errorf("-send needs -resume")
+	}
+	payload := map[string]any{
+		"action": "new_candidate_application",
+		"payload": map[string]any{
+			"application": map[string]any{
+				"id":   json.Number(applicationID),
+				"jobs": []map[string]any{{"id": json.Number(jobID)}},
+				"candidate": map[string]any{
+					"id": json.Number("5" + applicationID),
+					"attachments": []map[string]string{{
+						"filename": filepath.Base(resume),
+						"url":      fmt.Sprintf("http://%s/files/%s", addr, filepath.Base(resume)),
+						"type":     "resume",
+					}},
+				},
+			},
+		},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := utils.NewJSONRequest(target, body, map[string]string{
+		"Signature": "sha256 " + utils.SignPayload(secret, body),
+	})
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	reply, _ := io.ReadAll(resp.Body)
+	log.Printf("Webhook answered %d: %s", resp.StatusCode, reply)
+	return nil
+}
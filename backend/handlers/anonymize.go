@@ -0,0 +1,611 @@
+package handlers
+
+import (
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ScreeningSettings holds the per-job blind screening configuration
+type ScreeningSettings struct {
+	JobID          string    `json:"job_id"`
+	BlindScreening bool      `json:"blind_screening"`
+	Shortlisted    []string  `json:"shortlisted"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	UpdatedBy      string    `json:"updated_by,omitempty"`
+}
+
+// RedactionVault keeps the reversible mapping for an anonymized resume.
+// Only UnmaskCandidate reads it back, and only for shortlisted candidates.
+type RedactionVault struct {
+	ResumeID   string            `json:"resume_id"`
+	JobID      string            `json:"job_id"`
+	UploadFile string            `json:"upload_file"`
+	Tokens     map[string]string `json:"tokens"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UnmaskLog  []UnmaskEvent     `json:"unmask_log"`
+}
+
+// UnmaskEvent records who revealed a candidate's identity and when
+type UnmaskEvent struct {
+	JobID     string    `json:"job_id"`
+	User      string    `json:"user"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+const (
+	redactedNameToken = "REDACTED_NAME"
+	unmaskKeyHeader   = "X-Unmask-Key"
+)
+
+var (
+	redactEmailPattern    = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	redactPhonePattern    = regexp.MustCompile(`\+?\(?\d[\d\- \t().]{8,}\d`)
+	redactURLPattern      = regexp.MustCompile(`(?i)\b(?:https?://\S+|www\.\S+|(?:linkedin|github|gitlab)\.com/\S+)`)
+	redactAddressPattern  = regexp.MustCompile(`\b\d{1,5}[ \t]+(?:[A-Z][A-Za-z]*[ \t]+){1,4}(?:Street|St|Avenue|Ave|Road|Rd|Boulevard|Blvd|Lane|Ln|Drive|Dr|Court|Ct|Way|Place|Pl|Terrace|Nagar|Marg)\b\.?(?:,?[ \t]*[A-Z][A-Za-z]+)*(?:,?[ \t]*[A-Z]{2})?(?:[ \t]+\d{5,6}(?:-\d{4})?)?`)
+	redactYearPattern     = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+	educationLinePattern  = regexp.MustCompile(`(?i)\b(?:bachelor|master|b\.?\s?sc|m\.?\s?sc|b\.?\s?tech|m\.?\s?tech|b\.e\.|m\.e\.|ph\.?d|mba|diploma|degree|university|college|institute|school|graduat\w*|class of)\b`)
+	redactionTokenPattern = regexp.MustCompile(`REDACTED_[A-Z_]+_\d+|` + redactedNameToken)
+	namePattern           = regexp.MustCompile(`^[A-Z][A-Za-z'.\-]+(?:\s+[A-Z][A-Za-z'.\-]+){1,3}$`)
+)
+
+// genderedTerms maps gendered words to neutral replacements. They are not
+// identifying on their own so they are not stored in the vault.
+var genderedTerms = map[string]string{
+	"he": "they", "she": "they", "him": "them", "his": "their", "her": "their",
+	"hers": "theirs", "himself": "themselves", "herself": "themselves",
+	"male": "", "female": "",
+	"chairman": "chairperson", "chairwoman": "chairperson",
+	"businessman": "businessperson", "businesswoman": "businessperson",
+	"fraternity": "student organization", "sorority": "student organization",
+	"husband": "spouse", "wife": "spouse", "father": "parent", "mother": "parent",
+}
+
+var (
+	genderedTermPattern = regexp.MustCompile(`(?i)\b(?:` + strings.Join(genderedTermKeys(), "|") + `)\b`)
+	honorificPattern    = regexp.MustCompile(`\b(?:Mr|Mrs|Ms|Miss)\.?\s+`)
+)
+
+func genderedTermKeys() []string {
+	keys := make([]string, 0, len(genderedTerms))
+	for k := range genderedTerms {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// redactResumeText replaces identifying details in raw resume text with
+// placeholder tokens and returns the redacted text plus the token mapping.
+// Photos never reach this point since pdftotext only emits text.
+func redactResumeText(text string) (string, map[string]string) {
+	tokens := make(map[string]string)
+	counters := make(map[string]int)
+
+	replace := func(kind string) func(string) string {
+		return func(match string) string {
+			for token, original := range tokens {
+				if original == match && strings.HasPrefix(token, "REDACTED_"+kind) {
+					return token
+				}
+			}
+			counters[kind]++
+			token := fmt.Sprintf("REDACTED_%s_%d", kind, counters[kind])
+			tokens[token] = match
+			return token
+		}
+	}
+
+	// The candidate's name is almost always the first non-empty line
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		if namePattern.MatchString(trimmed) {
+			tokens[redactedNameToken] = trimmed
+		}
+		break
+	}
+	if name, ok := tokens[redactedNameToken]; ok {
+		text = strings.ReplaceAll(text, name, redactedNameToken)
+	}
+
+	text = redactEmailPattern.ReplaceAllStringFunc(text, replace("EMAIL"))
+	text = redactURLPattern.ReplaceAllStringFunc(text, replace("URL"))
+	text = redactAddressPattern.ReplaceAllStringFunc(text, replace("ADDRESS"))
+	text = redactPhonePattern.ReplaceAllStringFunc(text, func(match string) string {
+		digits := 0
+		for _, r := range match {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		// Date ranges like "2019 - 2021" also look like numbers with dashes
+		if digits < 10 || digits > 15 {
+			return match
+		}
+		return replace("PHONE")(match)
+	})
+
+	// Graduation years are an age proxy, so only redact years on education lines
+	lines = strings.Split(text, "\n")
+	for i, line := range lines {
+		if educationLinePattern.MatchString(line) {
+			lines[i] = redactYearPattern.ReplaceAllStringFunc(line, replace("GRAD_YEAR"))
+		}
+	}
+	text = strings.Join(lines, "\n")
+
+	return neutralizeGenderedTerms(text), tokens
+}
+
+// redactVaultValues replaces every value held in a redaction vault with its
+// token, longest first so a name containing an email is fully covered
+func redactVaultValues(text string, tokens map[string]string) string {
+	ordered := make([]string, 0, len(tokens))
+	for token, original := range tokens {
+		if original != "" && original != token {
+			ordered = append(ordered, token)
+		}
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		return len(tokens[ordered[i]]) > len(tokens[ordered[j]])
+	})
+	for _, token := range ordered {
+		text = strings.ReplaceAll(text, tokens[token], token)
+	}
+	return text
+}
+
+// redactEntityText applies a resume's redaction vault to the free text of
+// entities that were built straight from the upload rather than extracted
+// from the redacted text, as JSON Resume imports are. Anything the vault
+// holds becomes its token so unmasking can restore it.
+func redactEntityText(entities ExtractedEntities, vault map[string]string) ExtractedEntities {
+	// Graduation years are only redacted on education lines, not everywhere
+	tokens := make(map[string]string, len(vault))
+	for token, original := range vault {
+		if !strings.HasPrefix(token, "REDACTED_GRAD_YEAR") {
+			tokens[token] = original
+		}
+	}
+	redact := func(s string) string {
+		return neutralizeGenderedTerms(redactVaultValues(s, tokens))
+	}
+	redactAll := func(items []string) []string {
+		redacted := make([]string, len(items))
+		for i, item := range items {
+			redacted[i] = redact(item)
+		}
+		return redacted
+	}
+
+	entities.Experience = append([]Experience(nil), entities.Experience...)
+	for i, exp := range entities.Experience {
+		exp.Title = redact(exp.Title)
+		exp.Location = redactVaultValues(exp.Location, tokens)
+		exp.Description = redact(exp.Description)
+		exp.RoleDescription = redact(exp.RoleDescription)
+		exp.Responsibilities = redactAll(exp.Responsibilities)
+		exp.Achievements = redactAll(exp.Achievements)
+		entities.Experience[i] = exp
+	}
+	entities.Projects = append([]Project(nil), entities.Projects...)
+	for i, project := range entities.Projects {
+		project.Name = redact(project.Name)
+		project.Description = redact(project.Description)
+		project.Role = redact(project.Role)
+		project.Achievements = redactAll(project.Achievements)
+		entities.Projects[i] = project
+	}
+	entities.Education = append([]Education(nil), entities.Education...)
+	for i, edu := range entities.Education {
+		edu.Institution = redactVaultValues(edu.Institution, tokens)
+		entities.Education[i] = edu
+	}
+	entities.Certificates = append([]Certificate(nil), entities.Certificates...)
+	for i, cert := range entities.Certificates {
+		// Certificate links usually point at a profile under the candidate's name
+		cert.Name, cert.URL = redact(cert.Name), ""
+		entities.Certificates[i] = cert
+	}
+	return entities
+}
+
+// neutralizeGenderedTerms swaps gendered words for neutral ones
+func neutralizeGenderedTerms(text string) string {
+	text = honorificPattern.ReplaceAllString(text, "")
+	return genderedTermPattern.ReplaceAllStringFunc(text, func(match string) string {
+		replacement := genderedTerms[strings.ToLower(match)]
+		if replacement != "" && match[0] >= 'A' && match[0] <= 'Z' {
+			replacement = strings.ToUpper(replacement[:1]) + replacement[1:]
+		}
+		return replacement
+	})
+}
+
+// candidatePseudonym returns a stable display name for an anonymized resume
+func candidatePseudonym(resumeID string) string {
+	sum := sha1.Sum([]byte(normalizeID(resumeID, "resume")))
+	return "Candidate " + strings.ToUpper(hex.EncodeToString(sum[:])[:6])
+}
+
+// anonymizeEntities returns a copy of the entities with contact details,
+// addresses, graduation years and gendered terms removed.
+func anonymizeEntities(entities ExtractedEntities, resumeID string) ExtractedEntities {
+	anon := entities
+	anon.Name = candidatePseudonym(resumeID)
+	anon.Email = []string{}
+	anon.Phone = ""
+	anon.Preferences.Location = ""
+	anon.Preferences.WorkAuthorization = ""
+
+	anon.Education = make([]Education, len(entities.Education))
+	for i, edu := range entities.Education {
+		edu.Year = ""
+		edu.GraduationDate = ""
+		edu.Location = ""
+		anon.Education[i] = edu
+	}
+
+	anon.Experience = make([]Experience, len(entities.Experience))
+	for i, exp := range entities.Experience {
+		exp.Location = ""
+		exp.Description = neutralizeGenderedTerms(exp.Description)
+		exp.RoleDescription = neutralizeGenderedTerms(exp.RoleDescription)
+		anon.Experience[i] = exp
+	}
+
+	anon.Projects = make([]Project, len(entities.Projects))
+	for i, proj := range entities.Projects {
+		proj.Team = nil
+		proj.Description = neutralizeGenderedTerms(proj.Description)
+		anon.Projects[i] = proj
+	}
+
+	return anon
+}
+
+// anonymizeScore strips what a score shows about who the candidate is, for
+// display against a blind screening job
+func anonymizeScore(score ScoreResponse, resumeID string) ScoreResponse {
+	score.ProcessedEntities = anonymizeEntities(score.ProcessedEntities, resumeID)
+	score.IntegrityFlags = blindIntegrityFlags(score.IntegrityFlags)
+	// Knockout values repeat the candidate's location, salary and the like
+	knockouts := make([]KnockoutResult, len(score.Knockouts))
+	for i, knockout := range score.Knockouts {
+		knockout.Candidate = ""
+		knockouts[i] = knockout
+	}
+	if score.Knockouts != nil {
+		score.Knockouts = knockouts
+	}
+	return score
+}
+
+// unmaskEntities substitutes vault tokens back into the entities
+func unmaskEntities(entities ExtractedEntities, tokens map[string]string) ExtractedEntities {
+	restore := func(s string) string {
+		return redactionTokenPattern.ReplaceAllStringFunc(s, func(token string) string {
+			if original, ok := tokens[token]; ok {
+				return original
+			}
+			return token
+		})
+	}
+
+	if name, ok := tokens[redactedNameToken]; ok {
+		entities.Name = name
+	}
+	entities.Email = []string{}
+	for token, original := range tokens {
+		if strings.HasPrefix(token, "REDACTED_EMAIL") {
+			entities.Email = append(entities.Email, original)
+		}
+		if strings.HasPrefix(token, "REDACTED_PHONE") && (entities.Phone == "" || entities.Phone == "Not provided") {
+			entities.Phone = original
+		}
+	}
+
+	for i := range entities.Education {
+		entities.Education[i].Year = restore(entities.Education[i].Year)
+		entities.Education[i].Institution = restore(entities.Education[i].Institution)
+	}
+	restoreAll := func(items []string) []string {
+		restored := make([]string, len(items))
+		for i, item := range items {
+			restored[i] = restore(item)
+		}
+		return restored
+	}
+	for i := range entities.Experience {
+		exp := &entities.Experience[i]
+		exp.Title = restore(exp.Title)
+		exp.Description = restore(exp.Description)
+		exp.RoleDescription = restore(exp.RoleDescription)
+		exp.Location = restore(exp.Location)
+		exp.Responsibilities = restoreAll(exp.Responsibilities)
+		exp.Achievements = restoreAll(exp.Achievements)
+	}
+	for i := range entities.Projects {
+		project := &entities.Projects[i]
+		project.Name = restore(project.Name)
+		project.Description = restore(project.Description)
+		project.Role = restore(project.Role)
+		project.Achievements = restoreAll(project.Achievements)
+	}
+	return entities
+}
+
+func screeningSettingsPath(jobID string) string {
+	return filepath.Join("processed_texts", "screening", fmt.Sprintf("screening_%s.json", pathID(jobID, "job")))
+}
+
+func redactionVaultPath(resumeID string) string {
+	return filepath.Join("processed_texts", "redactions", fmt.Sprintf("redaction_%s.json", pathID(resumeID, "resume")))
+}
+
+// loadScreeningSettings returns the job's settings, defaulting to blind screening off
+func loadScreeningSettings(jobID string) ScreeningSettings {
+	settings := ScreeningSettings{
+		JobID:       normalizeID(jobID, "job"),
+		Shortlisted: []string{},
+	}
+	if jobID == "" {
+		return settings
+	}
+	if err := utils.LoadJSONFile(screeningSettingsPath(jobID), &settings); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error loading screening settings for job %s: %v", jobID, err)
+	}
+	return settings
+}
+
+func saveScreeningSettings(settings ScreeningSettings) error {
+	settings.UpdatedAt = time.Now()
+	return utils.SaveJSONFile(screeningSettingsPath(settings.JobID), settings, 0644)
+}
+
+// isBlindScreeningJob reports whether the job has anonymized screening enabled
+func isBlindScreeningJob(jobID string) bool {
+	return loadScreeningSettings(jobID).BlindScreening
+}
+
+// blindUploadPath marks an uploaded file as belonging to an anonymized resume
+func blindUploadPath(uploadFile string) string {
+	return filepath.Join("processed_texts", "redactions", "uploads", filepath.Base(uploadFile)+".json")
+}
+
+// saveRedactionVault stores a new vault and indexes its upload
+func saveRedactionVault(vault RedactionVault) error {
+	if err := utils.SaveJSONFile(redactionVaultPath(vault.ResumeID), vault, 0600); err != nil {
+		return err
+	}
+	return utils.SaveJSONFile(blindUploadPath(vault.UploadFile), fiber.Map{"resume_id": vault.ResumeID}, 0600)
+}
+
+// isBlindUpload reports whether an uploaded file belongs to an anonymized resume,
+// in which case the original PDF (which may contain a photo) must not be served.
+func isBlindUpload(uploadFile string) bool {
+	_, err := os.Stat(blindUploadPath(uploadFile))
+	return err == nil
+}
+
+// hasRedactionVault reports whether a resume was uploaded blind
+func hasRedactionVault(resumeID string) bool {
+	_, err := os.Stat(redactionVaultPath(resumeID))
+	return err == nil
+}
+
+// unmaskIdentity returns who holds the unmask key sent with the request.
+// UNMASK_API_KEYS gives each person their own key as comma-separated
+// name:key pairs; the shared UNMASK_API_KEY is recorded as "shared-unmask-key".
+func unmaskIdentity(c *fiber.Ctx) (string, bool) {
+	provided := []byte(c.Get(unmaskKeyHeader))
+	if len(provided) == 0 {
+		return "", false
+	}
+	for _, pair := range strings.Split(os.Getenv("UNMASK_API_KEYS"), ",") {
+		name, key, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && name != "" && key != "" && subtle.ConstantTimeCompare([]byte(key), provided) == 1 {
+			return name, true
+		}
+	}
+	if key := os.Getenv("UNMASK_API_KEY"); key != "" && subtle.ConstantTimeCompare([]byte(key), provided) == 1 {
+		return "shared-unmask-key", true
+	}
+	return "", false
+}
+
+// requestActor names the caller for audit fields: the holder of the unmask
+// key sent with the request, or "anonymous" without one
+func requestActor(c *fiber.Ctx) string {
+	if actor, ok := unmaskIdentity(c); ok {
+		return actor
+	}
+	return "anonymous"
+}
+
+// GetScreeningSettings returns a job's blind screening configuration
+func GetScreeningSettings(c *fiber.Ctx) error {
+	return c.JSON(loadScreeningSettings(c.Params("id")))
+}
+
+// UpdateScreeningSettings turns blind screening on or off for a job. Turning
+// it off reveals everything masked at display time, so it needs the unmask key.
+func UpdateScreeningSettings(c *fiber.Ctx) error {
+	actor, ok := unmaskIdentity(c)
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not authorized to change screening settings",
+		})
+	}
+
+	var request struct {
+		BlindScreening bool `json:"blind_screening"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	jobID := c.Params("id")
+	if _, err := LoadTextData("job_"+cleanID(jobID, "job"), "job"); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description data not found",
+		})
+	}
+
+	settings := loadScreeningSettings(jobID)
+	settings.BlindScreening = request.BlindScreening
+	settings.UpdatedBy = actor
+	log.Printf("Blind screening for job %s set to %t by %s", settings.JobID, settings.BlindScreening, actor)
+	if err := saveScreeningSettings(settings); err != nil {
+		log.Printf("Error saving screening settings: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save screening settings",
+		})
+	}
+
+	return c.JSON(settings)
+}
+
+// ShortlistCandidate marks a resume as shortlisted for a blind job so it can
+// be unmasked. Shortlisting is what unlocks unmasking, so it needs the
+// unmask key too.
+func ShortlistCandidate(c *fiber.Ctx) error {
+	actor, ok := unmaskIdentity(c)
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not authorized to shortlist candidates",
+		})
+	}
+
+	var request struct {
+		ResumeID string `json:"resume_id"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.ResumeID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "resume_id is required",
+		})
+	}
+
+	jobID := c.Params("id")
+	if !jobExists(jobID) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description data not found",
+		})
+	}
+	if !isBlindScreeningJob(jobID) {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Job does not use blind screening",
+		})
+	}
+
+	resumeID := normalizeID(request.ResumeID, "resume")
+	if _, err := LoadTextData("resume_"+resumeID, "resume"); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume data not found",
+		})
+	}
+
+	settings := loadScreeningSettings(jobID)
+	for _, id := range settings.Shortlisted {
+		if id == resumeID {
+			return c.JSON(settings)
+		}
+	}
+	settings.Shortlisted = append(settings.Shortlisted, resumeID)
+	log.Printf("Candidate %s shortlisted for job %s by %s", resumeID, settings.JobID, actor)
+
+	if err := saveScreeningSettings(settings); err != nil {
+		log.Printf("Error saving shortlist: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save shortlist",
+		})
+	}
+
+	return c.JSON(settings)
+}
+
+// UnmaskCandidate reveals a blind-screened candidate's identity. The caller must
+// present an unmask key and the candidate must be shortlisted for the job.
+func UnmaskCandidate(c *fiber.Ctx) error {
+	actor, ok := unmaskIdentity(c)
+	if !ok {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Not authorized to unmask candidates",
+		})
+	}
+
+	jobID := c.Query("job_id")
+	if jobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "job_id is required",
+		})
+	}
+
+	resumeID := normalizeID(c.Params("id"), "resume")
+	settings := loadScreeningSettings(jobID)
+	shortlisted := false
+	for _, id := range settings.Shortlisted {
+		if id == resumeID {
+			shortlisted = true
+			break
+		}
+	}
+	if !shortlisted {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Candidate must be shortlisted before unmasking",
+		})
+	}
+
+	resumeData, err := LoadTextData("resume_"+resumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume data not found",
+		})
+	}
+
+	// Resumes processed without redaction have no vault; their stored entities
+	// are already the originals and were only masked at display time.
+	entities := resumeData.Entities
+	var vault RedactionVault
+	vaultPath := redactionVaultPath(resumeID)
+	if err := utils.LoadJSONFile(vaultPath, &vault); err == nil {
+		entities = unmaskEntities(entities, vault.Tokens)
+		vault.UnmaskLog = append(vault.UnmaskLog, UnmaskEvent{
+			JobID:     settings.JobID,
+			User:      actor,
+			Timestamp: time.Now(),
+		})
+		if err := utils.SaveJSONFile(vaultPath, vault, 0600); err != nil {
+			log.Printf("Error recording unmask event: %v", err)
+		}
+	} else if !os.IsNotExist(err) {
+		log.Printf("Error loading redaction vault: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load redaction vault",
+		})
+	}
+
+	log.Printf("Candidate %s unmasked for job %s by %s", resumeID, settings.JobID, actor)
+	return c.JSON(fiber.Map{
+		"resume_id": resumeID,
+		"job_id":    settings.JobID,
+		"entities":  entities,
+	})
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+const blindResumeText = `Jane Doe
+jane.doe@example.com | +1 (555) 123-4567 | linkedin.com/in/janedoe
+42 Baker Street, London
+BSc Computer Science, University of Leeds, 2012
+Software Engineer, Acme 2015 - 2020
+She led the platform team as chairman of the guild.`
+
+func TestRedactResumeText(t *testing.T) {
+	redacted, tokens := redactResumeText(blindResumeText)
+
+	tests := []struct {
+		name    string
+		removed string
+		token   string
+	}{
+		{"name", "Jane Doe", redactedNameToken},
+		{"email", "jane.doe@example.com", "REDACTED_EMAIL_1"},
+		{"phone", "+1 (555) 123-4567", "REDACTED_PHONE_1"},
+		{"profile URL", "linkedin.com/in/janedoe", "REDACTED_URL_1"},
+		{"address", "42 Baker Street", "REDACTED_ADDRESS_1"},
+		{"graduation year", "2012", "REDACTED_GRAD_YEAR_1"},
+		{"gendered pronoun", "She ", ""},
+		{"gendered title", "chairman", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if strings.Contains(redacted, tt.removed) {
+				t.Errorf("redacted text still contains %q:\n%s", tt.removed, redacted)
+			}
+			if tt.token == "" {
+				return
+			}
+			if !strings.Contains(redacted, tt.token) {
+				t.Errorf("redacted text has no %s token:\n%s", tt.token, redacted)
+			}
+			if !strings.HasPrefix(tokens[tt.token], tt.removed) {
+				t.Errorf("vault maps %s to %q, want %q", tt.token, tokens[tt.token], tt.removed)
+			}
+		})
+	}
+
+	// Employment dates are not an age proxy and look like phone numbers
+	for _, kept := range []string{"2015 - 2020", "They led", "chairperson"} {
+		if !strings.Contains(redacted, kept) {
+			t.Errorf("redacted text lost %q:\n%s", kept, redacted)
+		}
+	}
+}
+
+func TestUnmaskEntitiesRestoresVault(t *testing.T) {
+	_, tokens := redactResumeText(blindResumeText)
+	masked := ExtractedEntities{
+		Name:  redactedNameToken,
+		Phone: "Not provided",
+		Education: []Education{
+			{Institution: "University of Leeds", Year: "REDACTED_GRAD_YEAR_1"},
+		},
+		Experience: []Experience{
+			{Title: "Software Engineer", Description: "Based at REDACTED_ADDRESS_1, reachable at REDACTED_EMAIL_1"},
+		},
+		Projects: []Project{
+			{Name: "Portfolio", Description: "REDACTED_URL_1 and REDACTED_UNKNOWN_9"},
+		},
+	}
+
+	unmasked := unmaskEntities(masked, tokens)
+	tests := []struct {
+		field, got, want string
+	}{
+		{"name", unmasked.Name, "Jane Doe"},
+		{"phone", unmasked.Phone, "+1 (555) 123-4567"},
+		{"email", strings.Join(unmasked.Email, ","), "jane.doe@example.com"},
+		{"graduation year", unmasked.Education[0].Year, "2012"},
+		{"experience text", unmasked.Experience[0].Description, "Based at " + tokens["REDACTED_ADDRESS_1"] + ", reachable at jane.doe@example.com"},
+		{"unknown tokens stay", unmasked.Projects[0].Description, "linkedin.com/in/janedoe and REDACTED_UNKNOWN_9"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestRedactEntityText(t *testing.T) {
+	_, vault := redactResumeText(blindResumeText)
+	entities := ExtractedEntities{
+		Experience: []Experience{
+			{Description: "Jane Doe ran the team; he reported to her", Location: "42 Baker Street, London"},
+		},
+		Education:    []Education{{Institution: "University of Leeds", Year: "2012"}},
+		Certificates: []Certificate{{Name: "CKA for Jane Doe", URL: "https://example.com/jane"}},
+	}
+
+	redacted := redactEntityText(entities, vault)
+	tests := []struct {
+		field, got, want string
+	}{
+		{"description", redacted.Experience[0].Description, redactedNameToken + " ran the team; they reported to their"},
+		{"location", redacted.Experience[0].Location, "REDACTED_ADDRESS_1"},
+		{"certificate", redacted.Certificates[0].Name, "CKA for " + redactedNameToken},
+		{"certificate URL", redacted.Certificates[0].URL, ""},
+		// Years are only redacted on education lines, which entities don't have
+		{"education year", redacted.Education[0].Year, "2012"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+	if entities.Experience[0].Location != "42 Baker Street, London" {
+		t.Error("redactEntityText modified the caller's entities")
+	}
+}
+
+func TestAnonymizeScore(t *testing.T) {
+	score := ScoreResponse{
+		ProcessedEntities: ExtractedEntities{
+			Name:        "Jane Doe",
+			Email:       []string{"jane.doe@example.com"},
+			Phone:       "+1 555 123 4567",
+			Education:   []Education{{Degree: "BSc", Year: "2012", GraduationDate: "Jun 2012", Location: "Leeds"}},
+			Experience:  []Experience{{Title: "Engineer", Location: "London", Description: "She shipped it"}},
+			Projects:    []Project{{Name: "Guild", Team: []string{"Jane Doe", "Ann Lee"}}},
+			Preferences: CandidatePreferences{Location: "London", WorkAuthorization: "UK citizen"},
+		},
+		IntegrityFlags: []IntegrityFlag{
+			{Code: "hidden_keywords", Evidence: []string{"Jane Doe kubernetes"}},
+			{Code: "date_overlap", Evidence: []string{"Acme / Initech"}},
+		},
+		Knockouts: []KnockoutResult{{Field: "location", Candidate: "London", Status: "pass"}},
+	}
+
+	anon := anonymizeScore(score, "resume_42")
+	entities := anon.ProcessedEntities
+	tests := []struct {
+		field string
+		got   any
+		want  any
+	}{
+		{"name", entities.Name, candidatePseudonym("42")},
+		{"email", len(entities.Email), 0},
+		{"phone", entities.Phone, ""},
+		{"location preference", entities.Preferences.Location, ""},
+		{"work authorization", entities.Preferences.WorkAuthorization, ""},
+		{"graduation year", entities.Education[0].Year + entities.Education[0].GraduationDate, ""},
+		{"degree kept", entities.Education[0].Degree, "BSc"},
+		{"experience location", entities.Experience[0].Location, ""},
+		{"experience text", entities.Experience[0].Description, "They shipped it"},
+		{"project team", len(entities.Projects[0].Team), 0},
+		{"hidden keyword evidence", len(anon.IntegrityFlags[0].Evidence), 0},
+		{"other evidence kept", len(anon.IntegrityFlags[1].Evidence), 1},
+		{"knockout value", anon.Knockouts[0].Candidate, ""},
+		{"knockout status kept", anon.Knockouts[0].Status, "pass"},
+		{"original knockout untouched", score.Knockouts[0].Candidate, "London"},
+		{"original name untouched", score.ProcessedEntities.Name, "Jane Doe"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestCandidatePseudonymIsStable(t *testing.T) {
+	a, b := candidatePseudonym("resume_42"), candidatePseudonym("42")
+	if a != b || !strings.HasPrefix(a, "Candidate ") {
+		t.Errorf("pseudonyms %q and %q should match and start with Candidate", a, b)
+	}
+	if candidatePseudonym("43") == a {
+		t.Error("different resumes share a pseudonym")
+	}
+}
+
+func TestUnmaskIdentity(t *testing.T) {
+	t.Setenv("UNMASK_API_KEYS", "alice:k-alice, bob:k-bob,broken")
+	t.Setenv("UNMASK_API_KEY", "k-shared")
+
+	tests := []struct {
+		key    string
+		want   string
+		wantOK bool
+	}{
+		{"k-alice", "alice", true},
+		{"k-bob", "bob", true},
+		{"k-shared", "shared-unmask-key", true},
+		{"broken", "", false},
+		{"k-eve", "", false},
+		{"", "", false},
+	}
+	app := fiber.New()
+	app.Get("/", func(c *fiber.Ctx) error {
+		actor, ok := unmaskIdentity(c)
+		return c.JSON(fiber.Map{"actor": actor, "ok": ok, "request_actor": requestActor(c)})
+	})
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set(unmaskKeyHeader, tt.key)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got struct {
+				Actor        string `json:"actor"`
+				OK           bool   `json:"ok"`
+				RequestActor string `json:"request_actor"`
+			}
+			json.NewDecoder(resp.Body).Decode(&got)
+			if got.Actor != tt.want || got.OK != tt.wantOK {
+				t.Errorf("unmaskIdentity = (%q, %v), want (%q, %v)", got.Actor, got.OK, tt.want, tt.wantOK)
+			}
+			if want := map[bool]string{true: tt.want, false: "anonymous"}[tt.wantOK]; got.RequestActor != want {
+				t.Errorf("requestActor = %q, want %q", got.RequestActor, want)
+			}
+		})
+	}
+}
+
+func TestBlindScreeningEndpoints(t *testing.T) {
+	t.Setenv("UNMASK_API_KEYS", "alice:k-alice")
+	saveTestText(t, "job", "9001", TextData{ProcessedText: "Backend engineer"})
+	saveTestText(t, "resume", "9002", TextData{
+		ProcessedText: "REDACTED_NAME",
+		Entities:      ExtractedEntities{Name: redactedNameToken},
+	})
+	if err := utils.SaveJSONFile(redactionVaultPath("9002"), RedactionVault{
+		ResumeID: "9002",
+		Tokens:   map[string]string{redactedNameToken: "Jane Doe"},
+	}, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Put("/jobs/:id/screening", UpdateScreeningSettings)
+	app.Post("/jobs/:id/shortlist", ShortlistCandidate)
+	app.Get("/resumes/:id/unmask", UnmaskCandidate)
+
+	steps := []struct {
+		name, method, path, body, key string
+		want                          int
+		wantBody                      string
+	}{
+		{"settings need a key", "PUT", "/jobs/9001/screening", `{"blind_screening":true}`, "", 403, "Not authorized"},
+		{"settings reject a wrong key", "PUT", "/jobs/9001/screening", `{"blind_screening":true}`, "k-eve", 403, "Not authorized"},
+		{"settings for a missing job", "PUT", "/jobs/404/screening", `{"blind_screening":true}`, "k-alice", 404, ""},
+		{"settings record who changed them", "PUT", "/jobs/9001/screening", `{"blind_screening":true}`, "k-alice", 200, `"updated_by":"alice"`},
+		{"unmask before shortlisting", "GET", "/resumes/9002/unmask?job_id=9001", "", "k-alice", 403, "shortlisted"},
+		{"shortlist needs a key", "POST", "/jobs/9001/shortlist", `{"resume_id":"9002"}`, "", 403, "Not authorized"},
+		{"shortlist", "POST", "/jobs/9001/shortlist", `{"resume_id":"9002"}`, "k-alice", 200, `"shortlisted":["9002"]`},
+		{"unmask needs a key", "GET", "/resumes/9002/unmask?job_id=9001", "", "", 403, "Not authorized"},
+		{"unmask restores the name", "GET", "/resumes/9002/unmask?job_id=9001", "", "k-alice", 200, `"name":"Jane Doe"`},
+	}
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.path, strings.NewReader(step.body))
+		req.Header.Set("Content-Type", "application/json")
+		if step.key != "" {
+			req.Header.Set(unmaskKeyHeader, step.key)
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != step.want || !strings.Contains(string(body), step.wantBody) {
+			t.Fatalf("%s: got %d %s, want %d containing %q", step.name, resp.StatusCode, body, step.want, step.wantBody)
+		}
+	}
+
+	var vault RedactionVault
+	if err := utils.LoadJSONFile(redactionVaultPath("9002"), &vault); err != nil {
+		t.Fatal(err)
+	}
+	if len(vault.UnmaskLog) != 1 || vault.UnmaskLog[0].User != "alice" || vault.UnmaskLog[0].JobID != "9001" {
+		t.Errorf("unmask log = %+v, want one entry by alice for job 9001", vault.UnmaskLog)
+	}
+}
@@ -0,0 +1,629 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+// ATS connector stages, recorded as each step finishes so retries resume
+// where the last attempt stopped
+const (
+	ATSStageReceived     = "received"
+	ATSStagePreprocessed = "preprocessed"
+	ATSStageScored       = "scored"
+	ATSStagePosted       = "posted"
+)
+
+const atsRequestTimeout = 60 * time.Second
+
+var (
+	// Events that mean a new application, per vendor naming
+	atsApplicationEvents = map[string]bool{
+		"new_candidate_application": true, // Greenhouse
+		"applicationCreated":        true, // Lever
+		"application_created":       true,
+		"application.created":       true,
+	}
+	atsSignatureHeaders = []string{"Signature", "X-Signature", "X-ATS-Signature"}
+
+	atsClient = &http.Client{Timeout: atsRequestTimeout}
+
+	// Serialises read-modify-write of application records and the job map
+	atsMu sync.Mutex
+)
+
+// ATSApplication tracks one application received from the ATS
+type ATSApplication struct {
+	ID             string     `json:"id"` // ATS application ID
+	Event          string     `json:"event"`
+	CandidateID    string     `json:"candidate_id,omitempty"` // ATS candidate ID
+	ATSJobID       string     `json:"ats_job_id,omitempty"`
+	JobID          string     `json:"job_id,omitempty"`
+	ResumeURL      string     `json:"resume_url,omitempty"`
+	ResumeFilename string     `json:"resume_filename,omitempty"`
+	Status         string     `json:"status"`
+	Stage          string     `json:"stage"`
+	TaskID         string     `json:"task_id,omitempty"`
+	ResumeID       string     `json:"resume_id,omitempty"`
+	LocalCandidate string     `json:"local_candidate_id,omitempty"`
+	OverallScore   *float64   `json:"overall_score,omitempty"`
+	KnockedOut     bool       `json:"knocked_out,omitempty"`
+	IdempotencyKey string     `json:"idempotency_key,omitempty"`
+	PostedAt       *time.Time `json:"posted_at,omitempty"`
+	Deliveries     int        `json:"deliveries"`
+	Error          string     `json:"error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// ATSScorePayload is what the connector posts back to the ATS
+type ATSScorePayload struct {
+	ApplicationID string             `json:"application_id"`
+	CandidateID   string             `json:"candidate_id,omitempty"`
+	JobID         string             `json:"job_id,omitempty"`
+	Score         float64            `json:"score"`
+	Status        string             `json:"status"` // completed or disqualified
+	Details       map[string]float64 `json:"details"`
+	Feedback      []string           `json:"feedback"`
+	ScoredAt      time.Time          `json:"scored_at"`
+}
+
+// atsID accepts IDs sent as JSON numbers (Greenhouse) or strings (Lever)
+type atsID string
+
+func (id *atsID) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*id = atsID(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = atsID(n.String())
+	return nil
+}
+
+type atsAttachment struct {
+	Filename string `json:"filename"`
+	URL      string `json:"url"`
+	Type     string `json:"type"`
+}
+
+// atsWebhook covers the Greenhouse shape ({action, payload.application}) and
+// the Lever-style shape ({event, data})
+type atsWebhook struct {
+	Action  string `json:"action"`
+	Payload struct {
+		Application struct {
+			ID        atsID `json:"id"`
+			Candidate struct {
+				ID          atsID           `json:"id"`
+				Attachments []atsAttachment `json:"attachments"`
+			} `json:"candidate"`
+			Jobs []struct {
+				ID atsID `json:"id"`
+			} `json:"jobs"`
+			Attachments []atsAttachment `json:"attachments"`
+		} `json:"application"`
+	} `json:"payload"`
+
+	Event string `json:"event"`
+	Data  struct {
+		ApplicationID  atsID  `json:"applicationId"`
+		CandidateID    atsID  `json:"candidateId"`
+		OpportunityID  atsID  `json:"opportunityId"`
+		PostingID      atsID  `json:"postingId"`
+		ResumeURL      string `json:"resumeUrl"`
+		ResumeFilename string `json:"resumeFilename"`
+	} `json:"data"`
+}
+
+// application normalises either webhook shape into a new record
+func (w *atsWebhook) application() *ATSApplication {
+	if w.Action != "" {
+		app := w.Payload.Application
+		record := &ATSApplication{
+			Event:       w.Action,
+			ID:          string(app.ID),
+			CandidateID: string(app.Candidate.ID),
+		}
+		if len(app.Jobs) > 0 {
+			record.ATSJobID = string(app.Jobs[0].ID)
+		}
+		attachments := append(append([]atsAttachment{}, app.Attachments...), app.Candidate.Attachments...)
+		for _, attachment := range attachments {
+			if strings.EqualFold(attachment.Type, "resume") && record.ResumeURL == "" {
+				record.ResumeURL, record.ResumeFilename = attachment.URL, attachment.Filename
+			}
+		}
+		return record
+	}
+
+	candidateID := w.Data.CandidateID
+	if candidateID == "" {
+		candidateID = w.Data.OpportunityID
+	}
+	return &ATSApplication{
+		Event:          w.Event,
+		ID:             string(w.Data.ApplicationID),
+		CandidateID:    string(candidateID),
+		ATSJobID:       string(w.Data.PostingID),
+		ResumeURL:      w.Data.ResumeURL,
+		ResumeFilename: w.Data.ResumeFilename,
+	}
+}
+
+func atsDir() string {
+	return filepath.Join("processed_texts", "ats")
+}
+
+func atsApplicationPath(id string) string {
+	return filepath.Join(atsDir(), "applications", safeUploadName(id)+".json")
+}
+
+func loadATSApplication(id string) (*ATSApplication, error) {
+	var record ATSApplication
+	if err := utils.LoadJSONFile(atsApplicationPath(id), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func saveATSApplication(record *ATSApplication) error {
+	record.UpdatedAt = time.Now()
+	return utils.SaveJSONFile(atsApplicationPath(record.ID), record, 0644)
+}
+
+// updateATSApplication applies fn to a stored record under the ATS lock
+func updateATSApplication(id string, fn func(*ATSApplication)) (*ATSApplication, error) {
+	atsMu.Lock()
+	defer atsMu.Unlock()
+	record, err := loadATSApplication(id)
+	if err != nil {
+		return nil, err
+	}
+	fn(record)
+	return record, saveATSApplication(record)
+}
+
+func loadATSJobMap() map[string]string {
+	jobs := make(map[string]string)
+	if err := utils.LoadJSONFile(filepath.Join(atsDir(), "job_map.json"), &jobs); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error reading ATS job map: %v", err)
+	}
+	return jobs
+}
+
+// mapATSJob returns the local job for an ATS job: an explicit mapping, a
+// local job with the same ID, or ATS_DEFAULT_JOB_ID
+func mapATSJob(atsJobID string) string {
+	if jobID := loadATSJobMap()[atsJobID]; jobID != "" {
+		return jobID
+	}
+	if atsJobID != "" && jobExists(atsJobID) {
+		return normalizeID(atsJobID, "job")
+	}
+	if jobID := os.Getenv("ATS_DEFAULT_JOB_ID"); jobID != "" {
+		return normalizeID(jobID, "job")
+	}
+	return ""
+}
+
+// atsAuthorize adds the ATS API key (as the Basic auth username, which both
+// Greenhouse and Lever use) to requests bound for the ATS API host
+func atsAuthorize(req *http.Request) {
+	key := os.Getenv("ATS_API_KEY")
+	base, err := url.Parse(os.Getenv("ATS_API_URL"))
+	if key == "" || err != nil || base.Host == "" || !strings.EqualFold(base.Host, req.URL.Host) {
+		return
+	}
+	req.SetBasicAuth(key, "")
+}
+
+// queueATSApplication hands an application to the task workers
+func queueATSApplication(record *ATSApplication) error {
+	task := &AnalysisTask{
+		Type:   "ats_application",
+		JobID:  record.JobID,
+		Params: map[string]string{"application_id": record.ID},
+	}
+	if err := tasks.enqueue(task); err != nil {
+		return err
+	}
+	record.TaskID, record.Status, record.Error = task.ID, TaskQueued, ""
+	return nil
+}
+
+// runATSApplicationTask downloads, preprocesses and scores an application
+// and posts the score back. Finished stages are skipped on retry.
+func runATSApplicationTask(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+	id := task.Params["application_id"]
+	record, err := updateATSApplication(id, func(r *ATSApplication) { r.Status = TaskRunning })
+	if err != nil {
+		return nil, fmt.Errorf("ATS application %s not found: %v", id, err)
+	}
+
+	fail := func(err error) (any, error) {
+		updateATSApplication(id, func(r *ATSApplication) { r.Status, r.Error = TaskFailed, err.Error() })
+		return nil, err
+	}
+	// The local copy moves on even if saving fails, so later stages see it
+	setStage := func(fn func(*ATSApplication)) {
+		if updated, err := updateATSApplication(id, fn); err == nil {
+			record = updated
+		} else {
+			log.Printf("Error saving ATS application %s: %v", id, err)
+			fn(record)
+		}
+	}
+
+	if record.ResumeID == "" {
+		progress.report("downloading", 0, 3, nil)
+		result, err := preprocessATSResume(ctx, record)
+		if err != nil {
+			return fail(err)
+		}
+		setStage(func(r *ATSApplication) {
+			r.Stage, r.ResumeID, r.LocalCandidate = ATSStagePreprocessed, result.ID, result.CandidateID
+		})
+	}
+
+	progress.report("scoring", 1, 3, nil)
+	resumeData, err := LoadTextData(record.ResumeID, "resume")
+	if err != nil {
+		return fail(fmt.Errorf("resume data not found: %v", err))
+	}
+	jobData, err := LoadTextData(record.JobID, "job")
+	if err != nil {
+		return fail(fmt.Errorf("job data not found: %v", err))
+	}
+	score := scoreResumeAgainstJob(resumeData, jobData, nil)
+	answers := savedKnockoutAnswers(record.ResumeID, record.JobID)
+	reapplyKnockoutAnswers(&score, resumeData, jobData.Requirements, answers)
+	if err := saveScoreRecord(record.ResumeID, record.JobID, jobData.Version, score, answers); err != nil {
+		log.Printf("Error saving score record: %v", err)
+	}
+	emitScoreCompleted(record.ResumeID, record.JobID, jobData.Version, score, answers)
+	overall := math.Round(score.OverallScore*100) / 100
+	setStage(func(r *ATSApplication) {
+		r.Stage, r.OverallScore, r.KnockedOut = ATSStageScored, &overall, score.KnockedOut
+		// Stable for a resume and job version, so a retried post can't be
+		// recorded twice but a rescore against a new version still goes out
+		r.IdempotencyKey = fmt.Sprintf("score-%s-%s-%s-v%d", r.ID, r.ResumeID, r.JobID, max(jobData.Version, 1))
+	})
+
+	progress.report("posting", 2, 3, nil)
+	if err := postATSScore(ctx, record, score); err != nil {
+		return fail(err)
+	}
+	setStage(func(r *ATSApplication) {
+		now := time.Now()
+		r.Status, r.Error = TaskCompleted, ""
+		if os.Getenv("ATS_API_URL") != "" {
+			r.Stage, r.PostedAt = ATSStagePosted, &now
+		}
+	})
+	return record, nil
+}
+
+// preprocessATSResume downloads the application's resume and runs it through
+// the usual pipeline
+func preprocessATSResume(ctx context.Context, record *ATSApplication) (*PreprocessedData, error) {
+	source, err := url.Parse(record.ResumeURL)
+	if err != nil || (source.Scheme != "http" && source.Scheme != "https") {
+		return nil, fmt.Errorf("resume URL %q is not an http(s) URL", record.ResumeURL)
+	}
+	name := record.ResumeFilename
+	if name == "" {
+		name = path.Base(source.Path)
+	}
+	if !ingestExtensions[strings.ToLower(filepath.Ext(name))] {
+		return nil, fmt.Errorf("resume %q is not a PDF, DOCX or JSON Resume file", name)
+	}
+
+	content, _, err := utils.DoWithRetry(ctx, atsClient, utils.DefaultRetryPolicy, maxBulkFileBytes, func() (*http.Request, error) {
+		req, err := http.NewRequest(http.MethodGet, source.String(), nil)
+		if err == nil {
+			atsAuthorize(req)
+		}
+		return req, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("downloading resume: %v", err)
+	}
+
+	stagedPath := filepath.Join("uploads", "ats", fmt.Sprintf("%s-%s", safeUploadName(record.ID), safeUploadName(name)))
+	if err := os.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(stagedPath, content, 0644); err != nil {
+		return nil, err
+	}
+	return processResumeFile(stagedPath, name, resumeProcessingOptions{
+		JobID: record.JobID,
+		Source: map[string]string{
+			"channel":            "ats",
+			"ats_application_id": record.ID,
+			"ats_candidate_id":   record.CandidateID,
+		},
+	}, nil)
+}
+
+// postATSScore sends the score to ATS_API_URL/applications/<id>/scores. It is
+// a no-op when no ATS API is configured.
+func postATSScore(ctx context.Context, record *ATSApplication, score ScoreResponse) error {
+	base := strings.TrimRight(os.Getenv("ATS_API_URL"), "/")
+	if base == "" {
+		return nil
+	}
+
+	details := map[string]float64{
+		"experience_match": score.ExperienceMatch,
+		"education_match":  score.EducationMatch,
+		"logistics_fit":    score.LogisticsFit.Score,
+	}
+	for name, value := range score.DetailedScores {
+		details[name] = value
+	}
+	for name, value := range details {
+		details[name] = math.Round(value*100) / 100
+	}
+	payload := ATSScorePayload{
+		ApplicationID: record.ID,
+		CandidateID:   record.CandidateID,
+		JobID:         record.ATSJobID,
+		Score:         math.Round(score.OverallScore*100) / 100,
+		Status:        "completed",
+		Details:       details,
+		Feedback:      score.Feedback,
+		ScoredAt:      time.Now(),
+	}
+	if score.KnockedOut {
+		payload.Status = "disqualified"
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	endpoint := fmt.Sprintf("%s/applications/%s/scores", base, url.PathEscape(record.ID))
+	secret := os.Getenv("ATS_WEBHOOK_SECRET")
+	_, _, err = utils.DoWithRetry(ctx, atsClient, utils.DefaultRetryPolicy, 1<<20, func() (*http.Request, error) {
+		req, err := utils.NewJSONRequest(endpoint, body, map[string]string{
+			"Idempotency-Key": record.IdempotencyKey,
+			"X-Signature":     "sha256=" + utils.SignPayload(secret, body),
+		})
+		if err == nil {
+			atsAuthorize(req)
+		}
+		return req, err
+	})
+	if err != nil {
+		return fmt.Errorf("posting score to ATS: %v", err)
+	}
+	return nil
+}
+
+// ReceiveATSWebhook accepts signed "application created" webhooks. Deliveries
+// are idempotent per application: repeats are acknowledged without
+// reprocessing unless the earlier attempt failed.
+func ReceiveATSWebhook(c *fiber.Ctx) error {
+	secret := os.Getenv("ATS_WEBHOOK_SECRET")
+	if secret == "" {
+		return c.Status(503).JSON(fiber.Map{
+			"error": "ATS webhooks are not configured",
+		})
+	}
+	body := c.Body()
+	signature := ""
+	for _, header := range atsSignatureHeaders {
+		if signature = c.Get(header); signature != "" {
+			break
+		}
+	}
+	if !utils.VerifySignature(secret, body, signature) {
+		return c.Status(401).JSON(fiber.Map{
+			"error": "Invalid webhook signature",
+		})
+	}
+
+	var webhook atsWebhook
+	if err := json.Unmarshal(body, &webhook); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid webhook payload",
+		})
+	}
+	incoming := webhook.application()
+	if !atsApplicationEvents[incoming.Event] {
+		return c.JSON(fiber.Map{
+			"ignored": true,
+			"event":   incoming.Event,
+		})
+	}
+	if incoming.ID == "" || incoming.ResumeURL == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Webhook has no application ID or resume attachment",
+		})
+	}
+
+	atsMu.Lock()
+	defer atsMu.Unlock()
+	record, err := loadATSApplication(incoming.ID)
+	if err == nil {
+		record.Deliveries++
+		if record.Status != TaskFailed {
+			saveATSApplication(record)
+			return c.JSON(fiber.Map{
+				"duplicate":   true,
+				"application": record,
+			})
+		}
+		// A redelivery retries a failed application with the latest job
+		// mapping and resume; a new resume is downloaded again
+		if incoming.ResumeURL != record.ResumeURL || incoming.ResumeFilename != record.ResumeFilename {
+			record.ResumeURL, record.ResumeFilename = incoming.ResumeURL, incoming.ResumeFilename
+			record.ResumeID, record.LocalCandidate, record.Stage = "", "", ATSStageReceived
+		}
+		if record.JobID == "" {
+			record.JobID = mapATSJob(record.ATSJobID)
+		}
+	} else {
+		record = incoming
+		record.Deliveries = 1
+		record.Stage = ATSStageReceived
+		record.CreatedAt = time.Now()
+		record.JobID = mapATSJob(record.ATSJobID)
+	}
+
+	if record.JobID == "" {
+		record.Status = TaskFailed
+		record.Error = fmt.Sprintf("No local job is mapped to ATS job %q", record.ATSJobID)
+	} else if err := queueATSApplication(record); err != nil {
+		log.Printf("Error queueing ATS application %s: %v", record.ID, err)
+		record.Status, record.Error = TaskFailed, "Failed to queue application"
+	}
+	if err := saveATSApplication(record); err != nil {
+		log.Printf("Error saving ATS application %s: %v", record.ID, err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save application",
+		})
+	}
+	return c.Status(202).JSON(record)
+}
+
+// ListATSApplications lists received applications, newest first
+func ListATSApplications(c *fiber.Ctx) error {
+	paths, err := filepath.Glob(filepath.Join(atsDir(), "applications", "*.json"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list applications",
+		})
+	}
+	status := c.Query("status")
+	applications := make([]ATSApplication, 0, len(paths))
+	for _, p := range paths {
+		var record ATSApplication
+		if err := utils.LoadJSONFile(p, &record); err != nil {
+			continue
+		}
+		if status == "" || record.Status == status {
+			applications = append(applications, record)
+		}
+	}
+	sort.Slice(applications, func(i, j int) bool {
+		return applications[i].CreatedAt.After(applications[j].CreatedAt)
+	})
+	return c.JSON(fiber.Map{
+		"count":        len(applications),
+		"applications": applications,
+	})
+}
+
+// GetATSApplication returns one application's progress
+func GetATSApplication(c *fiber.Ctx) error {
+	record, err := loadATSApplication(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Application not found",
+		})
+	}
+	return c.JSON(record)
+}
+
+// RetryATSApplication requeues a failed application, e.g. after fixing the job map
+func RetryATSApplication(c *fiber.Ctx) error {
+	atsMu.Lock()
+	defer atsMu.Unlock()
+	record, err := loadATSApplication(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Application not found",
+		})
+	}
+	if record.Status != TaskFailed {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Only failed applications can be retried",
+		})
+	}
+	if record.JobID == "" {
+		if record.JobID = mapATSJob(record.ATSJobID); record.JobID == "" {
+			return c.Status(422).JSON(fiber.Map{
+				"error": fmt.Sprintf("No local job is mapped to ATS job %q", record.ATSJobID),
+			})
+		}
+	}
+	if err := queueATSApplication(record); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to queue application",
+		})
+	}
+	if err := saveATSApplication(record); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save application",
+		})
+	}
+	return c.Status(202).JSON(record)
+}
+
+// GetATSJobMap returns the ATS job to local job mapping
+func GetATSJobMap(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"jobs":           loadATSJobMap(),
+		"default_job_id": os.Getenv("ATS_DEFAULT_JOB_ID"),
+	})
+}
+
+// UpdateATSJobMap maps ATS job IDs to local jobs; an empty local ID removes
+// the mapping
+func UpdateATSJobMap(c *fiber.Ctx) error {
+	var request struct {
+		Jobs map[string]string `json:"jobs"`
+	}
+	if err := c.BodyParser(&request); err != nil || len(request.Jobs) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Expected {\"jobs\": {\"<ats job id>\": \"<job id>\"}}",
+		})
+	}
+
+	atsMu.Lock()
+	defer atsMu.Unlock()
+	jobs := loadATSJobMap()
+	for atsJobID, jobID := range request.Jobs {
+		if jobID == "" {
+			delete(jobs, atsJobID)
+			continue
+		}
+		if !jobExists(jobID) {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Job " + jobID + " not found",
+			})
+		}
+		jobs[atsJobID] = normalizeID(jobID, "job")
+	}
+	if err := utils.SaveJSONFile(filepath.Join(atsDir(), "job_map.json"), jobs, 0644); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save job map",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"jobs": jobs,
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+func TestReceiveATSWebhookSignature(t *testing.T) {
+	t.Setenv("ATS_WEBHOOK_SECRET", "s3cret")
+	app := fiber.New()
+	app.Post("/webhook", ReceiveATSWebhook)
+
+	// An event we don't act on, so an accepted delivery has no side effects
+	body := []byte(`{"event":"candidate.updated","data":{"applicationId":"a1"}}`)
+	valid := utils.SignPayload("s3cret", body)
+
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		body   []byte
+		want   int
+	}{
+		{"Signature header", "Signature", valid, body, 200},
+		{"X-Signature with prefix", "X-Signature", "sha256=" + valid, body, 200},
+		{"X-ATS-Signature", "X-ATS-Signature", valid, body, 200},
+		{"missing signature", "", "", body, 401},
+		{"signed with another secret", "X-Signature", utils.SignPayload("other", body), body, 401},
+		{"body changed after signing", "X-Signature", valid, append([]byte(" "), body...), 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/webhook", bytes.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.value)
+			}
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestReceiveATSWebhookUnconfigured(t *testing.T) {
+	t.Setenv("ATS_WEBHOOK_SECRET", "")
+	app := fiber.New()
+	app.Post("/webhook", ReceiveATSWebhook)
+
+	resp, err := app.Test(httptest.NewRequest("POST", "/webhook", bytes.NewReader([]byte(`{}`))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != 503 {
+		t.Errorf("status = %d, want 503 when no secret is configured", resp.StatusCode)
+	}
+}
+
+func TestATSWebhookApplication(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want ATSApplication
+	}{
+		{
+			"Greenhouse",
+			`{"action":"new_candidate_application","payload":{"application":{"id":101,"candidate":{"id":7,
+			"attachments":[{"type":"cover_letter","url":"https://x/cl.pdf","filename":"cl.pdf"},
+			{"type":"resume","url":"https://x/cv.pdf","filename":"cv.pdf"}]},"jobs":[{"id":55}]}}}`,
+			ATSApplication{Event: "new_candidate_application", ID: "101", CandidateID: "7", ATSJobID: "55",
+				ResumeURL: "https://x/cv.pdf", ResumeFilename: "cv.pdf"},
+		},
+		{
+			"Lever",
+			`{"event":"applicationCreated","data":{"applicationId":"ap-1","opportunityId":"op-2","postingId":"po-3",
+			"resumeUrl":"https://x/r.docx","resumeFilename":"r.docx"}}`,
+			ATSApplication{Event: "applicationCreated", ID: "ap-1", CandidateID: "op-2", ATSJobID: "po-3",
+				ResumeURL: "https://x/r.docx", ResumeFilename: "r.docx"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var webhook atsWebhook
+			if err := json.Unmarshal([]byte(tt.body), &webhook); err != nil {
+				t.Fatal(err)
+			}
+			got := webhook.application()
+			if got.Event != tt.want.Event || got.ID != tt.want.ID || got.CandidateID != tt.want.CandidateID ||
+				got.ATSJobID != tt.want.ATSJobID || got.ResumeURL != tt.want.ResumeURL || got.ResumeFilename != tt.want.ResumeFilename {
+				t.Errorf("application() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
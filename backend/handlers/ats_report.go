@@ -0,0 +1,279 @@
+package handlers
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Keyword match states
+const (
+	KeywordExact   = "exact"
+	KeywordSynonym = "synonym"
+	KeywordMissing = "missing"
+)
+
+const (
+	keywordStuffingDensity = 3.0 // Percent of all words; above this reads as stuffing
+	synonymCredit          = 0.5 // ATS keyword filters rarely give full credit for synonyms
+)
+
+var requiredSections = []string{"experience", "education", "skills"}
+
+// ATSKeyword is how one job keyword shows up in the resume
+type ATSKeyword struct {
+	Keyword     string  `json:"keyword"`
+	Status      string  `json:"status"`
+	ResumeTerm  string  `json:"resume_term,omitempty"`
+	Occurrences int     `json:"occurrences"`
+	Density     float64 `json:"density"` // percent of resume words
+}
+
+// ATSSectionReport covers section-header recognizability
+type ATSSectionReport struct {
+	Recognized   []string `json:"recognized"`
+	Unrecognized []string `json:"unrecognized"`
+	Missing      []string `json:"missing"`
+}
+
+// ATSFix is one recommended change, ranked by expected impact
+type ATSFix struct {
+	Rank     int     `json:"rank"`
+	Impact   string  `json:"impact"`
+	Category string  `json:"category"`
+	Action   string  `json:"action"`
+	weight   float64 // ranking weight
+}
+
+// ATSReport explains how an ATS would parse and keyword-match a resume
+type ATSReport struct {
+	ResumeID             string           `json:"resume_id"`
+	JobID                string           `json:"job_id"`
+	GeneratedAt          time.Time        `json:"generated_at"`
+	ATSScore             float64          `json:"ats_score"`
+	KeywordCoverage      float64          `json:"keyword_coverage"` // percent, synonyms at partial credit
+	ExactCoverage        float64          `json:"exact_coverage"`   // percent
+	Keywords             []ATSKeyword     `json:"keywords"`
+	MissingKeywords      []string         `json:"missing_keywords"`
+	SynonymOnly          []ATSKeyword     `json:"synonym_only"`
+	OverusedKeywords     []string         `json:"overused_keywords"`
+	Sections             ATSSectionReport `json:"sections"`
+	ParseIssues          []ParseIssue     `json:"parse_issues"`
+	DiagnosticsAvailable bool             `json:"diagnostics_available"`
+	Fixes                []ATSFix         `json:"fixes"`
+}
+
+// GetATSReport builds an ATS keyword optimization report for a resume
+// against a job
+func GetATSReport(c *fiber.Ctx) error {
+	resumeID := normalizeID(c.Query("resume_id"), "resume")
+	jobID := normalizeID(c.Query("job_id"), "job")
+	if resumeID == "" || jobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+
+	resumeData, jobData, err := loadTaskPair(&AnalysisTask{ResumeID: resumeID, JobID: jobID})
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	// Resumes uploaded before diagnostics existed still get a keyword report
+	diagnostics, _ := loadExtractionDiagnostics(resumeID)
+	return c.JSON(buildATSReport(resumeID, resumeData, jobID, jobData, diagnostics))
+}
+
+func buildATSReport(resumeID string, resumeData *TextData, jobID string, jobData *TextData, diagnostics *ExtractionDiagnostics) ATSReport {
+	report := ATSReport{
+		ResumeID:         resumeID,
+		JobID:            jobID,
+		GeneratedAt:      time.Now(),
+		Keywords:         []ATSKeyword{},
+		MissingKeywords:  []string{},
+		SynonymOnly:      []ATSKeyword{},
+		OverusedKeywords: []string{},
+		ParseIssues:      []ParseIssue{},
+		Fixes:            []ATSFix{},
+	}
+
+	keywords := uniqueStrings(append(append([]string{}, jobData.Requirements.Skills...), jobData.Requirements.Experience.Areas...))
+	text := resumeData.ProcessedText
+	wordCount := len(strings.Fields(text))
+
+	_, partialMatches, _ := analyzeSkillMatches(resumeData.Entities.Skills, keywords)
+	partialByKeyword := make(map[string]PartialMatch)
+	for _, pm := range partialMatches {
+		partialByKeyword[strings.ToLower(pm.JobSkill)] = pm
+	}
+	resumeSkills := make(map[string]bool)
+	for _, skill := range resumeData.Entities.Skills {
+		resumeSkills[strings.ToLower(skill)] = true
+	}
+
+	var credit float64
+	exact := 0
+	for _, keyword := range keywords {
+		kw := ATSKeyword{Keyword: keyword, Occurrences: countPhrase(text, keyword)}
+		if wordCount > 0 {
+			kw.Density = float64(kw.Occurrences*len(strings.Fields(keyword))) / float64(wordCount) * 100
+		}
+
+		// The ATS sees the exact phrase either in the skills list or the body
+		switch pm, partial := partialByKeyword[strings.ToLower(keyword)]; {
+		case resumeSkills[strings.ToLower(keyword)] || kw.Occurrences > 0:
+			kw.Status = KeywordExact
+			credit++
+			exact++
+		case partial:
+			kw.Status = KeywordSynonym
+			kw.ResumeTerm = pm.ResumeSkill
+			credit += synonymCredit
+			report.SynonymOnly = append(report.SynonymOnly, kw)
+		default:
+			kw.Status = KeywordMissing
+			report.MissingKeywords = append(report.MissingKeywords, keyword)
+		}
+
+		if kw.Density > keywordStuffingDensity {
+			report.OverusedKeywords = append(report.OverusedKeywords, keyword)
+		}
+		report.Keywords = append(report.Keywords, kw)
+	}
+	if len(keywords) > 0 {
+		report.KeywordCoverage = credit / float64(len(keywords)) * 100
+		report.ExactCoverage = float64(exact) / float64(len(keywords)) * 100
+	} else {
+		report.KeywordCoverage, report.ExactCoverage = 100, 100
+	}
+
+	// Section headers and parse problems come from extraction time
+	report.Sections = ATSSectionReport{Recognized: []string{}, Unrecognized: []string{}, Missing: []string{}}
+	parseScore := 1.0
+	sectionScore := 1.0
+	if diagnostics != nil {
+		report.DiagnosticsAvailable = true
+		report.Sections.Recognized = diagnostics.SectionHeaders
+		report.Sections.Unrecognized = diagnostics.UnrecognizedHeaders
+		found := make(map[string]bool)
+		for _, section := range diagnostics.SectionHeaders {
+			found[section] = true
+		}
+		for _, section := range requiredSections {
+			if !found[section] {
+				report.Sections.Missing = append(report.Sections.Missing, section)
+			}
+		}
+		sectionScore = 1 - float64(len(report.Sections.Missing))/float64(len(requiredSections))
+
+		for _, issue := range diagnostics.Issues {
+			if strings.HasPrefix(issue.Code, "missing_section_") || issue.Code == "nonstandard_headers" {
+				continue // reported under sections
+			}
+			report.ParseIssues = append(report.ParseIssues, issue)
+			parseScore -= issuePenalty(issue.Severity)
+		}
+		parseScore = math.Max(parseScore, 0)
+	}
+
+	report.ATSScore = report.KeywordCoverage*0.6 + sectionScore*100*0.2 + parseScore*100*0.2
+	report.Fixes = rankATSFixes(report, len(keywords))
+	return report
+}
+
+func issuePenalty(severity string) float64 {
+	switch severity {
+	case IssueHigh:
+		return 0.35
+	case IssueMedium:
+		return 0.15
+	default:
+		return 0.05
+	}
+}
+
+// countPhrase counts case-insensitive whole-phrase occurrences
+func countPhrase(text, phrase string) int {
+	phrase = strings.TrimSpace(phrase)
+	if phrase == "" {
+		return 0
+	}
+	pattern, err := regexp.Compile(`(?i)(^|[^\w])` + regexp.QuoteMeta(phrase) + `($|[^\w])`)
+	if err != nil {
+		return 0
+	}
+	return len(pattern.FindAllStringIndex(text, -1))
+}
+
+// rankATSFixes turns the report's findings into a fix list ordered by impact
+func rankATSFixes(report ATSReport, keywordCount int) []ATSFix {
+	var fixes []ATSFix
+	add := func(weight float64, category, action string) {
+		fixes = append(fixes, ATSFix{Category: category, Action: action, weight: weight})
+	}
+
+	for _, issue := range report.ParseIssues {
+		add(issueFixWeight(issue.Severity), "parsing", issue.Message)
+	}
+	if keywordCount > 0 {
+		// Each missing keyword is worth its share of the keyword score
+		perKeyword := 0.6 / float64(keywordCount)
+		for _, keyword := range report.MissingKeywords {
+			add(0.5+perKeyword, "keywords",
+				fmt.Sprintf("Add %q if you have this experience; it is required and absent from the resume", keyword))
+		}
+		for _, kw := range report.SynonymOnly {
+			add(0.4+perKeyword*synonymCredit, "keywords",
+				fmt.Sprintf("Use the exact phrase %q alongside %q; keyword filters may not treat them as equivalent", kw.Keyword, kw.ResumeTerm))
+		}
+	}
+	for _, section := range report.Sections.Missing {
+		add(0.55, "sections", fmt.Sprintf("Add a standard %q section header", strings.ToUpper(section[:1])+section[1:]))
+	}
+	for _, header := range report.Sections.Unrecognized {
+		add(0.25, "sections", fmt.Sprintf("Rename the %q header to a standard one such as Experience, Education or Skills", header))
+	}
+	for _, keyword := range report.OverusedKeywords {
+		add(0.2, "keywords", fmt.Sprintf("Reduce repetition of %q; heavy repetition can be flagged as keyword stuffing", keyword))
+	}
+	if !report.DiagnosticsAvailable {
+		add(0.1, "parsing", "Re-upload the resume to check for layout problems such as tables, columns and images")
+	}
+
+	sort.SliceStable(fixes, func(i, j int) bool { return fixes[i].weight > fixes[j].weight })
+	for i := range fixes {
+		fixes[i].Rank = i + 1
+		switch {
+		case fixes[i].weight >= 0.6:
+			fixes[i].Impact = IssueHigh
+		case fixes[i].weight >= 0.35:
+			fixes[i].Impact = IssueMedium
+		default:
+			fixes[i].Impact = IssueLow
+		}
+	}
+	if fixes == nil {
+		fixes = []ATSFix{}
+	}
+	return fixes
+}
+
+// issueFixWeight ranks high-severity parse issues above any keyword fix,
+// since an unparseable resume never reaches keyword matching
+func issueFixWeight(severity string) float64 {
+	switch severity {
+	case IssueHigh:
+		return 1.2
+	case IssueMedium:
+		return 0.45
+	default:
+		return 0.2
+	}
+}
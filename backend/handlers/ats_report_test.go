@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestCountPhrase(t *testing.T) {
+	tests := []struct {
+		text, phrase string
+		want         int
+	}{
+		{"Go, Golang and go.", "Go", 2},
+		{"C++ and C", "C++", 1},
+		{"machine learning; Machine Learning", "machine learning", 2},
+		{"anything", " ", 0},
+	}
+	for _, tt := range tests {
+		if got := countPhrase(tt.text, tt.phrase); got != tt.want {
+			t.Errorf("countPhrase(%q, %q) = %d, want %d", tt.text, tt.phrase, got, tt.want)
+		}
+	}
+}
+
+func TestBuildATSReport(t *testing.T) {
+	resume := &TextData{
+		ProcessedText: "Go Go Go Go developer. Built services.",
+		Entities:      ExtractedEntities{Skills: []string{"Go", "Postgres"}},
+	}
+	job := &TextData{Requirements: JobRequirements{Skills: []string{"Go", "PostgreSQL", "Kubernetes"}}}
+
+	tests := []struct {
+		name        string
+		diagnostics *ExtractionDiagnostics
+		wantScore   float64
+		wantMissing string
+		wantFixes   []string // category of each fix, best first
+	}{
+		{
+			name:        "without diagnostics",
+			wantScore:   50*0.6 + 20 + 20,
+			wantMissing: "",
+			wantFixes:   []string{"keywords", "keywords", "keywords", "parsing"},
+		},
+		{
+			name: "with a scanned resume",
+			diagnostics: &ExtractionDiagnostics{
+				SectionHeaders:      []string{"experience"},
+				UnrecognizedHeaders: []string{"MY JOURNEY"},
+				Issues: []ParseIssue{
+					{Code: "image_only_text", Severity: IssueHigh, Message: "scanned"},
+					{Code: "missing_section_skills", Severity: IssueMedium, Message: "reported under sections"},
+				},
+			},
+			wantScore:   50*0.6 + 100.0/3*0.2 + 65*0.2,
+			wantMissing: "education,skills",
+			wantFixes:   []string{"parsing", "keywords", "sections", "sections", "keywords", "sections", "keywords"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := buildATSReport("1", resume, "2", job, tt.diagnostics)
+
+			var statuses []string
+			for _, kw := range report.Keywords {
+				statuses = append(statuses, kw.Keyword+"="+kw.Status)
+			}
+			if got := strings.Join(statuses, ","); got != "Go=exact,PostgreSQL=synonym,Kubernetes=missing" {
+				t.Errorf("keywords = %s", got)
+			}
+			if math.Abs(report.KeywordCoverage-50) > 1e-9 || math.Abs(report.ExactCoverage-100.0/3) > 1e-9 {
+				t.Errorf("coverage = %.2f (exact %.2f), want 50 (exact 33.33)", report.KeywordCoverage, report.ExactCoverage)
+			}
+			if strings.Join(report.OverusedKeywords, ",") != "Go" {
+				t.Errorf("overused = %v, want [Go]", report.OverusedKeywords)
+			}
+			if math.Abs(report.ATSScore-tt.wantScore) > 1e-9 {
+				t.Errorf("ATS score = %.4f, want %.4f", report.ATSScore, tt.wantScore)
+			}
+			if got := strings.Join(report.Sections.Missing, ","); got != tt.wantMissing {
+				t.Errorf("missing sections = %q, want %q", got, tt.wantMissing)
+			}
+			var categories []string
+			for i, fix := range report.Fixes {
+				categories = append(categories, fix.Category)
+				if fix.Rank != i+1 {
+					t.Errorf("fix %d has rank %d", i, fix.Rank)
+				}
+			}
+			if strings.Join(categories, ",") != strings.Join(tt.wantFixes, ",") {
+				t.Errorf("fixes = %v, want %v", categories, tt.wantFixes)
+			}
+			for _, issue := range report.ParseIssues {
+				if strings.HasPrefix(issue.Code, "missing_section_") {
+					t.Errorf("section issue %s listed as a parse issue", issue.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildATSReportWithoutKeywords(t *testing.T) {
+	report := buildATSReport("1", &TextData{}, "2", &TextData{}, nil)
+	if report.KeywordCoverage != 100 || report.ExactCoverage != 100 || len(report.Fixes) != 1 {
+		t.Errorf("report = %+v, want full coverage and only the re-upload fix", report)
+	}
+}
@@ -0,0 +1,644 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"mime/multipart"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	defaultSelectionThreshold = 70.0 // Overall score treated as "advanced to interview"
+	fourFifthsRatio           = 0.8  // EEOC four-fifths rule
+	minGroupSize              = 5    // Below this, group statistics are unreliable
+	proxyCorrelationFlag      = 0.3  // |r| at or above this is reported as a concern
+	employmentGapMonths       = 6.0
+)
+
+// BiasAuditReport summarizes adverse impact of scoring across groups
+type BiasAuditReport struct {
+	ID                string             `json:"id"`
+	JobID             string             `json:"job_id"`
+	CreatedAt         time.Time          `json:"created_at"`
+	Threshold         float64            `json:"selection_threshold"`
+	CandidateCount    int                `json:"candidate_count"`
+	LabeledCount      int                `json:"labeled_count"`
+	Attributes        []AttributeAudit   `json:"attributes"`
+	ProxyAttributions []ProxyAttribution `json:"proxy_attributions"`
+	Notes             []string           `json:"notes"`
+}
+
+// AttributeAudit holds the per-group statistics for one demographic attribute
+type AttributeAudit struct {
+	Attribute      string       `json:"attribute"`
+	ReferenceGroup string       `json:"reference_group"`
+	AdverseImpact  bool         `json:"adverse_impact"`
+	Groups         []GroupStats `json:"groups"`
+}
+
+// GroupStats holds selection and score distribution figures for a group
+type GroupStats struct {
+	Group               string  `json:"group"`
+	Count               int     `json:"count"`
+	Selected            int     `json:"selected"`
+	SelectionRate       float64 `json:"selection_rate"`
+	ImpactRatio         float64 `json:"impact_ratio"`
+	FourFifthsViolation bool    `json:"four_fifths_violation"`
+	MeanScore           float64 `json:"mean_score"`
+	MedianScore         float64 `json:"median_score"`
+	StdDevScore         float64 `json:"std_dev_score"`
+	MeanDifference      float64 `json:"mean_difference"`
+	EffectSize          float64 `json:"effect_size"`
+	SmallSample         bool    `json:"small_sample"`
+}
+
+// ProxyAttribution reports how strongly a score component tracks a protected proxy
+type ProxyAttribution struct {
+	Proxy          string  `json:"proxy"`
+	ScoreComponent string  `json:"score_component"`
+	Prevalence     float64 `json:"prevalence"`
+	Correlation    float64 `json:"correlation"`
+	Flagged        bool    `json:"flagged"`
+}
+
+// auditCandidate pairs a score with the candidate's optional demographic labels
+type auditCandidate struct {
+	record ScoreRecord
+	labels map[string]string
+}
+
+var yearPattern = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+
+// durationSeparator splits "Jan 2020 – Mar 2022"; PDFs usually keep the en or em dash
+var durationSeparator = regexp.MustCompile(`[-‐–—]`)
+
+// RunBiasAudit builds an adverse-impact report for a job's scored candidates.
+// Demographic labels are read from an uploaded CSV, used only for this report
+// and never stored per candidate or fed back into scoring.
+func RunBiasAudit(c *fiber.Ctx) error {
+	jobID := normalizeID(c.FormValue("job_id"), "job")
+	if jobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "job_id is required",
+		})
+	}
+
+	threshold := defaultSelectionThreshold
+	if raw := c.FormValue("threshold"); raw != "" {
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil || parsed < 0 || parsed > maxScore {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "threshold must be a number between 0 and 100",
+			})
+		}
+		threshold = parsed
+	}
+
+	// Use uploaded batch results if given, otherwise the stored score records
+	var records []ScoreRecord
+	if resultsFile, err := c.FormFile("results"); err == nil {
+		content, err := readFormFile(resultsFile)
+		if err != nil || json.Unmarshal(content, &records) != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error": "results must be a JSON array of score records",
+			})
+		}
+	} else {
+		records, err = loadScoreRecordsForJob(jobID)
+		if err != nil {
+			log.Printf("Error loading score records: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to load score records",
+			})
+		}
+	}
+
+	if len(records) == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "No scored candidates found for this job",
+		})
+	}
+
+	labels := map[string]map[string]string{}
+	if demographicsFile, err := c.FormFile("demographics"); err == nil {
+		content, err := readFormFile(demographicsFile)
+		if err == nil {
+			labels, err = parseDemographicsCSV(content)
+		}
+		if err != nil {
+			return c.Status(400).JSON(fiber.Map{
+				"error":   "Invalid demographics file",
+				"details": err.Error(),
+			})
+		}
+	}
+
+	candidates := make([]auditCandidate, 0, len(records))
+	labeled := 0
+	for _, record := range records {
+		candidateLabels := labels[normalizeID(record.ResumeID, "resume")]
+		if len(candidateLabels) > 0 {
+			labeled++
+		}
+		candidates = append(candidates, auditCandidate{record: record, labels: candidateLabels})
+	}
+
+	report := BiasAuditReport{
+		ID:             fmt.Sprintf("audit_%d", time.Now().UnixNano()),
+		JobID:          jobID,
+		CreatedAt:      time.Now(),
+		Threshold:      threshold,
+		CandidateCount: len(candidates),
+		LabeledCount:   labeled,
+		Attributes:     auditAttributes(candidates, threshold),
+	}
+	report.ProxyAttributions = auditProxies(candidates)
+
+	if labeled == 0 {
+		report.Notes = append(report.Notes, "No demographic labels supplied; only proxy analysis was performed")
+	}
+	if len(candidates) < 2*minGroupSize {
+		report.Notes = append(report.Notes, "Fewer than 10 candidates; results are indicative only")
+	}
+
+	if err := utils.SaveJSONFile(auditReportPath(report.ID), report, 0644); err != nil {
+		log.Printf("Error saving audit report: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save audit report",
+		})
+	}
+
+	return c.JSON(report)
+}
+
+// GetBiasAudit returns a stored audit report
+func GetBiasAudit(c *fiber.Ctx) error {
+	report, err := loadAuditReport(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Audit report not found",
+		})
+	}
+	return c.JSON(report)
+}
+
+// ExportBiasAudit downloads a stored audit report as JSON or CSV
+func ExportBiasAudit(c *fiber.Ctx) error {
+	report, err := loadAuditReport(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Audit report not found",
+		})
+	}
+
+	switch strings.ToLower(c.Query("format", "json")) {
+	case "json":
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", report.ID))
+		return c.JSON(report)
+	case "csv":
+		data, err := auditReportCSV(report)
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to build CSV export",
+			})
+		}
+		c.Set("Content-Type", "text/csv")
+		c.Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", report.ID))
+		return c.Send(data)
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be json or csv",
+		})
+	}
+}
+
+func auditReportPath(id string) string {
+	return filepath.Join("processed_texts", "audits", safeID(id)+".json")
+}
+
+func loadAuditReport(id string) (*BiasAuditReport, error) {
+	var report BiasAuditReport
+	if err := utils.LoadJSONFile(auditReportPath(id), &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// readFormFile reads an uploaded multipart file into memory
+func readFormFile(header *multipart.FileHeader) ([]byte, error) {
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// parseDemographicsCSV reads "resume_id,<attribute>,..." rows into a lookup
+func parseDemographicsCSV(content []byte) (map[string]map[string]string, error) {
+	rows, err := csv.NewReader(bytes.NewReader(content)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("expected a header row and at least one candidate")
+	}
+
+	header := rows[0]
+	idColumn := -1
+	for i, column := range header {
+		if strings.EqualFold(strings.TrimSpace(column), "resume_id") {
+			idColumn = i
+			break
+		}
+	}
+	if idColumn == -1 {
+		return nil, fmt.Errorf("missing resume_id column")
+	}
+
+	labels := make(map[string]map[string]string)
+	for _, row := range rows[1:] {
+		if idColumn >= len(row) {
+			continue
+		}
+		resumeID := normalizeID(strings.TrimSpace(row[idColumn]), "resume")
+		candidateLabels := make(map[string]string)
+		for i, value := range row {
+			value = strings.TrimSpace(value)
+			if i == idColumn || i >= len(header) || value == "" {
+				continue
+			}
+			candidateLabels[strings.TrimSpace(header[i])] = value
+		}
+		labels[resumeID] = candidateLabels
+	}
+	return labels, nil
+}
+
+// auditAttributes computes selection-rate ratios and score differences per group
+func auditAttributes(candidates []auditCandidate, threshold float64) []AttributeAudit {
+	attributeNames := map[string]bool{}
+	for _, candidate := range candidates {
+		for attribute := range candidate.labels {
+			attributeNames[attribute] = true
+		}
+	}
+
+	var audits []AttributeAudit
+	for _, attribute := range sortedKeys(attributeNames) {
+		groupScores := map[string][]float64{}
+		var allScores []float64
+		for _, candidate := range candidates {
+			group, ok := candidate.labels[attribute]
+			if !ok {
+				continue
+			}
+			score := candidate.record.Score.OverallScore
+			groupScores[group] = append(groupScores[group], score)
+			allScores = append(allScores, score)
+		}
+
+		overallMean := mean(allScores)
+		audit := AttributeAudit{Attribute: attribute}
+		bestRate := 0.0
+		for _, group := range sortedKeys(groupScores) {
+			scores := groupScores[group]
+			selected := 0
+			for _, score := range scores {
+				if score >= threshold {
+					selected++
+				}
+			}
+
+			var others []float64
+			for otherGroup, otherScores := range groupScores {
+				if otherGroup != group {
+					others = append(others, otherScores...)
+				}
+			}
+
+			stats := GroupStats{
+				Group:          group,
+				Count:          len(scores),
+				Selected:       selected,
+				SelectionRate:  float64(selected) / float64(len(scores)),
+				MeanScore:      mean(scores),
+				MedianScore:    median(scores),
+				StdDevScore:    stdDev(scores),
+				MeanDifference: mean(scores) - overallMean,
+				EffectSize:     cohensD(scores, others),
+				SmallSample:    len(scores) < minGroupSize,
+			}
+			if stats.SelectionRate > bestRate {
+				bestRate = stats.SelectionRate
+				audit.ReferenceGroup = group
+			}
+			audit.Groups = append(audit.Groups, stats)
+		}
+
+		for i := range audit.Groups {
+			if bestRate > 0 {
+				audit.Groups[i].ImpactRatio = audit.Groups[i].SelectionRate / bestRate
+			} else {
+				audit.Groups[i].ImpactRatio = 1
+			}
+			if audit.Groups[i].ImpactRatio < fourFifthsRatio {
+				audit.Groups[i].FourFifthsViolation = true
+				audit.AdverseImpact = true
+			}
+		}
+		audits = append(audits, audit)
+	}
+	return audits
+}
+
+// auditProxies correlates each score component with proxies for protected
+// characteristics that can be derived from the resume itself
+func auditProxies(candidates []auditCandidate) []ProxyAttribution {
+	proxies := map[string][]float64{}
+	n := len(candidates)
+	addProxy := func(name string, i int, value float64) {
+		if _, ok := proxies[name]; !ok {
+			proxies[name] = make([]float64, n)
+		}
+		proxies[name][i] = value
+	}
+
+	// Only institutions shared by several candidates can show a pattern
+	schoolCounts := map[string]int{}
+	for _, candidate := range candidates {
+		seen := map[string]bool{}
+		for _, edu := range candidate.record.Score.ProcessedEntities.Education {
+			school := strings.ToLower(strings.TrimSpace(edu.Institution))
+			if school != "" && !seen[school] {
+				seen[school] = true
+				schoolCounts[school]++
+			}
+		}
+	}
+
+	var knownYears []float64
+	for i, candidate := range candidates {
+		entities := candidate.record.Score.ProcessedEntities
+		addProxy("employment_gap", i, boolToFloat(hasEmploymentGap(entities.Experience)))
+		addProxy("no_degree_listed", i, boolToFloat(len(entities.Education) == 0))
+		year := float64(latestGraduationYear(entities.Education))
+		addProxy("graduation_year", i, year)
+		if year > 0 {
+			knownYears = append(knownYears, year)
+		}
+		for _, edu := range entities.Education {
+			school := strings.ToLower(strings.TrimSpace(edu.Institution))
+			if schoolCounts[school] >= 2 && schoolCounts[school] < n {
+				addProxy("school:"+school, i, 1)
+			}
+		}
+	}
+
+	// Graduation year is an age proxy; fill unknown years with the mean so
+	// missing data doesn't dominate the correlation
+	if len(knownYears) < 2 {
+		delete(proxies, "graduation_year")
+	} else {
+		avgYear := mean(knownYears)
+		for i, year := range proxies["graduation_year"] {
+			if year == 0 {
+				proxies["graduation_year"][i] = avgYear
+			}
+		}
+	}
+
+	components := map[string][]float64{}
+	for i, candidate := range candidates {
+		score := candidate.record.Score
+		values := map[string]float64{
+			"overall_score":    score.OverallScore,
+			"experience_match": score.ExperienceMatch,
+			"education_match":  score.EducationMatch,
+		}
+		for key, value := range score.DetailedScores {
+			values[key] = value
+		}
+		for key, value := range values {
+			if _, ok := components[key]; !ok {
+				components[key] = make([]float64, n)
+			}
+			components[key][i] = value
+		}
+	}
+
+	var attributions []ProxyAttribution
+	for _, proxy := range sortedKeys(proxies) {
+		values := proxies[proxy]
+		nonZero := 0
+		for _, v := range values {
+			if v != 0 {
+				nonZero++
+			}
+		}
+		prevalence := float64(nonZero) / float64(n)
+		if proxy == "graduation_year" {
+			prevalence = float64(len(knownYears)) / float64(n)
+		}
+		for _, component := range sortedKeys(components) {
+			r := pearson(values, components[component])
+			attributions = append(attributions, ProxyAttribution{
+				Proxy:          proxy,
+				ScoreComponent: component,
+				Prevalence:     prevalence,
+				Correlation:    r,
+				Flagged:        n >= minGroupSize && math.Abs(r) >= proxyCorrelationFlag,
+			})
+		}
+	}
+	return attributions
+}
+
+// hasEmploymentGap reports a break of more than six months between roles
+func hasEmploymentGap(experiences []Experience) bool {
+	spans := experienceSpans(experiences)
+	for i := 1; i < len(spans); i++ {
+		if spans[i].start.Sub(spans[i-1].end).Hours()/24/30 > employmentGapMonths {
+			return true
+		}
+	}
+	return false
+}
+
+type dateSpan struct {
+	start time.Time
+	end   time.Time
+	index int
+}
+
+// experienceSpans parses role durations into date ranges sorted by start
+func experienceSpans(experiences []Experience) []dateSpan {
+	var spans []dateSpan
+	for i, exp := range experiences {
+		parts := durationSeparator.Split(exp.Duration, -1)
+		if len(parts) != 2 {
+			continue
+		}
+		start := parseDate(strings.TrimSpace(parts[0]))
+		end := parseDate(strings.TrimSpace(parts[1]))
+		if start.IsZero() || end.IsZero() || end.Before(start) {
+			continue
+		}
+		spans = append(spans, dateSpan{start: start, end: end, index: i})
+	}
+	sort.Slice(spans, func(i, j int) bool {
+		return spans[i].start.Before(spans[j].start)
+	})
+	return spans
+}
+
+// latestGraduationYear returns the most recent year mentioned in education
+func latestGraduationYear(education []Education) int {
+	latest := 0
+	for _, edu := range education {
+		for _, match := range yearPattern.FindAllString(edu.Year+" "+edu.GraduationDate, -1) {
+			if year, err := strconv.Atoi(match); err == nil && year > latest {
+				latest = year
+			}
+		}
+	}
+	return latest
+}
+
+// auditReportCSV flattens a report into section,attribute,group,metric,value rows
+func auditReportCSV(report *BiasAuditReport) ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	rows := [][]string{{"section", "attribute", "group", "metric", "value"}}
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 4, 64) }
+
+	for _, attribute := range report.Attributes {
+		for _, g := range attribute.Groups {
+			for _, metric := range []struct {
+				name  string
+				value string
+			}{
+				{"count", strconv.Itoa(g.Count)},
+				{"selected", strconv.Itoa(g.Selected)},
+				{"selection_rate", f(g.SelectionRate)},
+				{"impact_ratio", f(g.ImpactRatio)},
+				{"four_fifths_violation", strconv.FormatBool(g.FourFifthsViolation)},
+				{"mean_score", f(g.MeanScore)},
+				{"median_score", f(g.MedianScore)},
+				{"std_dev_score", f(g.StdDevScore)},
+				{"mean_difference", f(g.MeanDifference)},
+				{"effect_size", f(g.EffectSize)},
+				{"small_sample", strconv.FormatBool(g.SmallSample)},
+			} {
+				rows = append(rows, []string{"selection", attribute.Attribute, g.Group, metric.name, metric.value})
+			}
+		}
+	}
+	for _, p := range report.ProxyAttributions {
+		rows = append(rows,
+			[]string{"proxy", p.Proxy, p.ScoreComponent, "correlation", f(p.Correlation)},
+			[]string{"proxy", p.Proxy, p.ScoreComponent, "flagged", strconv.FormatBool(p.Flagged)},
+		)
+	}
+
+	if err := w.WriteAll(rows); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func mean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+// cohensD is the standardized mean difference between two samples
+func cohensD(a, b []float64) float64 {
+	if len(a) < 2 || len(b) < 2 {
+		return 0
+	}
+	pooled := math.Sqrt(((float64(len(a)-1))*math.Pow(stdDev(a), 2) +
+		(float64(len(b)-1))*math.Pow(stdDev(b), 2)) / float64(len(a)+len(b)-2))
+	if pooled == 0 {
+		return 0
+	}
+	return safeFloat64((mean(a) - mean(b)) / pooled)
+}
+
+func pearson(x, y []float64) float64 {
+	if len(x) != len(y) || len(x) < 2 {
+		return 0
+	}
+	mx, my := mean(x), mean(y)
+	var cov, vx, vy float64
+	for i := range x {
+		cov += (x[i] - mx) * (y[i] - my)
+		vx += (x[i] - mx) * (x[i] - mx)
+		vy += (y[i] - my) * (y[i] - my)
+	}
+	if vx == 0 || vy == 0 {
+		return 0
+	}
+	return safeFloat64(cov / math.Sqrt(vx*vy))
+}
@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseDemographicsCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    map[string]string // labels of resume 1
+		wantErr bool
+	}{
+		{"labels by header", "resume_id,gender,age_band\n1,F,40+\n2,M,\n", map[string]string{"gender": "F", "age_band": "40+"}, false},
+		{"id column anywhere", "gender,Resume_ID\n F ,resume_1\n", map[string]string{"gender": "F"}, false},
+		{"no id column", "id,gender\n1,F\n", nil, true},
+		{"header only", "resume_id,gender\n", nil, true},
+		{"ragged rows", "resume_id,gender\n1,F,extra\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			labels, err := parseDemographicsCSV([]byte(tt.csv))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			got := labels["1"]
+			if len(got) != len(tt.want) {
+				t.Fatalf("labels = %v, want %v", got, tt.want)
+			}
+			for k, v := range tt.want {
+				if got[k] != v {
+					t.Errorf("labels[%q] = %q, want %q", k, got[k], v)
+				}
+			}
+		})
+	}
+}
+
+func TestAuditAttributes(t *testing.T) {
+	candidate := func(group string, score float64) auditCandidate {
+		c := auditCandidate{record: ScoreRecord{Score: ScoreResponse{OverallScore: score}}}
+		if group != "" {
+			c.labels = map[string]string{"group": group}
+		}
+		return c
+	}
+	// a: 4 of 5 selected, b: 2 of 5, c: 1 of 2, one unlabeled candidate
+	candidates := []auditCandidate{
+		candidate("a", 90), candidate("a", 80), candidate("a", 75), candidate("a", 70), candidate("a", 40),
+		candidate("b", 85), candidate("b", 70), candidate("b", 60), candidate("b", 50), candidate("b", 30),
+		candidate("c", 72), candidate("c", 20),
+		candidate("", 99),
+	}
+	audits := auditAttributes(candidates, 70)
+	if len(audits) != 1 {
+		t.Fatalf("got %d attributes, want 1", len(audits))
+	}
+	audit := audits[0]
+	if audit.ReferenceGroup != "a" || !audit.AdverseImpact {
+		t.Errorf("reference %q, adverse impact %v, want a and true", audit.ReferenceGroup, audit.AdverseImpact)
+	}
+
+	tests := []struct {
+		group     string
+		count     int
+		rate      float64
+		ratio     float64
+		violation bool
+		small     bool
+	}{
+		{"a", 5, 0.8, 1, false, false},
+		{"b", 5, 0.4, 0.5, true, false},
+		{"c", 2, 0.5, 0.625, true, true},
+	}
+	for i, tt := range tests {
+		g := audit.Groups[i]
+		if g.Group != tt.group || g.Count != tt.count || math.Abs(g.SelectionRate-tt.rate) > 1e-9 ||
+			math.Abs(g.ImpactRatio-tt.ratio) > 1e-9 || g.FourFifthsViolation != tt.violation || g.SmallSample != tt.small {
+			t.Errorf("group %d = %+v, want %+v", i, g, tt)
+		}
+	}
+
+	none := auditAttributes([]auditCandidate{candidate("a", 10), candidate("b", 20)}, 70)
+	for _, g := range none[0].Groups {
+		if g.ImpactRatio != 1 || g.FourFifthsViolation {
+			t.Errorf("with nobody selected, group %s = %+v, want ratio 1 and no violation", g.Group, g)
+		}
+	}
+}
+
+func TestHasEmploymentGap(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []string
+		want      bool
+	}{
+		{"back to back", []string{"Jan 2018 – Dec 2019", "Jan 2020 – Dec 2021"}, false},
+		{"a year off", []string{"Jan 2020 – Dec 2021", "Jan 2017 – Dec 2018"}, true},
+		{"short break", []string{"Jan 2018 - Jun 2019", "Oct 2019 - 2021"}, false},
+		{"unparseable roles are ignored", []string{"Jan 2015 - Dec 2016", "sometime", "Jan 2017 - Present"}, false},
+		{"ISO dates have too many dashes", []string{"2015-01 - 2015-12", "2019-01 - 2020-01"}, false},
+		{"single role", []string{"2010 - 2012"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var experiences []Experience
+			for _, duration := range tt.durations {
+				experiences = append(experiences, Experience{Duration: duration})
+			}
+			if got := hasEmploymentGap(experiences); got != tt.want {
+				t.Errorf("hasEmploymentGap(%q) = %v, want %v", tt.durations, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLatestGraduationYear(t *testing.T) {
+	tests := []struct {
+		education []Education
+		want      int
+	}{
+		{[]Education{{Year: "2012"}, {GraduationDate: "May 2016"}}, 2016},
+		{[]Education{{Year: "2008 - 2012"}}, 2012},
+		{[]Education{{Year: "Class of '99"}}, 0},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := latestGraduationYear(tt.education); got != tt.want {
+			t.Errorf("latestGraduationYear(%+v) = %d, want %d", tt.education, got, tt.want)
+		}
+	}
+}
+
+func TestAuditProxies(t *testing.T) {
+	var candidates []auditCandidate
+	for i := 0; i < 6; i++ {
+		score := ScoreResponse{OverallScore: 80}
+		if i%2 == 0 {
+			// Candidates without a degree score lower
+			score.OverallScore = 40
+		} else {
+			score.ProcessedEntities.Education = []Education{{Institution: "State University", Degree: "BSc"}}
+		}
+		candidates = append(candidates, auditCandidate{record: ScoreRecord{Score: score}})
+	}
+
+	attributions := auditProxies(candidates)
+	found, school := false, false
+	for _, a := range attributions {
+		if a.Proxy == "graduation_year" {
+			t.Error("graduation year reported without any known years")
+		}
+		school = school || a.Proxy == "school:state university"
+		if a.Proxy == "no_degree_listed" && a.ScoreComponent == "overall_score" {
+			found = true
+			if math.Abs(a.Correlation+1) > 1e-9 || !a.Flagged || a.Prevalence != 0.5 {
+				t.Errorf("no_degree_listed = %+v, want r=-1, flagged, prevalence 0.5", a)
+			}
+		}
+	}
+	if !found {
+		t.Error("no attribution for no_degree_listed against overall_score")
+	}
+	if !school {
+		t.Error("no attribution for a school shared by half the candidates")
+	}
+}
+
+func TestAuditStatistics(t *testing.T) {
+	tests := []struct {
+		name      string
+		got, want float64
+	}{
+		{"mean", mean([]float64{1, 2, 3, 4}), 2.5},
+		{"mean of nothing", mean(nil), 0},
+		{"odd median", median([]float64{3, 1, 2}), 2},
+		{"even median", median([]float64{4, 1, 3, 2}), 2.5},
+		{"sample std dev", stdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9}), math.Sqrt(32.0 / 7)},
+		{"std dev of one", stdDev([]float64{5}), 0},
+		{"cohen's d", cohensD([]float64{1, 2, 3}, []float64{3, 4, 5}), -2},
+		{"cohen's d without spread", cohensD([]float64{1, 1}, []float64{1, 1}), 0},
+		{"perfect correlation", pearson([]float64{1, 2, 3}, []float64{2, 4, 6}), 1},
+		{"constant series", pearson([]float64{1, 1, 1}, []float64{1, 2, 3}), 0},
+		{"length mismatch", pearson([]float64{1, 2}, []float64{1}), 0},
+	}
+	for _, tt := range tests {
+		if math.Abs(tt.got-tt.want) > 1e-9 {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+		}
+	}
+}
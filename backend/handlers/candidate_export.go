@@ -0,0 +1,278 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+// candidateExportColumns is the fixed column order of candidate exports.
+// New columns go at the end so spreadsheets built on earlier exports keep working.
+var candidateExportColumns = []string{
+	"rank",
+	"resume_id",
+	"name",
+	"email",
+	"phone",
+	"location",
+	"overall_score",
+	"technical_skills_score",
+	"soft_skills_score",
+	"experience_score",
+	"education_score",
+	"qualifications_score",
+	"logistics_fit_score",
+	"knocked_out",
+	"failed_knockouts",
+	"exact_skills",
+	"partial_skills",
+	"missing_skills",
+	"years_experience",
+	"education_level",
+	"job_version",
+	"scored_at",
+}
+
+// educationLevels are checked highest first
+var educationLevels = []struct {
+	label   string
+	pattern *regexp.Regexp
+}{
+	{"Doctorate", regexp.MustCompile(`(?i)\b(ph\.?\s?d|doctor\w*|d\.?phil|ed\.?d)\b`)},
+	{"Master's", regexp.MustCompile(`(?i)\b(master\w*|m\.?\s?sc|m\.?\s?s|m\.?\s?a|m\.?\s?eng|m\.?\s?tech|mba|m\.e\.)\b`)},
+	{"Bachelor's", regexp.MustCompile(`(?i)\b(bachelor\w*|b\.?\s?sc|b\.?\s?s|b\.?\s?a|b\.?\s?eng|b\.?\s?tech|b\.e\.)\b`)},
+	{"Associate", regexp.MustCompile(`(?i)\bassociate\b`)},
+	{"Diploma", regexp.MustCompile(`(?i)\b(diploma|certificate|a-levels?|high school)\b`)},
+}
+
+// highestEducationLevel names the highest degree level on the resume
+func highestEducationLevel(education []Education) string {
+	for _, level := range educationLevels {
+		for _, edu := range education {
+			if level.pattern.MatchString(edu.Degree) {
+				return level.label
+			}
+		}
+	}
+	if len(education) > 0 {
+		return "Other"
+	}
+	return ""
+}
+
+// rankedScoreFile is what the first pass keeps per candidate: enough to
+// order the export without holding the records themselves
+type rankedScoreFile struct {
+	path     string
+	resumeID string
+	score    float64
+}
+
+// rankScoreFiles orders a job's score files best first, breaking ties on
+// resume ID so repeated exports list candidates in the same order
+func rankScoreFiles(jobID string) ([]rankedScoreFile, error) {
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "scores", fmt.Sprintf("score_*_%s.json", pathID(jobID, "job"))))
+	if err != nil {
+		return nil, err
+	}
+
+	ranked := make([]rankedScoreFile, 0, len(paths))
+	for _, path := range paths {
+		var record ScoreRecord
+		if err := utils.LoadJSONFile(path, &record); err != nil {
+			log.Printf("Skipping unreadable score record %s: %v", path, err)
+			continue
+		}
+		if record.JobID == jobID {
+			ranked = append(ranked, rankedScoreFile{path: path, resumeID: record.ResumeID, score: record.Score.OverallScore})
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return ranked[i].resumeID < ranked[j].resumeID
+	})
+	return ranked, nil
+}
+
+// candidateExportRow flattens a score record into the export columns. Blind
+// screening jobs get the pseudonym and no contact details.
+func candidateExportRow(rank int, record *ScoreRecord, blind bool) []any {
+	score := record.Score
+	entities := score.ProcessedEntities
+
+	name, email, phone, location := provided(entities.Name), strings.Join(entities.Email, "; "), provided(entities.Phone), entities.Preferences.Location
+	if blind {
+		name, email, phone, location = candidatePseudonym(record.ResumeID), "", "", ""
+	}
+
+	var failed []string
+	for _, result := range score.Knockouts {
+		if result.Status == KnockoutFail {
+			failed = append(failed, result.Field)
+		}
+	}
+	partial := make([]string, 0, len(score.MatchedSkills.PartialMatches))
+	for _, match := range score.MatchedSkills.PartialMatches {
+		partial = append(partial, fmt.Sprintf("%s (via %s, %.0f%%)", match.JobSkill, match.ResumeSkill, match.Similarity*100))
+	}
+
+	return []any{
+		rank,
+		record.ResumeID,
+		name,
+		email,
+		phone,
+		location,
+		round1(score.OverallScore),
+		round1(score.DetailedScores["technical_skills"]),
+		round1(score.DetailedScores["soft_skills"]),
+		round1(score.ExperienceMatch),
+		round1(score.EducationMatch),
+		round1(score.DetailedScores["qualifications"]),
+		round1(score.LogisticsFit.Score),
+		score.KnockedOut,
+		strings.Join(failed, "; "),
+		strings.Join(score.MatchedSkills.ExactMatches, "; "),
+		strings.Join(partial, "; "),
+		strings.Join(score.MatchedSkills.MissingSkills, "; "),
+		round1(listedExperienceYears(entities.Experience)),
+		highestEducationLevel(entities.Education),
+		max(record.JobVersion, 1),
+		record.ScoredAt.UTC().Format(time.RFC3339),
+	}
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
+
+// csvSafe stops spreadsheet apps from evaluating resume text as a formula
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// ExportJobCandidates streams every candidate scored against a job, best
+// first, as CSV or XLSX. Records are read one at a time while writing so
+// large exports don't have to fit in memory.
+func ExportJobCandidates(c *fiber.Ctx) error {
+	// Copied out of the request buffer since the stream outlives the handler
+	jobID := normalizeID(strings.Clone(c.Params("id")), "job")
+	format := strings.ToLower(strings.Clone(c.Query("format", "csv")))
+	if format != "csv" && format != "xlsx" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be csv or xlsx",
+		})
+	}
+	if !jobExists(jobID) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description not found",
+		})
+	}
+
+	ranked, err := rankScoreFiles(jobID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load scores",
+		})
+	}
+	blind := isBlindScreeningJob(jobID)
+
+	filename := fmt.Sprintf("candidates_%s_%s.%s", jobID, time.Now().Format("20060102"), format)
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "xlsx" {
+		c.Set(fiber.HeaderContentType, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	} else {
+		c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	}
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		rows := func(write func(cells []any, header bool) error, flush func() error) {
+			header := make([]any, len(candidateExportColumns))
+			for i, column := range candidateExportColumns {
+				header[i] = column
+			}
+			if err := write(header, true); err != nil {
+				return
+			}
+			rank := 0
+			for _, file := range ranked {
+				var record ScoreRecord
+				if err := utils.LoadJSONFile(file.path, &record); err != nil {
+					log.Printf("Skipping score record %s removed during export: %v", file.path, err)
+					continue
+				}
+				rank++
+				if err := write(candidateExportRow(rank, &record, blind), false); err != nil {
+					log.Printf("Candidate export for %s aborted: %v", jobID, err)
+					return
+				}
+				if rank%100 == 0 {
+					if err := flush(); err != nil {
+						return
+					}
+				}
+			}
+		}
+
+		if format == "xlsx" {
+			xw, err := utils.NewXLSXWriter(w, "Candidates "+jobID)
+			if err != nil {
+				log.Printf("Candidate export for %s failed: %v", jobID, err)
+				return
+			}
+			rows(xw.WriteRow, func() error {
+				if err := xw.Flush(); err != nil {
+					return err
+				}
+				return w.Flush()
+			})
+			if err := xw.Close(); err != nil {
+				log.Printf("Candidate export for %s failed: %v", jobID, err)
+			}
+			return
+		}
+
+		// Excel reads a CSV without a byte order mark as the system code page,
+		// mangling accented names
+		if _, err := w.WriteString("\xEF\xBB\xBF"); err != nil {
+			return
+		}
+		cw := csv.NewWriter(w)
+		rows(func(cells []any, _ bool) error {
+			record := make([]string, len(cells))
+			for i, cell := range cells {
+				if s, ok := cell.(string); ok {
+					record[i] = csvSafe(s)
+				} else {
+					record[i] = fmt.Sprint(cell)
+				}
+			}
+			return cw.Write(record)
+		}, func() error {
+			cw.Flush()
+			if err := cw.Error(); err != nil {
+				return err
+			}
+			return w.Flush()
+		})
+		cw.Flush()
+	})
+	return nil
+}
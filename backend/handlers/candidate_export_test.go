@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestCSVSafe(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Jane Doe", "Jane Doe"},
+		{"=HYPERLINK(\"x\")", "'=HYPERLINK(\"x\")"},
+		{"+15551234567", "'+15551234567"},
+		{"-1", "'-1"},
+		{"@SUM(A1)", "'@SUM(A1)"},
+		{"\tindented", "'\tindented"},
+		{"a=b", "a=b"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := csvSafe(tt.in); got != tt.want {
+			t.Errorf("csvSafe(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestHighestEducationLevel(t *testing.T) {
+	tests := []struct {
+		degrees []string
+		want    string
+	}{
+		{[]string{"B.Sc. Computer Science", "MBA"}, "Master's"},
+		{[]string{"PhD Physics", "BA"}, "Doctorate"},
+		{[]string{"Bachelor of Arts"}, "Bachelor's"},
+		{[]string{"Bootcamp"}, "Other"},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		var education []Education
+		for _, degree := range tt.degrees {
+			education = append(education, Education{Degree: degree})
+		}
+		if got := highestEducationLevel(education); got != tt.want {
+			t.Errorf("highestEducationLevel(%v) = %q, want %q", tt.degrees, got, tt.want)
+		}
+	}
+}
+
+func TestExportJobCandidates(t *testing.T) {
+	score := func(name string, overall float64) ScoreResponse {
+		return ScoreResponse{OverallScore: overall, ProcessedEntities: ExtractedEntities{
+			Name:  name,
+			Email: []string{"cand@example.com"},
+			Phone: "+1 555 123 4567",
+		}}
+	}
+	for _, jobID := range []string{"5001", "5002"} {
+		saveTestText(t, "job", jobID, TextData{ProcessedText: "Backend Engineer"})
+		for resumeID, s := range map[string]ScoreResponse{"5001": score("José Núñez", 61), "5002": score("=cmd|' /C calc'!A0", 88)} {
+			if err := saveScoreRecord(resumeID, jobID, 1, s, nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := saveScreeningSettings(ScreeningSettings{JobID: "5002", BlindScreening: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/jobs/:id/candidates/export", ExportJobCandidates)
+	get := func(path string) (int, []byte) {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	tests := []struct {
+		name     string
+		jobID    string
+		wantRows [][2]string // resume_id and name per rank
+	}{
+		{"open job", "5001", [][2]string{{"5002", "'=cmd|' /C calc'!A0"}, {"5001", "José Núñez"}}},
+		{"blind job", "5002", [][2]string{{"5002", candidatePseudonym("5002")}, {"5001", candidatePseudonym("5001")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := get("/jobs/" + tt.jobID + "/candidates/export")
+			if status != 200 {
+				t.Fatalf("status = %d: %s", status, body)
+			}
+			if !bytes.HasPrefix(body, []byte("\xEF\xBB\xBF")) {
+				t.Error("CSV has no byte order mark")
+			}
+			records, err := csv.NewReader(bytes.NewReader(body[3:])).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(records[0], ",") != strings.Join(candidateExportColumns, ",") {
+				t.Errorf("header = %v", records[0])
+			}
+			if len(records) != len(tt.wantRows)+1 {
+				t.Fatalf("got %d rows, want %d", len(records)-1, len(tt.wantRows))
+			}
+			for i, want := range tt.wantRows {
+				row := records[i+1]
+				if row[1] != want[0] || row[2] != want[1] {
+					t.Errorf("row %d = %s %q, want %s %q", i+1, row[1], row[2], want[0], want[1])
+				}
+				if blind := isBlindScreeningJob(tt.jobID); blind != (row[3] == "" && row[4] == "") {
+					t.Errorf("row %d contact = %q %q, blind %v", i+1, row[3], row[4], blind)
+				}
+			}
+		})
+	}
+
+	status, body := get("/jobs/5001/candidates/export?format=xlsx")
+	if status != 200 {
+		t.Fatalf("xlsx status = %d", status)
+	}
+	if _, err := zip.NewReader(bytes.NewReader(body), int64(len(body))); err != nil {
+		t.Errorf("xlsx export is not a zip: %v", err)
+	}
+	if status, _ := get("/jobs/5001/candidates/export?format=pdf"); status != 400 {
+		t.Errorf("pdf format: status = %d, want 400", status)
+	}
+	if status, _ := get("/jobs/5999/candidates/export"); status != 404 {
+		t.Errorf("unknown job: status = %d, want 404", status)
+	}
+}
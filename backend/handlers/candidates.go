@@ -0,0 +1,585 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Candidate groups the resume versions one person has submitted
+type Candidate struct {
+	ID         string          `json:"id"`
+	Name       string          `json:"name"`
+	Emails     []string        `json:"emails"`
+	Phones     []string        `json:"phones"` // E.164
+	Employers  []string        `json:"employers"`
+	Versions   []ResumeVersion `json:"versions"`
+	MergedInto string          `json:"merged_into,omitempty"`
+	MergedFrom []MergeRecord   `json:"merged_from,omitempty"`
+	MatchKeys  []string        `json:"match_keys,omitempty"` // hashed emails, phones and name
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// ResumeVersion is one resume upload belonging to a candidate
+type ResumeVersion struct {
+	ResumeID   string    `json:"resume_id"`
+	Version    int       `json:"version"`
+	Filename   string    `json:"filename,omitempty"`
+	UploadedAt time.Time `json:"uploaded_at"`
+	SimHash    string    `json:"simhash,omitempty"` // resume text fingerprint, hex
+}
+
+// candidateIdentity is what a resume tells us about who submitted it
+type candidateIdentity struct {
+	Name      string
+	Emails    []string
+	Phones    []string
+	Employers []string
+	Keys      []string // match keys of the name, emails and phones
+	SimHash   uint64
+	Blind     bool // details came from a redaction vault and only Keys are kept
+}
+
+// DateChange is a role whose dates differ between two resume versions
+type DateChange struct {
+	Role string `json:"role"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// ResumeDiff is the structured difference between two resume versions
+type ResumeDiff struct {
+	CandidateID      string       `json:"candidate_id"`
+	FromVersion      int          `json:"from_version"`
+	ToVersion        int          `json:"to_version"`
+	FromResumeID     string       `json:"from_resume_id"`
+	ToResumeID       string       `json:"to_resume_id"`
+	SkillsAdded      []string     `json:"skills_added"`
+	SkillsRemoved    []string     `json:"skills_removed"`
+	RolesAdded       []string     `json:"roles_added"`
+	RolesRemoved     []string     `json:"roles_removed"`
+	DateChanges      []DateChange `json:"date_changes"`
+	EducationAdded   []string     `json:"education_added"`
+	EducationRemoved []string     `json:"education_removed"`
+	ProjectsAdded    []string     `json:"projects_added"`
+	ProjectsRemoved  []string     `json:"projects_removed"`
+}
+
+// TimelineEntry is a candidate's score against a job for one resume version.
+// Versions not yet scored against the job have Scored false and no score.
+type TimelineEntry struct {
+	Version      int        `json:"version"`
+	ResumeID     string     `json:"resume_id"`
+	UploadedAt   time.Time  `json:"uploaded_at"`
+	Scored       bool       `json:"scored"`
+	OverallScore float64    `json:"overall_score,omitempty"`
+	Change       float64    `json:"change,omitempty"` // against the previous scored version
+	ScoredAt     *time.Time `json:"scored_at,omitempty"`
+	JobVersion   int        `json:"job_version,omitempty"`
+}
+
+// candidatesMu serializes matching so two uploads can't create the same candidate twice
+var candidatesMu sync.Mutex
+
+func candidatesDir() string {
+	return filepath.Join("processed_texts", "candidates")
+}
+
+func candidatePath(id string) string {
+	return filepath.Join(candidatesDir(), safeID(id)+".json")
+}
+
+func saveCandidate(candidate *Candidate) error {
+	return utils.SaveJSONFile(candidatePath(candidate.ID), candidate, 0600)
+}
+
+func loadCandidate(id string) (*Candidate, error) {
+	var candidate Candidate
+	if err := utils.LoadJSONFile(candidatePath(id), &candidate); err != nil {
+		return nil, err
+	}
+	return &candidate, nil
+}
+
+// loadAllCandidates reads every candidate, oldest first
+func loadAllCandidates() ([]*Candidate, error) {
+	paths, err := filepath.Glob(filepath.Join(candidatesDir(), "cand_*.json"))
+	if err != nil {
+		return nil, err
+	}
+	candidates := make([]*Candidate, 0, len(paths))
+	for _, path := range paths {
+		var candidate Candidate
+		if err := utils.LoadJSONFile(path, &candidate); err != nil {
+			log.Printf("Skipping unreadable candidate %s: %v", path, err)
+			continue
+		}
+		candidates = append(candidates, &candidate)
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].CreatedAt.Before(candidates[j].CreatedAt) })
+	return candidates, nil
+}
+
+// resumeIdentity returns the identifying details of a resume. Blind uploads
+// only have contact details in the redaction vault, and those are kept as
+// match keys alone so they never reach the candidate file.
+func resumeIdentity(resumeID string, entities ExtractedEntities, text string) candidateIdentity {
+	identity := candidateIdentity{Name: entities.Name, Emails: entities.Email, SimHash: simHash(text)}
+	if entities.Phone != "" {
+		identity.Phones = []string{entities.Phone}
+	}
+	for _, exp := range entities.Experience {
+		identity.Employers = append(identity.Employers, exp.Company)
+	}
+
+	vaulted, blind := vaultIdentity(resumeID)
+	if blind {
+		identity.Name, identity.Emails, identity.Phones = vaulted.Name, vaulted.Emails, vaulted.Phones
+	}
+	identity = normalizeIdentity(identity)
+	if blind {
+		identity.Name, identity.Emails, identity.Phones, identity.Blind = "", nil, nil, true
+	}
+	return identity
+}
+
+// vaultIdentity reads the original name, emails and phones from a resume's
+// redaction vault. It reports false for resumes processed without one.
+func vaultIdentity(resumeID string) (candidateIdentity, bool) {
+	var vault RedactionVault
+	if err := utils.LoadJSONFile(redactionVaultPath(resumeID), &vault); err != nil {
+		return candidateIdentity{}, false
+	}
+	identity := candidateIdentity{Name: vault.Tokens[redactedNameToken]}
+	for token, original := range vault.Tokens {
+		switch {
+		case strings.HasPrefix(token, "REDACTED_EMAIL"):
+			identity.Emails = append(identity.Emails, original)
+		case strings.HasPrefix(token, "REDACTED_PHONE"):
+			identity.Phones = append(identity.Phones, original)
+		}
+	}
+	return identity, true
+}
+
+// matchKey hashes an identifying value so blind candidates can still be
+// matched without storing it. CANDIDATE_MATCH_SECRET keys the hash; changing
+// it stops new uploads matching candidates stored under the old secret.
+func matchKey(kind, value string) string {
+	secret := os.Getenv("CANDIDATE_MATCH_SECRET")
+	if secret == "" {
+		secret = "interviewme-candidate-match"
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(kind + ":" + value))
+	return kind + ":" + hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// keysOfKind returns the match keys of one kind: email, phone or name
+func keysOfKind(keys []string, kind string) []string {
+	var matching []string
+	for _, key := range keys {
+		if strings.HasPrefix(key, kind+":") {
+			matching = append(matching, key)
+		}
+	}
+	return matching
+}
+
+func normalizeIdentity(identity candidateIdentity) candidateIdentity {
+	normalized := candidateIdentity{Name: strings.Join(strings.Fields(identity.Name), " "), SimHash: identity.SimHash, Blind: identity.Blind}
+	if strings.EqualFold(normalized.Name, "Not provided") || redactionTokenPattern.MatchString(normalized.Name) {
+		normalized.Name = ""
+	}
+	for _, email := range identity.Emails {
+		if email = strings.ToLower(strings.TrimSpace(email)); strings.Contains(email, "@") {
+			normalized.Emails = append(normalized.Emails, email)
+		}
+	}
+	for _, phone := range identity.Phones {
+		if phone = normalizePhone(phone); phone != "" {
+			normalized.Phones = append(normalized.Phones, phone)
+		}
+	}
+	for _, employer := range identity.Employers {
+		if employer = normalizeEmployer(employer); employer != "" {
+			normalized.Employers = append(normalized.Employers, employer)
+		}
+	}
+	normalized.Emails = uniqueStrings(normalized.Emails)
+	normalized.Phones = uniqueStrings(normalized.Phones)
+	normalized.Employers = uniqueStrings(normalized.Employers)
+
+	keys := append([]string{}, identity.Keys...)
+	for _, email := range normalized.Emails {
+		keys = append(keys, matchKey("email", email))
+	}
+	for _, phone := range normalized.Phones {
+		keys = append(keys, matchKey("phone", phone))
+	}
+	if name := normalizeName(normalized.Name); name != "" {
+		keys = append(keys, matchKey("name", name))
+	}
+	normalized.Keys = uniqueStrings(keys)
+	return normalized
+}
+
+// blindResumeIDs returns the candidate's resumes that were redacted on upload
+func blindResumeIDs(candidate *Candidate) []string {
+	var ids []string
+	for _, version := range candidate.Versions {
+		if hasRedactionVault(version.ResumeID) {
+			ids = append(ids, version.ResumeID)
+		}
+	}
+	return ids
+}
+
+// candidateDisplayName is the candidate's name, or the pseudonym of their
+// latest blind resume if they have one
+func candidateDisplayName(candidate *Candidate) string {
+	if blindIDs := blindResumeIDs(candidate); len(blindIDs) > 0 {
+		return candidatePseudonym(blindIDs[len(blindIDs)-1])
+	}
+	return candidate.Name
+}
+
+// candidateView is what a request gets to see of a candidate. A candidate
+// with any blind resume is shown under a pseudonym with no contact details
+// or filenames; identities are only revealed per resume by UnmaskCandidate,
+// once the candidate is shortlisted for the job.
+func candidateView(candidate *Candidate) *Candidate {
+	view := *candidate
+	view.MatchKeys = nil
+	if len(blindResumeIDs(candidate)) == 0 {
+		return &view
+	}
+
+	view.Name = candidateDisplayName(candidate)
+	view.Emails, view.Phones = []string{}, []string{}
+	view.Versions = make([]ResumeVersion, len(candidate.Versions))
+	for i, version := range candidate.Versions {
+		version.Filename = ""
+		view.Versions[i] = version
+	}
+	view.MergedFrom = make([]MergeRecord, len(candidate.MergedFrom))
+	for i, record := range candidate.MergedFrom {
+		record.Name, record.Emails, record.Phones, record.MatchKeys = "", []string{}, []string{}, nil
+		view.MergedFrom[i] = record
+	}
+	if candidate.MergedFrom == nil {
+		view.MergedFrom = nil
+	}
+	return &view
+}
+
+// linkResumeToCandidate adds a resume as the newest version of its candidate.
+// Uncertain matches get a new candidate plus a dedup review item.
+func linkResumeToCandidate(resumeID, filename string, uploadedAt time.Time, identity candidateIdentity) (*Candidate, error) {
+	candidatesMu.Lock()
+	defer candidatesMu.Unlock()
+
+	candidates, err := loadAllCandidates()
+	if err != nil {
+		return nil, err
+	}
+	resumeID = normalizeID(resumeID, "resume")
+	for _, candidate := range candidates {
+		for _, v := range candidate.Versions {
+			if v.ResumeID == resumeID {
+				return candidate, nil
+			}
+		}
+	}
+
+	match := bestDuplicate(activeCandidates(candidates), identity)
+	candidate := match.candidate
+	if match.Decision != DedupAutoMerge {
+		candidate = &Candidate{
+			ID:        fmt.Sprintf("cand_%d", time.Now().UnixNano()),
+			CreatedAt: uploadedAt,
+			Emails:    []string{},
+			Phones:    []string{},
+			Employers: []string{},
+			Versions:  []ResumeVersion{},
+		}
+	}
+	if identity.Name != "" {
+		candidate.Name = identity.Name
+	}
+	candidate.Emails = uniqueStrings(append(candidate.Emails, identity.Emails...))
+	candidate.Phones = uniqueStrings(append(candidate.Phones, identity.Phones...))
+	candidate.Employers = uniqueStrings(append(candidate.Employers, identity.Employers...))
+	candidate.MatchKeys = uniqueStrings(append(candidate.MatchKeys, identity.Keys...))
+	if identity.Blind {
+		// Original filenames often carry the candidate's name
+		filename = ""
+	}
+	version := ResumeVersion{
+		ResumeID:   resumeID,
+		Version:    len(candidate.Versions) + 1,
+		Filename:   filename,
+		UploadedAt: uploadedAt,
+	}
+	if identity.SimHash != 0 {
+		version.SimHash = fmt.Sprintf("%016x", identity.SimHash)
+	}
+	candidate.Versions = append(candidate.Versions, version)
+	candidate.UpdatedAt = time.Now()
+
+	if err := saveCandidate(candidate); err != nil {
+		return nil, err
+	}
+	if match.Decision == DedupReview {
+		if err := queueDedupReview(candidate, match); err != nil {
+			log.Printf("Error queueing dedup review: %v", err)
+		}
+	}
+	return candidate, nil
+}
+
+// ListCandidates lists candidates with their version counts
+func ListCandidates(c *fiber.Ctx) error {
+	candidates, err := loadAllCandidates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read candidates",
+		})
+	}
+
+	candidates = activeCandidates(candidates)
+	summaries := make([]fiber.Map, 0, len(candidates))
+	for _, candidate := range candidates {
+		summary := fiber.Map{
+			"id":         candidate.ID,
+			"name":       candidateView(candidate).Name,
+			"versions":   len(candidate.Versions),
+			"updated_at": candidate.UpdatedAt,
+		}
+		if n := len(candidate.Versions); n > 0 {
+			summary["latest_resume_id"] = candidate.Versions[n-1].ResumeID
+		}
+		summaries = append(summaries, summary)
+	}
+	return c.JSON(fiber.Map{
+		"count":      len(summaries),
+		"candidates": summaries,
+	})
+}
+
+// GetCandidate returns a candidate and their resume versions. Candidates with
+// a blind resume are always pseudonymized; see UnmaskCandidate.
+func GetCandidate(c *fiber.Ctx) error {
+	candidate, err := loadCandidate(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate not found",
+		})
+	}
+	return c.JSON(candidateView(candidate))
+}
+
+// GetCandidateDiff compares two resume versions, defaulting to the last two
+func GetCandidateDiff(c *fiber.Ctx) error {
+	candidate, err := loadCandidate(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate not found",
+		})
+	}
+	if len(candidate.Versions) < 2 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate has only one resume version",
+		})
+	}
+
+	to := c.QueryInt("to", len(candidate.Versions))
+	from := c.QueryInt("from", to-1)
+	if from < 1 || to < 1 || from > len(candidate.Versions) || to > len(candidate.Versions) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": fmt.Sprintf("Versions must be between 1 and %d", len(candidate.Versions)),
+		})
+	}
+
+	older, newer := candidate.Versions[from-1], candidate.Versions[to-1]
+	olderData, err := LoadTextData("resume_"+older.ResumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Resume for version %d not found", from),
+		})
+	}
+	newerData, err := LoadTextData("resume_"+newer.ResumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Resume for version %d not found", to),
+		})
+	}
+
+	diff := diffResumes(olderData.Entities, newerData.Entities)
+	diff.CandidateID = candidate.ID
+	diff.FromVersion, diff.ToVersion = from, to
+	diff.FromResumeID, diff.ToResumeID = older.ResumeID, newer.ResumeID
+	return c.JSON(diff)
+}
+
+func diffResumes(older, newer ExtractedEntities) ResumeDiff {
+	diff := ResumeDiff{DateChanges: []DateChange{}}
+	diff.SkillsAdded, diff.SkillsRemoved = diffLists(older.Skills, newer.Skills)
+
+	oldRoles, newRoles := roleDurations(older.Experience), roleDurations(newer.Experience)
+	diff.RolesAdded, diff.RolesRemoved = diffLists(sortedKeys(oldRoles), sortedKeys(newRoles))
+	for _, role := range sortedKeys(newRoles) {
+		if from, ok := oldRoles[role]; ok && !strings.EqualFold(strings.TrimSpace(from), strings.TrimSpace(newRoles[role])) {
+			diff.DateChanges = append(diff.DateChanges, DateChange{Role: role, From: from, To: newRoles[role]})
+		}
+	}
+
+	diff.EducationAdded, diff.EducationRemoved = diffLists(educationLabels(older.Education), educationLabels(newer.Education))
+	diff.ProjectsAdded, diff.ProjectsRemoved = diffLists(projectNames(older.Projects), projectNames(newer.Projects))
+
+	for _, list := range []*[]string{&diff.SkillsAdded, &diff.SkillsRemoved, &diff.RolesAdded, &diff.RolesRemoved,
+		&diff.EducationAdded, &diff.EducationRemoved, &diff.ProjectsAdded, &diff.ProjectsRemoved} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	return diff
+}
+
+// roleDurations keys each role by "Title at Company"
+func roleDurations(experience []Experience) map[string]string {
+	roles := make(map[string]string)
+	for _, exp := range experience {
+		label := strings.TrimSpace(exp.Title)
+		if exp.Company != "" {
+			label = strings.TrimSpace(label + " at " + exp.Company)
+		}
+		if label != "" {
+			roles[label] = exp.Duration
+		}
+	}
+	return roles
+}
+
+func educationLabels(education []Education) []string {
+	var labels []string
+	for _, edu := range education {
+		label := strings.Trim(strings.TrimSpace(edu.Degree+", "+edu.Institution), ", ")
+		if label != "" {
+			labels = append(labels, label)
+		}
+	}
+	return labels
+}
+
+func projectNames(projects []Project) []string {
+	var names []string
+	for _, project := range projects {
+		if name := strings.TrimSpace(project.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// GetCandidateTimeline shows how a candidate's score against a job changed
+// across resume versions. Versions never scored against the job are listed
+// as unscored; scoring them is left to the scoring endpoints.
+func GetCandidateTimeline(c *fiber.Ctx) error {
+	jobID := normalizeID(c.Query("job_id"), "job")
+	if jobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "job_id is required",
+		})
+	}
+	candidate, err := loadCandidate(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate not found",
+		})
+	}
+	if !jobExists(jobID) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description not found",
+		})
+	}
+
+	timeline := []TimelineEntry{}
+	var previous *TimelineEntry
+	for _, version := range candidate.Versions {
+		entry := TimelineEntry{
+			Version:    version.Version,
+			ResumeID:   version.ResumeID,
+			UploadedAt: version.UploadedAt,
+		}
+		if record, err := loadScoreRecord(version.ResumeID, jobID); err == nil {
+			entry.Scored = true
+			entry.OverallScore = record.Score.OverallScore
+			entry.ScoredAt = &record.ScoredAt
+			entry.JobVersion = record.JobVersion
+			if previous != nil {
+				entry.Change = entry.OverallScore - previous.OverallScore
+			}
+			previous = &entry
+		}
+		timeline = append(timeline, entry)
+	}
+
+	return c.JSON(fiber.Map{
+		"candidate_id": candidate.ID,
+		"job_id":       jobID,
+		"timeline":     timeline,
+	})
+}
+
+// ReindexCandidates links every stored resume that isn't yet part of a
+// candidate, oldest first, so resumes uploaded before candidates existed
+// get grouped too
+func ReindexCandidates(c *fiber.Ctx) error {
+	entries, err := os.ReadDir(filepath.Join("processed_texts", "resume"))
+	if err != nil && !os.IsNotExist(err) {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read resumes",
+		})
+	}
+
+	var resumes []*TextData
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), "resume_") {
+			continue
+		}
+		if data, err := LoadTextData(entry.Name(), "resume"); err == nil {
+			resumes = append(resumes, data)
+		}
+	}
+	sort.Slice(resumes, func(i, j int) bool { return resumes[i].Timestamp.Before(resumes[j].Timestamp) })
+
+	linked := 0
+	for _, data := range resumes {
+		resumeID := normalizeID(data.ID, "resume")
+		if _, err := linkResumeToCandidate(resumeID, "", data.Timestamp, resumeIdentity(resumeID, data.Entities, data.ProcessedText)); err != nil {
+			log.Printf("Error linking resume %s: %v", resumeID, err)
+			continue
+		}
+		linked++
+	}
+
+	candidates, _ := loadAllCandidates()
+	return c.JSON(fiber.Map{
+		"resumes":    linked,
+		"candidates": len(activeCandidates(candidates)),
+	})
+}
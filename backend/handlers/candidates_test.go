@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+func saveTestVault(t *testing.T, resumeID string, tokens map[string]string) {
+	t.Helper()
+	if err := utils.SaveJSONFile(redactionVaultPath(resumeID), RedactionVault{ResumeID: resumeID, Tokens: tokens}, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCandidateView(t *testing.T) {
+	saveTestVault(t, "7102", map[string]string{redactedNameToken: "Jane Doe"})
+	candidate := func(resumeIDs ...string) *Candidate {
+		c := &Candidate{
+			ID:        "cand_1",
+			Name:      "Jane Doe",
+			Emails:    []string{"jane@example.com"},
+			Phones:    []string{"+15551234567"},
+			Employers: []string{"acme"},
+			MatchKeys: []string{"email:abc"},
+			MergedFrom: []MergeRecord{
+				{CandidateID: "cand_0", Name: "J. Doe", Emails: []string{"jd@example.com"}, MatchKeys: []string{"name:def"}},
+			},
+		}
+		for i, id := range resumeIDs {
+			c.Versions = append(c.Versions, ResumeVersion{ResumeID: id, Version: i + 1, Filename: "jane_doe_cv.pdf"})
+		}
+		return c
+	}
+
+	tests := []struct {
+		name      string
+		candidate *Candidate
+		wantName  string
+		wantBlind bool
+	}{
+		{"no blind resumes", candidate("7101"), "Jane Doe", false},
+		{"latest resume blind", candidate("7101", "7102"), candidatePseudonym("7102"), true},
+		{"older resume blind", candidate("7102", "7103"), candidatePseudonym("7102"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := *tt.candidate
+			view := candidateView(tt.candidate)
+
+			if view.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", view.Name, tt.wantName)
+			}
+			if view.MatchKeys != nil {
+				t.Error("match keys are never shown")
+			}
+			if !reflect.DeepEqual(view.Employers, tt.candidate.Employers) {
+				t.Errorf("employers = %v, want them kept", view.Employers)
+			}
+			hidden := len(view.Emails) == 0 && len(view.Phones) == 0 && view.Versions[0].Filename == "" &&
+				view.MergedFrom[0].Name == "" && len(view.MergedFrom[0].Emails) == 0 && view.MergedFrom[0].MatchKeys == nil
+			if hidden != tt.wantBlind {
+				t.Errorf("contact details hidden = %v, want %v: %+v", hidden, tt.wantBlind, view)
+			}
+			if tt.candidate.Name != before.Name || len(tt.candidate.Emails) == 0 || tt.candidate.Versions[0].Filename == "" ||
+				tt.candidate.MergedFrom[0].Name == "" {
+				t.Error("candidateView modified the stored candidate")
+			}
+		})
+	}
+}
+
+func TestResumeIdentityForBlindResumes(t *testing.T) {
+	saveTestVault(t, "7201", map[string]string{
+		redactedNameToken:  "Jane Doe",
+		"REDACTED_EMAIL_1": "Jane@Example.com",
+		"REDACTED_PHONE_1": "+1 (555) 123-4567",
+	})
+	entities := ExtractedEntities{
+		Name:       redactedNameToken,
+		Email:      []string{},
+		Experience: []Experience{{Company: "Acme Inc."}},
+	}
+	plain := ExtractedEntities{
+		Name:  "Jane Doe",
+		Email: []string{"jane@example.com"},
+		Phone: "+1 (555) 123-4567",
+	}
+
+	blind := resumeIdentity("7201", entities, "REDACTED_NAME")
+	open := resumeIdentity("7202", plain, "Jane Doe")
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"blind flag", blind.Blind, true},
+		{"blind name", blind.Name, ""},
+		{"blind emails", len(blind.Emails), 0},
+		{"blind phones", len(blind.Phones), 0},
+		{"employers kept", len(blind.Employers), 1},
+		// The vault's originals still produce the same keys as an open upload
+		{"match keys", blind.Keys, open.Keys},
+		{"open flag", open.Blind, false},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestDiffResumes(t *testing.T) {
+	older := ExtractedEntities{
+		Skills:     []string{"Go", "SQL"},
+		Experience: []Experience{{Title: "Engineer", Company: "Acme", Duration: "2019 - 2021"}, {Title: "Intern", Company: "Initech"}},
+		Education:  []Education{{Degree: "BSc", Institution: "Leeds"}},
+		Projects:   []Project{{Name: "Guild"}},
+	}
+	newer := ExtractedEntities{
+		Skills:     []string{"Go", "Kubernetes"},
+		Experience: []Experience{{Title: "Engineer", Company: "Acme", Duration: "2019 - 2022"}, {Title: "Lead", Company: "Globex"}},
+		Education:  []Education{{Degree: "BSc", Institution: "Leeds"}, {Degree: "MSc", Institution: "York"}},
+	}
+
+	diff := diffResumes(older, newer)
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"skills added", diff.SkillsAdded, []string{"Kubernetes"}},
+		{"skills removed", diff.SkillsRemoved, []string{"SQL"}},
+		{"roles added", diff.RolesAdded, []string{"Lead at Globex"}},
+		{"roles removed", diff.RolesRemoved, []string{"Intern at Initech"}},
+		{"date changes", diff.DateChanges, []DateChange{{Role: "Engineer at Acme", From: "2019 - 2021", To: "2019 - 2022"}}},
+		{"education added", diff.EducationAdded, []string{"MSc, York"}},
+		{"education removed", diff.EducationRemoved, []string{}},
+		{"projects removed", diff.ProjectsRemoved, []string{"Guild"}},
+		{"projects added", diff.ProjectsAdded, []string{}},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %#v, want %#v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestGetCandidateTimelineIsReadOnly(t *testing.T) {
+	saveTestText(t, "job", "7301", TextData{ProcessedText: "Backend engineer"})
+	candidate := &Candidate{
+		ID: "cand_7300",
+		Versions: []ResumeVersion{
+			{ResumeID: "7301", Version: 1},
+			{ResumeID: "7302", Version: 2},
+			{ResumeID: "7303", Version: 3},
+		},
+	}
+	if err := saveCandidate(candidate); err != nil {
+		t.Fatal(err)
+	}
+	for id, score := range map[string]float64{"7301": 60, "7303": 75} {
+		if err := saveScoreRecord(id, "7301", 1, ScoreResponse{OverallScore: score}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := fiber.New()
+	app.Get("/candidates/:id/timeline", GetCandidateTimeline)
+
+	tests := []struct {
+		name, path string
+		want       int
+	}{
+		{"missing job_id", "/candidates/cand_7300/timeline", 400},
+		{"unknown candidate", "/candidates/cand_404/timeline?job_id=7301", 404},
+		{"unknown job", "/candidates/cand_7300/timeline?job_id=404", 404},
+		{"timeline", "/candidates/cand_7300/timeline?job_id=7301", 200},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Fatalf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+		if tt.want != 200 {
+			continue
+		}
+
+		var body struct {
+			Timeline []TimelineEntry `json:"timeline"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		got := make([][3]float64, len(body.Timeline))
+		for i, entry := range body.Timeline {
+			scored := 0.0
+			if entry.Scored {
+				scored = 1
+			}
+			got[i] = [3]float64{scored, entry.OverallScore, entry.Change}
+		}
+		// The unscored version is skipped when computing the change
+		want := [][3]float64{{1, 60, 0}, {0, 0, 0}, {1, 75, 15}}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("timeline (scored, score, change) = %v, want %v", got, want)
+		}
+	}
+
+	if _, err := os.Stat(scoreRecordPath("7302", "7301")); !os.IsNotExist(err) {
+		t.Errorf("reading the timeline scored version 2: %v", err)
+	}
+}
@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+	"unicode"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Sentence grounding states
+const (
+	SentenceGrounded    = "grounded"
+	SentenceBoilerplate = "boilerplate"
+	SentenceUngrounded  = "ungrounded"
+)
+
+var (
+	coverLetterTones   = map[string]string{"professional": "formal and confident", "enthusiastic": "energetic and warm", "concise": "direct and brief", "friendly": "personable and conversational"}
+	coverLetterLengths = map[string]int{"short": 150, "medium": 250, "long": 400} // target word counts
+	boilerplatePattern = regexp.MustCompile(`(?i)^(dear\b|to whom|hello\b|sincerely|best regards|kind regards|regards|thank you|thanks\b|i look forward|i would welcome|i am writing|i'm writing|please find)`)
+	evidenceRefPattern = regexp.MustCompile(`\s*\[(E\d+(?:\s*,\s*E\d+)*)\]`)
+)
+
+// EvidenceItem is a resume fact a cover letter sentence may cite
+type EvidenceItem struct {
+	ID      string `json:"id"`
+	Section string `json:"section"`
+	Label   string `json:"label"`
+	Text    string `json:"text"`
+}
+
+// LetterSentence is one sentence of the letter with its sources
+type LetterSentence struct {
+	Paragraph int      `json:"paragraph"`
+	Text      string   `json:"text"`
+	Sources   []string `json:"sources"`
+	Status    string   `json:"status"`
+	Issues    []string `json:"issues,omitempty"`
+}
+
+// GroundingCheck summarizes how much of the letter traces back to the resume
+type GroundingCheck struct {
+	Passed          bool    `json:"passed"`
+	GroundedRatio   float64 `json:"grounded_ratio"` // of non-boilerplate sentences
+	UngroundedCount int     `json:"ungrounded_count"`
+	Flagged         []int   `json:"flagged_sentences"`
+}
+
+// CoverLetter is a generated letter with per-sentence evidence links
+type CoverLetter struct {
+	ID        string           `json:"id"`
+	ResumeID  string           `json:"resume_id"`
+	JobID     string           `json:"job_id"`
+	Tone      string           `json:"tone"`
+	Length    string           `json:"length"`
+	Source    string           `json:"source"`
+	Text      string           `json:"text"`
+	Sentences []LetterSentence `json:"sentences"`
+	Evidence  []EvidenceItem   `json:"evidence"`
+	Grounding GroundingCheck   `json:"grounding"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// GenerateCoverLetter writes a cover letter from the stored resume entities
+// and job requirements, linking each claim to a resume fact
+func GenerateCoverLetter(c *fiber.Ctx) error {
+	var request struct {
+		ResumeID string `json:"resume_id"`
+		JobID    string `json:"job_id"`
+		Tone     string `json:"tone"`
+		Length   string `json:"length"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.ResumeID == "" || request.JobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+	if request.Tone == "" {
+		request.Tone = "professional"
+	}
+	if request.Length == "" {
+		request.Length = "medium"
+	}
+	if _, ok := coverLetterTones[request.Tone]; !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "tone must be one of professional, enthusiastic, concise, friendly",
+		})
+	}
+	if _, ok := coverLetterLengths[request.Length]; !ok {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "length must be one of short, medium, long",
+		})
+	}
+
+	resumeID := normalizeID(request.ResumeID, "resume")
+	jobID := normalizeID(request.JobID, "job")
+	resumeData, jobData, err := loadTaskPair(&AnalysisTask{ResumeID: resumeID, JobID: jobID})
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	letter := buildCoverLetter(context.Background(), resumeID, resumeData, jobID, jobData, request.Tone, request.Length)
+	if err := utils.SaveJSONFile(coverLetterPath(resumeID, jobID), letter, 0644); err != nil {
+		log.Printf("Error saving cover letter: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save cover letter",
+		})
+	}
+	return c.JSON(letter)
+}
+
+// GetCoverLetter returns the last cover letter generated for a pair
+func GetCoverLetter(c *fiber.Ctx) error {
+	var letter CoverLetter
+	if err := utils.LoadJSONFile(coverLetterPath(c.Query("resume_id"), c.Query("job_id")), &letter); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Cover letter not found",
+		})
+	}
+	return c.JSON(letter)
+}
+
+func coverLetterPath(resumeID, jobID string) string {
+	return filepath.Join("processed_texts", "cover_letters",
+		fmt.Sprintf("cover_%s_%s.json", pathID(resumeID, "resume"), pathID(jobID, "job")))
+}
+
+func buildCoverLetter(ctx context.Context, resumeID string, resumeData *TextData, jobID string, jobData *TextData, tone, length string) *CoverLetter {
+	letter := &CoverLetter{
+		ID:        fmt.Sprintf("cover_%s_%s", resumeID, jobID),
+		ResumeID:  resumeID,
+		JobID:     jobID,
+		Tone:      tone,
+		Length:    length,
+		Source:    "llm",
+		Evidence:  collectEvidence(resumeData.Entities),
+		CreatedAt: time.Now(),
+	}
+
+	sentences, err := generateCoverLetterWithLLM(ctx, letter.Evidence, jobData, tone, length)
+	if err != nil {
+		log.Printf("Falling back to template cover letter: %v", err)
+		letter.Source = "template"
+		sentences = templateCoverLetter(resumeData.Entities, letter.Evidence, jobData, tone, length)
+	}
+
+	// The job's own wording and the candidate's name need no citation, but
+	// skills and figures still have to come from the resume
+	allowed := strings.Join(append([]string{jobData.ProcessedText, resumeData.Entities.Name, "Hiring Manager"},
+		jobData.Requirements.Responsibilities...), " ")
+	letter.Sentences = checkGrounding(sentences, letter.Evidence, resumeData.Entities.Skills, jobData.Requirements.Skills, allowed)
+	letter.Grounding = summarizeGrounding(letter.Sentences)
+	letter.Text = renderLetter(letter.Sentences)
+	return letter
+}
+
+// collectEvidence numbers every resume fact a letter may draw on
+func collectEvidence(entities ExtractedEntities) []EvidenceItem {
+	var evidence []EvidenceItem
+	add := func(section, label, text string) {
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		evidence = append(evidence, EvidenceItem{
+			ID:      fmt.Sprintf("E%d", len(evidence)+1),
+			Section: section,
+			Label:   label,
+			Text:    strings.TrimSpace(text),
+		})
+	}
+
+	if len(entities.Skills) > 0 {
+		add("skills", "Skills", strings.Join(entities.Skills, ", "))
+	}
+	for _, exp := range entities.Experience {
+		add("role", exp.Company, fmt.Sprintf("%s at %s (%s)", exp.Title, exp.Company, exp.Duration))
+	}
+	for _, bullet := range collectResumeBullets(entities) {
+		add(bullet.section, bullet.entryLabel, bullet.text)
+	}
+	for _, project := range entities.Projects {
+		if len(project.Technologies) > 0 {
+			add("project", project.Name, fmt.Sprintf("%s built with %s", project.Name, strings.Join(project.Technologies, ", ")))
+		}
+	}
+	for _, edu := range entities.Education {
+		add("education", edu.Institution, fmt.Sprintf("%s, %s %s", edu.Degree, edu.Institution, edu.Year))
+	}
+	return evidence
+}
+
+func generateCoverLetterWithLLM(ctx context.Context, evidence []EvidenceItem, jobData *TextData, tone, length string) ([]LetterSentence, error) {
+	llm, err := newLLMProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer llm.Close()
+
+	var lines []string
+	for _, item := range evidence {
+		lines = append(lines, fmt.Sprintf("[%s] (%s: %s) %s", item.ID, item.Section, item.Label, item.Text))
+	}
+
+	prompt := fmt.Sprintf(`Write a cover letter for this job using ONLY the candidate facts listed below.
+
+Job description: %s
+Required skills: %s
+Responsibilities: %s
+
+Candidate facts:
+%s
+
+Rules:
+- Tone: %s. Length: about %d words.
+- Every sentence about the candidate must cite the facts it uses, e.g. [E2] or [E2, E5].
+- Never invent employers, numbers, skills, titles or achievements that are not in the facts.
+- Greetings and sign-offs need no citation.
+
+Return only JSON: {"sentences": [{"paragraph": 0, "text": "", "sources": ["E1"]}]}`,
+		sanitizeText(jobData.ProcessedText, maxPromptLength),
+		strings.Join(jobData.Requirements.Skills, ", "),
+		strings.Join(jobData.Requirements.Responsibilities, "; "),
+		strings.Join(lines, "\n"),
+		coverLetterTones[tone], coverLetterLengths[length])
+
+	text, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr := cleanJSONString(text)
+	if jsonStr == "" {
+		return nil, fmt.Errorf("no JSON in model response")
+	}
+
+	var parsed struct {
+		Sentences []LetterSentence `json:"sentences"`
+	}
+	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
+		return nil, err
+	}
+	if len(parsed.Sentences) == 0 {
+		return nil, fmt.Errorf("model returned an empty letter")
+	}
+
+	// Models sometimes inline citations instead of listing them
+	for i := range parsed.Sentences {
+		s := &parsed.Sentences[i]
+		for _, m := range evidenceRefPattern.FindAllStringSubmatch(s.Text, -1) {
+			for _, id := range strings.Split(m[1], ",") {
+				s.Sources = append(s.Sources, strings.TrimSpace(id))
+			}
+		}
+		s.Text = strings.TrimSpace(evidenceRefPattern.ReplaceAllString(s.Text, ""))
+		s.Sources = uniqueStrings(s.Sources)
+	}
+	return parsed.Sentences, nil
+}
+
+// templateCoverLetter assembles a letter directly from the evidence, so every
+// claim is grounded by construction
+func templateCoverLetter(entities ExtractedEntities, evidence []EvidenceItem, jobData *TextData, tone, length string) []LetterSentence {
+	opening := map[string]string{
+		"professional": "I am writing to apply for this role.",
+		"enthusiastic": "I was excited to see this opening and would love to join your team.",
+		"concise":      "I am applying for this role.",
+		"friendly":     "I'm reaching out about this role, which looks like a great fit.",
+	}[tone]
+	closing := map[string]string{
+		"professional": "Thank you for your consideration; I look forward to discussing how I can contribute.",
+		"enthusiastic": "Thank you so much for your time, and I look forward to talking soon!",
+		"concise":      "Thank you for your consideration.",
+		"friendly":     "Thanks for reading, and I'd love to chat further.",
+	}[tone]
+
+	sentences := []LetterSentence{
+		{Paragraph: 0, Text: "Dear Hiring Manager,"},
+		{Paragraph: 1, Text: opening},
+	}
+
+	exactMatches, _, _ := analyzeSkillMatches(entities.Skills, jobData.Requirements.Skills)
+	if len(exactMatches) > 0 && len(evidence) > 0 && evidence[0].Section == "skills" {
+		sentences = append(sentences, LetterSentence{
+			Paragraph: 1,
+			Text:      fmt.Sprintf("My background includes %s, which this role calls for.", joinWithAnd(exactMatches)),
+			Sources:   []string{evidence[0].ID},
+		})
+	}
+	// Roles are listed most recent first
+	for _, item := range evidence {
+		if item.Section == "role" {
+			sentences = append(sentences, LetterSentence{
+				Paragraph: 1,
+				Text:      fmt.Sprintf("Most recently I worked as %s.", strings.TrimSuffix(item.Text, " ()")),
+				Sources:   []string{item.ID},
+			})
+			break
+		}
+	}
+
+	// Cite the bullets that mention the most required skills
+	budget := map[string]int{"short": 1, "medium": 3, "long": 5}[length]
+	type scored struct {
+		item  EvidenceItem
+		score int
+	}
+	var candidates []scored
+	for _, item := range evidence {
+		if item.Section != "experience" && item.Section != "project" {
+			continue
+		}
+		if score := len(keywordsIn(item.Text, jobData.Requirements.Skills)); score > 0 {
+			candidates = append(candidates, scored{item, score})
+		}
+	}
+	introduced := make(map[string]bool)
+	for i := 0; i < len(candidates) && budget > 0; i++ {
+		best := i
+		for j := i + 1; j < len(candidates); j++ {
+			if candidates[j].score > candidates[best].score {
+				best = j
+			}
+		}
+		candidates[i], candidates[best] = candidates[best], candidates[i]
+		item := candidates[i].item
+		text := strings.TrimSuffix(item.Text, ".") + "."
+		sources := []string{item.ID}
+		if introduced[item.Label] {
+			text = "I also " + strings.ToLower(text[:1]) + text[1:]
+		} else if item.Section == "project" {
+			text = fmt.Sprintf("On my %s project: %s", item.Label, text)
+		} else {
+			text = fmt.Sprintf("In my work as %s: %s", item.Label, text)
+		}
+		// Cite the role header too, since the sentence names the role
+		for _, role := range evidence {
+			if role.Section == "role" && strings.HasPrefix(role.Text, item.Label) {
+				sources = append(sources, role.ID)
+				break
+			}
+		}
+		introduced[item.Label] = true
+		sentences = append(sentences, LetterSentence{Paragraph: 2, Text: text, Sources: sources})
+		budget--
+	}
+
+	sentences = append(sentences,
+		LetterSentence{Paragraph: 3, Text: closing},
+		LetterSentence{Paragraph: 4, Text: "Sincerely,"},
+	)
+	if entities.Name != "" {
+		sentences = append(sentences, LetterSentence{Paragraph: 5, Text: entities.Name})
+	}
+	return sentences
+}
+
+func joinWithAnd(items []string) string {
+	if len(items) <= 1 {
+		return strings.Join(items, "")
+	}
+	return strings.Join(items[:len(items)-1], ", ") + " and " + items[len(items)-1]
+}
+
+// checkGrounding verifies each sentence against the evidence it cites and
+// flags sentences that can't be traced back to the resume. allowedText holds
+// names any sentence may mention, such as the company, the job title and
+// the candidate's name; skills and figures are only accepted from the
+// resume evidence.
+func checkGrounding(sentences []LetterSentence, evidence []EvidenceItem, resumeSkills, jobSkills []string, allowedText string) []LetterSentence {
+	byID := make(map[string]EvidenceItem)
+	evidenceText := make([]string, 0, len(evidence))
+	for _, item := range evidence {
+		byID[item.ID] = item
+		evidenceText = append(evidenceText, item.Text)
+	}
+	allowedLower := strings.ToLower(allowedText)
+	evidenceLower := strings.ToLower(strings.Join(evidenceText, " "))
+	skills := append(append([]string{}, resumeSkills...), jobSkills...)
+
+	checked := make([]LetterSentence, 0, len(sentences))
+	for _, s := range sentences {
+		s.Text = strings.TrimSpace(s.Text)
+		if s.Text == "" {
+			continue
+		}
+		s.Issues = nil
+		if s.Sources == nil {
+			s.Sources = []string{}
+		}
+
+		var cited []string
+		for _, id := range s.Sources {
+			if item, ok := byID[id]; ok {
+				cited = append(cited, item.Text)
+			} else {
+				s.Issues = append(s.Issues, fmt.Sprintf("cites unknown source %s", id))
+			}
+		}
+
+		if len(cited) == 0 {
+			// A sentence with no citation is fine as long as it states no facts
+			if len(s.Issues) == 0 && (boilerplatePattern.MatchString(s.Text) || len(factTerms(s.Text, skills, allowedLower, evidenceLower)) == 0) {
+				s.Status = SentenceBoilerplate
+			} else {
+				s.Status = SentenceUngrounded
+				s.Issues = append(s.Issues, "states facts without citing a resume source")
+			}
+			checked = append(checked, s)
+			continue
+		}
+
+		source := strings.Join(cited, " ")
+		s.Issues = append(s.Issues, detectAddedClaims(source, s.Text, resumeSkills, jobSkills)...)
+		sourceLower := strings.ToLower(source)
+		for _, term := range factTerms(s.Text, nil, allowedLower, "") {
+			// Figures are already covered by detectAddedClaims
+			if !strings.Contains(sourceLower, strings.ToLower(term)) && !strings.ContainsAny(term, "0123456789") && !issuesMention(s.Issues, term) {
+				s.Issues = append(s.Issues, fmt.Sprintf("%q does not appear in the cited sources", term))
+			}
+		}
+		s.Status = SentenceGrounded
+		if len(s.Issues) > 0 {
+			s.Status = SentenceUngrounded
+		}
+		checked = append(checked, s)
+	}
+	return checked
+}
+
+// factTerms returns the checkable facts in a sentence: names (capitalized
+// words after the first), figures and skill mentions. Terms found in the
+// resume evidence are skipped; allowedLower only excuses names, never a
+// figure or a word of one of the skills.
+func factTerms(sentence string, skills []string, allowedLower, evidenceLower string) []string {
+	var terms []string
+	for i, word := range strings.Fields(sentence) {
+		word = strings.Trim(word, ".,;:!?()\"'")
+		lower := strings.ToLower(word)
+		if word == "" || word == "I" || strings.HasPrefix(word, "I'") || (evidenceLower != "" && strings.Contains(evidenceLower, lower)) {
+			continue
+		}
+		if strings.Contains(allowedLower, lower) && !strings.ContainsAny(word, "0123456789") && !isSkillWord(word, skills) {
+			continue
+		}
+		first := []rune(word)[0]
+		if strings.ContainsAny(word, "0123456789") || (i > 0 && unicode.IsUpper(first)) {
+			terms = append(terms, word)
+		}
+	}
+	for _, skill := range keywordsIn(sentence, skills) {
+		if !strings.Contains(evidenceLower, strings.ToLower(skill)) {
+			terms = append(terms, skill)
+		}
+	}
+	return uniqueStrings(terms)
+}
+
+// isSkillWord reports whether word is one of the words of a skill
+func isSkillWord(word string, skills []string) bool {
+	for _, skill := range skills {
+		for _, part := range strings.Fields(skill) {
+			if strings.EqualFold(strings.Trim(part, ".,;:()"), word) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func issuesMention(issues []string, term string) bool {
+	for _, issue := range issues {
+		if strings.Contains(issue, fmt.Sprintf("%q", term)) {
+			return true
+		}
+	}
+	return false
+}
+
+func summarizeGrounding(sentences []LetterSentence) GroundingCheck {
+	check := GroundingCheck{Flagged: []int{}}
+	substantive := 0
+	grounded := 0
+	for i, s := range sentences {
+		switch s.Status {
+		case SentenceGrounded:
+			substantive++
+			grounded++
+		case SentenceUngrounded:
+			substantive++
+			check.UngroundedCount++
+			check.Flagged = append(check.Flagged, i)
+		}
+	}
+	check.GroundedRatio = 1
+	if substantive > 0 {
+		check.GroundedRatio = float64(grounded) / float64(substantive)
+	}
+	check.Passed = check.UngroundedCount == 0
+	return check
+}
+
+// renderLetter joins sentences into paragraphs
+func renderLetter(sentences []LetterSentence) string {
+	var paragraphs []string
+	var current []string
+	paragraph := -1
+	for _, s := range sentences {
+		if s.Paragraph != paragraph && len(current) > 0 {
+			paragraphs = append(paragraphs, strings.Join(current, " "))
+			current = nil
+		}
+		paragraph = s.Paragraph
+		current = append(current, s.Text)
+	}
+	if len(current) > 0 {
+		paragraphs = append(paragraphs, strings.Join(current, " "))
+	}
+	return strings.Join(paragraphs, "\n\n")
+}
@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+)
+
+func coverLetterEntities() ExtractedEntities {
+	return ExtractedEntities{
+		Name:   "Jane Doe",
+		Skills: []string{"Go", "PostgreSQL"},
+		Experience: []Experience{{
+			Title: "Backend Engineer", Company: "Acme", Duration: "2019 - 2023",
+			Responsibilities: []string{"Built payment services in Go handling 2M requests a day"},
+		}},
+		Projects:  []Project{{Name: "Ledger", Technologies: []string{"Go"}}},
+		Education: []Education{{Degree: "BSc Computer Science", Institution: "State University", Year: "2019"}},
+	}
+}
+
+func TestCollectEvidence(t *testing.T) {
+	var got []string
+	for _, item := range collectEvidence(coverLetterEntities()) {
+		got = append(got, item.ID+":"+item.Section+":"+item.Text)
+	}
+	want := []string{
+		"E1:skills:Go, PostgreSQL",
+		"E2:role:Backend Engineer at Acme (2019 - 2023)",
+		"E3:experience:Built payment services in Go handling 2M requests a day",
+		"E4:project:Ledger built with Go",
+		"E5:education:BSc Computer Science, State University 2019",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("evidence = %q, want %q", got, want)
+	}
+}
+
+func TestCheckGrounding(t *testing.T) {
+	evidence := collectEvidence(coverLetterEntities())
+	resumeSkills := []string{"Go", "PostgreSQL"}
+	jobSkills := []string{"Go", "Kubernetes"}
+	allowed := "Globex is hiring a Platform Engineer. Jane Doe Hiring Manager"
+
+	tests := []struct {
+		name       string
+		sentence   LetterSentence
+		wantStatus string
+		wantIssue  string
+	}{
+		{"greeting", LetterSentence{Text: "Dear Hiring Manager,"}, SentenceBoilerplate, ""},
+		{"job wording needs no citation", LetterSentence{Text: "I am excited about the Platform Engineer role at Globex."}, SentenceBoilerplate, ""},
+		{"cited fact", LetterSentence{Text: "At Acme I built payment services in Go.", Sources: []string{"E2", "E3"}}, SentenceGrounded, ""},
+		{"uncited fact", LetterSentence{Text: "I ran Kubernetes clusters for years."}, SentenceUngrounded, "states facts without citing"},
+		{"uncited figure", LetterSentence{Text: "I handled 5M requests a day."}, SentenceUngrounded, "states facts without citing"},
+		{"inflated figure", LetterSentence{Text: "I built services handling 20M requests a day.", Sources: []string{"E3"}}, SentenceUngrounded, `new figure "20M"`},
+		{"skill the job wants but the resume lacks", LetterSentence{Text: "I built payment services in Go on Kubernetes.", Sources: []string{"E3"}}, SentenceUngrounded, `new skill "Kubernetes"`},
+		{"name not in the cited source", LetterSentence{Text: "I built payment services in Go at Initech.", Sources: []string{"E3"}}, SentenceUngrounded, `"Initech" does not appear`},
+		{"unknown source", LetterSentence{Text: "I built payment services.", Sources: []string{"E9"}}, SentenceUngrounded, "cites unknown source E9"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checked := checkGrounding([]LetterSentence{tt.sentence}, evidence, resumeSkills, jobSkills, allowed)
+			if len(checked) != 1 {
+				t.Fatalf("got %d sentences", len(checked))
+			}
+			got := checked[0]
+			if got.Status != tt.wantStatus {
+				t.Errorf("status = %s (issues %q), want %s", got.Status, got.Issues, tt.wantStatus)
+			}
+			if tt.wantIssue != "" && !strings.Contains(strings.Join(got.Issues, "|"), tt.wantIssue) {
+				t.Errorf("issues = %q, want one containing %q", got.Issues, tt.wantIssue)
+			}
+		})
+	}
+
+	if checked := checkGrounding([]LetterSentence{{Text: "  "}}, evidence, nil, nil, ""); len(checked) != 0 {
+		t.Errorf("empty sentences kept: %+v", checked)
+	}
+}
+
+func TestTemplateCoverLetterIsGrounded(t *testing.T) {
+	entities := coverLetterEntities()
+	evidence := collectEvidence(entities)
+	job := &TextData{ProcessedText: "Platform Engineer", Requirements: JobRequirements{Skills: []string{"Go", "PostgreSQL"}}}
+
+	for _, tone := range []string{"professional", "enthusiastic", "concise", "friendly"} {
+		for _, length := range []string{"short", "long"} {
+			sentences := templateCoverLetter(entities, evidence, job, tone, length)
+			checked := checkGrounding(sentences, evidence, entities.Skills, job.Requirements.Skills, job.ProcessedText+" Jane Doe Hiring Manager")
+			if grounding := summarizeGrounding(checked); !grounding.Passed {
+				for _, i := range grounding.Flagged {
+					t.Errorf("%s/%s: %q flagged: %q", tone, length, checked[i].Text, checked[i].Issues)
+				}
+			}
+			if text := renderLetter(checked); !strings.HasPrefix(text, "Dear Hiring Manager,\n\n") || !strings.HasSuffix(text, "Sincerely,\n\nJane Doe") {
+				t.Errorf("%s/%s letter = %q", tone, length, text)
+			}
+		}
+	}
+}
+
+func TestSummarizeGrounding(t *testing.T) {
+	sentences := []LetterSentence{
+		{Status: SentenceBoilerplate},
+		{Status: SentenceGrounded},
+		{Status: SentenceUngrounded},
+		{Status: SentenceGrounded},
+	}
+	check := summarizeGrounding(sentences)
+	if check.Passed || check.UngroundedCount != 1 || check.GroundedRatio != 2.0/3 || len(check.Flagged) != 1 || check.Flagged[0] != 2 {
+		t.Errorf("grounding = %+v", check)
+	}
+	if empty := summarizeGrounding(nil); !empty.Passed || empty.GroundedRatio != 1 {
+		t.Errorf("empty letter grounding = %+v", empty)
+	}
+}
+
+func TestJoinWithAnd(t *testing.T) {
+	tests := []struct {
+		items []string
+		want  string
+	}{
+		{nil, ""},
+		{[]string{"Go"}, "Go"},
+		{[]string{"Go", "SQL"}, "Go and SQL"},
+		{[]string{"Go", "SQL", "Kafka"}, "Go, SQL and Kafka"},
+	}
+	for _, tt := range tests {
+		if got := joinWithAnd(tt.items); got != tt.want {
+			t.Errorf("joinWithAnd(%q) = %q, want %q", tt.items, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,659 @@
+package handlers
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"math"
+	"math/bits"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Dedup decisions
+const (
+	DedupAutoMerge = "auto_merge"
+	DedupReview    = "review"
+	DedupNoMatch   = "no_match"
+)
+
+// Dedup review statuses
+const (
+	ReviewPending  = "pending"
+	ReviewMerged   = "merged"
+	ReviewRejected = "rejected"
+)
+
+const (
+	autoMergeConfidence = 0.85
+	reviewConfidence    = 0.55
+	reviewNameSimilar   = 0.9 // A near-identical name alone is worth a human look
+	defaultCountryCode  = "1"
+)
+
+var (
+	employerSuffixes = regexp.MustCompile(`\b(inc|llc|ltd|limited|corp|corporation|co|gmbh|plc|pvt|company|group)\b`)
+	nonAlphaNum      = regexp.MustCompile(`[^a-z0-9 ]+`)
+)
+
+// DedupSignals are the pieces of evidence behind a match
+type DedupSignals struct {
+	EmailMatch       bool     `json:"email_match"`
+	PhoneMatch       bool     `json:"phone_match"`
+	ConflictingEmail bool     `json:"conflicting_email"`
+	NameSimilarity   float64  `json:"name_similarity"`
+	SharedEmployers  []string `json:"shared_employers"`
+	TextSimilarity   float64  `json:"text_similarity"` // SimHash agreement; 0.5 is chance
+	Evidence         []string `json:"evidence"`        // e.g. "email matched"; never the contact itself
+}
+
+// DedupMatch is how strongly a resume or candidate matches an existing candidate
+type DedupMatch struct {
+	CandidateID string       `json:"candidate_id"`
+	Name        string       `json:"name"`
+	Confidence  float64      `json:"confidence"`
+	Decision    string       `json:"decision"`
+	Signals     DedupSignals `json:"signals"`
+	candidate   *Candidate
+}
+
+// DedupReviewItem is an uncertain match waiting for a recruiter's decision
+type DedupReviewItem struct {
+	ID          string     `json:"id"`
+	CandidateID string     `json:"candidate_id"` // the newer candidate
+	MatchID     string     `json:"match_id"`     // the existing candidate it may duplicate
+	Match       DedupMatch `json:"match"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	ResolvedAt  *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy  string     `json:"resolved_by,omitempty"`
+}
+
+// MergeRecord remembers what a merge added so it can be undone
+type MergeRecord struct {
+	CandidateID string    `json:"candidate_id"`
+	Name        string    `json:"name"`
+	ResumeIDs   []string  `json:"resume_ids"`
+	Emails      []string  `json:"emails"`    // only those the target didn't already have
+	Phones      []string  `json:"phones"`    // likewise
+	Employers   []string  `json:"employers"` // likewise
+	MergedAt    time.Time `json:"merged_at"`
+	MergedBy    string    `json:"merged_by"`
+	MatchKeys   []string  `json:"match_keys,omitempty"`
+}
+
+// normalizePhone converts a phone number to E.164. Numbers without a country
+// code get DEFAULT_PHONE_COUNTRY_CODE (default 1).
+func normalizePhone(phone string) string {
+	phone = strings.TrimSpace(phone)
+	digits := strings.Map(func(r rune) rune {
+		if unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, phone)
+	switch {
+	case len(digits) < 7 || len(digits) > 15:
+		return ""
+	case strings.HasPrefix(phone, "+"):
+		return "+" + digits
+	case strings.HasPrefix(digits, "00"):
+		return "+" + digits[2:]
+	}
+
+	countryCode := os.Getenv("DEFAULT_PHONE_COUNTRY_CODE")
+	if countryCode == "" {
+		countryCode = defaultCountryCode
+	}
+	if strings.HasPrefix(digits, countryCode) && len(digits) > 10 {
+		return "+" + digits
+	}
+	// National trunk prefixes like the UK's leading 0 are dropped
+	return "+" + countryCode + strings.TrimPrefix(digits, "0")
+}
+
+// normalizeEmployer lowercases a company name and drops legal suffixes
+func normalizeEmployer(employer string) string {
+	employer = nonAlphaNum.ReplaceAllString(strings.ToLower(employer), " ")
+	employer = employerSuffixes.ReplaceAllString(employer, " ")
+	return strings.Join(strings.Fields(employer), " ")
+}
+
+// simHash fingerprints text so near-identical resumes have nearly equal hashes
+func simHash(text string) uint64 {
+	words := strings.Fields(strings.ToLower(text))
+	if len(words) < 3 {
+		return 0
+	}
+	var weights [64]int
+	for i := 0; i+2 < len(words); i++ {
+		h := fnv.New64a()
+		h.Write([]byte(words[i] + " " + words[i+1] + " " + words[i+2]))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+	var fingerprint uint64
+	for bit, w := range weights {
+		if w > 0 {
+			fingerprint |= 1 << uint(bit)
+		}
+	}
+	return fingerprint
+}
+
+func simHashSimilarity(a, b uint64) float64 {
+	return 1 - float64(bits.OnesCount64(a^b))/64
+}
+
+// nameSimilarity compares names by edit distance, ignoring word order and punctuation
+func nameSimilarity(a, b string) float64 {
+	a, b = normalizeName(a), normalizeName(b)
+	if a == "" || b == "" {
+		return 0
+	}
+	similarity := func(x, y string) float64 {
+		longest := max(len([]rune(x)), len([]rune(y)))
+		return 1 - float64(levenshteinDistance(x, y))/float64(longest)
+	}
+	sortedWords := func(s string) string {
+		words := strings.Fields(s)
+		sort.Strings(words)
+		return strings.Join(words, " ")
+	}
+	return math.Max(similarity(a, b), similarity(sortedWords(a), sortedWords(b)))
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(nonAlphaNum.ReplaceAllString(strings.ToLower(name), " ")), " ")
+}
+
+// compareIdentity scores a resume identity against an existing candidate.
+// A shared email or phone is decisive; otherwise name, employers and text
+// similarity are weighed together.
+func compareIdentity(candidate *Candidate, identity candidateIdentity) DedupMatch {
+	match := DedupMatch{CandidateID: candidate.ID, Name: candidateDisplayName(candidate), candidate: candidate, Signals: DedupSignals{SharedEmployers: []string{}}}
+	signals := &match.Signals
+
+	// Match keys cover plain and blind contact details alike
+	known := candidateAsIdentity(candidate).Keys
+	signals.EmailMatch = sharesAny(keysOfKind(known, "email"), keysOfKind(identity.Keys, "email"))
+	signals.PhoneMatch = sharesAny(keysOfKind(known, "phone"), keysOfKind(identity.Keys, "phone"))
+	signals.ConflictingEmail = !signals.EmailMatch && len(keysOfKind(known, "email")) > 0 && len(keysOfKind(identity.Keys, "email")) > 0
+	if candidate.Name != "" && identity.Name != "" {
+		signals.NameSimilarity = nameSimilarity(candidate.Name, identity.Name)
+	} else if sharesAny(keysOfKind(known, "name"), keysOfKind(identity.Keys, "name")) {
+		// A blind name is only known by its key, so only an exact match counts
+		signals.NameSimilarity = 1
+	}
+	for _, employer := range identity.Employers {
+		for _, known := range candidate.Employers {
+			if employer == known {
+				signals.SharedEmployers = append(signals.SharedEmployers, employer)
+			}
+		}
+	}
+	if identity.SimHash != 0 {
+		for _, version := range candidate.Versions {
+			if hash, err := strconv.ParseUint(version.SimHash, 16, 64); err == nil && hash != 0 {
+				signals.TextSimilarity = math.Max(signals.TextSimilarity, simHashSimilarity(hash, identity.SimHash))
+			}
+		}
+	}
+
+	signals.Evidence = dedupEvidence(*signals)
+
+	if signals.EmailMatch || signals.PhoneMatch {
+		match.Confidence, match.Decision = 1, DedupAutoMerge
+		return match
+	}
+
+	employerScore := 0.0
+	if len(signals.SharedEmployers) > 0 {
+		employerScore = 1
+	}
+	// Unrelated texts agree on about half their bits
+	textScore := math.Max(0, (signals.TextSimilarity-0.5)*2)
+	match.Confidence = 0.5*signals.NameSimilarity + 0.25*employerScore + 0.25*textScore
+	if signals.ConflictingEmail {
+		match.Confidence *= 0.7
+	}
+
+	switch {
+	case match.Confidence >= autoMergeConfidence && !signals.ConflictingEmail:
+		match.Decision = DedupAutoMerge
+	case match.Confidence >= reviewConfidence || signals.NameSimilarity >= reviewNameSimilar:
+		match.Decision = DedupReview
+	default:
+		match.Decision = DedupNoMatch
+	}
+	return match
+}
+
+// dedupEvidence describes the signals in words. Shared contacts are named by
+// kind only so a review never shows a blind candidate's email or phone.
+func dedupEvidence(signals DedupSignals) []string {
+	evidence := []string{}
+	if signals.EmailMatch {
+		evidence = append(evidence, "email matched")
+	}
+	if signals.PhoneMatch {
+		evidence = append(evidence, "phone matched")
+	}
+	if signals.ConflictingEmail {
+		evidence = append(evidence, "emails differ")
+	}
+	if signals.NameSimilarity >= reviewNameSimilar {
+		evidence = append(evidence, "name matched")
+	}
+	if len(signals.SharedEmployers) > 0 {
+		evidence = append(evidence, "employer matched")
+	}
+	if signals.TextSimilarity >= 0.9 {
+		evidence = append(evidence, "resume text nearly identical")
+	}
+	return evidence
+}
+
+// bestDuplicate returns the strongest match among the candidates
+func bestDuplicate(candidates []*Candidate, identity candidateIdentity) DedupMatch {
+	best := DedupMatch{Decision: DedupNoMatch}
+	for _, candidate := range candidates {
+		match := compareIdentity(candidate, identity)
+		if match.Decision != DedupNoMatch && match.Confidence > best.Confidence {
+			best = match
+		}
+	}
+	return best
+}
+
+func sharesAny(a, b []string) bool {
+	set := make(map[string]bool, len(a))
+	for _, v := range a {
+		set[v] = true
+	}
+	for _, v := range b {
+		if v != "" && set[v] {
+			return true
+		}
+	}
+	return false
+}
+
+// activeCandidates drops candidates that were merged into another
+func activeCandidates(candidates []*Candidate) []*Candidate {
+	active := make([]*Candidate, 0, len(candidates))
+	for _, candidate := range candidates {
+		if candidate.MergedInto == "" {
+			active = append(active, candidate)
+		}
+	}
+	return active
+}
+
+// candidateAsIdentity lets an existing candidate be matched against the others
+func candidateAsIdentity(candidate *Candidate) candidateIdentity {
+	identity := normalizeIdentity(candidateIdentity{
+		Name:      candidate.Name,
+		Emails:    candidate.Emails,
+		Phones:    candidate.Phones,
+		Employers: candidate.Employers,
+		Keys:      candidate.MatchKeys,
+	})
+	if n := len(candidate.Versions); n > 0 {
+		identity.SimHash, _ = strconv.ParseUint(candidate.Versions[n-1].SimHash, 16, 64)
+	}
+	return identity
+}
+
+func dedupReviewPath(id string) string {
+	return filepath.Join("processed_texts", "dedup", safeID(id)+".json")
+}
+
+func queueDedupReview(candidate *Candidate, match DedupMatch) error {
+	item := DedupReviewItem{
+		ID:          fmt.Sprintf("review_%d", time.Now().UnixNano()),
+		CandidateID: candidate.ID,
+		MatchID:     match.CandidateID,
+		Match:       match,
+		Status:      ReviewPending,
+		CreatedAt:   time.Now(),
+	}
+	return utils.SaveJSONFile(dedupReviewPath(item.ID), item, 0644)
+}
+
+func loadDedupReviews() ([]DedupReviewItem, error) {
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "dedup", "review_*.json"))
+	if err != nil {
+		return nil, err
+	}
+	items := make([]DedupReviewItem, 0, len(paths))
+	for _, path := range paths {
+		var item DedupReviewItem
+		if err := utils.LoadJSONFile(path, &item); err != nil {
+			log.Printf("Skipping unreadable dedup review %s: %v", path, err)
+			continue
+		}
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].CreatedAt.Before(items[j].CreatedAt) })
+	return items, nil
+}
+
+// mergeCandidates folds source into target and records how to undo it
+func mergeCandidates(target, source *Candidate, actor string) error {
+	if target.ID == source.ID {
+		return fiber.NewError(400, "Cannot merge a candidate into itself")
+	}
+	if target.MergedInto != "" || source.MergedInto != "" {
+		return fiber.NewError(409, "Candidate has already been merged")
+	}
+
+	record := MergeRecord{
+		CandidateID: source.ID,
+		Name:        source.Name,
+		ResumeIDs:   []string{},
+		MergedAt:    time.Now(),
+		MergedBy:    actor,
+	}
+	record.Emails, _ = diffLists(target.Emails, source.Emails)
+	record.Phones, _ = diffLists(target.Phones, source.Phones)
+	record.Employers, _ = diffLists(target.Employers, source.Employers)
+	record.MatchKeys, _ = diffLists(target.MatchKeys, source.MatchKeys)
+	for _, version := range source.Versions {
+		record.ResumeIDs = append(record.ResumeIDs, version.ResumeID)
+	}
+
+	target.Emails = append(target.Emails, record.Emails...)
+	target.Phones = append(target.Phones, record.Phones...)
+	target.Employers = append(target.Employers, record.Employers...)
+	target.MatchKeys = append(target.MatchKeys, record.MatchKeys...)
+	target.Versions = renumberVersions(append(target.Versions, source.Versions...))
+	target.MergedFrom = append(target.MergedFrom, record)
+	if target.Name == "" {
+		target.Name = source.Name
+	}
+	target.UpdatedAt = time.Now()
+
+	source.Versions = []ResumeVersion{}
+	source.MergedInto = target.ID
+	source.UpdatedAt = time.Now()
+
+	if err := saveCandidate(source); err != nil {
+		return err
+	}
+	return saveCandidate(target)
+}
+
+// unmergeCandidate splits a previously merged candidate back out of target
+func unmergeCandidate(target *Candidate, sourceID string) (*Candidate, error) {
+	if target.MergedInto != "" {
+		return nil, fiber.NewError(409, "Candidate was itself merged into "+target.MergedInto+"; unmerge that first")
+	}
+	index := -1
+	for i, record := range target.MergedFrom {
+		if record.CandidateID == sourceID {
+			index = i
+		}
+	}
+	if index == -1 {
+		return nil, fiber.NewError(404, "No merge of that candidate found")
+	}
+	record := target.MergedFrom[index]
+
+	source, err := loadCandidate(sourceID)
+	if err != nil {
+		return nil, fiber.NewError(404, "Merged candidate not found")
+	}
+
+	moved := make(map[string]bool)
+	for _, id := range record.ResumeIDs {
+		moved[id] = true
+	}
+	var kept, restored []ResumeVersion
+	for _, version := range target.Versions {
+		if moved[version.ResumeID] {
+			restored = append(restored, version)
+		} else {
+			kept = append(kept, version)
+		}
+	}
+	target.Versions = renumberVersions(kept)
+	source.Versions = renumberVersions(restored)
+
+	target.Emails = removeStrings(target.Emails, record.Emails)
+	target.Phones = removeStrings(target.Phones, record.Phones)
+	target.Employers = removeStrings(target.Employers, record.Employers)
+	target.MatchKeys = removeStrings(target.MatchKeys, record.MatchKeys)
+	target.MergedFrom = append(target.MergedFrom[:index], target.MergedFrom[index+1:]...)
+	target.UpdatedAt = time.Now()
+	source.MergedInto = ""
+	source.UpdatedAt = time.Now()
+
+	if err := saveCandidate(source); err != nil {
+		return nil, err
+	}
+	if err := saveCandidate(target); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// renumberVersions orders versions by upload time and numbers them from 1
+func renumberVersions(versions []ResumeVersion) []ResumeVersion {
+	if versions == nil {
+		return []ResumeVersion{}
+	}
+	sort.SliceStable(versions, func(i, j int) bool { return versions[i].UploadedAt.Before(versions[j].UploadedAt) })
+	for i := range versions {
+		versions[i].Version = i + 1
+	}
+	return versions
+}
+
+func removeStrings(values, remove []string) []string {
+	drop := make(map[string]bool, len(remove))
+	for _, v := range remove {
+		drop[strings.ToLower(v)] = true
+	}
+	kept := []string{}
+	for _, v := range values {
+		if !drop[strings.ToLower(v)] {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// GetCandidateDuplicates lists the other candidates that may be the same person
+func GetCandidateDuplicates(c *fiber.Ctx) error {
+	candidate, err := loadCandidate(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate not found",
+		})
+	}
+	candidates, err := loadAllCandidates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read candidates",
+		})
+	}
+
+	identity := candidateAsIdentity(candidate)
+	matches := []DedupMatch{}
+	for _, other := range activeCandidates(candidates) {
+		if other.ID == candidate.ID {
+			continue
+		}
+		if match := compareIdentity(other, identity); match.Decision != DedupNoMatch {
+			matches = append(matches, match)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Confidence > matches[j].Confidence })
+
+	return c.JSON(fiber.Map{
+		"candidate_id": candidate.ID,
+		"matches":      matches,
+	})
+}
+
+// MergeCandidates merges source_id into target_id
+func MergeCandidates(c *fiber.Ctx) error {
+	var request struct {
+		TargetID string `json:"target_id"`
+		SourceID string `json:"source_id"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.TargetID == "" || request.SourceID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "target_id and source_id are required",
+		})
+	}
+
+	candidatesMu.Lock()
+	defer candidatesMu.Unlock()
+
+	target, err := loadCandidate(request.TargetID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Target candidate not found",
+		})
+	}
+	source, err := loadCandidate(request.SourceID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Source candidate not found",
+		})
+	}
+	if err := mergeCandidates(target, source, requestActor(c)); err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(candidateView(target))
+}
+
+// UnmergeCandidate splits source_id back out of the candidate it was merged into
+func UnmergeCandidate(c *fiber.Ctx) error {
+	var request struct {
+		SourceID string `json:"source_id"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.SourceID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "source_id is required",
+		})
+	}
+
+	candidatesMu.Lock()
+	defer candidatesMu.Unlock()
+
+	target, err := loadCandidate(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate not found",
+		})
+	}
+	source, err := unmergeCandidate(target, request.SourceID)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"candidate": candidateView(target),
+		"restored":  candidateView(source),
+	})
+}
+
+// ListDedupReviews returns the review queue, pending items by default
+func ListDedupReviews(c *fiber.Ctx) error {
+	status := c.Query("status", ReviewPending)
+	items, err := loadDedupReviews()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read review queue",
+		})
+	}
+	filtered := []DedupReviewItem{}
+	for _, item := range items {
+		if status == "all" || item.Status == status {
+			filtered = append(filtered, item)
+		}
+	}
+	return c.JSON(fiber.Map{
+		"count":   len(filtered),
+		"reviews": filtered,
+	})
+}
+
+// ResolveDedupReview merges or rejects an uncertain match
+func ResolveDedupReview(c *fiber.Ctx) error {
+	var request struct {
+		Action string `json:"action"` // merge or reject
+	}
+	if err := c.BodyParser(&request); err != nil || (request.Action != "merge" && request.Action != "reject") {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "action must be merge or reject",
+		})
+	}
+
+	candidatesMu.Lock()
+	defer candidatesMu.Unlock()
+
+	var item DedupReviewItem
+	if err := utils.LoadJSONFile(dedupReviewPath(c.Params("id")), &item); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Review not found",
+		})
+	}
+	if item.Status != ReviewPending {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Review has already been resolved",
+		})
+	}
+
+	actor := requestActor(c)
+	if request.Action == "merge" {
+		target, err := loadCandidate(item.MatchID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Matched candidate not found"})
+		}
+		source, err := loadCandidate(item.CandidateID)
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{"error": "Candidate not found"})
+		}
+		if err := mergeCandidates(target, source, actor); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		item.Status = ReviewMerged
+	} else {
+		item.Status = ReviewRejected
+	}
+
+	now := time.Now()
+	item.ResolvedAt = &now
+	item.ResolvedBy = actor
+	if err := utils.SaveJSONFile(dedupReviewPath(item.ID), item, 0644); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save review",
+		})
+	}
+	return c.JSON(item)
+}
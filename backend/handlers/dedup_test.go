@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		country, phone, want string
+	}{
+		{"", "(555) 123-4567", "+15551234567"},
+		{"", "+1 555 123 4567", "+15551234567"},
+		{"", "1-555-123-4567", "+15551234567"},
+		{"", "0044 20 7946 0000", "+442079460000"},
+		{"44", "020 7946 0000", "+442079460000"},
+		{"44", "+44 20 7946 0000", "+442079460000"},
+		{"", "12345", ""},
+		{"", "1234567890123456", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.phone, func(t *testing.T) {
+			t.Setenv("DEFAULT_PHONE_COUNTRY_CODE", tt.country)
+			if got := normalizePhone(tt.phone); got != tt.want {
+				t.Errorf("normalizePhone(%q) = %q, want %q", tt.phone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeEmployer(t *testing.T) {
+	tests := []struct {
+		employer, want string
+	}{
+		{"Acme Inc.", "acme"},
+		{"ACME, Inc", "acme"},
+		{"Globex Corporation", "globex"},
+		{"Initech Pvt. Ltd.", "initech"},
+		{"Co-operative Bank", "operative bank"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeEmployer(tt.employer); got != tt.want {
+			t.Errorf("normalizeEmployer(%q) = %q, want %q", tt.employer, got, tt.want)
+		}
+	}
+}
+
+func TestNameSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		min, max float64
+	}{
+		{"Jane Doe", "Jane Doe", 1, 1},
+		{"Jane Doe", "jane  doe", 1, 1},
+		{"Doe, Jane", "Jane Doe", 1, 1},
+		{"Jane Doe", "Jane Do", 0.85, 0.9},
+		{"Jane Doe", "John Smith", 0, 0.3},
+		{"Jane Doe", "", 0, 0},
+	}
+	for _, tt := range tests {
+		got := nameSimilarity(tt.a, tt.b)
+		if got < tt.min || got > tt.max {
+			t.Errorf("nameSimilarity(%q, %q) = %.3f, want between %.2f and %.2f", tt.a, tt.b, got, tt.min, tt.max)
+		}
+	}
+}
+
+func TestCompareIdentity(t *testing.T) {
+	text := "Senior backend engineer building payment systems in Go and Postgres for eight years"
+	existing := &Candidate{
+		ID:        "cand_1",
+		Name:      "Jane Doe",
+		Emails:    []string{"jane@example.com"},
+		Phones:    []string{"+15551234567"},
+		Employers: []string{"acme"},
+		Versions:  []ResumeVersion{{ResumeID: "1", SimHash: fmt.Sprintf("%016x", simHash(text))}},
+	}
+	identity := func(name, email, phone string, employers ...string) candidateIdentity {
+		id := candidateIdentity{Name: name, Employers: employers}
+		if email != "" {
+			id.Emails = []string{email}
+		}
+		if phone != "" {
+			id.Phones = []string{phone}
+		}
+		return normalizeIdentity(id)
+	}
+	blind := func(id candidateIdentity) candidateIdentity {
+		id.Name, id.Emails, id.Phones, id.Blind = "", nil, nil, true
+		return id
+	}
+	withText := func(id candidateIdentity) candidateIdentity {
+		id.SimHash = simHash(text)
+		return id
+	}
+
+	tests := []struct {
+		name     string
+		identity candidateIdentity
+		want     string
+		evidence string
+	}{
+		{"same email", identity("J. Doe", "JANE@example.com", ""), DedupAutoMerge, "email matched"},
+		{"same phone", identity("", "", "555 123 4567"), DedupAutoMerge, "phone matched"},
+		{"blind upload with the same email", blind(identity("Jane Doe", "jane@example.com", "")), DedupAutoMerge, "email matched"},
+		{"blind upload with the same name", blind(identity("Jane Doe", "", "")), DedupReview, "name matched"},
+		{"name, employer and text", withText(identity("Jane Doe", "", "", "acme")), DedupAutoMerge, "resume text nearly identical"},
+		{"name alone", identity("Jane Doe", "", ""), DedupReview, "name matched"},
+		{"name with a different email", withText(identity("Jane Doe", "jdoe@other.org", "", "acme")), DedupReview, "emails differ"},
+		{"employer alone", identity("John Smith", "", "", "acme"), DedupNoMatch, "employer matched"},
+		{"stranger", identity("John Smith", "john@example.com", ""), DedupNoMatch, "emails differ"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match := compareIdentity(existing, tt.identity)
+			if match.Decision != tt.want {
+				t.Errorf("decision = %s (confidence %.2f, signals %+v), want %s", match.Decision, match.Confidence, match.Signals, tt.want)
+			}
+			found := false
+			for _, evidence := range match.Signals.Evidence {
+				found = found || evidence == tt.evidence
+				if strings.Contains(evidence, "@") || strings.Contains(evidence, "555") {
+					t.Errorf("evidence %q reveals a contact detail", evidence)
+				}
+			}
+			if !found {
+				t.Errorf("evidence %v, want %q", match.Signals.Evidence, tt.evidence)
+			}
+		})
+	}
+}
+
+func TestMergeAndUnmergeCandidates(t *testing.T) {
+	now := time.Now()
+	target := &Candidate{
+		ID:        "cand_8001",
+		Name:      "Jane Doe",
+		Emails:    []string{"jane@example.com"},
+		Phones:    []string{},
+		Employers: []string{"acme"},
+		Versions:  []ResumeVersion{{ResumeID: "8001", Version: 1, UploadedAt: now.Add(-2 * time.Hour)}},
+	}
+	source := &Candidate{
+		ID:        "cand_8002",
+		Name:      "J. Doe",
+		Emails:    []string{"jane@example.com", "jdoe@other.org"},
+		Phones:    []string{"+15551234567"},
+		Employers: []string{"acme", "globex"},
+		Versions:  []ResumeVersion{{ResumeID: "8002", Version: 1, UploadedAt: now.Add(-3 * time.Hour)}},
+	}
+	original := *target
+
+	if err := mergeCandidates(target, target, "alice"); err == nil {
+		t.Fatal("merging a candidate into itself should fail")
+	}
+	if err := mergeCandidates(target, source, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	merged := []struct {
+		field     string
+		got, want any
+	}{
+		{"emails", target.Emails, []string{"jane@example.com", "jdoe@other.org"}},
+		{"phones", target.Phones, []string{"+15551234567"}},
+		{"employers", target.Employers, []string{"acme", "globex"}},
+		{"versions by upload time", []string{target.Versions[0].ResumeID, target.Versions[1].ResumeID}, []string{"8002", "8001"}},
+		{"version numbers", []int{target.Versions[0].Version, target.Versions[1].Version}, []int{1, 2}},
+		{"source points at target", source.MergedInto, target.ID},
+		{"merge recorded", target.MergedFrom[0].MergedBy, "alice"},
+		{"record keeps only new emails", target.MergedFrom[0].Emails, []string{"jdoe@other.org"}},
+	}
+	for _, tt := range merged {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("after merge, %s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+	if err := mergeCandidates(target, source, "alice"); err == nil {
+		t.Error("merging an already merged candidate should fail")
+	}
+
+	restored, err := unmergeCandidate(target, source.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	unmerged := []struct {
+		field     string
+		got, want any
+	}{
+		{"target emails", target.Emails, original.Emails},
+		{"target phones", target.Phones, []string{}},
+		{"target employers", target.Employers, original.Employers},
+		{"target versions", len(target.Versions), 1},
+		{"target merges", len(target.MergedFrom), 0},
+		{"source versions", restored.Versions[0].ResumeID, "8002"},
+		{"source active", restored.MergedInto, ""},
+	}
+	for _, tt := range unmerged {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("after unmerge, %s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+	if _, err := unmergeCandidate(target, source.ID); err == nil {
+		t.Error("unmerging twice should fail")
+	}
+}
@@ -11,8 +11,6 @@ import (
 	"log"
 
 	"github.com/gofiber/fiber/v2"
-	"github.com/google/generative-ai-go/genai"
-	"google.golang.org/api/option"
 )
 
 // Only keep experience-specific types here
@@ -100,57 +98,15 @@ func GetProcessedExperience(c *fiber.Ctx) error {
 		})
 	}
 
-	// Initialize Gemini
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(os.Getenv("GEMINI_API_KEY")))
+	response, err := analyzeResumeExperience(context.Background(), resumeFilename, resumeData.Entities, jobFilename, jobData.Requirements, jobData.ProcessedText, nil)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to initialize AI client",
 		})
 	}
-	defer client.Close()
-
-	model := client.GenerativeModel("gemini-pro")
-
-	// Process each experience
-	var processedExperiences []ProcessedExperience
-	var totalMonths float64
-
-	// Log the experience data we're processing
-	fmt.Printf("Processing %d experiences\n", len(resumeData.Entities.Experience))
-
-	for _, exp := range resumeData.Entities.Experience {
-		fmt.Printf("Processing experience: %s at %s\n", exp.Title, exp.Company)
-
-		months := calculateDurationInMonths(exp.Duration)
-		totalMonths += months
-
-		// Extract skills from the experience description
-		relevantSkills := extractRelevantSkills(exp.Description, jobData.Requirements)
-
-		processed := ProcessedExperience{
-			Title:          exp.Title,
-			Company:        exp.Company,
-			Duration:       exp.Duration,
-			Description:    generateEnhancedDescription(model, ctx, exp.Description, jobData.ProcessedText),
-			RelevantSkills: relevantSkills,
-			JobFitSummary:  analyzeJobFit(model, ctx, exp.Description, jobData.ProcessedText),
-		}
-
-		processedExperiences = append(processedExperiences, processed)
-	}
-
-	// Generate overall fit analysis
-	overallFit := analyzeOverallFit(model, ctx, processedExperiences, jobData.ProcessedText)
-
-	response := ExperienceResponse{
-		TotalYearsExperience: totalMonths / 12.0, // Convert months to years
-		Experiences:          processedExperiences,
-		OverallFit:           overallFit,
-	}
 
 	// Log the response before sending
-	fmt.Printf("Sending response with %d experiences\n", len(processedExperiences))
+	fmt.Printf("Sending response with %d experiences\n", len(response.Experiences))
 
 	return c.JSON(response)
 }
@@ -192,34 +148,71 @@ func AnalyzeExperience(c *fiber.Ctx) error {
 		})
 	}
 
-	// Initialize Gemini
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(os.Getenv("GEMINI_API_KEY")))
+	response, err := analyzeResumeExperience(context.Background(), resumeId, resumeData.Entities, jobId, jobData.Requirements, jobData.ProcessedText, nil)
 	if err != nil {
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to initialize AI",
 		})
 	}
-	defer client.Close()
 
-	model := client.GenerativeModel("gemini-pro")
+	return c.JSON(response)
+}
+
+// analyzeResumeExperience enhances and summarizes each role against the job.
+// It makes two Gemini calls per role plus one for the overall fit, reporting
+// progress after each, and stores the result for later use.
+func analyzeResumeExperience(ctx context.Context, resumeID string, resume ExtractedEntities, jobID string, jobReqs JobRequirements, jobText string, progress progressFunc) (ExperienceResponse, error) {
+	if len(resume.Experience) == 0 {
+		return ExperienceResponse{
+			TotalYearsExperience: 0,
+			Experiences:          []ProcessedExperience{},
+			OverallFit:           "No experience data found in resume",
+		}, nil
+	}
+
+	// Initialize the LLM provider
+	llm, err := newLLMProvider(ctx)
+	if err != nil {
+		return ExperienceResponse{}, err
+	}
+	defer llm.Close()
 
 	var processedExperiences []ProcessedExperience
 	var totalMonths float64
+	total := 2*len(resume.Experience) + 1
+	completed := 0
+
+	// Stream generated text to the caller when it is listening for progress
+	tokenStream := func(index int, field string) func(string) {
+		if progress == nil {
+			return nil
+		}
+		return func(token string) {
+			progress.report(stageToken, completed, total, TokenChunk{Index: index, Field: field, Text: token})
+		}
+	}
 
 	// Process each experience
-	for _, exp := range resumeData.Entities.Experience {
+	for i, exp := range resume.Experience {
+		if err := ctx.Err(); err != nil {
+			return ExperienceResponse{}, err
+		}
+		log.Printf("Processing experience: %s at %s", exp.Title, exp.Company)
+
 		months := calculateDurationInMonths(exp.Duration)
 		totalMonths += months
 
 		// Generate enhanced description using Gemini
-		enhancedDesc := generateEnhancedDescription(model, ctx, exp.Description, jobData.ProcessedText)
+		enhancedDesc := generateEnhancedDescription(ctx, llm, exp.Description, jobText, tokenStream(i, "description"))
+		completed++
+		progress.report("description_enhanced", completed, total, nil)
 
 		// Extract relevant skills
-		relevantSkills := extractRelevantSkills(exp.Description, jobData.Requirements)
+		relevantSkills := extractRelevantSkills(exp.Description, jobReqs)
 
 		// Analyze job fit
-		jobFit := analyzeJobFit(model, ctx, exp.Description, jobData.ProcessedText)
+		jobFit := analyzeJobFit(ctx, llm, exp.Description, jobText, tokenStream(i, "job_fit_summary"))
+		completed++
 
 		processed := ProcessedExperience{
 			Title:          exp.Title,
@@ -231,36 +224,42 @@ func AnalyzeExperience(c *fiber.Ctx) error {
 		}
 
 		processedExperiences = append(processedExperiences, processed)
+		progress.report("experience_analyzed", completed, total, processed)
 	}
 
 	// Generate overall fit analysis
-	overallFit := analyzeOverallFit(model, ctx, processedExperiences, jobData.ProcessedText)
+	overallFit := analyzeOverallFit(ctx, llm, processedExperiences, jobText)
+	progress.report("overall_fit_analyzed", total, total, nil)
 
 	response := ExperienceResponse{
-		TotalYearsExperience: totalMonths / 12.0,
+		TotalYearsExperience: totalMonths / 12.0, // Convert months to years
 		Experiences:          processedExperiences,
 		OverallFit:           overallFit,
 	}
 
-	return c.JSON(response)
+	if err := saveAnalysisResult("experience", resumeID, jobID, response); err != nil {
+		log.Printf("Error saving experience analysis: %v", err)
+	}
+
+	return response, nil
 }
 
-func analyzeJobFit(model *genai.GenerativeModel, ctx context.Context, expDesc, jobDesc string) string {
+func analyzeJobFit(ctx context.Context, llm LLMProvider, expDesc, jobDesc string, onToken func(string)) string {
 	prompt := fmt.Sprintf(
 		`Analyze how well this experience matches the job requirements and provide a brief one-sentence summary:
         
         Job Description: %s
         Experience: %s`, jobDesc, expDesc)
 
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	text, err := generateText(ctx, llm, prompt, onToken)
 	if err != nil {
 		return "Analysis not available"
 	}
 
-	return string(resp.Candidates[0].Content.Parts[0].(genai.Text))
+	return text
 }
 
-func analyzeOverallFit(model *genai.GenerativeModel, ctx context.Context, experiences []ProcessedExperience, jobDesc string) string {
+func analyzeOverallFit(ctx context.Context, llm LLMProvider, experiences []ProcessedExperience, jobDesc string) string {
 	prompt := fmt.Sprintf(
 		`Analyze the overall fit of the candidate's experience for this job and provide a concise summary:
         
@@ -272,12 +271,12 @@ func analyzeOverallFit(model *genai.GenerativeModel, ctx context.Context, experi
 		float64(len(experiences)),
 		formatExperienceSummary(experiences))
 
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	text, err := llm.Generate(ctx, prompt)
 	if err != nil {
 		return "Overall analysis not available"
 	}
 
-	return string(resp.Candidates[0].Content.Parts[0].(genai.Text))
+	return text
 }
 
 func formatExperienceSummary(experiences []ProcessedExperience) string {
@@ -372,7 +371,7 @@ func extractRelevantSkills(description string, jobReqs JobRequirements) []string
 	return relevantSkills
 }
 
-func generateEnhancedDescription(model *genai.GenerativeModel, ctx context.Context, description string, jobDesc string) string {
+func generateEnhancedDescription(ctx context.Context, llm LLMProvider, description string, jobDesc string, onToken func(string)) string {
 	prompt := fmt.Sprintf(
 		`Enhance this experience description to better align with the job requirements. 
         Make it more impactful and quantifiable where possible:
@@ -380,12 +379,12 @@ func generateEnhancedDescription(model *genai.GenerativeModel, ctx context.Conte
         Job Description: %s
         Experience Description: %s`, jobDesc, description)
 
-	resp, err := model.GenerateContent(ctx, genai.Text(prompt))
+	text, err := generateText(ctx, llm, prompt, onToken)
 	if err != nil {
 		return description // Return original description if enhancement fails
 	}
 
-	return string(resp.Candidates[0].Content.Parts[0].(genai.Text))
+	return text
 }
 
 // Add this new function to load data directly from path
@@ -0,0 +1,490 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Parse issue severities
+const (
+	IssueHigh   = "high"
+	IssueMedium = "medium"
+	IssueLow    = "low"
+)
+
+const (
+	minCharsPerPage      = 200 // Below this a page is probably an image
+	multiColumnLineRatio = 0.2 // Share of layout lines with a wide gutter
+	tableLineThreshold   = 3   // Layout lines with 3+ cells that suggest a table
+	minHiddenTextWords   = 3   // Hidden words needed before a PDF is flagged
+	maxPDFStreamBytes    = 4 << 20
+)
+
+var (
+	columnGapPattern = regexp.MustCompile(`\S {4,}\S`)
+	cellGapPattern   = regexp.MustCompile(` {3,}|\t|\|`)
+	pdfPagesPattern  = regexp.MustCompile(`(?m)^Pages:\s+(\d+)`)
+
+	// Content stream operators that change fill colour, render mode or
+	// graphics state, and the text-showing operators they apply to
+	pdfStreamPattern = regexp.MustCompile(`(?s)stream\r?\n(.*?)\r?\n?endstream`)
+	pdfTextOpPattern = regexp.MustCompile(`([\d.]+)\s+([\d.]+)\s+([\d.]+)\s+rg\b|([\d.]+)\s+g\b|([\d.]+)\s+([\d.]+)\s+([\d.]+)\s+([\d.]+)\s+k\b|(\d)\s+Tr\b|(\((?:\\.|[^\\)])*\))\s*Tj|\[((?:\\.|[^\]])*)\]\s*TJ|\bq\b|\bQ\b|(?:^|\s)(f\*?|F|B\*?|b\*?)(?:\s|$)`)
+	pdfStringPattern = regexp.MustCompile(`\((?:\\.|[^\\)])*\)|-?[\d.]+`)
+
+	// Standard section headers that ATS parsers recognise
+	standardSectionHeaders = map[string]string{
+		"experience": "experience", "work experience": "experience", "professional experience": "experience",
+		"employment history": "experience", "work history": "experience",
+		"education": "education", "academic background": "education",
+		"skills": "skills", "technical skills": "skills", "core competencies": "skills",
+		"projects": "projects", "personal projects": "projects",
+		"summary": "summary", "professional summary": "summary", "profile": "summary", "objective": "summary",
+		"certifications": "certifications", "licenses and certifications": "certifications",
+		"awards": "awards", "publications": "publications", "volunteer experience": "volunteering",
+		"languages": "languages",
+	}
+)
+
+// ParseIssue is a problem found while extracting resume text
+type ParseIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
+
+// ExtractionDiagnostics records how cleanly a resume file could be read
+type ExtractionDiagnostics struct {
+	ResumeID            string       `json:"resume_id,omitempty"`
+	Format              string       `json:"format"`
+	PageCount           int          `json:"page_count,omitempty"`
+	CharCount           int          `json:"char_count"`
+	ImageCount          int          `json:"image_count"`
+	TableCount          int          `json:"table_count"`
+	MultiColumn         bool         `json:"multi_column"`
+	HiddenText          []string     `json:"hidden_text,omitempty"`
+	SectionHeaders      []string     `json:"section_headers"`
+	UnrecognizedHeaders []string     `json:"unrecognized_headers"`
+	Issues              []ParseIssue `json:"issues"`
+}
+
+func (d *ExtractionDiagnostics) addIssue(code, severity, message string) {
+	d.Issues = append(d.Issues, ParseIssue{Code: code, Severity: severity, Message: message})
+}
+
+// extractResumeText extracts plain text from an uploaded resume and records
+// anything that may trip up an ATS parser
+func extractResumeText(content []byte, ext string) (string, *ExtractionDiagnostics, error) {
+	diagnostics := &ExtractionDiagnostics{
+		Format:              strings.TrimPrefix(ext, "."),
+		SectionHeaders:      []string{},
+		UnrecognizedHeaders: []string{},
+		Issues:              []ParseIssue{},
+	}
+
+	var text string
+	switch ext {
+	case ".pdf":
+		cmd := exec.Command("pdftotext", "-", "-")
+		cmd.Stdin = bytes.NewReader(content)
+		var out bytes.Buffer
+		cmd.Stdout = &out
+		if err := cmd.Run(); err != nil {
+			return "", nil, fiber.NewError(500, "Could not extract text from PDF")
+		}
+		text = out.String()
+		diagnosePDF(content, text, diagnostics)
+	case ".json":
+		// JSON Resume documents are rendered to text for the text-based checks
+		resume, err := parseJSONResume(content)
+		if err != nil {
+			return "", nil, err
+		}
+		text = jsonResumeText(resume)
+	case ".docx":
+		// Fall back to the raw bytes when the document body can't be read
+		if text = diagnoseDOCX(content, diagnostics); text == "" {
+			text = string(content)
+		}
+	default:
+		return "", nil, fiber.NewError(400, "Unsupported file format")
+	}
+
+	diagnostics.CharCount = len(strings.TrimSpace(text))
+	diagnoseSections(text, diagnostics)
+	return text, diagnostics, nil
+}
+
+func diagnosePDF(content []byte, text string, d *ExtractionDiagnostics) {
+	// pdfinfo and pdfimages ship with pdftotext; missing tools only skip checks
+	if out, err := runPDFTool(content, "pdfinfo", "-"); err == nil {
+		if m := pdfPagesPattern.FindStringSubmatch(out); m != nil {
+			d.PageCount, _ = strconv.Atoi(m[1])
+		}
+	}
+	if out, err := runPDFTool(content, "pdfimages", "-list", "-"); err == nil {
+		// Output has two header lines followed by one line per image
+		if lines := strings.Split(strings.TrimSpace(out), "\n"); len(lines) > 2 {
+			d.ImageCount = len(lines) - 2
+		}
+	}
+
+	if d.PageCount > 0 && len(strings.TrimSpace(text))/d.PageCount < minCharsPerPage {
+		d.addIssue("image_only_text", IssueHigh,
+			"Very little text could be extracted; the resume may be a scanned image that an ATS cannot read")
+	}
+	if d.ImageCount > 0 {
+		d.addIssue("images", IssueLow,
+			fmt.Sprintf("%d images found; any text inside them (logos, icons, skill charts) is invisible to an ATS", d.ImageCount))
+	}
+
+	if layout, err := runPDFTool(content, "pdftotext", "-layout", "-", "-"); err == nil {
+		diagnoseLayout(layout, d)
+	}
+
+	hidden, overFill := hiddenPDFText(content)
+	if len(strings.Fields(strings.Join(hidden, " "))) >= minHiddenTextWords {
+		d.HiddenText = hidden
+		d.addIssue("hidden_text", IssueHigh,
+			fmt.Sprintf("%d pieces of white or invisible text found; an ATS reads them but a reviewer never sees them", len(hidden)))
+	}
+	if len(strings.Fields(strings.Join(overFill, " "))) >= minHiddenTextWords {
+		d.addIssue("white_text_on_fill", IssueLow,
+			fmt.Sprintf("%d pieces of white text drawn on a page with filled shapes; check they sit on a dark background and are visible", len(overFill)))
+	}
+}
+
+// hiddenPDFText walks the PDF's content streams and returns text drawn in
+// white or with the invisible render mode. White text drawn after a coloured
+// shape was filled in the same stream is probably on a dark banner or sidebar,
+// so it is returned separately in overFill rather than as hidden. Font-encoded
+// (hex) strings can't be decoded without the font, so only literal strings are
+// reported.
+func hiddenPDFText(content []byte) (hidden, overFill []string) {
+	type textState struct {
+		white     bool
+		invisible bool
+	}
+	for _, m := range pdfStreamPattern.FindAllSubmatch(content, -1) {
+		data := m[1]
+		if r, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+			inflated, err := io.ReadAll(io.LimitReader(r, maxPDFStreamBytes))
+			r.Close()
+			if err != nil && len(inflated) == 0 {
+				continue
+			}
+			data = inflated
+		}
+		if !bytes.Contains(data, []byte("BT")) {
+			continue
+		}
+
+		state := textState{}
+		var saved []textState
+		// Painted shapes stay on the page, so this isn't part of the saved state
+		filled := false
+		for _, op := range pdfTextOpPattern.FindAllStringSubmatch(string(data), -1) {
+			switch {
+			case op[1] != "":
+				state.white = pdfNumber(op[1]) >= 0.95 && pdfNumber(op[2]) >= 0.95 && pdfNumber(op[3]) >= 0.95
+			case op[4] != "":
+				state.white = pdfNumber(op[4]) >= 0.95
+			case op[5] != "":
+				state.white = pdfNumber(op[5]) <= 0.05 && pdfNumber(op[6]) <= 0.05 &&
+					pdfNumber(op[7]) <= 0.05 && pdfNumber(op[8]) <= 0.05
+			case op[9] != "":
+				state.invisible = op[9] == "3" || op[9] == "7"
+			case op[10] != "" || op[11] != "":
+				if !state.white && !state.invisible {
+					continue
+				}
+				text := pdfShownText(op[10] + op[11])
+				switch {
+				case text == "":
+				case state.white && !state.invisible && filled:
+					overFill = append(overFill, text)
+				default:
+					hidden = append(hidden, text)
+				}
+			case op[12] != "":
+				if !state.white {
+					filled = true
+				}
+			case strings.TrimSpace(op[0]) == "q":
+				saved = append(saved, state)
+			case strings.TrimSpace(op[0]) == "Q" && len(saved) > 0:
+				state, saved = saved[len(saved)-1], saved[:len(saved)-1]
+			}
+		}
+	}
+	return hidden, overFill
+}
+
+// pdfShownText joins the literal strings of a Tj or TJ operand, treating large
+// negative kerning as a word break
+func pdfShownText(operand string) string {
+	var b strings.Builder
+	for _, part := range pdfStringPattern.FindAllString(operand, -1) {
+		if !strings.HasPrefix(part, "(") {
+			if pdfNumber(part) <= -200 {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		literal := part[1 : len(part)-1]
+		for i := 0; i < len(literal); i++ {
+			if literal[i] == '\\' && i+1 < len(literal) {
+				i++
+				switch literal[i] {
+				case 'n', 'r', 't':
+					b.WriteByte(' ')
+				default:
+					b.WriteByte(literal[i])
+				}
+				continue
+			}
+			b.WriteByte(literal[i])
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+func pdfNumber(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// diagnoseLayout looks for wide gutters and cell-like gaps in layout-preserving text
+func diagnoseLayout(layout string, d *ExtractionDiagnostics) {
+	var textLines, gutterLines, tableLines int
+	for _, line := range strings.Split(layout, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" {
+			continue
+		}
+		textLines++
+		if columnGapPattern.MatchString(trimmed) {
+			gutterLines++
+		}
+		if len(cellGapPattern.Split(trimmed, -1)) >= 3 {
+			tableLines++
+		}
+	}
+
+	if textLines > 0 && float64(gutterLines)/float64(textLines) >= multiColumnLineRatio {
+		d.MultiColumn = true
+		d.addIssue("multi_column", IssueHigh,
+			"Multi-column layout detected; ATS parsers often read across columns and scramble sections")
+	}
+	if tableLines >= tableLineThreshold {
+		d.TableCount = 1
+		d.addIssue("tables", IssueMedium,
+			"Table-like layout detected; cells may be read out of order or dropped")
+	}
+}
+
+// diagnoseDOCX inspects the DOCX package and returns the document body text
+func diagnoseDOCX(content []byte, d *ExtractionDiagnostics) string {
+	reader, err := zip.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		d.addIssue("unreadable_docx", IssueHigh, "The file is not a valid DOCX document")
+		return ""
+	}
+
+	var document string
+	var headerText bool
+	for _, f := range reader.File {
+		switch {
+		case f.Name == "word/document.xml":
+			document = readZipFile(f)
+		case strings.HasPrefix(f.Name, "word/media/"):
+			d.ImageCount++
+		case strings.HasPrefix(f.Name, "word/header") || strings.HasPrefix(f.Name, "word/footer"):
+			if strings.Contains(readZipFile(f), "<w:t") {
+				headerText = true
+			}
+		}
+	}
+
+	d.TableCount = strings.Count(document, "<w:tbl>")
+	if d.TableCount > 0 {
+		d.addIssue("tables", IssueMedium,
+			fmt.Sprintf("%d tables found; cells may be read out of order or dropped", d.TableCount))
+	}
+	if strings.Contains(document, `w:num="2"`) || strings.Contains(document, `w:num="3"`) {
+		d.MultiColumn = true
+		d.addIssue("multi_column", IssueHigh,
+			"Multi-column layout detected; ATS parsers often read across columns and scramble sections")
+	}
+	if strings.Contains(document, "<w:txbxContent") {
+		d.addIssue("text_boxes", IssueHigh, "Text boxes found; many ATS parsers skip their contents entirely")
+	}
+	if d.ImageCount > 0 {
+		d.addIssue("images", IssueLow,
+			fmt.Sprintf("%d images found; any text inside them is invisible to an ATS", d.ImageCount))
+	}
+	if headerText {
+		d.addIssue("header_footer_text", IssueMedium,
+			"Text in the page header or footer (often contact details) is ignored by some ATS parsers")
+	}
+	return docxPlainText(document)
+}
+
+// docxPlainText pulls the text runs out of word/document.xml, one line per paragraph
+func docxPlainText(document string) string {
+	var b strings.Builder
+	decoder := xml.NewDecoder(strings.NewReader(document))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			break
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				b.WriteString("\t")
+			case "br":
+				b.WriteString("\n")
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+	return b.String()
+}
+
+func readZipFile(f *zip.File) string {
+	rc, err := f.Open()
+	if err != nil {
+		return ""
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+func runPDFTool(content []byte, name string, args ...string) (string, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Stdin = bytes.NewReader(content)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	if err := cmd.Run(); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
+
+// diagnoseSections finds heading-like lines and checks them against the
+// section names ATS parsers expect
+func diagnoseSections(text string, d *ExtractionDiagnostics) {
+	found := make(map[string]bool)
+	for _, line := range strings.Split(text, "\n") {
+		heading := strings.TrimRight(strings.TrimSpace(line), ":")
+		if !looksLikeHeading(heading) {
+			continue
+		}
+		if section, ok := standardSectionHeaders[strings.ToLower(heading)]; ok {
+			if !found[section] {
+				found[section] = true
+				d.SectionHeaders = append(d.SectionHeaders, section)
+			}
+			continue
+		}
+		if strings.ToUpper(heading) == heading {
+			d.UnrecognizedHeaders = append(d.UnrecognizedHeaders, heading)
+		}
+	}
+
+	for _, section := range []string{"experience", "education", "skills"} {
+		if !found[section] {
+			d.addIssue("missing_section_"+section, IssueMedium,
+				fmt.Sprintf("No recognizable %q section header; use a standard heading so the ATS can find it", section))
+		}
+	}
+	if len(d.UnrecognizedHeaders) > 0 {
+		d.addIssue("nonstandard_headers", IssueLow,
+			"Non-standard section headers found: "+strings.Join(d.UnrecognizedHeaders, ", "))
+	}
+}
+
+// redactDiagnostics makes diagnostics safe to show for a blind resume:
+// sections are re-detected on the redacted text, so the candidate's name
+// line is no longer reported as a header, and vault values are removed from
+// issue messages
+func redactDiagnostics(d *ExtractionDiagnostics, redactedText string, tokens map[string]string) {
+	issues := d.Issues[:0]
+	for _, issue := range d.Issues {
+		if strings.HasPrefix(issue.Code, "missing_section_") || issue.Code == "nonstandard_headers" {
+			continue
+		}
+		issue.Message = redactVaultValues(issue.Message, tokens)
+		issues = append(issues, issue)
+	}
+	d.Issues = issues
+	for i, text := range d.HiddenText {
+		d.HiddenText[i] = redactVaultValues(text, tokens)
+	}
+	d.SectionHeaders, d.UnrecognizedHeaders = []string{}, []string{}
+	diagnoseSections(redactedText, d)
+}
+
+// looksLikeHeading accepts short lines made mostly of letters
+func looksLikeHeading(line string) bool {
+	words := strings.Fields(line)
+	if len(words) == 0 || len(words) > 4 {
+		return false
+	}
+	for _, r := range line {
+		if !unicode.IsLetter(r) && r != ' ' && r != '&' && r != '/' {
+			return false
+		}
+	}
+	return unicode.IsUpper([]rune(line)[0])
+}
+
+func extractionDiagnosticsPath(resumeID string) string {
+	return filepath.Join("processed_texts", "diagnostics",
+		fmt.Sprintf("extraction_%s.json", pathID(resumeID, "resume")))
+}
+
+func saveExtractionDiagnostics(resumeID string, d *ExtractionDiagnostics) error {
+	d.ResumeID = resumeID
+	return utils.SaveJSONFile(extractionDiagnosticsPath(resumeID), d, 0644)
+}
+
+func loadExtractionDiagnostics(resumeID string) (*ExtractionDiagnostics, error) {
+	var d ExtractionDiagnostics
+	if err := utils.LoadJSONFile(extractionDiagnosticsPath(resumeID), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func issueCodes(d *ExtractionDiagnostics) string {
+	var codes []string
+	for _, issue := range d.Issues {
+		codes = append(codes, issue.Code)
+	}
+	return strings.Join(codes, ",")
+}
+
+func newDiagnostics() *ExtractionDiagnostics {
+	return &ExtractionDiagnostics{SectionHeaders: []string{}, UnrecognizedHeaders: []string{}, Issues: []ParseIssue{}}
+}
+
+func TestLooksLikeHeading(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"Experience", true},
+		{"WORK HISTORY", true},
+		{"Skills & Tools", true},
+		{"Licenses and Certifications Held Here", false},
+		{"experience", false},
+		{"Phone: 555", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := looksLikeHeading(tt.line); got != tt.want {
+			t.Errorf("looksLikeHeading(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDiagnoseSections(t *testing.T) {
+	tests := []struct {
+		name             string
+		text             string
+		wantSections     string
+		wantUnrecognized string
+		wantIssues       string
+	}{
+		{"standard headers", "Jane Doe\nWork Experience:\nAcme\nEDUCATION\nBSc\nTechnical Skills\nGo", "experience,education,skills", "", ""},
+		{"missing and unknown headers", "JANE DOE\nPROFESSIONAL JOURNEY\nAcme\nSkills\nGo", "skills", "JANE DOE,PROFESSIONAL JOURNEY",
+			"missing_section_experience,missing_section_education,nonstandard_headers"},
+		{"duplicate headers count once", "Experience\nWork History\nEducation\nSkills", "experience,education,skills", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDiagnostics()
+			diagnoseSections(tt.text, d)
+			if got := strings.Join(d.SectionHeaders, ","); got != tt.wantSections {
+				t.Errorf("sections = %q, want %q", got, tt.wantSections)
+			}
+			if got := strings.Join(d.UnrecognizedHeaders, ","); got != tt.wantUnrecognized {
+				t.Errorf("unrecognized = %q, want %q", got, tt.wantUnrecognized)
+			}
+			if got := issueCodes(d); got != tt.wantIssues {
+				t.Errorf("issues = %q, want %q", got, tt.wantIssues)
+			}
+		})
+	}
+}
+
+func TestDiagnoseLayout(t *testing.T) {
+	tests := []struct {
+		name        string
+		layout      string
+		wantColumns bool
+		wantTables  int
+		wantIssues  string
+	}{
+		{"single column", "Jane Doe\nEngineer at Acme\nBuilt things", false, 0, ""},
+		{"two columns", "Experience          Skills\nAcme                Go\nGlobex              SQL\nSummary", true, 0, "multi_column"},
+		{"table", "Skill | Years | Level\nGo | 5 | Expert\nSQL | 3 | Good\nOther text line\nMore text\nEven more\nLast line\nOne more\nTwo more\nThree more\nFour more\nFive more\nSix more\nSeven more\nEight more", false, 1, "tables"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDiagnostics()
+			diagnoseLayout(tt.layout, d)
+			if d.MultiColumn != tt.wantColumns || d.TableCount != tt.wantTables || issueCodes(d) != tt.wantIssues {
+				t.Errorf("columns %v, tables %d, issues %q; want %v, %d, %q", d.MultiColumn, d.TableCount, issueCodes(d), tt.wantColumns, tt.wantTables, tt.wantIssues)
+			}
+		})
+	}
+}
+
+// docxArchive builds a DOCX package from part names and contents
+func docxArchive(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, body := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(body))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDiagnoseDOCX(t *testing.T) {
+	paragraph := func(text string) string { return "<w:p><w:r><w:t>" + text + "</w:t></w:r></w:p>" }
+	document := func(body string) string {
+		return `<?xml version="1.0"?><w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` + body + `</w:body></w:document>`
+	}
+	tests := []struct {
+		name       string
+		parts      map[string]string
+		wantText   string
+		wantIssues string
+	}{
+		{"plain document", map[string]string{"word/document.xml": document(paragraph("Jane Doe") + paragraph("Experience"))}, "Jane Doe\nExperience\n", ""},
+		{"tables, text boxes, images and headers", map[string]string{
+			"word/document.xml":  document("<w:tbl>" + paragraph("Go") + "</w:tbl><w:txbxContent>" + paragraph("Boxed") + "</w:txbxContent>"),
+			"word/media/img.png": "png",
+			"word/header1.xml":   "<w:hdr><w:p><w:r><w:t>jane@example.com</w:t></w:r></w:p></w:hdr>",
+		}, "Go\nBoxed\n", "tables,text_boxes,images,header_footer_text"},
+		{"columns", map[string]string{"word/document.xml": document(paragraph("A") + `<w:sectPr><w:cols w:num="2"/></w:sectPr>`)}, "A\n", "multi_column"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newDiagnostics()
+			text := diagnoseDOCX(docxArchive(t, tt.parts), d)
+			if text != tt.wantText || issueCodes(d) != tt.wantIssues {
+				t.Errorf("text %q, issues %q; want %q, %q", text, issueCodes(d), tt.wantText, tt.wantIssues)
+			}
+		})
+	}
+
+	d := newDiagnostics()
+	if text := diagnoseDOCX([]byte("not a zip"), d); text != "" || issueCodes(d) != "unreadable_docx" {
+		t.Errorf("invalid DOCX: text %q, issues %q", text, issueCodes(d))
+	}
+}
+
+func TestRedactDiagnostics(t *testing.T) {
+	d := newDiagnostics()
+	d.HiddenText = []string{"Jane Doe keywords"}
+	d.addIssue("hidden_text", IssueHigh, "Hidden text near Jane Doe")
+	diagnoseSections("JANE DOE\nExperience\nEducation", d)
+
+	redactDiagnostics(d, "[CANDIDATE]\nExperience\nEducation", map[string]string{"[CANDIDATE]": "Jane Doe", "JANE DOE": "JANE DOE"})
+	tests := []struct {
+		field     string
+		got, want string
+	}{
+		{"issues", issueCodes(d), "hidden_text,missing_section_skills"},
+		{"issue message", d.Issues[0].Message, "Hidden text near [CANDIDATE]"},
+		{"hidden text", d.HiddenText[0], "[CANDIDATE] keywords"},
+		{"unrecognized headers", strings.Join(d.UnrecognizedHeaders, ","), ""},
+		{"sections", strings.Join(d.SectionHeaders, ","), "experience,education"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+}
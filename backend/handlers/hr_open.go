@@ -0,0 +1,536 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+const (
+	hrOpenCandidateScheme  = "interviewme-candidate"
+	hrOpenResumeScheme     = "interviewme-resume"
+	hrOpenJobScheme        = "interviewme-job"
+	hrOpenAssessmentScheme = "interviewme-score"
+)
+
+// HROpenDocument pairs an HR Open Candidate with the AssessmentResult of its
+// latest score
+type HROpenDocument struct {
+	Candidate        HROpenCandidate         `json:"candidate"`
+	AssessmentResult *HROpenAssessmentResult `json:"assessmentResult,omitempty"`
+}
+
+type HROpenIdentifier struct {
+	Value          string `json:"value"`
+	SchemeID       string `json:"schemeId,omitempty"`
+	SchemeAgencyID string `json:"schemeAgencyId,omitempty"`
+}
+
+type HROpenName struct {
+	Name string `json:"name"`
+}
+
+type HROpenCandidate struct {
+	DocumentID   HROpenIdentifier   `json:"documentId"`
+	AlternateIDs []HROpenIdentifier `json:"alternateIds,omitempty"`
+	Person       HROpenPerson       `json:"person"`
+	Profiles     []HROpenProfile    `json:"profiles"`
+}
+
+type HROpenPerson struct {
+	Name          HROpenPersonName     `json:"name"`
+	Communication *HROpenCommunication `json:"communication,omitempty"`
+}
+
+type HROpenPersonName struct {
+	FormattedName string `json:"formattedName"`
+	Given         string `json:"given,omitempty"`
+	Family        string `json:"family,omitempty"`
+}
+
+type HROpenCommunication struct {
+	Email   []HROpenEmail   `json:"email,omitempty"`
+	Phone   []HROpenPhone   `json:"phone,omitempty"`
+	Address []HROpenAddress `json:"address,omitempty"`
+}
+
+type HROpenEmail struct {
+	Address string `json:"address"`
+}
+
+type HROpenPhone struct {
+	FormattedNumber string `json:"formattedNumber"`
+}
+
+type HROpenAddress struct {
+	FormattedAddress string `json:"formattedAddress"`
+}
+
+type HROpenProfile struct {
+	ProfileID       HROpenIdentifier        `json:"profileId"`
+	CreatedDateTime string                  `json:"createdDateTime,omitempty"`
+	Employment      []HROpenEmployerHistory `json:"employment,omitempty"`
+	Education       []HROpenEducation       `json:"education,omitempty"`
+	Qualifications  []HROpenQualification   `json:"qualifications,omitempty"`
+	Certifications  []HROpenCertification   `json:"certifications,omitempty"`
+}
+
+type HROpenEmployerHistory struct {
+	Organization      HROpenName       `json:"organization"`
+	PositionHistories []HROpenPosition `json:"positionHistories"`
+}
+
+type HROpenPosition struct {
+	Title       string `json:"title"`
+	Start       string `json:"start,omitempty"`
+	End         string `json:"end,omitempty"`
+	Current     bool   `json:"current,omitempty"`
+	Description string `json:"description,omitempty"`
+	Location    string `json:"location,omitempty"`
+}
+
+type HROpenEducation struct {
+	Institution      HROpenName     `json:"institution"`
+	EducationDegrees []HROpenDegree `json:"educationDegrees,omitempty"`
+}
+
+type HROpenDegree struct {
+	Name            string       `json:"name"`
+	Date            string       `json:"date,omitempty"`
+	Specializations []HROpenName `json:"specializations,omitempty"`
+}
+
+type HROpenQualification struct {
+	CompetencyName string `json:"competencyName"`
+	Category       string `json:"category,omitempty"` // technical or soft
+}
+
+type HROpenCertification struct {
+	Name                string            `json:"name"`
+	IssuingAuthority    *HROpenName       `json:"issuingAuthority,omitempty"`
+	EffectiveTimePeriod *HROpenTimePeriod `json:"effectiveTimePeriod,omitempty"`
+}
+
+type HROpenTimePeriod struct {
+	ValidFrom string `json:"validFrom,omitempty"`
+}
+
+type HROpenAssessmentResult struct {
+	DocumentID        HROpenIdentifier `json:"documentId"`
+	CandidateID       HROpenIdentifier `json:"candidateId"`
+	PositionOpeningID HROpenIdentifier `json:"positionOpeningId"`
+	IssueDateTime     string           `json:"issueDateTime"`
+	Status            string           `json:"status,omitempty"` // Completed or Disqualified
+	OverallResult     HROpenResult     `json:"overallResult"`
+	Results           []HROpenResult   `json:"results,omitempty"`
+	Comments          []string         `json:"comments,omitempty"`
+}
+
+type HROpenResult struct {
+	Name        string      `json:"name"`
+	Score       HROpenScore `json:"score"`
+	Description string      `json:"description,omitempty"`
+}
+
+type HROpenScore struct {
+	Value   float64 `json:"value"`
+	Minimum float64 `json:"minimum"`
+	Maximum float64 `json:"maximum"`
+}
+
+// HROpenExportError reports a candidate whose document failed validation
+type HROpenExportError struct {
+	CandidateID string   `json:"candidate_id"`
+	Errors      []string `json:"errors"`
+}
+
+// provided drops the placeholder the extractor uses for missing values
+func provided(value string) string {
+	value = strings.TrimSpace(value)
+	if strings.EqualFold(value, "Not provided") {
+		return ""
+	}
+	return value
+}
+
+// hrOpenDocument converts a resume and, when given, its score into an HR Open
+// document. Resumes redacted on upload, candidates with any such resume and
+// scores against blind screening jobs get anonymized entities and no contact
+// details.
+func hrOpenDocument(candidate *Candidate, resumeData *TextData, record *ScoreRecord) HROpenDocument {
+	resumeID := normalizeID(resumeData.ID, "resume")
+	entities := resumeData.Entities
+	blind := hasRedactionVault(resumeID) || (record != nil && isBlindScreeningJob(record.JobID)) ||
+		(candidate != nil && len(blindResumeIDs(candidate)) > 0)
+	if blind {
+		entities = anonymizeEntities(entities, resumeID)
+	}
+
+	documentID := HROpenIdentifier{Value: resumeID, SchemeID: hrOpenResumeScheme}
+	var alternateIDs []HROpenIdentifier
+	if candidate != nil {
+		documentID = HROpenIdentifier{Value: candidate.ID, SchemeID: hrOpenCandidateScheme}
+		for _, version := range candidate.Versions {
+			alternateIDs = append(alternateIDs, HROpenIdentifier{Value: version.ResumeID, SchemeID: hrOpenResumeScheme})
+		}
+	}
+
+	doc := HROpenDocument{
+		Candidate: HROpenCandidate{
+			DocumentID:   documentID,
+			AlternateIDs: alternateIDs,
+			Person:       hrOpenPerson(candidate, entities, resumeID, blind),
+			Profiles:     []HROpenProfile{hrOpenProfile(resumeID, resumeData.Timestamp, entities)},
+		},
+	}
+	if record != nil {
+		doc.AssessmentResult = hrOpenAssessment(documentID, record)
+	}
+	return doc
+}
+
+func hrOpenPerson(candidate *Candidate, entities ExtractedEntities, resumeID string, blind bool) HROpenPerson {
+	name := provided(entities.Name)
+	if name == "" {
+		name = candidatePseudonym(resumeID)
+	}
+	person := HROpenPerson{Name: HROpenPersonName{FormattedName: name}}
+	if blind {
+		return person
+	}
+	if parts := strings.Fields(name); len(parts) > 1 {
+		person.Name.Given = parts[0]
+		person.Name.Family = parts[len(parts)-1]
+	}
+
+	comm := &HROpenCommunication{}
+	emails, phones := entities.Email, []string{provided(entities.Phone)}
+	if candidate != nil {
+		emails = append(append([]string{}, candidate.Emails...), emails...)
+		if len(candidate.Phones) > 0 {
+			phones = candidate.Phones
+		}
+	}
+	seen := make(map[string]bool)
+	for _, email := range emails {
+		email = strings.TrimSpace(email)
+		if email == "" || seen[strings.ToLower(email)] {
+			continue
+		}
+		seen[strings.ToLower(email)] = true
+		comm.Email = append(comm.Email, HROpenEmail{Address: email})
+	}
+	for _, phone := range uniqueStrings(phones) {
+		if phone != "" {
+			comm.Phone = append(comm.Phone, HROpenPhone{FormattedNumber: phone})
+		}
+	}
+	if location := strings.TrimSpace(entities.Preferences.Location); location != "" {
+		comm.Address = []HROpenAddress{{FormattedAddress: location}}
+	}
+	if len(comm.Email)+len(comm.Phone)+len(comm.Address) > 0 {
+		person.Communication = comm
+	}
+	return person
+}
+
+// hrOpenProfile maps resume sections onto a CandidateProfile. Consecutive roles
+// at the same employer share one EmployerHistory; entries without a title or
+// organization cannot be expressed in HR Open and are left out.
+func hrOpenProfile(resumeID string, created time.Time, entities ExtractedEntities) HROpenProfile {
+	profile := HROpenProfile{
+		ProfileID: HROpenIdentifier{Value: resumeID, SchemeID: hrOpenResumeScheme},
+	}
+	if !created.IsZero() {
+		profile.CreatedDateTime = created.UTC().Format(time.RFC3339)
+	}
+
+	for _, exp := range entities.Experience {
+		title, company := provided(exp.Title), provided(exp.Company)
+		if title == "" || company == "" {
+			continue
+		}
+		start, end := datesFromDuration(exp.Duration)
+		position := HROpenPosition{
+			Title:       title,
+			Start:       start,
+			End:         end,
+			Current:     end == "" && strings.Contains(strings.ToLower(exp.Duration), "present"),
+			Description: provided(exp.Description),
+			Location:    provided(exp.Location),
+		}
+		if n := len(profile.Employment); n > 0 &&
+			normalizeEmployer(profile.Employment[n-1].Organization.Name) == normalizeEmployer(company) {
+			profile.Employment[n-1].PositionHistories = append(profile.Employment[n-1].PositionHistories, position)
+			continue
+		}
+		profile.Employment = append(profile.Employment, HROpenEmployerHistory{
+			Organization:      HROpenName{Name: company},
+			PositionHistories: []HROpenPosition{position},
+		})
+	}
+
+	for _, edu := range entities.Education {
+		institution := provided(edu.Institution)
+		if institution == "" {
+			continue
+		}
+		attendance := HROpenEducation{Institution: HROpenName{Name: institution}}
+		if degree := provided(edu.Degree); degree != "" {
+			date := isoDate(edu.GraduationDate)
+			if date == "" {
+				date = isoDate(edu.Year)
+			}
+			entry := HROpenDegree{Name: degree, Date: date}
+			if area := provided(edu.Specialization); area != "" {
+				entry.Specializations = []HROpenName{{Name: area}}
+			}
+			attendance.EducationDegrees = []HROpenDegree{entry}
+		}
+		profile.Education = append(profile.Education, attendance)
+	}
+
+	for _, skill := range uniqueStrings(entities.Skills) {
+		if skill = strings.TrimSpace(skill); skill == "" {
+			continue
+		}
+		category := "technical"
+		if isSoftSkill(skill) {
+			category = "soft"
+		}
+		profile.Qualifications = append(profile.Qualifications, HROpenQualification{CompetencyName: skill, Category: category})
+	}
+
+	for _, cert := range entities.Certificates {
+		name := provided(cert.Name)
+		if name == "" {
+			continue
+		}
+		certification := HROpenCertification{Name: name}
+		if issuer := provided(cert.Issuer); issuer != "" {
+			certification.IssuingAuthority = &HROpenName{Name: issuer}
+		}
+		if date := isoDate(cert.Date); date != "" {
+			certification.EffectiveTimePeriod = &HROpenTimePeriod{ValidFrom: date}
+		}
+		profile.Certifications = append(profile.Certifications, certification)
+	}
+	return profile
+}
+
+// hrOpenAssessment turns a stored score into an AssessmentResult on a 0-100 scale
+func hrOpenAssessment(candidateID HROpenIdentifier, record *ScoreRecord) *HROpenAssessmentResult {
+	score := record.Score
+	result := func(name string, value float64, description string) HROpenResult {
+		return HROpenResult{
+			Name:        name,
+			Score:       HROpenScore{Value: math.Round(math.Min(math.Max(value, 0), 100)*100) / 100, Maximum: 100},
+			Description: description,
+		}
+	}
+
+	assessment := &HROpenAssessmentResult{
+		DocumentID: HROpenIdentifier{
+			Value:    fmt.Sprintf("score_%s_%s", record.ResumeID, record.JobID),
+			SchemeID: hrOpenAssessmentScheme,
+		},
+		CandidateID:       candidateID,
+		PositionOpeningID: HROpenIdentifier{Value: record.JobID, SchemeID: hrOpenJobScheme},
+		IssueDateTime:     record.ScoredAt.UTC().Format(time.RFC3339),
+		Status:            "Completed",
+		OverallResult:     result("overall", score.OverallScore, fmt.Sprintf("Scored against job version %d", max(record.JobVersion, 1))),
+		Comments:          score.Feedback,
+	}
+	if score.KnockedOut {
+		assessment.Status = "Disqualified"
+	}
+
+	names := make([]string, 0, len(score.DetailedScores))
+	for name := range score.DetailedScores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		assessment.Results = append(assessment.Results, result(name, score.DetailedScores[name], ""))
+	}
+	assessment.Results = append(assessment.Results,
+		result("experience_match", score.ExperienceMatch, ""),
+		result("education_match", score.EducationMatch, ""),
+		result("logistics_fit", score.LogisticsFit.Score, score.LogisticsFit.Level),
+	)
+	return assessment
+}
+
+// latestResumeID returns the newest resume version of a candidate
+func latestResumeID(candidate *Candidate) string {
+	latest := ResumeVersion{}
+	for _, version := range candidate.Versions {
+		if version.Version >= latest.Version {
+			latest = version
+		}
+	}
+	return latest.ResumeID
+}
+
+// validateHROpenDocument checks a document against the published HR Open 4.x
+// Candidate and AssessmentResult JSON schemas. They aren't bundled with the
+// server: HR_OPEN_CANDIDATE_SCHEMA and HR_OPEN_ASSESSMENT_RESULT_SCHEMA name
+// the entry files of a vendored copy, whose relative $refs are followed.
+// validated is false when the schemas aren't configured.
+func validateHROpenDocument(doc HROpenDocument) (violations []string, validated bool, err error) {
+	candidateSchema := os.Getenv("HR_OPEN_CANDIDATE_SCHEMA")
+	assessmentSchema := os.Getenv("HR_OPEN_ASSESSMENT_RESULT_SCHEMA")
+	if candidateSchema == "" || (doc.AssessmentResult != nil && assessmentSchema == "") {
+		return nil, false, nil
+	}
+
+	check := func(schema, name string, part any) error {
+		found, err := utils.ValidateJSONSchemaFile(schema, part)
+		if err != nil {
+			return err
+		}
+		for _, violation := range found {
+			violations = append(violations, "$."+name+strings.TrimPrefix(violation, "$"))
+		}
+		return nil
+	}
+	if err := check(candidateSchema, "candidate", doc.Candidate); err != nil {
+		return nil, false, err
+	}
+	if doc.AssessmentResult != nil {
+		if err := check(assessmentSchema, "assessmentResult", doc.AssessmentResult); err != nil {
+			return nil, false, err
+		}
+	}
+	return violations, true, nil
+}
+
+// candidateHROpenDocument builds and validates the document for a candidate's
+// latest resume. With a job ID the score against that job is used, otherwise
+// the most recent score; found reports whether a score was available.
+func candidateHROpenDocument(candidate *Candidate, jobID string) (doc HROpenDocument, found, validated bool, violations []string, err error) {
+	resumeID := latestResumeID(candidate)
+	if resumeID == "" {
+		return doc, false, false, nil, fiber.NewError(404, "Candidate has no resumes")
+	}
+	resumeData, err := LoadTextData(resumeID, "resume")
+	if err != nil {
+		return doc, false, false, nil, fiber.NewError(404, "Resume "+resumeID+" not found")
+	}
+
+	var record *ScoreRecord
+	if jobID != "" {
+		record, _ = loadScoreRecord(resumeID, jobID)
+	} else {
+		record, _ = loadLatestScoreRecord(resumeID)
+	}
+
+	doc = hrOpenDocument(candidate, resumeData, record)
+	violations, validated, err = validateHROpenDocument(doc)
+	if err != nil {
+		log.Printf("Error validating HR Open export: %v", err)
+		return doc, record != nil, false, nil, fiber.NewError(500, "Failed to load the HR Open schemas")
+	}
+	return doc, record != nil, validated, violations, nil
+}
+
+// ExportCandidateHROpen returns a candidate and their latest score as an HR
+// Open document
+func ExportCandidateHROpen(c *fiber.Ctx) error {
+	candidate, err := loadCandidate(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate not found",
+		})
+	}
+	if candidate.MergedInto != "" {
+		return c.Status(409).JSON(fiber.Map{
+			"error":       "Candidate was merged into " + candidate.MergedInto,
+			"merged_into": candidate.MergedInto,
+		})
+	}
+
+	jobID := c.Query("job_id")
+	if jobID != "" {
+		jobID = normalizeID(jobID, "job")
+	}
+	doc, found, validated, violations, err := candidateHROpenDocument(candidate, jobID)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if jobID != "" && !found {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Candidate has not been scored against job " + jobID,
+		})
+	}
+	if len(violations) > 0 {
+		return c.Status(422).JSON(fiber.Map{
+			"error":             "Export failed validation against the HR Open schemas",
+			"validation_errors": violations,
+		})
+	}
+	c.Set("X-HR-Open-Validated", strconv.FormatBool(validated))
+
+	if c.QueryBool("download") {
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="hropen_%s.json"`, candidate.ID))
+	}
+	return c.JSON(doc)
+}
+
+// ExportHROpenBulk exports every active candidate as HR Open documents. With
+// job_id only candidates scored against that job are included. Documents that
+// fail validation are reported instead of exported.
+func ExportHROpenBulk(c *fiber.Ctx) error {
+	candidates, err := loadAllCandidates()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read candidates",
+		})
+	}
+
+	jobID := c.Query("job_id")
+	if jobID != "" {
+		jobID = normalizeID(jobID, "job")
+	}
+
+	documents := make([]HROpenDocument, 0)
+	invalid := make([]HROpenExportError, 0)
+	allValidated := true
+	for _, candidate := range activeCandidates(candidates) {
+		doc, found, validated, violations, err := candidateHROpenDocument(candidate, jobID)
+		if err != nil {
+			log.Printf("Skipping candidate %s in HR Open export: %v", candidate.ID, err)
+			continue
+		}
+		if jobID != "" && !found {
+			continue
+		}
+		if len(violations) > 0 {
+			invalid = append(invalid, HROpenExportError{CandidateID: candidate.ID, Errors: violations})
+			continue
+		}
+		allValidated = allValidated && validated
+		documents = append(documents, doc)
+	}
+
+	if c.QueryBool("download") {
+		c.Set(fiber.HeaderContentDisposition, `attachment; filename="hropen_candidates.json"`)
+	}
+	return c.JSON(fiber.Map{
+		"count":     len(documents),
+		"validated": allValidated,
+		"documents": documents,
+		"invalid":   invalid,
+	})
+}
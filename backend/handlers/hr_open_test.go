@@ -0,0 +1,412 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestProvided(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"Acme", "Acme"},
+		{"  Acme ", "Acme"},
+		{"Not provided", ""},
+		{"not PROVIDED", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := provided(tt.value); got != tt.want {
+			t.Errorf("provided(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestHROpenProfile(t *testing.T) {
+	entities := ExtractedEntities{
+		Experience: []Experience{
+			{Title: "Engineer", Company: "Acme", Duration: "Jan 2018 - Dec 2019", Location: "Not provided"},
+			{Title: "Senior Engineer", Company: "Acme Inc.", Duration: "Jan 2020 - Present", Description: "Led payments"},
+			{Title: "Not provided", Company: "Globex"},
+			{Title: "Analyst", Company: "Initech", Duration: "2016 - 2017"},
+		},
+		Education: []Education{
+			{Degree: "BSc", Institution: "State University", Year: "2015", GraduationDate: "June 2016", Specialization: "Computer Science"},
+			{Degree: "MSc", Institution: "Tech Institute", Year: "2018"},
+			{Degree: "PhD", Institution: "Not provided"},
+		},
+		Skills:       []string{"Go", "go", "Communication", " "},
+		Certificates: []Certificate{{Name: "CKA", Issuer: "CNCF", Date: "Mar 2021"}, {Name: "Not provided"}},
+	}
+	profile := hrOpenProfile("4600", time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CET", 3600)), entities)
+
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"profile ID", profile.ProfileID.Value, "4600"},
+		{"created in UTC", profile.CreatedDateTime, "2024-05-01T11:00:00Z"},
+		{"employers, same one grouped", len(profile.Employment), 2},
+		{"positions at the first employer", len(profile.Employment[0].PositionHistories), 2},
+		{"first start", profile.Employment[0].PositionHistories[0].Start, "2018-01"},
+		{"first end", profile.Employment[0].PositionHistories[0].End, "2019-12"},
+		{"placeholder location dropped", profile.Employment[0].PositionHistories[0].Location, ""},
+		{"current role", profile.Employment[0].PositionHistories[1].Current, true},
+		{"current role has no end", profile.Employment[0].PositionHistories[1].End, ""},
+		{"second employer", profile.Employment[1].Organization.Name, "Initech"},
+		{"schools without a name dropped", len(profile.Education), 2},
+		{"graduation date preferred", profile.Education[0].EducationDegrees[0].Date, "2016-06"},
+		{"year as fallback", profile.Education[1].EducationDegrees[0].Date, "2018"},
+		{"specialization", profile.Education[0].EducationDegrees[0].Specializations[0].Name, "Computer Science"},
+		{"skills deduplicated", len(profile.Qualifications), 2},
+		{"technical skill", profile.Qualifications[0].Category, "technical"},
+		{"soft skill", profile.Qualifications[1].Category, "soft"},
+		{"certifications", len(profile.Certifications), 1},
+		{"issuer", profile.Certifications[0].IssuingAuthority.Name, "CNCF"},
+		{"certified from", profile.Certifications[0].EffectiveTimePeriod.ValidFrom, "2021-03"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+	if empty := hrOpenProfile("4600", time.Time{}, ExtractedEntities{}); empty.CreatedDateTime != "" {
+		t.Errorf("zero creation time exported as %q", empty.CreatedDateTime)
+	}
+}
+
+func TestHROpenAssessment(t *testing.T) {
+	record := &ScoreRecord{
+		ResumeID:   "4600",
+		JobID:      "4610",
+		JobVersion: 3,
+		ScoredAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+		Score: ScoreResponse{
+			OverallScore:    72.456,
+			DetailedScores:  map[string]float64{"technical_skills": 120, "skills_match": -5},
+			ExperienceMatch: 60,
+			LogisticsFit:    LogisticsFit{Score: 80, Level: LogisticsGood},
+			Feedback:        []string{"Strong Go background"},
+		},
+	}
+	candidateID := HROpenIdentifier{Value: "cand_1", SchemeID: hrOpenCandidateScheme}
+
+	tests := []struct {
+		name       string
+		knockedOut bool
+		wantStatus string
+	}{
+		{"completed", false, "Completed"},
+		{"knocked out", true, "Disqualified"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record.Score.KnockedOut = tt.knockedOut
+			assessment := hrOpenAssessment(candidateID, record)
+			if assessment.Status != tt.wantStatus {
+				t.Errorf("status = %q, want %q", assessment.Status, tt.wantStatus)
+			}
+			checks := []struct {
+				field     string
+				got, want any
+			}{
+				{"document ID", assessment.DocumentID.Value, "score_4600_4610"},
+				{"candidate", assessment.CandidateID, candidateID},
+				{"job", assessment.PositionOpeningID.Value, "4610"},
+				{"issued", assessment.IssueDateTime, "2024-05-01T12:00:00Z"},
+				{"overall rounded", assessment.OverallResult.Score.Value, 72.46},
+				{"overall maximum", assessment.OverallResult.Score.Maximum, 100.0},
+				{"job version", assessment.OverallResult.Description, "Scored against job version 3"},
+				{"detailed scores sorted first", assessment.Results[0].Name, "skills_match"},
+				{"clamped below", assessment.Results[0].Score.Value, 0.0},
+				{"clamped above", assessment.Results[1].Score.Value, 100.0},
+				{"logistics level", assessment.Results[4].Description, LogisticsGood},
+				{"comments", strings.Join(assessment.Comments, ","), "Strong Go background"},
+			}
+			for _, c := range checks {
+				if c.got != c.want {
+					t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+				}
+			}
+		})
+	}
+}
+
+func TestHROpenDocumentBlindPaths(t *testing.T) {
+	entities := ExtractedEntities{
+		Name:        "Jane Doe",
+		Email:       []string{"jane@example.com"},
+		Phone:       "+1 555 123 4567",
+		Preferences: CandidatePreferences{Location: "Leeds, UK"},
+		Experience:  []Experience{{Title: "Engineer", Company: "Acme", Duration: "2018 - 2020"}},
+	}
+	for _, id := range []string{"4620", "4621", "4622", "4623"} {
+		saveTestText(t, "resume", id, TextData{Entities: entities})
+	}
+	saveTestVault(t, "4621", map[string]string{redactedNameToken: "Jane Doe"})
+	saveTestVault(t, "4624", map[string]string{redactedNameToken: "Jane Doe"})
+	saveTestText(t, "job", "4625", TextData{})
+	if err := saveScreeningSettings(ScreeningSettings{JobID: "4625", BlindScreening: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		resumeID  string
+		candidate *Candidate
+		record    *ScoreRecord
+		blind     bool
+	}{
+		{"open resume", "4620", nil, nil, false},
+		{"open candidate", "4620", &Candidate{ID: "cand_4620", Emails: []string{"jdoe@other.org"}, Versions: []ResumeVersion{{ResumeID: "4620"}}}, nil, false},
+		{"resume redacted on upload", "4621", nil, nil, true},
+		{"scored against a blind job", "4622", nil, &ScoreRecord{ResumeID: "4622", JobID: "4625"}, true},
+		{"candidate with a redacted version", "4623", &Candidate{ID: "cand_4623", Versions: []ResumeVersion{{ResumeID: "4623", Version: 2}, {ResumeID: "4624", Version: 1}}}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resumeData, err := LoadTextData(tt.resumeID, "resume")
+			if err != nil {
+				t.Fatal(err)
+			}
+			doc := hrOpenDocument(tt.candidate, resumeData, tt.record)
+			raw, _ := json.Marshal(doc)
+			for _, secret := range []string{"Jane", "jane@example.com", "555", "Leeds"} {
+				if strings.Contains(string(raw), secret) == tt.blind {
+					t.Errorf("document contains %q = %v, blind %v", secret, !tt.blind, tt.blind)
+				}
+			}
+			if tt.blind && doc.Candidate.Person.Communication != nil {
+				t.Errorf("blind document has communication %+v", doc.Candidate.Person.Communication)
+			}
+			if (tt.record != nil) != (doc.AssessmentResult != nil) {
+				t.Errorf("assessment = %+v, record %+v", doc.AssessmentResult, tt.record)
+			}
+			if tt.candidate != nil && (doc.Candidate.DocumentID.Value != tt.candidate.ID || len(doc.Candidate.AlternateIDs) != len(tt.candidate.Versions)) {
+				t.Errorf("candidate document IDs = %+v, %+v", doc.Candidate.DocumentID, doc.Candidate.AlternateIDs)
+			}
+		})
+	}
+
+	resumeData, _ := LoadTextData("4620", "resume")
+	open := hrOpenDocument(&Candidate{ID: "cand_4620", Emails: []string{"JANE@example.com", "jdoe@other.org"}, Versions: []ResumeVersion{{ResumeID: "4620"}}}, resumeData, nil)
+	if comm := open.Candidate.Person.Communication; comm == nil || len(comm.Email) != 2 || open.Candidate.Person.Name.Family != "Doe" {
+		t.Errorf("open document person = %+v, want two distinct emails and a family name", open.Candidate.Person)
+	}
+}
+
+// writeSchemas writes named schema files into a temporary directory and
+// returns their paths
+func writeSchemas(t *testing.T, schemas map[string]string) map[string]string {
+	t.Helper()
+	dir := t.TempDir()
+	paths := make(map[string]string)
+	for name, schema := range schemas {
+		paths[name] = filepath.Join(dir, name)
+		if err := os.WriteFile(paths[name], []byte(schema), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return paths
+}
+
+func TestValidateHROpenDocument(t *testing.T) {
+	schemas := writeSchemas(t, map[string]string{
+		"candidate.json":  `{"type":"object","required":["documentId","person"],"properties":{"person":{"$ref":"person.json"}}}`,
+		"person.json":     `{"type":"object","required":["name"],"properties":{"name":{"type":"object","required":["formattedName","given"]}}}`,
+		"assessment.json": `{"type":"object","properties":{"status":{"enum":["Completed"]}}}`,
+		"broken.json":     `{"type":`,
+	})
+	named := HROpenDocument{Candidate: HROpenCandidate{Person: HROpenPerson{Name: HROpenPersonName{FormattedName: "Jane Doe", Given: "Jane"}}}}
+	unnamed := HROpenDocument{Candidate: HROpenCandidate{Person: HROpenPerson{Name: HROpenPersonName{FormattedName: "Candidate A"}}}}
+	disqualified := named
+	disqualified.AssessmentResult = &HROpenAssessmentResult{Status: "Disqualified"}
+
+	tests := []struct {
+		name          string
+		candidate     string
+		assessment    string
+		doc           HROpenDocument
+		wantValidated bool
+		wantPrefix    string
+		wantErr       bool
+	}{
+		{"not configured", "", "", named, false, "", false},
+		{"valid", schemas["candidate.json"], "", named, true, "", false},
+		{"referenced schema violation", schemas["candidate.json"], "", unnamed, true, "$.candidate.person.name", false},
+		{"assessment schema missing", schemas["candidate.json"], "", disqualified, false, "", false},
+		{"assessment violation", schemas["candidate.json"], schemas["assessment.json"], disqualified, true, "$.assessmentResult.status", false},
+		{"unreadable schema", filepath.Join(filepath.Dir(schemas["candidate.json"]), "missing.json"), "", named, false, "", true},
+		{"invalid schema", schemas["broken.json"], "", named, false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HR_OPEN_CANDIDATE_SCHEMA", tt.candidate)
+			t.Setenv("HR_OPEN_ASSESSMENT_RESULT_SCHEMA", tt.assessment)
+			violations, validated, err := validateHROpenDocument(tt.doc)
+			if (err != nil) != tt.wantErr || validated != tt.wantValidated {
+				t.Fatalf("validated, err = %v, %v; want %v, error %v", validated, err, tt.wantValidated, tt.wantErr)
+			}
+			if tt.wantPrefix == "" && len(violations) > 0 {
+				t.Errorf("violations = %v, want none", violations)
+			}
+			if tt.wantPrefix != "" && (len(violations) != 1 || !strings.HasPrefix(violations[0], tt.wantPrefix)) {
+				t.Errorf("violations = %v, want one at %s", violations, tt.wantPrefix)
+			}
+		})
+	}
+}
+
+func TestExportCandidateHROpen(t *testing.T) {
+	entities := ExtractedEntities{Name: "Jane Doe", Email: []string{"jane@example.com"}}
+	saveTestText(t, "resume", "4631", TextData{Entities: ExtractedEntities{Name: "Old Version"}})
+	saveTestText(t, "resume", "4632", TextData{Entities: entities})
+	if err := saveScoreRecord("4632", "4630", 1, ScoreResponse{OverallScore: 70}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, candidate := range []*Candidate{
+		{ID: "cand_4631", Versions: []ResumeVersion{{ResumeID: "4631", Version: 1}, {ResumeID: "4632", Version: 2}}},
+		{ID: "cand_4632", MergedInto: "cand_4631"},
+		{ID: "cand_4633"},
+	} {
+		if err := saveCandidate(candidate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schemas := writeSchemas(t, map[string]string{
+		"candidate.json":  `{"type":"object","required":["documentId"]}`,
+		"assessment.json": `{"type":"object","required":["overallResult"]}`,
+		"strict.json":     `{"type":"object","required":["extension"]}`,
+	})
+
+	app := fiber.New()
+	app.Get("/candidates/:id/hropen", ExportCandidateHROpen)
+
+	tests := []struct {
+		name          string
+		path          string
+		candidate     string
+		want          int
+		wantValidated string
+		contains      string
+	}{
+		{"unvalidated export", "/candidates/cand_4631/hropen", "", 200, "false", `"formattedName":"Jane Doe"`},
+		{"validated export", "/candidates/cand_4631/hropen", schemas["candidate.json"], 200, "true", `"assessmentResult"`},
+		{"score for a job", "/candidates/cand_4631/hropen?job_id=job_4630", schemas["candidate.json"], 200, "true", `"value":"4630"`},
+		{"not scored against the job", "/candidates/cand_4631/hropen?job_id=4639", "", 404, "", "not been scored"},
+		{"fails validation", "/candidates/cand_4631/hropen", schemas["strict.json"], 422, "", `$.candidate: `},
+		{"schema cannot be read", "/candidates/cand_4631/hropen", schemas["candidate.json"] + ".missing", 500, "", "schemas"},
+		{"merged candidate", "/candidates/cand_4632/hropen", "", 409, "", "cand_4631"},
+		{"candidate without resumes", "/candidates/cand_4633/hropen", "", 404, "", "no resumes"},
+		{"unknown candidate", "/candidates/cand_4639/hropen", "", 404, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HR_OPEN_CANDIDATE_SCHEMA", tt.candidate)
+			t.Setenv("HR_OPEN_ASSESSMENT_RESULT_SCHEMA", schemas["assessment.json"])
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.want || !strings.Contains(string(body), tt.contains) {
+				t.Fatalf("got %d %s, want %d containing %s", resp.StatusCode, body, tt.want, tt.contains)
+			}
+			if got := resp.Header.Get("X-HR-Open-Validated"); got != tt.wantValidated {
+				t.Errorf("X-HR-Open-Validated = %q, want %q", got, tt.wantValidated)
+			}
+		})
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/candidates/cand_4631/hropen?download=true", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := resp.Header.Get(fiber.HeaderContentDisposition); !strings.Contains(got, "hropen_cand_4631.json") {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+}
+
+func TestExportHROpenBulk(t *testing.T) {
+	saveTestText(t, "resume", "4641", TextData{Entities: ExtractedEntities{Name: "Jane Doe"}})
+	saveTestText(t, "resume", "4642", TextData{Entities: ExtractedEntities{Name: "John Roe"}})
+	if err := saveScoreRecord("4641", "4640", 1, ScoreResponse{OverallScore: 70}, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, candidate := range []*Candidate{
+		{ID: "cand_4641", Versions: []ResumeVersion{{ResumeID: "4641", Version: 1}}},
+		{ID: "cand_4642", Versions: []ResumeVersion{{ResumeID: "4642", Version: 1}}},
+		{ID: "cand_4643", MergedInto: "cand_4641"},
+		{ID: "cand_4644", Versions: []ResumeVersion{{ResumeID: "4649", Version: 1}}},
+	} {
+		if err := saveCandidate(candidate); err != nil {
+			t.Fatal(err)
+		}
+	}
+	schemas := writeSchemas(t, map[string]string{
+		"candidate.json":  `{"type":"object","required":["documentId"]}`,
+		"assessment.json": `{"type":"object","required":["overallResult"]}`,
+		"no_john.json":    `{"type":"object","properties":{"person":{"properties":{"name":{"properties":{"formattedName":{"enum":["Jane Doe"]}}}}}}}`,
+	})
+
+	app := fiber.New()
+	app.Get("/candidates/hropen", ExportHROpenBulk)
+
+	tests := []struct {
+		name          string
+		query         string
+		candidate     string
+		wantDocs      []string
+		wantInvalid   []string
+		wantValidated bool
+	}{
+		{"everyone active", "", "", []string{"cand_4641", "cand_4642"}, nil, false},
+		{"validated", "", schemas["candidate.json"], []string{"cand_4641", "cand_4642"}, nil, true},
+		{"scored against the job", "?job_id=4640", schemas["candidate.json"], []string{"cand_4641"}, nil, true},
+		{"invalid documents reported", "", schemas["no_john.json"], []string{"cand_4641"}, []string{"cand_4642"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("HR_OPEN_CANDIDATE_SCHEMA", tt.candidate)
+			t.Setenv("HR_OPEN_ASSESSMENT_RESULT_SCHEMA", schemas["assessment.json"])
+			resp, err := app.Test(httptest.NewRequest("GET", "/candidates/hropen"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var result struct {
+				Validated bool                `json:"validated"`
+				Documents []HROpenDocument    `json:"documents"`
+				Invalid   []HROpenExportError `json:"invalid"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+			// Other tests store candidates too; only look at this test's
+			var docs, invalid []string
+			for _, doc := range result.Documents {
+				if id := doc.Candidate.DocumentID.Value; strings.HasPrefix(id, "cand_464") {
+					docs = append(docs, id)
+				}
+			}
+			for _, e := range result.Invalid {
+				if strings.HasPrefix(e.CandidateID, "cand_464") {
+					invalid = append(invalid, e.CandidateID)
+				}
+			}
+			if strings.Join(docs, ",") != strings.Join(tt.wantDocs, ",") || strings.Join(invalid, ",") != strings.Join(tt.wantInvalid, ",") {
+				t.Errorf("documents %v, invalid %v; want %v, %v", docs, invalid, tt.wantDocs, tt.wantInvalid)
+			}
+			if result.Validated != tt.wantValidated {
+				t.Errorf("validated = %v, want %v", result.Validated, tt.wantValidated)
+			}
+		})
+	}
+}
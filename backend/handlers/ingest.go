@@ -0,0 +1,664 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Ingest file statuses
+const (
+	IngestQueued    = "queued"
+	IngestRunning   = "running"
+	IngestCompleted = "completed"
+	IngestFailed    = "failed"
+	IngestRejected  = "rejected"
+)
+
+// Ingest sources
+const (
+	IngestSourceZip   = "zip"
+	IngestSourceInbox = "inbox"
+)
+
+const (
+	// MaxBulkArchiveBytes caps the size of an uploaded archive; only the bulk
+	// upload route accepts a body this large
+	MaxBulkArchiveBytes     = 100 << 20
+	maxBulkFiles            = 200      // Resumes accepted from one archive
+	maxBulkFileBytes        = 10 << 20 // Largest single resume once extracted
+	maxBulkExtractedBytes   = 250 << 20
+	defaultInboxPollSeconds = 30
+
+	// Task params carrying file metadata through to the resume record
+	sourceParamPrefix = "source_"
+)
+
+var (
+	ingestExtensions  = map[string]bool{".pdf": true, ".docx": true, ".json": true}
+	unsafeNamePattern = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+	// Serialises batch refreshes so concurrent readers don't interleave writes
+	ingestMu sync.Mutex
+)
+
+// IngestBatch tracks a group of resumes ingested together from an archive or
+// the watched inbox
+type IngestBatch struct {
+	ID        string         `json:"id"`
+	Source    string         `json:"source"`
+	Origin    string         `json:"origin,omitempty"` // Archive name or inbox directory
+	JobID     string         `json:"job_id,omitempty"`
+	Blind     bool           `json:"blind,omitempty"`
+	Files     []IngestFile   `json:"files"`
+	Summary   map[string]int `json:"summary"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// IngestFile is the per-file status of an ingested resume
+type IngestFile struct {
+	Name        string            `json:"name"`
+	Size        int64             `json:"size"`
+	Status      string            `json:"status"`
+	JobID       string            `json:"job_id,omitempty"`   // Overrides the batch's job
+	Metadata    map[string]string `json:"metadata,omitempty"` // Passed to the resume record as its source
+	TaskID      string            `json:"task_id,omitempty"`
+	ResumeID    string            `json:"resume_id,omitempty"`
+	CandidateID string            `json:"candidate_id,omitempty"`
+	Error       string            `json:"error,omitempty"`
+}
+
+func newIngestBatch(source, origin, jobID string, blind bool) *IngestBatch {
+	now := time.Now()
+	return &IngestBatch{
+		ID:        fmt.Sprintf("batch_%d", now.UnixNano()),
+		Source:    source,
+		Origin:    origin,
+		JobID:     jobID,
+		Blind:     blind,
+		Files:     []IngestFile{},
+		Summary:   map[string]int{},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func ingestBatchPath(id string) string {
+	return filepath.Join("processed_texts", "ingest", safeID(id)+".json")
+}
+
+func saveIngestBatch(batch *IngestBatch) error {
+	batch.UpdatedAt = time.Now()
+	batch.Summary = make(map[string]int)
+	for _, file := range batch.Files {
+		batch.Summary[file.Status]++
+	}
+	return utils.SaveJSONFile(ingestBatchPath(batch.ID), batch, 0644)
+}
+
+// loadIngestBatch reads a batch and brings its file statuses up to date with
+// their preprocessing tasks
+func loadIngestBatch(id string) (*IngestBatch, error) {
+	ingestMu.Lock()
+	defer ingestMu.Unlock()
+
+	var batch IngestBatch
+	if err := utils.LoadJSONFile(ingestBatchPath(id), &batch); err != nil {
+		return nil, err
+	}
+	if refreshIngestBatch(&batch) {
+		if err := saveIngestBatch(&batch); err != nil {
+			log.Printf("Error saving ingest batch %s: %v", batch.ID, err)
+		}
+	}
+	return &batch, nil
+}
+
+func refreshIngestBatch(batch *IngestBatch) bool {
+	changed := false
+	for i := range batch.Files {
+		file := &batch.Files[i]
+		if file.TaskID == "" || file.Status == IngestCompleted || file.Status == IngestFailed {
+			continue
+		}
+		task, err := loadTask(file.TaskID)
+		if err != nil {
+			continue
+		}
+		status := file.Status
+		switch task.Status {
+		case TaskRunning:
+			status = IngestRunning
+		case TaskCompleted:
+			var result PreprocessedData
+			if err := json.Unmarshal(task.Result, &result); err == nil {
+				file.ResumeID = result.ID
+				file.CandidateID = result.CandidateID
+			}
+			status = IngestCompleted
+		case TaskFailed:
+			status = IngestFailed
+			file.Error = task.Error
+		}
+		if status != file.Status {
+			file.Status = status
+			changed = true
+		}
+	}
+	return changed
+}
+
+// queueIngestFile stages a resume under uploads/ and queues it for
+// preprocessing, recording unsupported files as rejected
+func queueIngestFile(batch *IngestBatch, file IngestFile, content []byte) {
+	if file.JobID == "" {
+		file.JobID = batch.JobID
+	}
+	blind := batch.Blind || isBlindScreeningJob(file.JobID)
+	if blind {
+		file = blindIngestFile(file, len(batch.Files)+1)
+	}
+	name := file.Name
+	file.Size, file.Status = int64(len(content)), IngestQueued
+	defer func() { batch.Files = append(batch.Files, file) }()
+
+	if !ingestExtensions[strings.ToLower(filepath.Ext(name))] {
+		file.Status, file.Error = IngestRejected, "Only PDF, DOCX and JSON Resume files are supported"
+		return
+	}
+
+	// Prefix with the position in the batch so identically named files
+	// from different folders don't overwrite each other
+	stagedPath := filepath.Join("uploads", batch.ID, fmt.Sprintf("%03d-%s", len(batch.Files)+1, safeUploadName(name)))
+	if err := os.MkdirAll(filepath.Dir(stagedPath), 0755); err != nil {
+		file.Status, file.Error = IngestFailed, "Failed to create upload directory"
+		return
+	}
+	if err := os.WriteFile(stagedPath, content, 0644); err != nil {
+		file.Status, file.Error = IngestFailed, "Failed to save file"
+		return
+	}
+
+	task := &AnalysisTask{
+		Type:  "preprocess_resume",
+		JobID: file.JobID,
+		Params: map[string]string{
+			"path":     stagedPath,
+			"filename": name,
+			"batch_id": batch.ID,
+			"blind":    strconv.FormatBool(blind),
+			"channel":  batch.Source,
+		},
+	}
+	for key, value := range file.Metadata {
+		task.Params[sourceParamPrefix+key] = value
+	}
+	if err := tasks.enqueue(task); err != nil {
+		log.Printf("Error queueing %s: %v", name, err)
+		file.Status, file.Error = IngestFailed, "Failed to queue file"
+		return
+	}
+	file.TaskID = task.ID
+}
+
+// blindIngestFile strips what identifies the applicant from a file headed for
+// a blind job before it is stored in the batch or the task params: the
+// original filename becomes its position in the batch, and the sender and
+// subject of an emailed application are dropped
+func blindIngestFile(file IngestFile, position int) IngestFile {
+	file.Name = fmt.Sprintf("resume-%03d%s", position, strings.ToLower(filepath.Ext(file.Name)))
+	if file.Metadata != nil {
+		metadata := make(map[string]string, len(file.Metadata))
+		for key, value := range file.Metadata {
+			if key != "from" && key != "subject" {
+				metadata[key] = value
+			}
+		}
+		file.Metadata = metadata
+	}
+	return file
+}
+
+// recordIngestFile adds a file that was not queued to the batch, anonymized
+// the same way as queued files when it was headed for a blind job
+func recordIngestFile(batch *IngestBatch, file IngestFile) {
+	jobID := file.JobID
+	if jobID == "" {
+		jobID = batch.JobID
+	}
+	if batch.Blind || isBlindScreeningJob(jobID) {
+		file = blindIngestFile(file, len(batch.Files)+1)
+	}
+	batch.Files = append(batch.Files, file)
+}
+
+// runPreprocessResumeTask sends a staged resume through the same pipeline as
+// PreprocessResume
+func runPreprocessResumeTask(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+	source := map[string]string{"channel": task.Params["channel"], "batch_id": task.Params["batch_id"]}
+	for key, value := range task.Params {
+		if strings.HasPrefix(key, sourceParamPrefix) {
+			source[strings.TrimPrefix(key, sourceParamPrefix)] = value
+		}
+	}
+	return processResumeFile(task.Params["path"], task.Params["filename"], resumeProcessingOptions{
+		JobID:  task.JobID,
+		Blind:  task.Params["blind"] == "true",
+		Source: source,
+	}, progress)
+}
+
+func safeUploadName(name string) string {
+	name = unsafeNamePattern.ReplaceAllString(filepath.Base(name), "_")
+	if name == "" || name == "." || name == ".." {
+		return "resume"
+	}
+	return name
+}
+
+// extractBulkArchive queues every resume in a ZIP archive. Entries are never
+// written under their archive path, and entries with absolute or parent
+// paths are rejected outright; sizes are enforced on the bytes actually
+// read, not the sizes the archive claims.
+func extractBulkArchive(batch *IngestBatch, archive []byte) error {
+	reader, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return fiber.NewError(400, "File is not a valid ZIP archive")
+	}
+
+	var total int64
+	accepted, capped := 0, false
+	for _, entry := range reader.File {
+		name := strings.ReplaceAll(entry.Name, "\\", "/")
+		base := path.Base(name)
+		if entry.FileInfo().IsDir() || strings.HasPrefix(name, "__MACOSX/") || strings.HasPrefix(base, ".") {
+			continue
+		}
+		reject := func(reason string) {
+			recordIngestFile(batch, IngestFile{Name: name, Size: int64(entry.UncompressedSize64), Status: IngestRejected, Error: reason})
+		}
+
+		switch {
+		case !isSafeArchivePath(name):
+			reject("Unsafe path in archive")
+			continue
+		case !entry.Mode().IsRegular():
+			reject("Not a regular file")
+			continue
+		case capped:
+			reject(fmt.Sprintf("Archive expands to more than %d MB", maxBulkExtractedBytes>>20))
+			continue
+		case accepted >= maxBulkFiles:
+			reject(fmt.Sprintf("Archive holds more than %d files", maxBulkFiles))
+			continue
+		case entry.UncompressedSize64 > maxBulkFileBytes:
+			reject(fmt.Sprintf("File is larger than %d MB", maxBulkFileBytes>>20))
+			continue
+		}
+
+		// Never read past the extracted-size cap, and stop reading entries once it's hit
+		budget := min(maxBulkFileBytes, int(maxBulkExtractedBytes-total))
+		content, err := readArchiveEntry(entry, budget)
+		if err != nil && len(content) > 0 {
+			capped = true
+			reject(fmt.Sprintf("Archive expands to more than %d MB", maxBulkExtractedBytes>>20))
+			continue
+		}
+		if err != nil {
+			reject(err.Error())
+			continue
+		}
+		total += int64(len(content))
+		accepted++
+		queueIngestFile(batch, IngestFile{Name: base}, content)
+	}
+	return nil
+}
+
+func isSafeArchivePath(name string) bool {
+	if name == "" || strings.ContainsRune(name, 0) || strings.HasPrefix(name, "/") || filepath.VolumeName(name) != "" ||
+		(len(name) > 1 && name[1] == ':') {
+		return false
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." {
+			return false
+		}
+	}
+	return true
+}
+
+// readArchiveEntry reads at most limit bytes of an entry. An entry over a
+// limit smaller than maxBulkFileBytes returns what was read along with the
+// error, so the caller can tell the archive's total cap was hit.
+func readArchiveEntry(entry *zip.File, limit int) ([]byte, error) {
+	rc, err := entry.Open()
+	if err != nil {
+		return nil, fiber.NewError(400, "Could not open file in archive")
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(io.LimitReader(rc, int64(limit)+1))
+	if err != nil {
+		return nil, fiber.NewError(400, "Could not read file in archive")
+	}
+	if len(content) > maxBulkFileBytes {
+		return nil, fiber.NewError(413, fmt.Sprintf("File is larger than %d MB", maxBulkFileBytes>>20))
+	}
+	if len(content) > limit {
+		return content, fiber.NewError(413, fmt.Sprintf("Archive expands to more than %d MB", maxBulkExtractedBytes>>20))
+	}
+	return content, nil
+}
+
+// LimitRequestBody enforces the body limit per route. The server streams
+// request bodies so that /upload/bulk can take an archive of up to
+// MaxBulkArchiveBytes; every other route keeps Fiber's default limit and
+// gets its body read into memory here as before.
+func LimitRequestBody(c *fiber.Ctx) error {
+	req := c.Request()
+	reject := func(status int, message string) error {
+		// The unread body would otherwise be parsed as the next request
+		c.Context().SetConnectionClose()
+		return c.Status(status).JSON(fiber.Map{
+			"error": message,
+		})
+	}
+	if c.Path() == "/upload/bulk" {
+		switch length := req.Header.ContentLength(); {
+		case length > MaxBulkArchiveBytes+1<<20:
+			return reject(413, fmt.Sprintf("Archive is larger than %d MB", MaxBulkArchiveBytes>>20))
+		case length < 0:
+			// A chunked body can't be checked before it's read
+			return reject(411, "Content-Length is required")
+		}
+		return c.Next()
+	}
+
+	if req.Header.ContentLength() > fiber.DefaultBodyLimit {
+		return reject(413, "Request body too large")
+	}
+	if stream := req.BodyStream(); stream != nil {
+		body, err := io.ReadAll(io.LimitReader(stream, fiber.DefaultBodyLimit+1))
+		if err != nil {
+			return reject(400, "Unable to read request body")
+		}
+		if len(body) > fiber.DefaultBodyLimit {
+			return reject(413, "Request body too large")
+		}
+		req.SetBody(body)
+	}
+	return c.Next()
+}
+
+// BulkUpload accepts a ZIP archive of resumes and queues each one for
+// preprocessing
+func BulkUpload(c *fiber.Ctx) error {
+	header, err := c.FormFile("archive")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Error retrieving the archive",
+		})
+	}
+	if strings.ToLower(filepath.Ext(header.Filename)) != ".zip" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Only ZIP archives are allowed",
+		})
+	}
+	if header.Size > MaxBulkArchiveBytes {
+		return c.Status(413).JSON(fiber.Map{
+			"error": fmt.Sprintf("Archive is larger than %d MB", MaxBulkArchiveBytes>>20),
+		})
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Unable to read the archive",
+		})
+	}
+	defer file.Close()
+	archive, err := io.ReadAll(io.LimitReader(file, MaxBulkArchiveBytes))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Unable to read the archive",
+		})
+	}
+
+	batch := newIngestBatch(IngestSourceZip, header.Filename, c.FormValue("job_id"), c.FormValue("blind") == "true")
+	if err := extractBulkArchive(batch, archive); err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	if len(batch.Files) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Archive contains no resumes",
+		})
+	}
+	if err := saveIngestBatch(batch); err != nil {
+		log.Printf("Error saving ingest batch: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save ingest batch",
+		})
+	}
+	return c.Status(202).JSON(batch)
+}
+
+// GetIngestBatch returns a batch with up-to-date per-file statuses
+func GetIngestBatch(c *fiber.Ctx) error {
+	batch, err := loadIngestBatch(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Batch not found",
+		})
+	}
+	return c.JSON(batch)
+}
+
+// ListIngestBatches lists batches newest first, optionally filtered by source
+func ListIngestBatches(c *fiber.Ctx) error {
+	batches, err := listIngestBatches(c.Query("source"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list batches",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"batches": batches,
+		"count":   len(batches),
+	})
+}
+
+func listIngestBatches(source string) ([]*IngestBatch, error) {
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "ingest", "batch_*.json"))
+	if err != nil {
+		return nil, err
+	}
+	batches := []*IngestBatch{}
+	for _, p := range paths {
+		batch, err := loadIngestBatch(strings.TrimSuffix(filepath.Base(p), ".json"))
+		if err != nil || (source != "" && batch.Source != source) {
+			continue
+		}
+		batches = append(batches, batch)
+	}
+	sort.Slice(batches, func(i, j int) bool {
+		return batches[i].CreatedAt.After(batches[j].CreatedAt)
+	})
+	return batches, nil
+}
+
+// inboxWatcher polls a directory for resumes dropped into it
+type inboxWatcher struct {
+	mu       sync.Mutex
+	dir      string
+	jobID    string
+	interval time.Duration
+	lastScan time.Time
+	lastErr  string
+	sizes    map[string]int64 // Sizes from the previous scan, to skip files still being written
+}
+
+var inbox = &inboxWatcher{sizes: make(map[string]int64)}
+
+// StartInboxWatcher ingests resumes dropped into INBOX_DIR. Files are picked
+// up once their size is stable across two scans, then moved to processed/ or
+// rejected/ inside the inbox. INBOX_JOB_ID optionally targets a job.
+func StartInboxWatcher() {
+	dir := os.Getenv("INBOX_DIR")
+	if dir == "" {
+		return
+	}
+	seconds := defaultInboxPollSeconds
+	if raw := os.Getenv("INBOX_POLL_SECONDS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			seconds = n
+		}
+	}
+	for _, sub := range []string{"processed", "rejected"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0755); err != nil {
+			log.Printf("Inbox watcher disabled: %v", err)
+			return
+		}
+	}
+
+	inbox.mu.Lock()
+	inbox.dir = dir
+	inbox.jobID = os.Getenv("INBOX_JOB_ID")
+	inbox.interval = time.Duration(seconds) * time.Second
+	inbox.mu.Unlock()
+
+	go func() {
+		for {
+			inbox.scan()
+			time.Sleep(inbox.interval)
+		}
+	}()
+	log.Printf("Watching %s for resumes every %ds", dir, seconds)
+}
+
+func (w *inboxWatcher) scan() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.lastScan = time.Now()
+	entries, err := os.ReadDir(w.dir)
+	if err != nil {
+		w.lastErr = err.Error()
+		return
+	}
+	w.lastErr = ""
+
+	batch := newIngestBatch(IngestSourceInbox, w.dir, w.jobID, false)
+	seen := make(map[string]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.Type().IsRegular() || strings.HasPrefix(name, ".") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		seen[name] = true
+		if previous, ok := w.sizes[name]; !ok || previous != info.Size() {
+			w.sizes[name] = info.Size()
+			continue
+		}
+		delete(w.sizes, name)
+
+		before := len(batch.Files)
+		w.ingest(batch, name, info.Size())
+		destination := "processed"
+		for _, file := range batch.Files[before:] {
+			if file.Status == IngestRejected || file.Status == IngestFailed {
+				destination = "rejected"
+			}
+		}
+		moved := filepath.Join(w.dir, destination, fmt.Sprintf("%d-%s", time.Now().Unix(), name))
+		if err := os.Rename(filepath.Join(w.dir, name), moved); err != nil {
+			log.Printf("Error moving inbox file %s: %v", name, err)
+		}
+	}
+	// Forget files that disappeared before they settled
+	for name := range w.sizes {
+		if !seen[name] {
+			delete(w.sizes, name)
+		}
+	}
+
+	if len(batch.Files) > 0 {
+		if err := saveIngestBatch(batch); err != nil {
+			log.Printf("Error saving inbox batch: %v", err)
+		}
+	}
+}
+
+func (w *inboxWatcher) ingest(batch *IngestBatch, name string, size int64) {
+	isArchive := strings.ToLower(filepath.Ext(name)) == ".zip"
+	limit := int64(maxBulkFileBytes)
+	if isArchive {
+		limit = MaxBulkArchiveBytes
+	}
+	if size > limit {
+		recordIngestFile(batch, IngestFile{Name: name, Size: size, Status: IngestRejected,
+			Error: fmt.Sprintf("File is larger than %d MB", limit>>20)})
+		return
+	}
+	content, err := os.ReadFile(filepath.Join(w.dir, name))
+	if err != nil {
+		recordIngestFile(batch, IngestFile{Name: name, Size: size, Status: IngestFailed, Error: "Could not read file"})
+		return
+	}
+	if isArchive {
+		if err := extractBulkArchive(batch, content); err != nil {
+			recordIngestFile(batch, IngestFile{Name: name, Size: size, Status: IngestRejected, Error: err.Error()})
+		}
+		return
+	}
+	queueIngestFile(batch, IngestFile{Name: name}, content)
+}
+
+// GetInboxStatus reports the watched inbox and the batches it has ingested
+func GetInboxStatus(c *fiber.Ctx) error {
+	inbox.mu.Lock()
+	status := fiber.Map{
+		"enabled":      inbox.dir != "",
+		"directory":    inbox.dir,
+		"job_id":       inbox.jobID,
+		"poll_seconds": int(inbox.interval.Seconds()),
+		"pending":      len(inbox.sizes),
+		"error":        inbox.lastErr,
+	}
+	if !inbox.lastScan.IsZero() {
+		status["last_scan_at"] = inbox.lastScan
+	}
+	inbox.mu.Unlock()
+
+	batches, err := listIngestBatches(IngestSourceInbox)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list inbox batches",
+		})
+	}
+	status["batches"] = batches
+	return c.JSON(status)
+}
@@ -0,0 +1,264 @@
+package handlers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type archiveEntry struct {
+	name string
+	size int
+	// headerSize, when set, is written as the entry's uncompressed size in
+	// place of the real one
+	headerSize uint64
+}
+
+func buildArchive(t *testing.T, entries []archiveEntry) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, entry := range entries {
+		content := bytes.Repeat([]byte{'a'}, entry.size)
+		if entry.headerSize == 0 {
+			w, err := zw.Create(entry.name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w.Write(content)
+			continue
+		}
+		w, err := zw.CreateRaw(&zip.FileHeader{
+			Name:               entry.name,
+			Method:             zip.Store,
+			CRC32:              crc32.ChecksumIEEE(content),
+			CompressedSize64:   uint64(len(content)),
+			UncompressedSize64: entry.headerSize,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(content)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func fileStatuses(batch *IngestBatch) map[string]string {
+	statuses := make(map[string]string)
+	for _, file := range batch.Files {
+		statuses[file.Name] = file.Status + ": " + file.Error
+	}
+	return statuses
+}
+
+func TestExtractBulkArchiveFiles(t *testing.T) {
+	archive := buildArchive(t, []archiveEntry{
+		{name: "alice.pdf", size: 10},
+		{name: "folder/bob.docx", size: 10},
+		{name: "__MACOSX/._alice.pdf", size: 10},
+		{name: ".DS_Store", size: 10},
+		{name: "../escape.pdf", size: 10},
+		{name: "notes.txt", size: 10},
+		{name: "big.pdf", size: maxBulkFileBytes + 1},
+		{name: "lying.pdf", size: 4096, headerSize: 100},
+	})
+	batch := newIngestBatch(IngestSourceZip, "test.zip", "", false)
+	if err := extractBulkArchive(batch, archive); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		status string // prefix of "status: error"
+	}{
+		{"alice.pdf", IngestQueued},
+		{"bob.docx", IngestQueued},
+		{"../escape.pdf", IngestRejected + ": Unsafe path"},
+		{"notes.txt", IngestRejected + ": Only PDF"},
+		{"big.pdf", IngestRejected + ": File is larger than"},
+		{"lying.pdf", IngestRejected},
+	}
+	statuses := fileStatuses(batch)
+	for _, tt := range tests {
+		if got := statuses[tt.name]; !strings.HasPrefix(got, tt.status) {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.status)
+		}
+	}
+	if len(batch.Files) != len(tests) {
+		t.Errorf("batch has %d files, want %d (macOS metadata and dotfiles skipped): %v", len(batch.Files), len(tests), statuses)
+	}
+}
+
+func TestExtractBulkArchiveFileCount(t *testing.T) {
+	entries := make([]archiveEntry, maxBulkFiles+2)
+	for i := range entries {
+		entries[i] = archiveEntry{name: fmt.Sprintf("r%03d.pdf", i), size: 1}
+	}
+	batch := newIngestBatch(IngestSourceZip, "test.zip", "", false)
+	if err := extractBulkArchive(batch, buildArchive(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+
+	queued, rejected := 0, 0
+	for _, file := range batch.Files {
+		switch {
+		case file.Status == IngestQueued:
+			queued++
+		case strings.Contains(file.Error, "more than"):
+			rejected++
+		}
+	}
+	if queued != maxBulkFiles || rejected != 2 {
+		t.Errorf("queued %d and rejected %d, want %d and 2", queued, rejected, maxBulkFiles)
+	}
+}
+
+func TestExtractBulkArchiveTotalCap(t *testing.T) {
+	if testing.Short() {
+		t.Skip("extracts the full archive size cap")
+	}
+	perFile := maxBulkFileBytes
+	fit := maxBulkExtractedBytes / perFile
+	entries := make([]archiveEntry, fit+2)
+	for i := range entries {
+		entries[i] = archiveEntry{name: fmt.Sprintf("r%02d.pdf", i), size: perFile}
+	}
+	entries[len(entries)-1].size = 1 // small, but after the cap was hit
+
+	batch := newIngestBatch(IngestSourceZip, "test.zip", "", false)
+	if err := extractBulkArchive(batch, buildArchive(t, entries)); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, file := range batch.Files {
+		capped := strings.Contains(file.Error, "Archive expands to more than")
+		if want := i >= fit; capped != want {
+			t.Errorf("file %d (%s): capped = %v, want %v (%s)", i, file.Name, capped, want, file.Error)
+		}
+	}
+}
+
+func TestExtractBulkArchiveNotZip(t *testing.T) {
+	batch := newIngestBatch(IngestSourceZip, "test.zip", "", false)
+	err := extractBulkArchive(batch, []byte("not a zip"))
+	if errorStatus(err) != 400 {
+		t.Errorf("got %v, want a 400", err)
+	}
+}
+
+func TestIsSafeArchivePath(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"resume.pdf", true},
+		{"applicants/2024/resume.pdf", true},
+		{"..resume.pdf", true},
+		{"", false},
+		{"/etc/passwd", false},
+		{"../resume.pdf", false},
+		{"a/../../resume.pdf", false},
+		{"C:/resume.pdf", false},
+		{"c:resume.pdf", false},
+		{"resume\x00.pdf", false},
+	}
+	for _, tt := range tests {
+		if got := isSafeArchivePath(tt.name); got != tt.want {
+			t.Errorf("isSafeArchivePath(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLimitRequestBody(t *testing.T) {
+	app := fiber.New(fiber.Config{StreamRequestBody: true, DisablePreParseMultipartForm: true})
+	app.Use(LimitRequestBody)
+	echo := func(c *fiber.Ctx) error { return c.SendString(fmt.Sprint(len(c.Body()))) }
+	app.Post("/upload/bulk", echo)
+	app.Post("/other", echo)
+
+	tests := []struct {
+		name    string
+		path    string
+		size    int
+		chunked bool
+		want    int
+	}{
+		{"small body", "/other", 100, false, 200},
+		{"over the default limit", "/other", fiber.DefaultBodyLimit + 1, false, 413},
+		{"bulk upload over the default limit", "/upload/bulk", fiber.DefaultBodyLimit + 1, false, 200},
+		{"bulk upload without a length", "/upload/bulk", 100, true, 411},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, bytes.NewReader(bytes.Repeat([]byte{'x'}, tt.size)))
+			if tt.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+			resp, err := app.Test(req, -1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateTaskOnlyAcceptsPublicTypes(t *testing.T) {
+	app := fiber.New()
+	app.Post("/jobs", CreateTask)
+
+	tests := []struct {
+		taskType string
+		want     int
+	}{
+		{"analyze_projects", 202},
+		{"score_resume", 202},
+		{"preprocess_resume", 400},
+		{"ats_application", 400},
+		{"rescore_job", 400},
+		{"unknown", 400},
+	}
+	for _, tt := range tests {
+		t.Run(tt.taskType, func(t *testing.T) {
+			body := fmt.Sprintf(`{"type":%q,"resume_id":"1","job_id":"2","params":{"path":".env","filename":"x.docx"}}`, tt.taskType)
+			req := httptest.NewRequest("POST", "/jobs", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.want)
+			}
+			if tt.want != 202 {
+				return
+			}
+			var created struct {
+				ID string `json:"id"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+				t.Fatal(err)
+			}
+			task, err := loadTask(created.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(task.Params) != 0 {
+				t.Errorf("task kept params from the request body: %v", task.Params)
+			}
+		})
+	}
+}
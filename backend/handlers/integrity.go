@@ -0,0 +1,371 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const (
+	minOverlapMonths       = 3 // Overlaps shorter than this are usually notice periods
+	minDuplicateBulletWord = 6 // Shorter bullets repeat legitimately ("Led code reviews")
+	claimedYearsTolerance  = 2 // Allowed gap between claimed and listed experience
+	minHiddenStuffingWords = 10
+)
+
+var (
+	// Roles that can legitimately run alongside a full-time job
+	nonFullTimeRolePattern = regexp.MustCompile(`(?i)\b(part[- ]time|contract(or)?|freelance|consult(ant|ing)|intern(ship)?|volunteer|advis(or|er|ory)|board|adjunct|mentor|founder|co-founder|side project)\b`)
+	bachelorDegreePattern  = regexp.MustCompile(`(?i)\b(bachelor|b\.?s\.?c?|b\.?a|b\.?eng|b\.?tech|associate|undergraduate)\b`)
+	claimedExperiencePat   = regexp.MustCompile(`(?i)(\d{1,2})\+?\s*(?:years|yrs)(?:'|’)?\s+(?:of\s+)?(?:professional\s+|industry\s+|total\s+|work\s+)?experience\b`)
+	nonWordPattern         = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// IntegrityFlag is a possible fabrication or inconsistency found in a resume.
+// Flags are prompts for a reviewer, not proof; each carries the evidence that
+// triggered it.
+type IntegrityFlag struct {
+	Code     string   `json:"code"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Evidence []string `json:"evidence,omitempty"`
+}
+
+// validateResumeIntegrity runs every consistency check over the extracted
+// entities, the resume text and the extraction diagnostics
+func validateResumeIntegrity(entities ExtractedEntities, text string, diagnostics *ExtractionDiagnostics) []IntegrityFlag {
+	flags := []IntegrityFlag{}
+	add := func(flag IntegrityFlag) {
+		flags = append(flags, flag)
+	}
+
+	checkOverlappingRoles(entities.Experience, add)
+	checkGraduationAfterSeniorRole(entities, add)
+	checkTechnologyAge(entities.Experience, text, add)
+	checkDuplicateBullets(entities, add)
+	checkHiddenText(entities, diagnostics, add)
+	checkDateMismatches(entities, text, add)
+	return flags
+}
+
+// checkOverlappingRoles flags full-time roles at different employers that run
+// at the same time for more than a few months
+func checkOverlappingRoles(experiences []Experience, add func(IntegrityFlag)) {
+	spans := experienceSpans(experiences)
+	for i := 0; i < len(spans); i++ {
+		a := experiences[spans[i].index]
+		if !isFullTimeRole(a) {
+			continue
+		}
+		for j := i + 1; j < len(spans); j++ {
+			b := experiences[spans[j].index]
+			if !isFullTimeRole(b) || strings.EqualFold(normalizeEmployer(a.Company), normalizeEmployer(b.Company)) {
+				continue
+			}
+			end := spans[i].end
+			if spans[j].end.Before(end) {
+				end = spans[j].end
+			}
+			months := int(end.Sub(spans[j].start).Hours() / 24 / 30)
+			if months <= minOverlapMonths {
+				continue
+			}
+			add(IntegrityFlag{
+				Code:     "overlapping_roles",
+				Severity: IssueMedium,
+				Message:  fmt.Sprintf("Two full-time roles overlap by about %d months", months),
+				Evidence: []string{roleLabel(a), roleLabel(b)},
+			})
+		}
+	}
+}
+
+func isFullTimeRole(exp Experience) bool {
+	return !nonFullTimeRolePattern.MatchString(exp.Title + " " + exp.Level + " " + exp.RoleDescription)
+}
+
+func roleLabel(exp Experience) string {
+	label := fmt.Sprintf("%s at %s", exp.Title, exp.Company)
+	if exp.Duration != "" {
+		label += " (" + exp.Duration + ")"
+	}
+	return label
+}
+
+// checkGraduationAfterSeniorRole flags a senior title that starts before the
+// candidate's first degree was awarded
+func checkGraduationAfterSeniorRole(entities ExtractedEntities, add func(IntegrityFlag)) {
+	graduation, degree := firstGraduation(entities.Education)
+	if graduation == 0 {
+		return
+	}
+	for _, span := range experienceSpans(entities.Experience) {
+		exp := entities.Experience[span.index]
+		switch normalizeSeniority(seniorityPattern.FindString(exp.Title)) {
+		case "senior", "lead", "principal", "executive":
+		default:
+			continue
+		}
+		if span.start.Year() < graduation {
+			add(IntegrityFlag{
+				Code:     "graduation_after_senior_role",
+				Severity: IssueMedium,
+				Message: fmt.Sprintf("First senior role starts in %d, before the first degree was completed in %d",
+					span.start.Year(), graduation),
+				Evidence: []string{roleLabel(exp), degree},
+			})
+		}
+		// Spans are sorted, so only the earliest senior role matters
+		return
+	}
+}
+
+// firstGraduation returns the year of the earliest undergraduate degree, or of
+// the earliest degree when none looks undergraduate
+func firstGraduation(education []Education) (int, string) {
+	year, label, bachelorOnly := 0, "", false
+	for _, edu := range education {
+		graduated := latestGraduationYear([]Education{edu})
+		if graduated == 0 {
+			continue
+		}
+		isBachelor := bachelorDegreePattern.MatchString(edu.Degree)
+		switch {
+		case isBachelor && !bachelorOnly:
+			bachelorOnly = true
+		case bachelorOnly && !isBachelor:
+			continue
+		case year != 0 && graduated >= year:
+			continue
+		}
+		year = graduated
+		label = strings.TrimSpace(fmt.Sprintf("%s, %s (%d)", edu.Degree, edu.Institution, graduated))
+	}
+	return year, label
+}
+
+// checkTechnologyAge flags experience claims that predate the technology
+func checkTechnologyAge(experiences []Experience, text string, add func(IntegrityFlag)) {
+	currentYear := time.Now().Year()
+	seen := make(map[string]bool)
+	for _, m := range yearsOfTechPattern.FindAllStringSubmatch(text, -1) {
+		years, _ := strconv.Atoi(m[1])
+		tech, released := matchTechnology(strings.ToLower(strings.TrimSpace(m[2])))
+		if tech == "" || seen[tech] {
+			continue
+		}
+		if available := currentYear - released; years > available {
+			seen[tech] = true
+			add(IntegrityFlag{
+				Code:     "skill_predates_technology",
+				Severity: IssueHigh,
+				Message: fmt.Sprintf("Claims %d years of %s, which was released in %d (%d years ago)",
+					years, tech, released, available),
+				Evidence: []string{strings.TrimSpace(m[0])},
+			})
+		}
+	}
+
+	// A role that ended before a technology existed can't have used it
+	for _, span := range experienceSpans(experiences) {
+		exp := experiences[span.index]
+		for _, skill := range exp.Skills {
+			tech, released := matchTechnology(strings.ToLower(strings.TrimSpace(skill)))
+			if tech == "" || tech != strings.ToLower(strings.TrimSpace(skill)) || span.end.Year() >= released || seen[tech] {
+				continue
+			}
+			seen[tech] = true
+			add(IntegrityFlag{
+				Code:     "skill_predates_technology",
+				Severity: IssueHigh,
+				Message: fmt.Sprintf("Lists %s for a role that ended in %d, before it was released in %d",
+					skill, span.end.Year(), released),
+				Evidence: []string{roleLabel(exp)},
+			})
+		}
+	}
+}
+
+// checkDuplicateBullets flags the same bullet copied into more than one role
+func checkDuplicateBullets(entities ExtractedEntities, add func(IntegrityFlag)) {
+	roles := make(map[string]map[int]string)
+	var order []string
+	for _, bullet := range collectResumeBullets(entities) {
+		if bullet.section != "experience" {
+			continue
+		}
+		key := strings.TrimSpace(nonWordPattern.ReplaceAllString(strings.ToLower(bullet.text), " "))
+		if len(strings.Fields(key)) < minDuplicateBulletWord {
+			continue
+		}
+		if roles[key] == nil {
+			roles[key] = make(map[int]string)
+			order = append(order, bullet.text)
+		}
+		roles[key][bullet.entryIndex] = bullet.entryLabel
+	}
+
+	for _, text := range order {
+		key := strings.TrimSpace(nonWordPattern.ReplaceAllString(strings.ToLower(text), " "))
+		if len(roles[key]) < 2 {
+			continue
+		}
+		var labels []string
+		for _, label := range roles[key] {
+			labels = append(labels, label)
+		}
+		sort.Strings(labels)
+		add(IntegrityFlag{
+			Code:     "duplicate_bullets",
+			Severity: IssueLow,
+			Message:  fmt.Sprintf("The same bullet appears under %d different roles: %q", len(labels), text),
+			Evidence: labels,
+		})
+	}
+}
+
+// checkHiddenText turns white or invisible PDF text into a keyword stuffing
+// flag, naming any claimed skills that only appear in the hidden text
+func checkHiddenText(entities ExtractedEntities, diagnostics *ExtractionDiagnostics, add func(IntegrityFlag)) {
+	if diagnostics == nil || len(diagnostics.HiddenText) == 0 {
+		return
+	}
+	hidden := strings.Join(diagnostics.HiddenText, " ")
+	keywords := keywordsIn(hidden, entities.Skills)
+	switch {
+	case len(keywords) > 0:
+		add(IntegrityFlag{
+			Code:     "hidden_keywords",
+			Severity: IssueHigh,
+			Message:  fmt.Sprintf("Skills hidden in white or invisible text: %s", strings.Join(keywords, ", ")),
+			Evidence: diagnostics.HiddenText,
+		})
+	case len(strings.Fields(hidden)) >= minHiddenStuffingWords:
+		add(IntegrityFlag{
+			Code:     "hidden_keywords",
+			Severity: IssueMedium,
+			Message:  fmt.Sprintf("%d words of white or invisible text found in the PDF", len(strings.Fields(hidden))),
+			Evidence: diagnostics.HiddenText,
+		})
+	}
+}
+
+// checkDateMismatches compares dates that one part of the resume states about
+// another: the summary's total experience, repeated roles and degree dates
+func checkDateMismatches(entities ExtractedEntities, text string, add func(IntegrityFlag)) {
+	if m := claimedExperiencePat.FindStringSubmatch(text); m != nil {
+		claimed, _ := strconv.Atoi(m[1])
+		if listed := listedExperienceYears(entities.Experience); listed > 0 && float64(claimed) > listed+claimedYearsTolerance {
+			add(IntegrityFlag{
+				Code:     "date_mismatch",
+				Severity: IssueMedium,
+				Message: fmt.Sprintf("Claims %d years of experience but the listed roles cover about %.1f years",
+					claimed, listed),
+				Evidence: []string{strings.TrimSpace(m[0])},
+			})
+		}
+	}
+
+	durations := make(map[string]string)
+	for _, exp := range entities.Experience {
+		key := strings.ToLower(normalizeEmployer(exp.Company) + "|" + strings.TrimSpace(exp.Title))
+		if exp.Company == "" || exp.Duration == "" {
+			continue
+		}
+		if previous, ok := durations[key]; ok && !strings.EqualFold(previous, exp.Duration) {
+			add(IntegrityFlag{
+				Code:     "date_mismatch",
+				Severity: IssueMedium,
+				Message:  fmt.Sprintf("%s at %s is listed with different dates", exp.Title, exp.Company),
+				Evidence: []string{previous, exp.Duration},
+			})
+			continue
+		}
+		durations[key] = exp.Duration
+	}
+
+	for _, edu := range entities.Education {
+		year := latestGraduationYear([]Education{{Year: edu.Year}})
+		graduated := latestGraduationYear([]Education{{GraduationDate: edu.GraduationDate}})
+		if year != 0 && graduated != 0 && year != graduated {
+			add(IntegrityFlag{
+				Code:     "date_mismatch",
+				Severity: IssueLow,
+				Message:  fmt.Sprintf("%s at %s gives both %d and %d as the completion year", edu.Degree, edu.Institution, year, graduated),
+				Evidence: []string{edu.Year, edu.GraduationDate},
+			})
+		}
+	}
+}
+
+// listedExperienceYears totals the roles' date ranges without double
+// counting overlaps
+func listedExperienceYears(experiences []Experience) float64 {
+	var total time.Duration
+	var coveredUntil time.Time
+	for _, span := range experienceSpans(experiences) {
+		start := span.start
+		if start.Before(coveredUntil) {
+			start = coveredUntil
+		}
+		if span.end.After(start) {
+			total += span.end.Sub(start)
+			coveredUntil = span.end
+		}
+	}
+	return total.Hours() / 24 / 365
+}
+
+// resumeIntegrityFlags returns the flags stored with a resume, validating
+// resumes processed before the checks existed on the fly
+func resumeIntegrityFlags(resumeData *TextData) []IntegrityFlag {
+	if resumeData.IntegrityFlags != nil {
+		return resumeData.IntegrityFlags
+	}
+	diagnostics, _ := loadExtractionDiagnostics(resumeData.ID)
+	return validateResumeIntegrity(resumeData.Entities, resumeData.ProcessedText, diagnostics)
+}
+
+// blindIntegrityFlags drops the raw hidden text from flag evidence, since it
+// can hold anything the candidate typed, names and contacts included. Flags
+// shown for blind screening jobs go through this.
+func blindIntegrityFlags(flags []IntegrityFlag) []IntegrityFlag {
+	blind := make([]IntegrityFlag, len(flags))
+	for i, flag := range flags {
+		if flag.Code == "hidden_keywords" {
+			flag.Evidence = nil
+		}
+		blind[i] = flag
+	}
+	return blind
+}
+
+// GetResumeIntegrity returns the integrity flags for a resume
+func GetResumeIntegrity(c *fiber.Ctx) error {
+	resumeID := normalizeID(c.Params("id"), "resume")
+	resumeData, err := LoadTextData(resumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume not found",
+		})
+	}
+
+	flags := resumeIntegrityFlags(resumeData)
+	if c.QueryBool("refresh") {
+		diagnostics, _ := loadExtractionDiagnostics(resumeID)
+		flags = validateResumeIntegrity(resumeData.Entities, resumeData.ProcessedText, diagnostics)
+		if err := updateResumeRecord(resumeID, func(data *TextData) { data.IntegrityFlags = flags }); err != nil {
+			log.Printf("Error saving integrity flags: %v", err)
+		}
+	}
+	return c.JSON(fiber.Map{
+		"resume_id":       resumeID,
+		"integrity_flags": flags,
+	})
+}
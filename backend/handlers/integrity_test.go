@@ -0,0 +1,262 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"math"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func pdfWithStream(t *testing.T, ops string, compress bool) []byte {
+	t.Helper()
+	data := []byte(ops)
+	if compress {
+		var buf bytes.Buffer
+		w := zlib.NewWriter(&buf)
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		data = buf.Bytes()
+	}
+	return append(append([]byte("%PDF-1.4\n1 0 obj\n<<>>\nstream\n"), data...), "\nendstream\nendobj\n"...)
+}
+
+func TestHiddenPDFText(t *testing.T) {
+	tests := []struct {
+		name         string
+		ops          string
+		compress     bool
+		wantHidden   []string
+		wantOverFill []string
+	}{
+		{"white rgb", "BT 1 1 1 rg (Kubernetes Terraform) Tj ET", false, []string{"Kubernetes Terraform"}, nil},
+		{"white gray", "BT 1 g (gray white) Tj ET", false, []string{"gray white"}, nil},
+		{"white cmyk", "BT 0 0 0 0 k (cmyk white) Tj ET", false, []string{"cmyk white"}, nil},
+		{"invisible render mode", "BT 0 g 3 Tr (invisible words) Tj ET", false, []string{"invisible words"}, nil},
+		{"visible text", "BT 0 g (visible words) Tj ET", false, nil, nil},
+		{"kerning splits words", "BT 1 g [(Go) -250 (Rust) -20 (ish)] TJ ET", false, []string{"Go Rustish"}, nil},
+		{"escaped characters", `BT 1 g (a \(b\)\ncd) Tj ET`, false, []string{"a (b) cd"}, nil},
+		{"restored graphics state", "q BT 1 g (hidden one) Tj ET Q BT (shown) Tj ET", false, []string{"hidden one"}, nil},
+		{"white on a filled banner", "0 0 1 rg 0 0 100 50 re f BT 1 1 1 rg (Banner Title) Tj ET", false, nil, []string{"Banner Title"}},
+		{"invisible on a filled banner", "0 0 1 rg 0 0 100 50 re f BT 3 Tr (stuffed) Tj ET", false, []string{"stuffed"}, nil},
+		{"white fill does not count", "1 g 0 0 100 50 re f BT (white words) Tj ET", false, []string{"white words"}, nil},
+		{"compressed stream", "BT 1 1 1 rg (compressed words) Tj ET", true, []string{"compressed words"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hidden, overFill := hiddenPDFText(pdfWithStream(t, tt.ops, tt.compress))
+			if strings.Join(hidden, "|") != strings.Join(tt.wantHidden, "|") || strings.Join(overFill, "|") != strings.Join(tt.wantOverFill, "|") {
+				t.Errorf("hiddenPDFText = %q, %q; want %q, %q", hidden, overFill, tt.wantHidden, tt.wantOverFill)
+			}
+		})
+	}
+}
+
+func TestValidateResumeIntegrity(t *testing.T) {
+	role := func(title, company, duration string) Experience {
+		return Experience{Title: title, Company: company, Duration: duration}
+	}
+	copied := "Built a payment service handling two million transactions daily"
+
+	tests := []struct {
+		name        string
+		entities    ExtractedEntities
+		text        string
+		diagnostics *ExtractionDiagnostics
+		want        []string
+	}{
+		{"clean resume", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme", "Jan 2015 - Dec 2018"), role("Engineer", "Globex", "Jan 2019 - Dec 2022")},
+			Education:  []Education{{Degree: "BSc", Year: "2014"}},
+		}, "8 years of experience", nil, nil},
+		{"overlapping full-time roles", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme", "Jan 2018 - Dec 2020"), role("Engineer", "Globex", "Jan 2019 - Dec 2021")},
+		}, "", nil, []string{"overlapping_roles"}},
+		{"overlap with a freelance role", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme", "Jan 2018 - Dec 2020"), role("Freelance Consultant", "Globex", "Jan 2019 - Dec 2021")},
+		}, "", nil, nil},
+		{"overlap at the same employer", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme Inc.", "Jan 2018 - Dec 2020"), role("Senior Engineer", "ACME", "Jan 2019 – Dec 2021")},
+		}, "", nil, nil},
+		{"notice period overlap", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme", "Jan 2018 - Dec 2020"), role("Engineer", "Globex", "Oct 2020 - Dec 2022")},
+		}, "", nil, nil},
+		{"senior before graduating", ExtractedEntities{
+			Experience: []Experience{role("Senior Engineer", "Acme", "2014 - 2018")},
+			Education:  []Education{{Degree: "MSc", Year: "2010"}, {Degree: "BSc Computer Science", Year: "2016"}},
+		}, "", nil, []string{"graduation_after_senior_role"}},
+		{"claims more years than the technology", ExtractedEntities{}, "15 years of Kubernetes experience", nil, []string{"skill_predates_technology"}},
+		{"role ended before the technology", ExtractedEntities{
+			Experience: []Experience{{Title: "Engineer", Company: "Acme", Duration: "Jan 2008 - Dec 2010", Skills: []string{"Docker"}}},
+		}, "", nil, []string{"skill_predates_technology"}},
+		{"bullet copied across roles", ExtractedEntities{
+			Experience: []Experience{
+				{Title: "Engineer", Company: "Acme", Responsibilities: []string{copied, "Led code reviews"}},
+				{Title: "Engineer", Company: "Globex", Achievements: []string{strings.ToUpper(copied) + ".", "Led code reviews"}},
+			},
+		}, "", nil, []string{"duplicate_bullets"}},
+		{"hidden claimed skill", ExtractedEntities{Skills: []string{"Kubernetes"}}, "",
+			&ExtractionDiagnostics{HiddenText: []string{"kubernetes terraform"}}, []string{"hidden_keywords"}},
+		{"hidden stuffing", ExtractedEntities{}, "",
+			&ExtractionDiagnostics{HiddenText: []string{"one two three four five six seven eight nine ten"}}, []string{"hidden_keywords"}},
+		{"a few hidden words", ExtractedEntities{}, "",
+			&ExtractionDiagnostics{HiddenText: []string{"page footer"}}, nil},
+		{"claims more experience than listed", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme", "Jan 2018 - Dec 2020")},
+		}, "12 years of professional experience", nil, []string{"date_mismatch"}},
+		{"role listed twice with different dates", ExtractedEntities{
+			Experience: []Experience{role("Engineer", "Acme", "2018 - 2020"), role("Engineer", "Acme Inc", "2017 - 2020")},
+		}, "", nil, []string{"date_mismatch"}},
+		{"degree with two completion years", ExtractedEntities{
+			Education: []Education{{Degree: "BSc", Year: "2015", GraduationDate: "May 2016"}},
+		}, "", nil, []string{"date_mismatch"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags := validateResumeIntegrity(tt.entities, tt.text, tt.diagnostics)
+			var codes []string
+			for _, flag := range flags {
+				codes = append(codes, flag.Code)
+				if len(flag.Evidence) == 0 {
+					t.Errorf("flag %s has no evidence", flag.Code)
+				}
+			}
+			sort.Strings(codes)
+			if strings.Join(codes, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("flags = %+v, want codes %v", flags, tt.want)
+			}
+		})
+	}
+}
+
+func TestHiddenKeywordSeverity(t *testing.T) {
+	tests := []struct {
+		name   string
+		skills []string
+		hidden string
+		want   string
+	}{
+		{"names a claimed skill", []string{"Kubernetes"}, "kubernetes terraform", IssueHigh},
+		{"long unrelated text", nil, "one two three four five six seven eight nine ten", IssueMedium},
+	}
+	for _, tt := range tests {
+		flags := validateResumeIntegrity(ExtractedEntities{Skills: tt.skills}, "", &ExtractionDiagnostics{HiddenText: []string{tt.hidden}})
+		if len(flags) != 1 || flags[0].Severity != tt.want {
+			t.Errorf("%s: flags = %+v, want one %s flag", tt.name, flags, tt.want)
+		}
+	}
+}
+
+func TestFirstGraduation(t *testing.T) {
+	tests := []struct {
+		name      string
+		education []Education
+		wantYear  int
+	}{
+		{"bachelor preferred over an earlier masters", []Education{{Degree: "MSc", Year: "2010"}, {Degree: "BSc", Year: "2016"}}, 2016},
+		{"earliest bachelor", []Education{{Degree: "B.A.", Year: "2012"}, {Degree: "Bachelor of Science", GraduationDate: "June 2009"}}, 2009},
+		{"earliest degree when none is undergraduate", []Education{{Degree: "PhD", Year: "2020"}, {Degree: "MSc", Year: "2015"}}, 2015},
+		{"no dates", []Education{{Degree: "BSc"}}, 0},
+	}
+	for _, tt := range tests {
+		if year, _ := firstGraduation(tt.education); year != tt.wantYear {
+			t.Errorf("%s: firstGraduation = %d, want %d", tt.name, year, tt.wantYear)
+		}
+	}
+}
+
+func TestListedExperienceYears(t *testing.T) {
+	tests := []struct {
+		name      string
+		durations []string
+		want      float64
+	}{
+		{"single role", []string{"2015 - 2020"}, 5},
+		{"overlap counted once", []string{"2015 - 2020", "2018 - 2022"}, 7},
+		{"nested role", []string{"2015 - 2020", "2016 - 2017"}, 5},
+		{"gap not counted", []string{"2010 - 2012", "2015 - 2016"}, 3},
+		{"unparseable", []string{"a while"}, 0},
+	}
+	for _, tt := range tests {
+		var experiences []Experience
+		for _, duration := range tt.durations {
+			experiences = append(experiences, Experience{Duration: duration})
+		}
+		if got := listedExperienceYears(experiences); math.Abs(got-tt.want) > 0.02 {
+			t.Errorf("%s: listedExperienceYears = %.2f, want %.0f", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBlindIntegrityFlags(t *testing.T) {
+	flags := []IntegrityFlag{
+		{Code: "hidden_keywords", Evidence: []string{"Jane Doe jane@example.com kubernetes"}},
+		{Code: "overlapping_roles", Evidence: []string{"Engineer at Acme", "Engineer at Globex"}},
+	}
+	blind := blindIntegrityFlags(flags)
+
+	tests := []struct {
+		name      string
+		got, want int
+	}{
+		{"hidden text evidence dropped", len(blind[0].Evidence), 0},
+		{"other evidence kept", len(blind[1].Evidence), 2},
+		{"caller's flags untouched", len(flags[0].Evidence), 1},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s: got %d, want %d", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestGetResumeIntegrity(t *testing.T) {
+	overlapping := ExtractedEntities{Experience: []Experience{
+		{Title: "Engineer", Company: "Acme", Duration: "Jan 2018 - Dec 2020"},
+		{Title: "Engineer", Company: "Globex", Duration: "Jan 2019 - Dec 2021"},
+	}}
+	saveTestText(t, "resume", "4201", TextData{Entities: overlapping})
+	saveTestText(t, "resume", "4202", TextData{Entities: overlapping, IntegrityFlags: []IntegrityFlag{{Code: "stored"}}})
+
+	app := fiber.New()
+	app.Get("/resumes/:id/integrity", GetResumeIntegrity)
+
+	tests := []struct {
+		name     string
+		path     string
+		want     int
+		contains string
+	}{
+		{"computed on the fly", "/resumes/4201/integrity", 200, "overlapping_roles"},
+		{"stored flags", "/resumes/4202/integrity", 200, `"code":"stored"`},
+		{"refresh recomputes", "/resumes/4202/integrity?refresh=true", 200, "overlapping_roles"},
+		{"unknown resume", "/resumes/4299/integrity", 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.want || !strings.Contains(string(body), tt.contains) {
+				t.Errorf("got %d %s, want %d containing %s", resp.StatusCode, body, tt.want, tt.contains)
+			}
+		})
+	}
+
+	stored, err := LoadTextData("4202", "resume")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored.IntegrityFlags) != 1 || stored.IntegrityFlags[0].Code != "overlapping_roles" {
+		t.Errorf("refreshed flags were not saved: %+v", stored.IntegrityFlags)
+	}
+}
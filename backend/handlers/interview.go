@@ -0,0 +1,434 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Question categories
+const (
+	QuestionTechnical       = "technical"
+	QuestionPartialProbe    = "partial_match_probe"
+	QuestionBehavioral      = "behavioral"
+	QuestionProjectFollowUp = "project_follow_up"
+)
+
+const maxKitSkills = 12 // Cap technical questions so the kit stays usable
+
+// InterviewQuestion is one question in an interview kit
+type InterviewQuestion struct {
+	ID                 string   `json:"id"`
+	Category           string   `json:"category"`
+	Question           string   `json:"question"`
+	Difficulty         string   `json:"difficulty"`
+	Competency         string   `json:"competency"`
+	Skill              string   `json:"skill,omitempty"`
+	Project            string   `json:"project,omitempty"`
+	GoodAnswerIncludes []string `json:"good_answer_includes"`
+	FollowUps          []string `json:"follow_ups,omitempty"`
+}
+
+// InterviewKit is a structured set of questions tailored to a resume/job pair
+type InterviewKit struct {
+	ID                  string              `json:"id"`
+	ResumeID            string              `json:"resume_id"`
+	JobID               string              `json:"job_id"`
+	CreatedAt           time.Time           `json:"created_at"`
+	Source              string              `json:"source"`
+	FocusAreas          []string            `json:"focus_areas"`
+	TechnicalQuestions  []InterviewQuestion `json:"technical_questions"`
+	PartialMatchProbes  []InterviewQuestion `json:"partial_match_probes"`
+	BehavioralQuestions []InterviewQuestion `json:"behavioral_questions"`
+	ProjectFollowUps    []InterviewQuestion `json:"project_follow_ups"`
+}
+
+// interviewContext gathers everything the generator knows about the pair
+type interviewContext struct {
+	jobSkills      []string
+	exactMatches   []string
+	partialMatches []PartialMatch
+	missingSkills  []string
+	softSkills     []string
+	timeline       []string
+	projects       []ProjectAnalysis
+	level          string
+}
+
+// GenerateInterviewQuestions builds an interview kit from a resume's gaps
+// against a job
+func GenerateInterviewQuestions(c *fiber.Ctx) error {
+	var request struct {
+		ResumeID string `json:"resume_id"`
+		JobID    string `json:"job_id"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.ResumeID == "" || request.JobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+
+	resumeID := normalizeID(request.ResumeID, "resume")
+	jobID := normalizeID(request.JobID, "job")
+	resumeData, jobData, err := loadTaskPair(&AnalysisTask{ResumeID: resumeID, JobID: jobID})
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	kit := buildInterviewKit(context.Background(), resumeID, resumeData, jobID, jobData)
+	if err := utils.SaveJSONFile(interviewKitPath(resumeID, jobID), kit, 0644); err != nil {
+		log.Printf("Error saving interview kit: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save interview kit",
+		})
+	}
+
+	return c.JSON(kit)
+}
+
+// GetInterviewQuestions returns the last kit generated for a resume/job pair
+func GetInterviewQuestions(c *fiber.Ctx) error {
+	kit, err := loadInterviewKit(c.Query("resume_id"), c.Query("job_id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Interview kit not found",
+		})
+	}
+	return c.JSON(kit)
+}
+
+func interviewKitPath(resumeID, jobID string) string {
+	return filepath.Join("processed_texts", "interview_kits",
+		fmt.Sprintf("kit_%s_%s.json", pathID(resumeID, "resume"), pathID(jobID, "job")))
+}
+
+func loadInterviewKit(resumeID, jobID string) (*InterviewKit, error) {
+	var kit InterviewKit
+	if err := utils.LoadJSONFile(interviewKitPath(resumeID, jobID), &kit); err != nil {
+		return nil, err
+	}
+	return &kit, nil
+}
+
+// buildInterviewKit asks the LLM for a kit and fills any gaps from templates,
+// falling back entirely to templates when the LLM is unavailable
+func buildInterviewKit(ctx context.Context, resumeID string, resumeData *TextData, jobID string, jobData *TextData) *InterviewKit {
+	ictx := gatherInterviewContext(resumeID, resumeData, jobID, jobData)
+
+	kit := &InterviewKit{
+		ID:        fmt.Sprintf("kit_%s_%s", resumeID, jobID),
+		ResumeID:  resumeID,
+		JobID:     jobID,
+		CreatedAt: time.Now(),
+		Source:    "template",
+	}
+
+	if llmKit, err := generateInterviewKitWithLLM(ctx, ictx, jobData.ProcessedText); err != nil {
+		log.Printf("Falling back to template interview kit: %v", err)
+	} else {
+		kit.Source = "llm"
+		kit.TechnicalQuestions = llmKit.TechnicalQuestions
+		kit.PartialMatchProbes = llmKit.PartialMatchProbes
+		kit.BehavioralQuestions = llmKit.BehavioralQuestions
+		kit.ProjectFollowUps = llmKit.ProjectFollowUps
+	}
+
+	fillTemplateQuestions(kit, ictx)
+	normalizeKitQuestions(kit)
+
+	kit.FocusAreas = append(kit.FocusAreas, ictx.missingSkills...)
+	for _, pm := range ictx.partialMatches {
+		kit.FocusAreas = append(kit.FocusAreas, pm.JobSkill)
+	}
+	kit.FocusAreas = uniqueStrings(kit.FocusAreas)
+	return kit
+}
+
+func gatherInterviewContext(resumeID string, resumeData *TextData, jobID string, jobData *TextData) interviewContext {
+	ictx := interviewContext{
+		jobSkills: jobData.Requirements.Skills,
+		level:     strings.ToLower(jobData.Requirements.Experience.Level),
+	}
+	if len(ictx.jobSkills) > maxKitSkills {
+		ictx.jobSkills = ictx.jobSkills[:maxKitSkills]
+	}
+
+	ictx.exactMatches, ictx.partialMatches, ictx.missingSkills = analyzeSkillMatches(
+		resumeData.Entities.Skills,
+		jobData.Requirements.Skills,
+	)
+
+	ictx.softSkills = uniqueStrings(append(filterSoftSkills(jobData.Requirements.Skills), jobData.SoftSkills...))
+	if len(ictx.softSkills) == 0 {
+		ictx.softSkills = []string{"communication", "teamwork", "problem solving"}
+	}
+
+	for _, exp := range resumeData.Entities.Experience {
+		entry := fmt.Sprintf("%s at %s (%s)", exp.Title, exp.Company, exp.Duration)
+		if months := calculateDurationInMonths(exp.Duration); months > 0 {
+			entry += fmt.Sprintf(", %.0f months", months)
+		}
+		ictx.timeline = append(ictx.timeline, entry)
+	}
+
+	// Prefer the stored project analysis; fall back to the raw projects
+	var analysis ProjectAnalysisResponse
+	if err := loadAnalysisResult("projects", resumeID, jobID, &analysis); err == nil && len(analysis.Projects) > 0 {
+		ictx.projects = analysis.Projects
+	} else {
+		for _, p := range resumeData.Entities.Projects {
+			ictx.projects = append(ictx.projects, ProjectAnalysis{
+				Name:           p.Name,
+				Description:    p.Description,
+				TechStack:      p.Technologies,
+				MatchingSkills: enhanceSkillMatching(p.Technologies, jobData.Requirements.Skills),
+			})
+		}
+	}
+	if len(ictx.projects) > maxProjects {
+		ictx.projects = ictx.projects[:maxProjects]
+	}
+
+	return ictx
+}
+
+func generateInterviewKitWithLLM(ctx context.Context, ictx interviewContext, jobText string) (*InterviewKit, error) {
+	llm, err := newLLMProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer llm.Close()
+
+	var projectLines []string
+	for _, p := range ictx.projects {
+		projectLines = append(projectLines, fmt.Sprintf("- %s: %s (tech: %s)", p.Name, p.Description, strings.Join(p.TechStack, ", ")))
+	}
+	var partialLines []string
+	for _, pm := range ictx.partialMatches {
+		partialLines = append(partialLines, fmt.Sprintf("%s (candidate lists %s)", pm.JobSkill, pm.ResumeSkill))
+	}
+
+	prompt := fmt.Sprintf(`You are preparing a structured interview kit for a hiring panel.
+
+Job description: %s
+Seniority: %s
+Required skills: %s
+Skills the candidate clearly has: %s
+Skills the candidate only partially matches: %s
+Skills missing from the resume: %s
+Soft skills the role needs: %s
+Experience timeline: %s
+Projects:
+%s
+
+Return only JSON with this exact shape:
+{
+  "technical_questions": [{"question": "", "skill": "", "difficulty": "easy|medium|hard", "competency": "", "good_answer_includes": [""], "follow_ups": [""]}],
+  "partial_match_probes": [{"question": "", "skill": "", "difficulty": "", "competency": "", "good_answer_includes": [""]}],
+  "behavioral_questions": [{"question": "", "competency": "", "difficulty": "", "good_answer_includes": [""]}],
+  "project_follow_ups": [{"question": "", "project": "", "difficulty": "", "competency": "", "good_answer_includes": [""]}]
+}
+Write one technical question per required skill, one probe per partial match,
+one behavioral question per soft skill and one follow-up per project.`,
+		sanitizeText(jobText, maxPromptLength),
+		ictx.level,
+		strings.Join(ictx.jobSkills, ", "),
+		strings.Join(ictx.exactMatches, ", "),
+		strings.Join(partialLines, ", "),
+		strings.Join(ictx.missingSkills, ", "),
+		strings.Join(ictx.softSkills, ", "),
+		strings.Join(ictx.timeline, "; "),
+		strings.Join(projectLines, "\n"))
+
+	text, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonStr := cleanJSONString(text)
+	if jsonStr == "" {
+		return nil, fmt.Errorf("no JSON in model response")
+	}
+
+	var kit InterviewKit
+	if err := json.Unmarshal([]byte(jsonStr), &kit); err != nil {
+		return nil, err
+	}
+	return &kit, nil
+}
+
+// fillTemplateQuestions adds template questions for anything the kit doesn't cover
+func fillTemplateQuestions(kit *InterviewKit, ictx interviewContext) {
+	covered := func(questions []InterviewQuestion, key func(InterviewQuestion) string, value string) bool {
+		for _, q := range questions {
+			if strings.EqualFold(key(q), value) {
+				return true
+			}
+		}
+		return false
+	}
+	bySkill := func(q InterviewQuestion) string { return q.Skill }
+	byCompetency := func(q InterviewQuestion) string { return q.Competency }
+	byProject := func(q InterviewQuestion) string { return q.Project }
+
+	matched := make(map[string]bool)
+	for _, skill := range ictx.exactMatches {
+		matched[strings.ToLower(skill)] = true
+	}
+
+	for _, skill := range ictx.jobSkills {
+		if isSoftSkill(skill) || covered(kit.TechnicalQuestions, bySkill, skill) {
+			continue
+		}
+		// Probe fundamentals for unproven skills and depth for proven ones
+		if !matched[strings.ToLower(skill)] {
+			kit.TechnicalQuestions = append(kit.TechnicalQuestions, InterviewQuestion{
+				Question:   fmt.Sprintf("%s isn't clearly shown on your resume. Explain its core concepts, and how would you get productive with it?", skill),
+				Skill:      skill,
+				Difficulty: "easy",
+				Competency: skill,
+				GoodAnswerIncludes: []string{
+					fmt.Sprintf("Accurate understanding of what %s is used for", skill),
+					"A concrete learning plan with realistic timelines",
+					"Transferable experience from adjacent tools",
+				},
+			})
+			continue
+		}
+		kit.TechnicalQuestions = append(kit.TechnicalQuestions, InterviewQuestion{
+			Question:   fmt.Sprintf("Walk me through the most complex problem you solved with %s. What trade-offs did you make?", skill),
+			Skill:      skill,
+			Difficulty: seniorityDifficulty(ictx.level, "medium"),
+			Competency: skill,
+			GoodAnswerIncludes: []string{
+				"A specific situation with measurable scope",
+				fmt.Sprintf("Depth beyond the basics of %s (internals, limits, failure modes)", skill),
+				"Alternatives considered and why they were rejected",
+			},
+			FollowUps: []string{"What would you do differently today?"},
+		})
+	}
+
+	for _, pm := range ictx.partialMatches {
+		if isSoftSkill(pm.JobSkill) || covered(kit.PartialMatchProbes, bySkill, pm.JobSkill) {
+			continue
+		}
+		kit.PartialMatchProbes = append(kit.PartialMatchProbes, InterviewQuestion{
+			Question:   fmt.Sprintf("Your resume mentions %s. How does that experience carry over to %s, and where are the gaps?", pm.ResumeSkill, pm.JobSkill),
+			Skill:      pm.JobSkill,
+			Difficulty: "medium",
+			Competency: pm.JobSkill,
+			GoodAnswerIncludes: []string{
+				fmt.Sprintf("Clear distinction between %s and %s", pm.ResumeSkill, pm.JobSkill),
+				"Honest assessment of hands-on depth",
+				"Examples of applying the shared concepts",
+			},
+		})
+	}
+
+	for _, softSkill := range ictx.softSkills {
+		if covered(kit.BehavioralQuestions, byCompetency, softSkill) {
+			continue
+		}
+		kit.BehavioralQuestions = append(kit.BehavioralQuestions, InterviewQuestion{
+			Question:   fmt.Sprintf("Tell me about a time your %s made a real difference to the outcome of a project.", strings.ToLower(softSkill)),
+			Difficulty: "medium",
+			Competency: softSkill,
+			GoodAnswerIncludes: []string{
+				"Situation, task, action and result (STAR) structure",
+				"The candidate's own actions rather than the team's",
+				"A measurable or observable result and what they learned",
+			},
+		})
+	}
+
+	for _, project := range ictx.projects {
+		if project.Name == "" || project.Name == "None" || covered(kit.ProjectFollowUps, byProject, project.Name) {
+			continue
+		}
+		kit.ProjectFollowUps = append(kit.ProjectFollowUps, InterviewQuestion{
+			Question:   fmt.Sprintf("In %s, what part did you personally build, and how would it need to change to handle ten times the load?", project.Name),
+			Project:    project.Name,
+			Difficulty: seniorityDifficulty(ictx.level, "medium"),
+			Competency: "system design",
+			GoodAnswerIncludes: []string{
+				"Clear ownership of specific components",
+				fmt.Sprintf("Correct reasoning about %s", strings.Join(project.TechStack, ", ")),
+				"Bottlenecks identified with a concrete scaling approach",
+			},
+		})
+	}
+}
+
+// normalizeKitQuestions sets categories, IDs and valid difficulty levels
+func normalizeKitQuestions(kit *InterviewKit) {
+	groups := []struct {
+		category  string
+		prefix    string
+		questions []InterviewQuestion
+	}{
+		{QuestionTechnical, "T", kit.TechnicalQuestions},
+		{QuestionPartialProbe, "P", kit.PartialMatchProbes},
+		{QuestionBehavioral, "B", kit.BehavioralQuestions},
+		{QuestionProjectFollowUp, "F", kit.ProjectFollowUps},
+	}
+	for _, group := range groups {
+		for i := range group.questions {
+			q := &group.questions[i]
+			q.ID = fmt.Sprintf("%s%d", group.prefix, i+1)
+			q.Category = group.category
+			switch strings.ToLower(q.Difficulty) {
+			case "easy", "medium", "hard":
+				q.Difficulty = strings.ToLower(q.Difficulty)
+			default:
+				q.Difficulty = "medium"
+			}
+			if q.Competency == "" {
+				q.Competency = q.Skill
+			}
+			if q.GoodAnswerIncludes == nil {
+				q.GoodAnswerIncludes = []string{}
+			}
+		}
+	}
+}
+
+// seniorityDifficulty raises the difficulty for senior roles
+func seniorityDifficulty(level string, base string) string {
+	if strings.Contains(level, "senior") || strings.Contains(level, "lead") || strings.Contains(level, "principal") {
+		return "hard"
+	}
+	if strings.Contains(level, "entry") || strings.Contains(level, "junior") {
+		return "easy"
+	}
+	return base
+}
+
+func isSoftSkill(skill string) bool {
+	return len(filterSoftSkills([]string{skill})) > 0
+}
+
+// uniqueStrings drops case-insensitive duplicates, keeping the first spelling
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, v := range values {
+		key := strings.ToLower(strings.TrimSpace(v))
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		unique = append(unique, v)
+	}
+	return unique
+}
@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestFillTemplateQuestions(t *testing.T) {
+	ictx := interviewContext{
+		jobSkills:      []string{"Go", "Kubernetes", "Communication", "Kafka"},
+		exactMatches:   []string{"go"},
+		partialMatches: []PartialMatch{{JobSkill: "Kafka", ResumeSkill: "RabbitMQ"}, {JobSkill: "Leadership", ResumeSkill: "Mentoring"}},
+		softSkills:     []string{"Communication"},
+		projects:       []ProjectAnalysis{{Name: "Ledger", TechStack: []string{"Go", "Postgres"}}, {Name: "None"}, {Name: ""}},
+		level:          "senior",
+	}
+	// The LLM already asked about Kafka; templates must not repeat it
+	kit := &InterviewKit{TechnicalQuestions: []InterviewQuestion{{Skill: "kafka", Question: "LLM question", Difficulty: "HARD"}}}
+	fillTemplateQuestions(kit, ictx)
+	normalizeKitQuestions(kit)
+
+	tests := []struct {
+		name      string
+		questions []InterviewQuestion
+		want      []string // id:category:difficulty:skill or project
+	}{
+		{"technical", kit.TechnicalQuestions, []string{
+			"T1:technical:hard:kafka",
+			"T2:technical:hard:Go",
+			"T3:technical:easy:Kubernetes",
+		}},
+		{"partial probes skip soft skills", kit.PartialMatchProbes, []string{"P1:partial_match_probe:medium:Kafka"}},
+		{"behavioral", kit.BehavioralQuestions, []string{"B1:behavioral:medium:"}},
+		{"named projects only", kit.ProjectFollowUps, []string{"F1:project_follow_up:hard:Ledger"}},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, q := range tt.questions {
+			got = append(got, fmt.Sprintf("%s:%s:%s:%s%s", q.ID, q.Category, q.Difficulty, q.Skill, q.Project))
+			if q.Competency == "" || q.GoodAnswerIncludes == nil {
+				t.Errorf("%s: %s has no competency or rubric", tt.name, q.ID)
+			}
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if !strings.Contains(kit.TechnicalQuestions[2].Question, "isn't clearly shown") {
+		t.Errorf("unproven skill question = %q, want a fundamentals probe", kit.TechnicalQuestions[2].Question)
+	}
+	if !strings.Contains(kit.PartialMatchProbes[0].Question, "RabbitMQ") {
+		t.Errorf("partial probe = %q, want it to name the resume skill", kit.PartialMatchProbes[0].Question)
+	}
+}
+
+func TestGatherInterviewContext(t *testing.T) {
+	var skills []string
+	for i := 0; i < maxKitSkills+3; i++ {
+		skills = append(skills, fmt.Sprintf("Skill%d", i))
+	}
+	resume := &TextData{Entities: ExtractedEntities{
+		Experience: []Experience{{Title: "Engineer", Company: "Acme", Duration: "Jan 2020 - Jan 2021"}},
+		Projects:   []Project{{Name: "Ledger", Technologies: []string{"Go"}}},
+	}}
+	job := &TextData{Requirements: JobRequirements{Skills: skills}}
+	job.Requirements.Experience.Level = "Senior"
+
+	ictx := gatherInterviewContext("3001", resume, "3001", job)
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"skills capped", len(ictx.jobSkills), maxKitSkills},
+		{"default soft skills", strings.Join(ictx.softSkills, ","), "communication,teamwork,problem solving"},
+		{"timeline", ictx.timeline[0], "Engineer at Acme (Jan 2020 - Jan 2021), 12 months"},
+		{"projects from the resume", ictx.projects[0].Name, "Ledger"},
+		{"level", ictx.level, "senior"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+
+	// A stored project analysis wins over the raw resume projects
+	if err := saveAnalysisResult("projects", "3001", "3001", ProjectAnalysisResponse{Projects: []ProjectAnalysis{{Name: "Analyzed"}}}); err != nil {
+		t.Fatal(err)
+	}
+	if ictx := gatherInterviewContext("3001", resume, "3001", job); ictx.projects[0].Name != "Analyzed" {
+		t.Errorf("projects = %+v, want the stored analysis", ictx.projects)
+	}
+}
+
+func TestSeniorityDifficulty(t *testing.T) {
+	tests := []struct {
+		level, want string
+	}{
+		{"senior", "hard"},
+		{"tech lead", "hard"},
+		{"principal engineer", "hard"},
+		{"entry", "easy"},
+		{"junior", "easy"},
+		{"mid", "medium"},
+		{"", "medium"},
+	}
+	for _, tt := range tests {
+		if got := seniorityDifficulty(tt.level, "medium"); got != tt.want {
+			t.Errorf("seniorityDifficulty(%q) = %q, want %q", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestUniqueStrings(t *testing.T) {
+	got := uniqueStrings([]string{"Go", "go ", "", "Kafka", " ", "GO", "kafka"})
+	if strings.Join(got, ",") != "Go,Kafka" {
+		t.Errorf("uniqueStrings = %q, want [Go Kafka]", got)
+	}
+}
@@ -0,0 +1,427 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Lint issue categories
+const (
+	LintGenderCoded     = "gender_coded"
+	LintExclusionary    = "exclusionary"
+	LintUnrealistic     = "unrealistic_requirement"
+	LintTooManyMustHave = "too_many_requirements"
+	LintMissingInfo     = "missing_info"
+	LintContradiction   = "contradiction"
+)
+
+const (
+	maxMustHaveItems = 10 // Longer must-have lists discourage qualified applicants
+	maxJobSkills     = 15
+)
+
+// technologyReleaseYears is when each technology became publicly usable,
+// used to catch requirements longer than the technology has existed
+var technologyReleaseYears = map[string]int{
+	"kubernetes": 2014, "docker": 2013, "terraform": 2014, "ansible": 2012,
+	"go": 2009, "golang": 2009, "rust": 2015, "swift": 2014, "kotlin": 2016,
+	"typescript": 2012, "dart": 2011, "elixir": 2012, "julia": 2012,
+	"react": 2013, "react native": 2015, "vue": 2014, "vue.js": 2014, "angular": 2016,
+	"svelte": 2016, "next.js": 2016, "flutter": 2017, "node.js": 2009, "nodejs": 2009,
+	"deno": 2020, "bun": 2022, "fastapi": 2018, "graphql": 2015, "grpc": 2016,
+	"tensorflow": 2015, "pytorch": 2016, "keras": 2015, "spark": 2014, "kafka": 2011,
+	"snowflake": 2014, "dbt": 2016, "airflow": 2015, "aws lambda": 2014, "lambda": 2014,
+	"azure": 2010, "gcp": 2008, "google cloud": 2008, "aws": 2006,
+	"langchain": 2022, "chatgpt": 2022, "openai": 2020, "llm": 2020, "llms": 2020,
+	"github actions": 2019, "prometheus": 2015, "grafana": 2014, "istio": 2017,
+	"tailwind": 2017, "tailwindcss": 2017, "redux": 2015, "webpack": 2012, "vite": 2020,
+}
+
+var (
+	genderCodedTerms = map[string]string{
+		"rockstar": "skilled engineer", "rock star": "skilled engineer", "ninja": "expert",
+		"guru": "specialist", "superhero": "expert", "dominant": "leading", "dominate": "lead",
+		"aggressive": "proactive", "aggressively": "proactively", "fearless": "confident",
+		"manpower": "workforce", "chairman": "chairperson",
+		"salesman": "salesperson", "guys": "everyone", "headstrong": "determined",
+		"he": "they", "his": "their", "him": "them",
+	}
+	exclusionaryTerms = map[string]string{
+		"young":                 "(avoid age references; describe the work instead)",
+		"digital native":        "comfortable with digital tools",
+		"recent graduate":       "early-career",
+		"native english":        "fluent in English",
+		"native speaker":        "fluent speaker",
+		"culture fit":           "culture add",
+		"able-bodied":           "able to perform the essential functions, with or without accommodation",
+		"clean-shaven":          "(remove unless legally required)",
+		"no employment gaps":    "(remove; gaps are not a reliable signal)",
+		"must have own car":     "reliable transportation (if the role requires travel)",
+		"walk and stand":        "move between sites (describe the essential function)",
+		"perfect english":       "strong written and spoken English",
+		"top-tier universities": "relevant education or equivalent experience",
+	}
+	termPatterns = map[string]*regexp.Regexp{}
+
+	yearsOfTechPattern = regexp.MustCompile(`(?i)(\d{1,2})\+?\s*(?:years|yrs)(?:'|’)?\s+(?:of\s+)?(?:professional\s+|hands-on\s+|commercial\s+)?(?:experience\s+)?(?:with|in|using|of)?\s*([A-Za-z][A-Za-z0-9.#+ -]{0,24})`)
+	yearsPattern       = regexp.MustCompile(`(?i)(\d{1,2})\+?\s*(?:years?|yrs?)\b`)
+	salaryPattern      = regexp.MustCompile(`(?i)(salary|compensation|pay range|per hour|/hr|\bctc\b|\blpa\b|\bOTE\b)`)
+	salaryFigure       = regexp.MustCompile(`(?i)([$€£₹]\s?\d|\d+(\.\d+)?\s?k\b|\d{2,3},\d{3})`)
+	locationPattern    = regexp.MustCompile(`(?i)\b(location|remote|hybrid|on-?site|in-office|office in|based in|relocat)`)
+	mustHaveHeader     = regexp.MustCompile(`(?i)^\s*(requirements|required|must[- ]haves?|qualifications|what you('|’)ll need|minimum qualifications|you have)\s*:?\s*$`)
+	listItemPattern    = regexp.MustCompile(`^\s*([-•*·]|\d+[.)])\s+`)
+)
+
+func init() {
+	for term := range genderCodedTerms {
+		termPatterns[term] = regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+	}
+	for term := range exclusionaryTerms {
+		termPatterns[term] = regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(term) + `\b`)
+	}
+}
+
+// LintIssue is one problem found in a job description
+type LintIssue struct {
+	Code       string `json:"code"`
+	Category   string `json:"category"`
+	Severity   string `json:"severity"`
+	Excerpt    string `json:"excerpt,omitempty"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion"`
+}
+
+// JDLintReport is the result of linting a job description
+type JDLintReport struct {
+	JobID    string         `json:"job_id,omitempty"`
+	Score    float64        `json:"score"` // 100 means no issues
+	Counts   map[string]int `json:"counts"`
+	Issues   []LintIssue    `json:"issues"`
+	LintedAt time.Time      `json:"linted_at"`
+}
+
+// LintJobDescription lints a job description posted as text. With job_id the
+// job's extracted requirements are checked too and the report is saved.
+func LintJobDescription(c *fiber.Ctx) error {
+	var request struct {
+		Description string `json:"description"`
+		JobID       string `json:"job_id"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if strings.TrimSpace(request.Description) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "description is required",
+		})
+	}
+
+	var requirements *JobRequirements
+	if request.JobID != "" {
+		if jobData, err := LoadTextData(normalizeID(request.JobID, "job"), "job"); err == nil {
+			requirements = &jobData.Requirements
+		}
+	}
+
+	report := lintJobDescription(request.Description, requirements)
+	if request.JobID != "" {
+		report.JobID = normalizeID(request.JobID, "job")
+		if err := saveLintReport(report); err != nil {
+			log.Printf("Error saving lint report: %v", err)
+		}
+	}
+	return c.JSON(report)
+}
+
+// GetJobLintReport returns the lint report saved when the job was preprocessed
+func GetJobLintReport(c *fiber.Ctx) error {
+	var report JDLintReport
+	if err := utils.LoadJSONFile(lintReportPath(c.Params("id")), &report); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Lint report not found",
+		})
+	}
+	return c.JSON(report)
+}
+
+func lintReportPath(jobID string) string {
+	return filepath.Join("processed_texts", "lint", fmt.Sprintf("lint_%s.json", pathID(jobID, "job")))
+}
+
+func saveLintReport(report *JDLintReport) error {
+	return utils.SaveJSONFile(lintReportPath(report.JobID), report, 0644)
+}
+
+// lintJobDescription runs every check over the raw description. Extracted
+// requirements, when available, add checks on the structured data.
+func lintJobDescription(description string, requirements *JobRequirements) *JDLintReport {
+	report := &JDLintReport{
+		Counts:   make(map[string]int),
+		Issues:   []LintIssue{},
+		LintedAt: time.Now(),
+	}
+	add := func(issue LintIssue) {
+		report.Issues = append(report.Issues, issue)
+	}
+
+	lintWording(description, add)
+	lintUnrealisticYears(description, add)
+	lintMustHaveList(description, requirements, add)
+	lintMissingInfo(description, add)
+	lintContradictions(description, requirements, add)
+
+	sort.SliceStable(report.Issues, func(i, j int) bool {
+		return severityRank(report.Issues[i].Severity) > severityRank(report.Issues[j].Severity)
+	})
+
+	score := 100.0
+	for _, issue := range report.Issues {
+		report.Counts[issue.Category]++
+		score -= issuePenalty(issue.Severity) * 20
+	}
+	report.Score = math.Max(score, 0)
+	return report
+}
+
+func severityRank(severity string) int {
+	switch severity {
+	case IssueHigh:
+		return 3
+	case IssueMedium:
+		return 2
+	default:
+		return 1
+	}
+}
+
+func lintWording(description string, add func(LintIssue)) {
+	for _, term := range sortedKeys(genderCodedTerms) {
+		for _, loc := range termPatterns[term].FindAllStringIndex(description, -1) {
+			severity := IssueMedium
+			if len(term) <= 3 {
+				severity = IssueLow // pronouns; may refer to a specific person
+			}
+			add(LintIssue{
+				Code:       "gender_coded_term",
+				Category:   LintGenderCoded,
+				Severity:   severity,
+				Excerpt:    excerptAround(description, loc),
+				Message:    fmt.Sprintf("%q is gender-coded and can discourage some applicants", description[loc[0]:loc[1]]),
+				Suggestion: fmt.Sprintf("Use %q instead", genderCodedTerms[term]),
+			})
+		}
+	}
+	for _, term := range sortedKeys(exclusionaryTerms) {
+		for _, loc := range termPatterns[term].FindAllStringIndex(description, -1) {
+			add(LintIssue{
+				Code:       "exclusionary_term",
+				Category:   LintExclusionary,
+				Severity:   IssueHigh,
+				Excerpt:    excerptAround(description, loc),
+				Message:    fmt.Sprintf("%q can exclude candidates on age, origin, disability or background", description[loc[0]:loc[1]]),
+				Suggestion: fmt.Sprintf("Use %q instead", exclusionaryTerms[term]),
+			})
+		}
+	}
+}
+
+// lintUnrealisticYears flags year requirements longer than the technology
+// has existed
+func lintUnrealisticYears(description string, add func(LintIssue)) {
+	currentYear := time.Now().Year()
+	for _, m := range yearsOfTechPattern.FindAllStringSubmatchIndex(description, -1) {
+		years, _ := strconv.Atoi(description[m[2]:m[3]])
+		phrase := strings.ToLower(strings.TrimSpace(description[m[4]:m[5]]))
+		tech, released := matchTechnology(phrase)
+		if tech == "" {
+			continue
+		}
+		if available := currentYear - released; years > available {
+			add(LintIssue{
+				Code:     "years_exceed_technology_age",
+				Category: LintUnrealistic,
+				Severity: IssueHigh,
+				Excerpt:  excerptAround(description, []int{m[0], m[1]}),
+				Message: fmt.Sprintf("Asks for %d years of %s, which was released in %d (%d years ago)",
+					years, tech, released, available),
+				Suggestion: fmt.Sprintf("Ask for at most %d years, or describe the depth of %s experience needed", max(available-1, 1), tech),
+			})
+		}
+	}
+}
+
+// matchTechnology finds the longest known technology at the start of phrase
+func matchTechnology(phrase string) (string, int) {
+	best := ""
+	for tech := range technologyReleaseYears {
+		if (phrase == tech || strings.HasPrefix(phrase, tech+" ") || strings.HasPrefix(phrase, tech+",") || strings.HasPrefix(phrase, tech+".")) && len(tech) > len(best) {
+			best = tech
+		}
+	}
+	if best == "" {
+		return "", 0
+	}
+	return best, technologyReleaseYears[best]
+}
+
+func lintMustHaveList(description string, requirements *JobRequirements, add func(LintIssue)) {
+	// Count list items under a must-have style heading
+	inSection, items := false, 0
+	longest := 0
+	for _, line := range strings.Split(description, "\n") {
+		switch {
+		case mustHaveHeader.MatchString(line):
+			inSection, items = true, 0
+		case inSection && listItemPattern.MatchString(line):
+			items++
+			longest = max(longest, items)
+		case inSection && strings.TrimSpace(line) != "" && !listItemPattern.MatchString(line):
+			inSection = false
+		}
+	}
+	if longest > maxMustHaveItems {
+		add(LintIssue{
+			Code:       "long_must_have_list",
+			Category:   LintTooManyMustHave,
+			Severity:   IssueMedium,
+			Message:    fmt.Sprintf("The must-have list has %d items; long lists discourage qualified candidates from applying", longest),
+			Suggestion: fmt.Sprintf("Keep %d or fewer true must-haves and move the rest to a nice-to-have list", maxMustHaveItems),
+		})
+	}
+	if requirements != nil && len(requirements.Skills) > maxJobSkills {
+		add(LintIssue{
+			Code:       "too_many_skills",
+			Category:   LintTooManyMustHave,
+			Severity:   IssueLow,
+			Message:    fmt.Sprintf("%d distinct skills were extracted as requirements", len(requirements.Skills)),
+			Suggestion: "Focus on the skills the role uses daily",
+		})
+	}
+}
+
+func lintMissingInfo(description string, add func(LintIssue)) {
+	switch {
+	case salaryFigure.MatchString(description):
+	case salaryPattern.MatchString(description):
+		add(LintIssue{
+			Code:       "vague_salary",
+			Category:   LintMissingInfo,
+			Severity:   IssueLow,
+			Excerpt:    excerptAround(description, salaryPattern.FindStringIndex(description)),
+			Message:    "Pay is mentioned but no figure or range is given",
+			Suggestion: "Replace phrases like \"competitive salary\" with the actual range",
+		})
+	default:
+		add(LintIssue{
+			Code:       "missing_salary",
+			Category:   LintMissingInfo,
+			Severity:   IssueMedium,
+			Message:    "No salary or pay range is stated; several jurisdictions require one and postings without it get fewer applicants",
+			Suggestion: "Add the pay range and currency, e.g. \"$120,000 – $140,000 USD per year\"",
+		})
+	}
+	if !locationPattern.MatchString(description) {
+		add(LintIssue{
+			Code:       "missing_location",
+			Category:   LintMissingInfo,
+			Severity:   IssueMedium,
+			Message:    "No location or remote policy is stated",
+			Suggestion: "State the office location(s) and whether the role is remote, hybrid or on-site",
+		})
+	}
+}
+
+func lintContradictions(description string, requirements *JobRequirements, add func(LintIssue)) {
+	lower := strings.ToLower(description)
+	contradiction := func(code, message, suggestion string) {
+		add(LintIssue{Code: code, Category: LintContradiction, Severity: IssueHigh, Message: message, Suggestion: suggestion})
+	}
+
+	maxYears := 0
+	for _, m := range yearsPattern.FindAllStringSubmatch(description, -1) {
+		if years, err := strconv.Atoi(m[1]); err == nil {
+			maxYears = max(maxYears, years)
+		}
+	}
+	if requirements != nil {
+		maxYears = max(maxYears, requirements.Experience.MinYears)
+	}
+
+	entryLevel := containsAnyPhrase(lower, "entry level", "entry-level", "junior", "graduate role", "new grad")
+	if requirements != nil && strings.Contains(strings.ToLower(requirements.Experience.Level), "entry") {
+		entryLevel = true
+	}
+	if entryLevel && maxYears >= 3 {
+		contradiction("entry_level_with_experience",
+			fmt.Sprintf("The role is described as entry level but asks for %d+ years of experience", maxYears),
+			"Either lower the experience requirement or retitle the role")
+	}
+
+	senior := containsAnyPhrase(lower, "senior", "staff engineer", "principal engineer")
+	if senior && maxYears > 0 && maxYears <= 1 {
+		contradiction("senior_with_little_experience",
+			fmt.Sprintf("The role is described as senior but only asks for %d year of experience", maxYears),
+			"Align the title with the experience you expect")
+	}
+
+	if containsAnyPhrase(lower, "fully remote", "100% remote", "remote-first", "work from anywhere") &&
+		containsAnyPhrase(lower, "on-site", "onsite", "in the office", "in-office", "must relocate", "relocation required") {
+		contradiction("remote_and_onsite",
+			"The posting says the role is remote but also requires on-site presence or relocation",
+			"State the actual policy, e.g. \"hybrid, 2 days per week in Austin\"")
+	}
+
+	noDegree := containsAnyPhrase(lower, "no degree required", "degree not required", "degree is not required")
+	requiresDegree := containsAnyPhrase(strings.ReplaceAll(lower, "no degree required", ""),
+		"degree required", "degree is required", "must have a degree", "requires a bachelor", "bachelor's degree required")
+	if noDegree && requiresDegree {
+		contradiction("degree_requirement_conflict",
+			"The posting both requires a degree and says one is not required",
+			"Pick one, e.g. \"Bachelor's degree or equivalent practical experience\"")
+	}
+
+	if containsAnyPhrase(lower, "part-time", "part time") && containsAnyPhrase(lower, "full-time", "full time") {
+		contradiction("employment_type_conflict",
+			"The posting mentions both part-time and full-time employment",
+			"State a single employment type, or explain the options")
+	}
+}
+
+func containsAnyPhrase(text string, phrases ...string) bool {
+	for _, phrase := range phrases {
+		if strings.Contains(text, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
+// excerptAround returns the line containing loc, trimmed to a readable length
+func excerptAround(text string, loc []int) string {
+	start := strings.LastIndex(text[:loc[0]], "\n") + 1
+	end := strings.Index(text[loc[1]:], "\n")
+	if end == -1 {
+		end = len(text)
+	} else {
+		end += loc[1]
+	}
+	line := strings.TrimSpace(text[start:end])
+	if len(line) > 160 {
+		offset := max(loc[0]-start-60, 0)
+		line = "..." + strings.TrimSpace(line[offset:min(offset+160, len(line))]) + "..."
+	}
+	return line
+}
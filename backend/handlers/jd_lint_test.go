@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func lintCodes(report *JDLintReport) string {
+	var codes []string
+	for _, issue := range report.Issues {
+		codes = append(codes, issue.Code)
+	}
+	sort.Strings(codes)
+	return strings.Join(codes, ",")
+}
+
+func TestLintJobDescription(t *testing.T) {
+	// Pay and location are given so only the issue under test is reported
+	const base = "Pay: $120,000 – $140,000. Location: Austin, hybrid.\n"
+	var longList strings.Builder
+	longList.WriteString("Requirements:\n")
+	for i := 0; i < maxMustHaveItems+1; i++ {
+		fmt.Fprintf(&longList, "- Skill %d\n", i)
+	}
+
+	entryLevel := &JobRequirements{}
+	entryLevel.Experience.MinYears, entryLevel.Experience.Level = 4, "Entry"
+
+	tests := []struct {
+		name         string
+		description  string
+		requirements *JobRequirements
+		want         string
+	}{
+		{"clean", base + "We build payment APIs in Go.", nil, ""},
+		{"gender-coded term", base + "Join our team of rockstar engineers.", nil, "gender_coded_term"},
+		{"word boundaries", base + "Our rockstars shepherd the herd.", nil, ""},
+		{"exclusionary terms", base + "Young, digital native team; native English speaker.", nil, "exclusionary_term,exclusionary_term,exclusionary_term"},
+		{"years beyond the technology's age", base + "You have 15 years of experience with Kubernetes.", nil, "years_exceed_technology_age"},
+		{"years within the technology's age", base + "5+ years of Go.", nil, ""},
+		{"unknown technology", base + "30 years of experience in COBOL.", nil, ""},
+		{"long must-have list", base + longList.String(), nil, "long_must_have_list"},
+		{"too many extracted skills", base, &JobRequirements{Skills: make([]string, maxJobSkills+1)}, "too_many_skills"},
+		{"missing pay and location", "We build payment APIs.", nil, "missing_location,missing_salary"},
+		{"vague pay", "Competitive salary. Remote.", nil, "vague_salary"},
+		{"entry level with experience", base + "Entry-level role, 5 years required.", nil, "entry_level_with_experience"},
+		{"entry level from requirements", base, entryLevel, "entry_level_with_experience"},
+		{"senior with little experience", base + "Senior engineer, 1 year of experience.", nil, "senior_with_little_experience"},
+		{"remote and on-site", base + "Fully remote, but you must be on-site weekly.", nil, "remote_and_onsite"},
+		{"degree conflict", base + "No degree required. Bachelor's degree required.", nil, "degree_requirement_conflict"},
+		{"no degree alone", base + "No degree required.", nil, ""},
+		{"employment type conflict", base + "Full-time or part-time.", nil, "employment_type_conflict"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := lintJobDescription(tt.description, tt.requirements)
+			if got := lintCodes(report); got != tt.want {
+				t.Errorf("issues = %q, want %q", got, tt.want)
+			}
+			for i := 1; i < len(report.Issues); i++ {
+				if severityRank(report.Issues[i].Severity) > severityRank(report.Issues[i-1].Severity) {
+					t.Errorf("issues are not ordered by severity: %+v", report.Issues)
+				}
+			}
+		})
+	}
+}
+
+func TestLintScore(t *testing.T) {
+	report := lintJobDescription("Young rockstar wanted.", nil)
+	// exclusionary (high), gender-coded (medium), missing salary (medium), missing location (medium)
+	if want := 100 - (0.35+3*0.15)*20; report.Score != want {
+		t.Errorf("score = %.2f, want %.2f", report.Score, want)
+	}
+	if report.Counts[LintExclusionary] != 1 || report.Counts[LintMissingInfo] != 2 {
+		t.Errorf("counts = %v", report.Counts)
+	}
+	if report.Issues[0].Excerpt != "Young rockstar wanted." {
+		t.Errorf("excerpt = %q", report.Issues[0].Excerpt)
+	}
+}
+
+func TestMatchTechnology(t *testing.T) {
+	tests := []struct {
+		phrase   string
+		want     string
+		released int
+	}{
+		{"react native apps", "react native", 2015},
+		{"react", "react", 2013},
+		{"go, python", "go", 2009},
+		{"google cloud.", "google cloud", 2008},
+		{"golfing", "", 0},
+	}
+	for _, tt := range tests {
+		if tech, year := matchTechnology(tt.phrase); tech != tt.want || year != tt.released {
+			t.Errorf("matchTechnology(%q) = %q, %d, want %q, %d", tt.phrase, tech, year, tt.want, tt.released)
+		}
+	}
+}
+
+func TestExcerptAround(t *testing.T) {
+	long := strings.Repeat("word ", 60) + "rockstar " + strings.Repeat("word ", 60)
+	tests := []struct {
+		name, text, term string
+		want             func(string) bool
+	}{
+		{"line only", "First line\nWe want a rockstar.\nLast line", "rockstar", func(s string) bool { return s == "We want a rockstar." }},
+		{"long line trimmed", long, "rockstar", func(s string) bool {
+			return strings.HasPrefix(s, "...") && strings.HasSuffix(s, "...") && strings.Contains(s, "rockstar") && len(s) <= 166
+		}},
+	}
+	for _, tt := range tests {
+		i := strings.Index(tt.text, tt.term)
+		if got := excerptAround(tt.text, []int{i, i + len(tt.term)}); !tt.want(got) {
+			t.Errorf("%s: excerpt = %q", tt.name, got)
+		}
+	}
+}
@@ -0,0 +1,806 @@
+package handlers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Work arrangements
+const (
+	WorkRemote = "remote"
+	WorkHybrid = "hybrid"
+	WorkOnsite = "onsite"
+)
+
+// Knockout outcomes
+const (
+	KnockoutPass    = "pass"
+	KnockoutFail    = "fail"
+	KnockoutUnknown = "unknown"
+)
+
+var (
+	employmentTypes = []string{"full_time", "part_time", "contract", "internship", "temporary"}
+	seniorityLevels = []string{"intern", "junior", "mid", "senior", "lead", "principal", "executive"}
+
+	currencySymbols = map[string]string{"$": "USD", "€": "EUR", "£": "GBP", "₹": "INR"}
+
+	salaryAmount      = `(\d{1,3}(?:,\d{2})*(?:[,.]\d{3})+|\d+(?:\.\d+)?)\s?(k)?` // also lakh grouping, as in 1,00,000
+	salaryCurrency    = `([$€£₹]|\b(?:USD|EUR|GBP|CAD|AUD|INR)\b)`
+	salaryRangeRegex  = regexp.MustCompile(`(?i)` + salaryCurrency + `?\s?` + salaryAmount + `\s*(?:-|–|—|to)\s*` + salaryCurrency + `?\s?` + salaryAmount + `(?:\s*` + salaryCurrency + `)?`)
+	salarySingleRegex = regexp.MustCompile(`(?i)` + salaryCurrency + `\s?` + salaryAmount)
+	thousandsDot      = regexp.MustCompile(`\.\d{3}$`)
+	hourlyPattern     = regexp.MustCompile(`(?i)(per hour|an hour|/\s?h(ou)?r|hourly)`)
+	monthlyPattern    = regexp.MustCompile(`(?i)(per month|a month|/\s?mo(nth)?|monthly)`)
+
+	locationLinePattern = regexp.MustCompile(`(?im)^\s*(?:job\s+)?locations?\s*:\s*(.+)$`)
+	locationInPattern   = regexp.MustCompile(`\b(?:[Bb]ased|[Oo]ffice|[Ll]ocated|[Rr]elocate) (?:in|to) ((?:[A-Z][\w.'-]*)(?: [A-Z][\w.'-]*)*(?:, [A-Z][\w.'-]*(?: [A-Z][\w.'-]*)*)?)`)
+	locationSplit       = regexp.MustCompile(`\s*(?:;|/|\||\bor\b|\band\b)\s*`)
+
+	hybridPattern  = regexp.MustCompile(`(?i)\bhybrid\b`)
+	remotePattern  = regexp.MustCompile(`(?i)\b(fully remote|100% remote|remote[- ]first|remote)\b`)
+	notRemote      = regexp.MustCompile(`(?i)\b(not (a )?remote|no remote|non-remote)\b`)
+	onsitePattern  = regexp.MustCompile(`(?i)\b(on-?site|on site|in[- ]office|in the office)\b`)
+	employmentRegs = map[string]*regexp.Regexp{
+		"full_time":  regexp.MustCompile(`(?i)\b(full[- ]?time|permanent)\b`),
+		"part_time":  regexp.MustCompile(`(?i)\bpart[- ]?time\b`),
+		"contract":   regexp.MustCompile(`(?i)\b(contract|contractor|freelance)\b`),
+		"internship": regexp.MustCompile(`(?i)\b(internship|intern)\b`),
+		"temporary":  regexp.MustCompile(`(?i)\b(temporary|temp|fixed[- ]term)\b`),
+	}
+	seniorityPattern = regexp.MustCompile(`(?i)\b(intern|junior|jr\.?|entry[- ]level|graduate|mid[- ]level|intermediate|senior|sr\.?|lead|staff|principal|head of|director|vp|chief)\b`)
+
+	departmentLinePattern = regexp.MustCompile(`(?im)^\s*(?:department|team)\s*:\s*(.+)$`)
+	departmentJoinPattern = regexp.MustCompile(`(?i)\bjoin (?:our|the) ([a-z&/ -]{2,30}?) (?:team|department|org|organization)\b`)
+
+	noSponsorshipPattern = regexp.MustCompile(`(?i)(no (visa )?sponsorship|(unable|not able|cannot|can't|will not|won't|do not|don't) (to )?(provide |offer )?sponsor|without (the need for )?(visa )?sponsorship|sponsorship is not available|must be (legally )?authori[sz]ed to work)`)
+	sponsorshipPattern   = regexp.MustCompile(`(?i)((visa )?sponsorship (is )?(available|provided|offered)|(will|can|able to) sponsor|offer (visa )?sponsorship)`)
+
+	noTravelPattern      = regexp.MustCompile(`(?i)\bno travel\b`)
+	travelPercentPattern = regexp.MustCompile(`(?i)(?:(\d{1,3})\s?%\s*(?:of the time\s*)?travel|travel[^.\n]{0,20}?(\d{1,3})\s?%)`)
+	travelPattern        = regexp.MustCompile(`(?i)\b(travel (is )?required|willing(ness)? to travel|frequent travel|occasional travel|travel to client)`)
+)
+
+// fillJobDetails normalizes the structured fields the LLM returned and fills
+// any it missed from the description text
+func fillJobDetails(req *JobRequirements, description string) {
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Title == "" {
+		// Postings usually open with the title on its own line
+		if title, _, _ := strings.Cut(strings.TrimSpace(description), "\n"); len(title) <= 80 && !strings.HasSuffix(title, ".") {
+			req.Title = strings.TrimSpace(title)
+		}
+	}
+	req.WorkArrangement = normalizeWorkArrangement(req.WorkArrangement)
+	if req.WorkArrangement == "" {
+		req.WorkArrangement = detectWorkArrangement(description)
+	}
+	req.EmploymentType = normalizeEmploymentType(req.EmploymentType)
+	if req.EmploymentType == "" {
+		req.EmploymentType = detectEmploymentType(description)
+	}
+	req.Seniority = normalizeSeniority(req.Seniority)
+	if req.Seniority == "" {
+		req.Seniority = detectSeniority(description, req.Experience.Level, req.Experience.MinYears)
+	}
+
+	if req.Salary != nil {
+		req.Salary.Currency = strings.ToUpper(strings.TrimSpace(req.Salary.Currency))
+		req.Salary.Period = normalizeSalaryPeriod(req.Salary.Period)
+		if req.Salary.Max < req.Salary.Min {
+			req.Salary.Min, req.Salary.Max = req.Salary.Max, req.Salary.Min
+		}
+		if req.Salary.Max <= 0 {
+			req.Salary = nil
+		}
+	}
+	if req.Salary == nil {
+		req.Salary = parseSalary(description)
+	}
+
+	var locations []string
+	for _, location := range req.Locations {
+		if location = cleanLocation(location); location != "" {
+			locations = append(locations, location)
+		}
+	}
+	if len(locations) == 0 {
+		locations = parseLocations(description)
+	}
+	req.Locations = uniqueStrings(locations)
+
+	req.Department = strings.TrimSpace(req.Department)
+	if req.Department == "" {
+		req.Department = parseDepartment(description)
+	}
+	if req.VisaSponsorship == nil {
+		req.VisaSponsorship = parseVisaSponsorship(description)
+	}
+	if req.Travel == nil {
+		req.Travel = parseTravel(description)
+	}
+}
+
+// parseSalary finds the first salary figure or range with a currency attached
+func parseSalary(description string) *SalaryRange {
+	var salary *SalaryRange
+	var end int
+	// Ranges without a currency are usually years of experience
+	for _, m := range salaryRangeRegex.FindAllStringSubmatchIndex(description, -1) {
+		groups := submatches(description, m)
+		currency := firstNonEmpty(groups[1], groups[4], groups[7])
+		if currency == "" {
+			continue
+		}
+		low := parseSalaryAmount(groups[2], groups[3] != "" || (groups[6] != "" && !strings.ContainsAny(groups[2], ",.")))
+		high := parseSalaryAmount(groups[5], groups[6] != "")
+		salary = &SalaryRange{Min: low, Max: high, Currency: currencyCode(currency)}
+		end = m[1]
+		break
+	}
+	if salary == nil {
+		m := salarySingleRegex.FindStringSubmatchIndex(description)
+		if m == nil {
+			return nil
+		}
+		groups := submatches(description, m)
+		amount := parseSalaryAmount(groups[2], groups[3] != "")
+		salary = &SalaryRange{Min: amount, Max: amount, Currency: currencyCode(groups[1])}
+		end = m[1]
+	}
+	if salary.Max <= 0 {
+		return nil
+	}
+	if salary.Max < salary.Min {
+		salary.Min, salary.Max = salary.Max, salary.Min
+	}
+
+	// The pay period usually follows the figure
+	tail := description[end:min(len(description), end+30)]
+	switch {
+	case hourlyPattern.MatchString(tail):
+		salary.Period = "hourly"
+	case monthlyPattern.MatchString(tail):
+		salary.Period = "monthly"
+	default:
+		salary.Period = "yearly"
+	}
+	return salary
+}
+
+func submatches(text string, loc []int) []string {
+	groups := make([]string, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] >= 0 {
+			groups[i] = text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return groups
+}
+
+func parseSalaryAmount(amount string, thousands bool) float64 {
+	// "120,000" and "120.000" are both thousands separators in salary figures
+	if strings.Contains(amount, ",") || thousandsDot.MatchString(amount) {
+		amount = strings.NewReplacer(",", "", ".", "").Replace(amount)
+	}
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil {
+		return 0
+	}
+	if thousands {
+		value *= 1000
+	}
+	return value
+}
+
+func currencyCode(currency string) string {
+	if code, ok := currencySymbols[currency]; ok {
+		return code
+	}
+	return strings.ToUpper(currency)
+}
+
+func normalizeSalaryPeriod(period string) string {
+	switch p := strings.ToLower(strings.TrimSpace(period)); {
+	case strings.HasPrefix(p, "hour"):
+		return "hourly"
+	case strings.HasPrefix(p, "month"):
+		return "monthly"
+	default:
+		return "yearly"
+	}
+}
+
+// annualSalary converts a salary figure to a yearly amount for comparison
+func annualSalary(amount float64, period string) float64 {
+	switch period {
+	case "hourly":
+		return amount * 2080
+	case "monthly":
+		return amount * 12
+	default:
+		return amount
+	}
+}
+
+func parseLocations(description string) []string {
+	var raw []string
+	for _, m := range locationLinePattern.FindAllStringSubmatch(description, -1) {
+		raw = append(raw, locationSplit.Split(m[1], -1)...)
+	}
+	if len(raw) == 0 {
+		for _, m := range locationInPattern.FindAllStringSubmatch(description, -1) {
+			raw = append(raw, m[1])
+		}
+	}
+
+	var locations []string
+	for _, location := range raw {
+		if location = cleanLocation(location); location != "" {
+			locations = append(locations, location)
+		}
+	}
+	return locations
+}
+
+// cleanLocation drops work-arrangement words so only places remain
+func cleanLocation(location string) string {
+	location = strings.Trim(strings.TrimSpace(location), ".,;:()")
+	if normalizeWorkArrangement(location) != "" || strings.EqualFold(location, "anywhere") {
+		return ""
+	}
+	return location
+}
+
+func detectWorkArrangement(description string) string {
+	remote := remotePattern.MatchString(description) && !notRemote.MatchString(description)
+	onsite := onsitePattern.MatchString(description)
+	switch {
+	case hybridPattern.MatchString(description), remote && onsite:
+		return WorkHybrid
+	case remote:
+		return WorkRemote
+	case onsite:
+		return WorkOnsite
+	}
+	return ""
+}
+
+func normalizeWorkArrangement(value string) string {
+	switch v := strings.ToLower(strings.TrimSpace(value)); {
+	case v == "":
+		return ""
+	case strings.Contains(v, "hybrid"):
+		return WorkHybrid
+	case strings.Contains(v, "remote"):
+		return WorkRemote
+	case onsitePattern.MatchString(v), v == "office", v == "in person", v == "in-person":
+		return WorkOnsite
+	}
+	return ""
+}
+
+func detectEmploymentType(description string) string {
+	// Report the type mentioned first, since later mentions are often about other roles
+	best, bestAt := "", -1
+	for _, kind := range employmentTypes {
+		if loc := employmentRegs[kind].FindStringIndex(description); loc != nil && (bestAt == -1 || loc[0] < bestAt) {
+			best, bestAt = kind, loc[0]
+		}
+	}
+	return best
+}
+
+func normalizeEmploymentType(value string) string {
+	v := strings.ToLower(strings.TrimSpace(value))
+	if v == "" {
+		return ""
+	}
+	for _, kind := range employmentTypes {
+		if v == kind || employmentRegs[kind].MatchString(v) {
+			return kind
+		}
+	}
+	return ""
+}
+
+// detectSeniority prefers the level named in the title line, then anywhere
+// in the posting, then the extracted experience requirement
+func detectSeniority(description, level string, minYears int) string {
+	title, _, _ := strings.Cut(strings.TrimSpace(description), "\n")
+	for _, text := range []string{title, description} {
+		if m := seniorityPattern.FindString(text); m != "" {
+			return normalizeSeniority(m)
+		}
+	}
+	if s := normalizeSeniority(level); s != "" {
+		return s
+	}
+	switch {
+	case minYears <= 0:
+		return ""
+	case minYears < 2:
+		return "junior"
+	case minYears < 5:
+		return "mid"
+	default:
+		return "senior"
+	}
+}
+
+func normalizeSeniority(value string) string {
+	v := strings.ToLower(strings.TrimSpace(strings.TrimSuffix(value, ".")))
+	switch {
+	case v == "":
+		return ""
+	case v == "intern" || strings.HasPrefix(v, "internship"):
+		return "intern"
+	case v == "jr" || strings.Contains(v, "junior") || strings.Contains(v, "entry") || v == "graduate":
+		return "junior"
+	case strings.HasPrefix(v, "mid") || v == "intermediate":
+		return "mid"
+	case v == "sr" || strings.Contains(v, "senior"):
+		return "senior"
+	case v == "lead" || v == "staff":
+		return "lead"
+	case v == "principal":
+		return "principal"
+	case v == "head of" || v == "director" || v == "vp" || v == "chief" || v == "executive":
+		return "executive"
+	}
+	return ""
+}
+
+func parseDepartment(description string) string {
+	if m := departmentLinePattern.FindStringSubmatch(description); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	if m := departmentJoinPattern.FindStringSubmatch(description); m != nil {
+		return strings.TrimSpace(m[1])
+	}
+	return ""
+}
+
+func parseVisaSponsorship(description string) *bool {
+	var sponsors bool
+	switch {
+	case noSponsorshipPattern.MatchString(description):
+		sponsors = false
+	case sponsorshipPattern.MatchString(description):
+		sponsors = true
+	default:
+		return nil
+	}
+	return &sponsors
+}
+
+func parseTravel(description string) *TravelInfo {
+	if m := travelPercentPattern.FindStringSubmatch(description); m != nil {
+		percent, _ := strconv.Atoi(firstNonEmpty(m[1], m[2]))
+		return &TravelInfo{Required: percent > 0, Percent: percent}
+	}
+	switch {
+	case noTravelPattern.MatchString(description):
+		return &TravelInfo{Required: false}
+	case travelPattern.MatchString(description):
+		return &TravelInfo{Required: true}
+	}
+	return nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if strings.TrimSpace(v) != "" {
+			return strings.TrimSpace(v)
+		}
+	}
+	return ""
+}
+
+// JobSummary is the filterable view of a stored job description
+type JobSummary struct {
+	ID              string       `json:"id"`
+	CreatedAt       time.Time    `json:"created_at"`
+	Skills          []string     `json:"skills"`
+	Salary          *SalaryRange `json:"salary,omitempty"`
+	Locations       []string     `json:"locations"`
+	WorkArrangement string       `json:"work_arrangement,omitempty"`
+	EmploymentType  string       `json:"employment_type,omitempty"`
+	Seniority       string       `json:"seniority,omitempty"`
+	Department      string       `json:"department,omitempty"`
+	VisaSponsorship *bool        `json:"visa_sponsorship,omitempty"`
+	Travel          *TravelInfo  `json:"travel,omitempty"`
+}
+
+// jobFilter holds the parsed query filters for listing jobs
+type jobFilter struct {
+	workArrangement string
+	employmentType  string
+	seniority       string
+	location        string
+	department      string
+	currency        string
+	visaSponsorship *bool
+	travel          *bool
+	minSalary       float64
+	maxSalary       float64
+}
+
+// ListJobDescriptions lists stored jobs, filtered by their structured details
+func ListJobDescriptions(c *fiber.Ctx) error {
+	filter, err := parseJobFilter(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	jobs, err := loadAllJobs()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read job descriptions",
+		})
+	}
+
+	summaries := []JobSummary{}
+	for _, job := range jobs {
+		if filter.matches(job.Requirements) {
+			summaries = append(summaries, jobSummary(job))
+		}
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].CreatedAt.After(summaries[j].CreatedAt) })
+
+	return c.JSON(fiber.Map{
+		"count": len(summaries),
+		"jobs":  summaries,
+	})
+}
+
+func parseJobFilter(c *fiber.Ctx) (jobFilter, error) {
+	filter := jobFilter{
+		location:   strings.TrimSpace(c.Query("location")),
+		department: strings.TrimSpace(c.Query("department")),
+		currency:   strings.ToUpper(strings.TrimSpace(c.Query("currency"))),
+	}
+
+	if v := c.Query("work_arrangement"); v != "" {
+		if filter.workArrangement = normalizeWorkArrangement(v); filter.workArrangement == "" {
+			return filter, fmt.Errorf("work_arrangement must be one of %s, %s or %s", WorkRemote, WorkHybrid, WorkOnsite)
+		}
+	}
+	if v := c.Query("employment_type"); v != "" {
+		if filter.employmentType = normalizeEmploymentType(v); filter.employmentType == "" {
+			return filter, fmt.Errorf("employment_type must be one of %s", strings.Join(employmentTypes, ", "))
+		}
+	}
+	if v := c.Query("seniority"); v != "" {
+		if filter.seniority = normalizeSeniority(v); filter.seniority == "" {
+			return filter, fmt.Errorf("seniority must be one of %s", strings.Join(seniorityLevels, ", "))
+		}
+	}
+	for name, target := range map[string]**bool{"visa_sponsorship": &filter.visaSponsorship, "travel": &filter.travel} {
+		if v := c.Query(name); v != "" {
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be true or false", name)
+			}
+			*target = &b
+		}
+	}
+	for name, target := range map[string]*float64{"min_salary": &filter.minSalary, "max_salary": &filter.maxSalary} {
+		if v := c.Query(name); v != "" {
+			f, err := strconv.ParseFloat(v, 64)
+			if err != nil || f < 0 {
+				return filter, fmt.Errorf("%s must be a positive number", name)
+			}
+			*target = f
+		}
+	}
+	return filter, nil
+}
+
+// matches reports whether a job passes every filter that was set. Salary
+// filters are annual amounts and only match jobs that state a salary.
+func (f jobFilter) matches(req JobRequirements) bool {
+	if f.workArrangement != "" && req.WorkArrangement != f.workArrangement {
+		return false
+	}
+	if f.employmentType != "" && req.EmploymentType != f.employmentType {
+		return false
+	}
+	if f.seniority != "" && req.Seniority != f.seniority {
+		return false
+	}
+	if f.department != "" && !containsFold(req.Department, f.department) {
+		return false
+	}
+	if f.location != "" && !locationMatches(req.Locations, f.location) {
+		return false
+	}
+	if f.visaSponsorship != nil && (req.VisaSponsorship == nil || *req.VisaSponsorship != *f.visaSponsorship) {
+		return false
+	}
+	if f.travel != nil && (req.Travel == nil || req.Travel.Required != *f.travel) {
+		return false
+	}
+	if f.currency != "" || f.minSalary > 0 || f.maxSalary > 0 {
+		if req.Salary == nil || (f.currency != "" && req.Salary.Currency != f.currency) {
+			return false
+		}
+		if f.minSalary > 0 && annualSalary(req.Salary.Max, req.Salary.Period) < f.minSalary {
+			return false
+		}
+		if f.maxSalary > 0 && annualSalary(req.Salary.Min, req.Salary.Period) > f.maxSalary {
+			return false
+		}
+	}
+	return true
+}
+
+// locationMatches compares places loosely, so "Berlin" matches "Berlin, Germany"
+func locationMatches(locations []string, place string) bool {
+	place = strings.TrimSpace(place)
+	if place == "" {
+		return false
+	}
+	city, _, _ := strings.Cut(place, ",")
+	for _, location := range locations {
+		if containsFold(location, place) || containsFold(place, location) || containsFold(location, strings.TrimSpace(city)) {
+			return true
+		}
+	}
+	return false
+}
+
+func jobSummary(job *TextData) JobSummary {
+	req := job.Requirements
+	summary := JobSummary{
+		ID:              normalizeID(job.ID, "job"),
+		CreatedAt:       job.Timestamp,
+		Skills:          req.Skills,
+		Salary:          req.Salary,
+		Locations:       req.Locations,
+		WorkArrangement: req.WorkArrangement,
+		EmploymentType:  req.EmploymentType,
+		Seniority:       req.Seniority,
+		Department:      req.Department,
+		VisaSponsorship: req.VisaSponsorship,
+		Travel:          req.Travel,
+	}
+	if summary.Skills == nil {
+		summary.Skills = []string{}
+	}
+	if summary.Locations == nil {
+		summary.Locations = []string{}
+	}
+	return summary
+}
+
+// loadAllJobs reads every stored job description
+func loadAllJobs() ([]*TextData, error) {
+	entries, err := os.ReadDir(filepath.Join("processed_texts", "job"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var jobs []*TextData
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "job_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		job, err := LoadTextData(name, "job")
+		if err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// KnockoutAnswers are the candidate facts checked against a job's hard
+// requirements. Unset fields make the matching knockout "unknown".
+type KnockoutAnswers struct {
+	Location            string   `json:"location,omitempty"`
+	WillingToRelocate   *bool    `json:"willing_to_relocate,omitempty"`
+	RequiresSponsorship *bool    `json:"requires_sponsorship,omitempty"`
+	ExpectedSalary      float64  `json:"expected_salary,omitempty"` // annual, in the job's currency
+	WorkArrangements    []string `json:"work_arrangements,omitempty"`
+	EmploymentTypes     []string `json:"employment_types,omitempty"`
+	CanTravel           *bool    `json:"can_travel,omitempty"`
+	DesiredRoles        []string `json:"desired_roles,omitempty"`
+}
+
+// KnockoutResult is the outcome of one hard requirement check
+type KnockoutResult struct {
+	Field       string `json:"field"`
+	Requirement string `json:"requirement"`
+	Candidate   string `json:"candidate,omitempty"`
+	Status      string `json:"status"`
+	Reason      string `json:"reason"`
+}
+
+// merge overlays the answers that are set in other
+func (a KnockoutAnswers) merge(other KnockoutAnswers) KnockoutAnswers {
+	if other.Location != "" {
+		a.Location = other.Location
+	}
+	if other.WillingToRelocate != nil {
+		a.WillingToRelocate = other.WillingToRelocate
+	}
+	if other.RequiresSponsorship != nil {
+		a.RequiresSponsorship = other.RequiresSponsorship
+	}
+	if other.ExpectedSalary > 0 {
+		a.ExpectedSalary = other.ExpectedSalary
+	}
+	if len(other.WorkArrangements) > 0 {
+		a.WorkArrangements = other.WorkArrangements
+	}
+	if len(other.EmploymentTypes) > 0 {
+		a.EmploymentTypes = other.EmploymentTypes
+	}
+	if other.CanTravel != nil {
+		a.CanTravel = other.CanTravel
+	}
+	if len(other.DesiredRoles) > 0 {
+		a.DesiredRoles = other.DesiredRoles
+	}
+	return a
+}
+
+// knockoutAnswersFromResume takes the preferences the resume states, falling
+// back to the most recent work location
+func knockoutAnswersFromResume(entities ExtractedEntities) KnockoutAnswers {
+	prefs := entities.Preferences
+	answers := KnockoutAnswers{
+		Location:            prefs.Location,
+		WillingToRelocate:   prefs.WillingToRelocate,
+		RequiresSponsorship: prefs.RequiresSponsorship,
+		WorkArrangements:    prefs.WorkArrangements,
+		EmploymentTypes:     prefs.EmploymentTypes,
+		DesiredRoles:        prefs.DesiredRoles,
+	}
+	if answers.Location == "" {
+		for _, exp := range entities.Experience {
+			if location := cleanLocation(exp.Location); location != "" {
+				answers.Location = location
+				break
+			}
+		}
+	}
+	return answers
+}
+
+// evaluateKnockouts checks each hard requirement the job states
+func evaluateKnockouts(req JobRequirements, answers KnockoutAnswers) []KnockoutResult {
+	results := []KnockoutResult{}
+	add := func(field, requirement, candidate, status, reason string) {
+		results = append(results, KnockoutResult{Field: field, Requirement: requirement, Candidate: candidate, Status: status, Reason: reason})
+	}
+
+	if req.WorkArrangement != WorkRemote && len(req.Locations) > 0 {
+		requirement := strings.Join(req.Locations, " or ")
+		switch {
+		case answers.Location == "":
+			add("location", requirement, "", KnockoutUnknown, "Candidate location is not known")
+		case locationMatches(req.Locations, answers.Location):
+			add("location", requirement, answers.Location, KnockoutPass, "Candidate is in a job location")
+		case answers.WillingToRelocate != nil && *answers.WillingToRelocate:
+			add("location", requirement, answers.Location, KnockoutPass, "Candidate is willing to relocate")
+		case answers.WillingToRelocate != nil:
+			add("location", requirement, answers.Location, KnockoutFail, "Candidate is elsewhere and not willing to relocate")
+		default:
+			add("location", requirement, answers.Location, KnockoutUnknown, "Candidate is elsewhere; relocation willingness is not known")
+		}
+	}
+
+	if req.VisaSponsorship != nil && !*req.VisaSponsorship {
+		switch {
+		case answers.RequiresSponsorship == nil:
+			add("visa_sponsorship", "no sponsorship offered", "", KnockoutUnknown, "Candidate work authorization is not known")
+		case *answers.RequiresSponsorship:
+			add("visa_sponsorship", "no sponsorship offered", "requires sponsorship", KnockoutFail, "Candidate needs visa sponsorship, which the job does not offer")
+		default:
+			add("visa_sponsorship", "no sponsorship offered", "authorized to work", KnockoutPass, "Candidate does not need sponsorship")
+		}
+	}
+
+	if req.Salary != nil {
+		ceiling := annualSalary(req.Salary.Max, req.Salary.Period)
+		requirement := fmt.Sprintf("up to %.0f %s per year", ceiling, req.Salary.Currency)
+		switch {
+		case answers.ExpectedSalary <= 0:
+			add("salary", requirement, "", KnockoutUnknown, "Candidate salary expectation is not known")
+		case answers.ExpectedSalary > ceiling:
+			add("salary", requirement, fmt.Sprintf("%.0f", answers.ExpectedSalary), KnockoutFail, "Candidate expects more than the top of the range")
+		default:
+			add("salary", requirement, fmt.Sprintf("%.0f", answers.ExpectedSalary), KnockoutPass, "Expectation is within the range")
+		}
+	}
+
+	if req.WorkArrangement != "" {
+		accepts := acceptsOption(answers.WorkArrangements, req.WorkArrangement, normalizeWorkArrangement)
+		// Anyone happy to be on-site can work a hybrid schedule
+		if req.WorkArrangement == WorkHybrid && acceptsOption(answers.WorkArrangements, WorkOnsite, normalizeWorkArrangement) == KnockoutPass {
+			accepts = KnockoutPass
+		}
+		add("work_arrangement", req.WorkArrangement, strings.Join(answers.WorkArrangements, ", "), accepts, optionReason(accepts, "work arrangement"))
+	}
+
+	if req.EmploymentType != "" {
+		accepts := acceptsOption(answers.EmploymentTypes, req.EmploymentType, normalizeEmploymentType)
+		add("employment_type", req.EmploymentType, strings.Join(answers.EmploymentTypes, ", "), accepts, optionReason(accepts, "employment type"))
+	}
+
+	if req.Travel != nil && req.Travel.Required {
+		requirement := "travel required"
+		if req.Travel.Percent > 0 {
+			requirement = fmt.Sprintf("up to %d%% travel", req.Travel.Percent)
+		}
+		switch {
+		case answers.CanTravel == nil:
+			add("travel", requirement, "", KnockoutUnknown, "Candidate travel availability is not known")
+		case *answers.CanTravel:
+			add("travel", requirement, "can travel", KnockoutPass, "Candidate can travel")
+		default:
+			add("travel", requirement, "cannot travel", KnockoutFail, "Candidate cannot travel")
+		}
+	}
+	return results
+}
+
+func acceptsOption(accepted []string, required string, normalize func(string) string) string {
+	if len(accepted) == 0 {
+		return KnockoutUnknown
+	}
+	for _, option := range accepted {
+		if normalize(option) == required {
+			return KnockoutPass
+		}
+	}
+	return KnockoutFail
+}
+
+func optionReason(status, what string) string {
+	switch status {
+	case KnockoutPass:
+		return "Candidate accepts this " + what
+	case KnockoutFail:
+		return "Candidate does not accept this " + what
+	default:
+		return "Candidate " + what + " preference is not known"
+	}
+}
+
+// reapplyKnockoutAnswers re-evaluates knockouts with screening answers a
+// recruiter supplied, on top of what the resume implies
+func reapplyKnockoutAnswers(score *ScoreResponse, resumeData *TextData, req JobRequirements, answers *KnockoutAnswers) {
+	if answers != nil {
+		applyKnockouts(score, req, knockoutAnswersFromResume(resumeData.Entities).merge(*answers))
+	}
+}
+
+// applyKnockouts records knockout results and the logistics fit on a score.
+// Neither changes the skills-based scores.
+func applyKnockouts(score *ScoreResponse, req JobRequirements, answers KnockoutAnswers) {
+	score.Knockouts = evaluateKnockouts(req, answers)
+	score.LogisticsFit = logisticsFit(req, answers)
+	score.KnockedOut = false
+	for _, result := range score.Knockouts {
+		if result.Status == KnockoutFail {
+			score.KnockedOut = true
+		}
+	}
+}
@@ -0,0 +1,460 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestParseSalary(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        *SalaryRange
+	}{
+		{"dollar range", "Pay: $120,000 - $150,000 per year", &SalaryRange{120000, 150000, "USD", "yearly"}},
+		{"k suffix on both", "Salary £60k–£75k", &SalaryRange{60000, 75000, "GBP", "yearly"}},
+		{"k suffix on the top only", "$90-110k base", &SalaryRange{90000, 110000, "USD", "yearly"}},
+		{"trailing currency code", "Compensation 55.000 to 65.000 EUR", &SalaryRange{55000, 65000, "EUR", "yearly"}},
+		{"hourly", "Rate: $45 - $60 per hour", &SalaryRange{45, 60, "USD", "hourly"}},
+		{"monthly", "₹80,000 - ₹1,00,000 monthly", &SalaryRange{80000, 100000, "INR", "monthly"}},
+		{"single figure", "Up to €70k", &SalaryRange{70000, 70000, "EUR", "yearly"}},
+		{"reversed range", "$150,000 to $120,000", &SalaryRange{120000, 150000, "USD", "yearly"}},
+		{"years are not pay", "Requires 3-5 years of experience", nil},
+		{"no salary", "Competitive compensation", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseSalary(tt.description)
+			if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+				t.Errorf("parseSalary(%q) = %+v, want %+v", tt.description, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAnnualSalary(t *testing.T) {
+	tests := []struct {
+		amount float64
+		period string
+		want   float64
+	}{
+		{50, normalizeSalaryPeriod("Hourly"), 104000},
+		{5000, normalizeSalaryPeriod("month"), 60000},
+		{90000, normalizeSalaryPeriod("annual"), 90000},
+		{90000, normalizeSalaryPeriod(""), 90000},
+	}
+	for _, tt := range tests {
+		if got := annualSalary(tt.amount, tt.period); got != tt.want {
+			t.Errorf("annualSalary(%.0f, %s) = %.0f, want %.0f", tt.amount, tt.period, got, tt.want)
+		}
+	}
+}
+
+func TestParseLocations(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        []string
+	}{
+		{"location line", "Location: Berlin, Germany; London, UK", []string{"Berlin, Germany", "London, UK"}},
+		{"drops arrangement words", "Locations: Remote or Austin, TX", []string{"Austin, TX"}},
+		{"anywhere is not a place", "Location: Anywhere", nil},
+		{"based in", "The team is based in Toronto, Canada.", []string{"Toronto, Canada"}},
+		{"line wins over prose", "Location: Paris\nOur office in Lyon is nearby.", []string{"Paris"}},
+		{"no location", "We build software.", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLocations(tt.description); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("parseLocations(%q) = %q, want %q", tt.description, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDetectWorkArrangement(t *testing.T) {
+	tests := []struct {
+		description, want string
+	}{
+		{"This is a fully remote role.", WorkRemote},
+		{"Hybrid: three days a week in the office.", WorkHybrid},
+		{"Remote with monthly on-site meetups.", WorkHybrid},
+		{"Work on-site in our Leeds office.", WorkOnsite},
+		{"This is not a remote role; you will work on site.", WorkOnsite},
+		{"We build software.", ""},
+	}
+	for _, tt := range tests {
+		if got := detectWorkArrangement(tt.description); got != tt.want {
+			t.Errorf("detectWorkArrangement(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeWorkArrangement(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"Remote", WorkRemote},
+		{"remote-first", WorkRemote},
+		{"HYBRID", WorkHybrid},
+		{"on-site", WorkOnsite},
+		{"in person", WorkOnsite},
+		{"office", WorkOnsite},
+		{"flexible", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeWorkArrangement(tt.value); got != tt.want {
+			t.Errorf("normalizeWorkArrangement(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDetectEmploymentType(t *testing.T) {
+	tests := []struct {
+		description, want string
+	}{
+		{"This is a full-time, permanent position.", "full_time"},
+		{"Part time, 20 hours a week.", "part_time"},
+		{"Six month contract with possible extension to full time.", "contract"},
+		{"Summer internship for students.", "internship"},
+		{"Fixed-term cover for parental leave.", "temporary"},
+		{"We build software.", ""},
+	}
+	for _, tt := range tests {
+		if got := detectEmploymentType(tt.description); got != tt.want {
+			t.Errorf("detectEmploymentType(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeEmploymentType(t *testing.T) {
+	tests := []struct {
+		value, want string
+	}{
+		{"full_time", "full_time"},
+		{"Full-Time", "full_time"},
+		{"Permanent", "full_time"},
+		{"freelance", "contract"},
+		{"temp", "temporary"},
+		{"volunteer", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeEmploymentType(tt.value); got != tt.want {
+			t.Errorf("normalizeEmploymentType(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDetectSeniority(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		level       string
+		minYears    int
+		want        string
+	}{
+		{"title line", "Senior Backend Engineer\nYou will mentor junior engineers.", "", 0, "senior"},
+		{"abbreviation", "Sr. Data Analyst", "", 0, "senior"},
+		{"anywhere in the posting", "Backend Engineer\nThis is a staff-level role.", "", 0, "lead"},
+		{"extracted level", "Backend Engineer", "Mid-Level", 0, "mid"},
+		{"years fall back to junior", "Backend Engineer", "", 1, "junior"},
+		{"years fall back to mid", "Backend Engineer", "", 3, "mid"},
+		{"years fall back to senior", "Backend Engineer", "", 7, "senior"},
+		{"nothing known", "Backend Engineer", "", 0, ""},
+		{"executive", "Head of Engineering", "", 0, "executive"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectSeniority(tt.description, tt.level, tt.minYears); got != tt.want {
+				t.Errorf("detectSeniority = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDepartment(t *testing.T) {
+	tests := []struct {
+		description, want string
+	}{
+		{"Department: Platform Engineering\nWe build things.", "Platform Engineering"},
+		{"Team: Data", "Data"},
+		{"Join our payments infrastructure team to scale checkout.", "payments infrastructure"},
+		{"We build software.", ""},
+	}
+	for _, tt := range tests {
+		if got := parseDepartment(tt.description); got != tt.want {
+			t.Errorf("parseDepartment(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestParseVisaSponsorship(t *testing.T) {
+	tests := []struct {
+		description string
+		want        *bool
+	}{
+		{"Visa sponsorship is available for this role.", boolPtr(true)},
+		{"We will sponsor work visas.", boolPtr(true)},
+		{"We are unable to sponsor visas.", boolPtr(false)},
+		{"Candidates must be authorized to work in the US.", boolPtr(false)},
+		{"No visa sponsorship, although sponsorship is offered for other roles.", boolPtr(false)},
+		{"We build software.", nil},
+	}
+	for _, tt := range tests {
+		got := parseVisaSponsorship(tt.description)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseVisaSponsorship(%q) = %v, want %v", tt.description, formatOptionalBool(got), formatOptionalBool(tt.want))
+		}
+	}
+}
+
+func TestParseTravel(t *testing.T) {
+	tests := []struct {
+		description string
+		want        *TravelInfo
+	}{
+		{"Up to 25% travel.", &TravelInfo{Required: true, Percent: 25}},
+		{"Travel up to 10% to customer sites.", &TravelInfo{Required: true, Percent: 10}},
+		{"0% travel.", &TravelInfo{Required: false}},
+		{"No travel required.", &TravelInfo{Required: false}},
+		{"Willingness to travel to client sites.", &TravelInfo{Required: true}},
+		{"We build software.", nil},
+	}
+	for _, tt := range tests {
+		got := parseTravel(tt.description)
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) {
+			t.Errorf("parseTravel(%q) = %+v, want %+v", tt.description, got, tt.want)
+		}
+	}
+}
+
+func TestLocationMatches(t *testing.T) {
+	tests := []struct {
+		locations []string
+		place     string
+		want      bool
+	}{
+		{[]string{"Berlin, Germany"}, "Berlin", true},
+		{[]string{"Berlin"}, "Berlin, Germany", true},
+		{[]string{"London, UK"}, "london", true},
+		{[]string{"London, UK", "Berlin"}, "Berlin, DE", true},
+		{[]string{"Paris, France"}, "Berlin", false},
+		{[]string{"Paris"}, " ", false},
+		{nil, "Berlin", false},
+	}
+	for _, tt := range tests {
+		if got := locationMatches(tt.locations, tt.place); got != tt.want {
+			t.Errorf("locationMatches(%q, %q) = %v, want %v", tt.locations, tt.place, got, tt.want)
+		}
+	}
+}
+
+func TestJobFilterMatches(t *testing.T) {
+	req := JobRequirements{
+		Salary:          &SalaryRange{Min: 50, Max: 60, Currency: "USD", Period: "hourly"},
+		Locations:       []string{"Austin, TX"},
+		WorkArrangement: WorkHybrid,
+		EmploymentType:  "contract",
+		Seniority:       "senior",
+		Department:      "Platform Engineering",
+		VisaSponsorship: boolPtr(false),
+		Travel:          &TravelInfo{Required: true, Percent: 10},
+	}
+	tests := []struct {
+		name   string
+		filter jobFilter
+		want   bool
+	}{
+		{"no filters", jobFilter{}, true},
+		{"every field matches", jobFilter{workArrangement: WorkHybrid, employmentType: "contract", seniority: "senior", location: "Austin", department: "platform", visaSponsorship: boolPtr(false), travel: boolPtr(true)}, true},
+		{"work arrangement", jobFilter{workArrangement: WorkRemote}, false},
+		{"employment type", jobFilter{employmentType: "full_time"}, false},
+		{"seniority", jobFilter{seniority: "junior"}, false},
+		{"department", jobFilter{department: "sales"}, false},
+		{"location", jobFilter{location: "Dallas, TX"}, false},
+		{"visa sponsorship", jobFilter{visaSponsorship: boolPtr(true)}, false},
+		{"travel", jobFilter{travel: boolPtr(false)}, false},
+		{"annualized minimum within range", jobFilter{minSalary: 120000}, true},
+		{"annualized minimum above range", jobFilter{minSalary: 130000}, false},
+		{"annualized maximum below range", jobFilter{maxSalary: 100000}, false},
+		{"currency", jobFilter{currency: "EUR"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.matches(req); got != tt.want {
+				t.Errorf("matches = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if (jobFilter{minSalary: 1}).matches(JobRequirements{}) {
+		t.Error("a salary filter matched a job without a salary")
+	}
+}
+
+func TestListJobDescriptionsFilters(t *testing.T) {
+	saveTestText(t, "job", "3701", TextData{Requirements: JobRequirements{Title: "Remote role", WorkArrangement: WorkRemote, Seniority: "senior"}})
+	saveTestText(t, "job", "3702", TextData{Requirements: JobRequirements{Title: "Office role", WorkArrangement: WorkOnsite, Seniority: "junior"}})
+
+	app := fiber.New()
+	app.Get("/jobs", ListJobDescriptions)
+
+	tests := []struct {
+		query   string
+		want    int
+		wantIDs []string
+	}{
+		{"?work_arrangement=Remote&seniority=Sr", 200, []string{"3701"}},
+		{"?work_arrangement=on-site", 200, []string{"3702"}},
+		{"?work_arrangement=space", 400, nil},
+		{"?employment_type=volunteer", 400, nil},
+		{"?seniority=wizard", 400, nil},
+		{"?visa_sponsorship=maybe", 400, nil},
+		{"?min_salary=-5", 400, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/jobs"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+			if tt.want != 200 {
+				return
+			}
+			var result struct {
+				Jobs []JobSummary `json:"jobs"`
+			}
+			if err := json.Unmarshal(body, &result); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, job := range result.Jobs {
+				if strings.HasPrefix(job.ID, "37") {
+					ids = append(ids, job.ID)
+				}
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.wantIDs, ",") {
+				t.Errorf("jobs = %v, want %v", ids, tt.wantIDs)
+			}
+		})
+	}
+}
+
+func TestEvaluateKnockouts(t *testing.T) {
+	req := JobRequirements{
+		Salary:          &SalaryRange{Min: 80000, Max: 100000, Currency: "USD", Period: "yearly"},
+		Locations:       []string{"Austin, TX"},
+		WorkArrangement: WorkHybrid,
+		EmploymentType:  "full_time",
+		VisaSponsorship: boolPtr(false),
+		Travel:          &TravelInfo{Required: true, Percent: 20},
+	}
+	tests := []struct {
+		name    string
+		answers KnockoutAnswers
+		want    map[string]string
+	}{
+		{"nothing known", KnockoutAnswers{}, map[string]string{
+			"location": KnockoutUnknown, "visa_sponsorship": KnockoutUnknown, "salary": KnockoutUnknown,
+			"work_arrangement": KnockoutUnknown, "employment_type": KnockoutUnknown, "travel": KnockoutUnknown,
+		}},
+		{"fits every requirement", KnockoutAnswers{
+			Location: "Austin", RequiresSponsorship: boolPtr(false), ExpectedSalary: 95000,
+			WorkArrangements: []string{"on-site"}, EmploymentTypes: []string{"Full time"}, CanTravel: boolPtr(true),
+		}, map[string]string{
+			"location": KnockoutPass, "visa_sponsorship": KnockoutPass, "salary": KnockoutPass,
+			"work_arrangement": KnockoutPass, "employment_type": KnockoutPass, "travel": KnockoutPass,
+		}},
+		{"fails every requirement", KnockoutAnswers{
+			Location: "Denver, CO", WillingToRelocate: boolPtr(false), RequiresSponsorship: boolPtr(true), ExpectedSalary: 120000,
+			WorkArrangements: []string{"remote"}, EmploymentTypes: []string{"contract"}, CanTravel: boolPtr(false),
+		}, map[string]string{
+			"location": KnockoutFail, "visa_sponsorship": KnockoutFail, "salary": KnockoutFail,
+			"work_arrangement": KnockoutFail, "employment_type": KnockoutFail, "travel": KnockoutFail,
+		}},
+		{"willing to relocate", KnockoutAnswers{Location: "Denver, CO", WillingToRelocate: boolPtr(true)}, map[string]string{
+			"location": KnockoutPass,
+		}},
+		{"elsewhere, relocation unknown", KnockoutAnswers{Location: "Denver, CO"}, map[string]string{
+			"location": KnockoutUnknown,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := map[string]string{}
+			for _, result := range evaluateKnockouts(req, tt.answers) {
+				got[result.Field] = result.Status
+			}
+			for field, want := range tt.want {
+				if got[field] != want {
+					t.Errorf("%s = %q, want %q", field, got[field], want)
+				}
+			}
+		})
+	}
+
+	remote := JobRequirements{WorkArrangement: WorkRemote, Locations: []string{"Austin, TX"}, Salary: &SalaryRange{Max: 50, Currency: "USD", Period: "hourly"}}
+	results := evaluateKnockouts(remote, KnockoutAnswers{Location: "Denver, CO", ExpectedSalary: 100000})
+	for _, result := range results {
+		switch result.Field {
+		case "location":
+			t.Error("remote jobs should not check location")
+		case "salary":
+			if result.Status != KnockoutPass || !strings.Contains(result.Requirement, "104000") {
+				t.Errorf("hourly salary knockout = %+v, want a pass against the annualized ceiling", result)
+			}
+		}
+	}
+	if results := evaluateKnockouts(JobRequirements{}, KnockoutAnswers{}); len(results) != 0 {
+		t.Errorf("a job without hard requirements produced knockouts: %+v", results)
+	}
+}
+
+func TestApplyKnockouts(t *testing.T) {
+	req := JobRequirements{VisaSponsorship: boolPtr(false), EmploymentType: "full_time"}
+	resume := &TextData{Entities: ExtractedEntities{
+		Preferences: CandidatePreferences{RequiresSponsorship: boolPtr(true)},
+		Experience:  []Experience{{Location: "Remote"}, {Location: "Leeds, UK"}},
+	}}
+
+	answers := knockoutAnswersFromResume(resume.Entities)
+	if answers.Location != "Leeds, UK" {
+		t.Errorf("location from resume = %q, want the latest real place", answers.Location)
+	}
+
+	tests := []struct {
+		name       string
+		answers    *KnockoutAnswers
+		wantKnock  bool
+		wantStatus string
+	}{
+		{"resume says sponsorship is needed", &KnockoutAnswers{}, true, KnockoutFail},
+		{"recruiter answer overrides the resume", &KnockoutAnswers{RequiresSponsorship: boolPtr(false)}, false, KnockoutPass},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			score := &ScoreResponse{OverallScore: 80, KnockedOut: !tt.wantKnock}
+			reapplyKnockoutAnswers(score, resume, req, tt.answers)
+			if score.KnockedOut != tt.wantKnock || score.OverallScore != 80 {
+				t.Errorf("knocked out = %v, score %.0f; want %v and an unchanged score", score.KnockedOut, score.OverallScore, tt.wantKnock)
+			}
+			if score.Knockouts[0].Field != "visa_sponsorship" || score.Knockouts[0].Status != tt.wantStatus {
+				t.Errorf("visa knockout = %+v, want %s", score.Knockouts[0], tt.wantStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,446 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Candidate movement after a rescore
+const (
+	MovedUp   = "up"
+	MovedDown = "down"
+	Unchanged = "unchanged"
+)
+
+const movementTolerance = 0.5 // Score changes smaller than this are noise
+
+// jobVersionLocks serializes edits to the same job, so two concurrent edits
+// can't both archive version N and both save N+1
+var jobVersionLocks sync.Map
+
+// JobVersion is an archived revision of a job description
+type JobVersion struct {
+	JobID           string          `json:"job_id"`
+	Version         int             `json:"version"`
+	CreatedAt       time.Time       `json:"created_at"`
+	Description     string          `json:"description,omitempty"` // not kept for jobs created before versioning
+	Requirements    JobRequirements `json:"requirements"`
+	TechnicalSkills []string        `json:"technical_skills"`
+	SoftSkills      []string        `json:"soft_skills"`
+}
+
+// RequirementChange is one difference between two job versions. List fields
+// use Added/Removed; single values use From/To.
+type RequirementChange struct {
+	Field   string   `json:"field"`
+	From    string   `json:"from,omitempty"`
+	To      string   `json:"to,omitempty"`
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// JobDiff lists what changed between two versions of a job
+type JobDiff struct {
+	JobID       string              `json:"job_id"`
+	FromVersion int                 `json:"from_version"`
+	ToVersion   int                 `json:"to_version"`
+	Changes     []RequirementChange `json:"changes"`
+}
+
+// CandidateMovement is how one candidate's score changed after a rescore
+type CandidateMovement struct {
+	ResumeID   string  `json:"resume_id"`
+	Name       string  `json:"name"`
+	OldScore   float64 `json:"old_score"`
+	NewScore   float64 `json:"new_score"`
+	ScoreDelta float64 `json:"score_delta"`
+	OldRank    int     `json:"old_rank"`
+	NewRank    int     `json:"new_rank"`
+	Movement   string  `json:"movement"`
+}
+
+// RescoreReport summarizes rescoring a job's candidates against a new version
+type RescoreReport struct {
+	JobID       string              `json:"job_id"`
+	FromVersion int                 `json:"from_version"`
+	ToVersion   int                 `json:"to_version"`
+	RescoredAt  time.Time           `json:"rescored_at"`
+	Diff        JobDiff             `json:"diff"`
+	MovedUp     int                 `json:"moved_up"`
+	MovedDown   int                 `json:"moved_down"`
+	Unchanged   int                 `json:"unchanged"`
+	Candidates  []CandidateMovement `json:"candidates"`
+	Skipped     []string            `json:"skipped"`
+}
+
+func jobVersionPath(jobID string, version int) string {
+	return filepath.Join("processed_texts", "job_versions", pathID(jobID, "job"), fmt.Sprintf("v%d.json", version))
+}
+
+func rescoreReportPath(jobID string, version int) string {
+	return filepath.Join("processed_texts", "job_versions", pathID(jobID, "job"), fmt.Sprintf("rescore_v%d.json", version))
+}
+
+func saveJobVersion(jobData *TextData, description string) error {
+	version := JobVersion{
+		JobID:           normalizeID(jobData.ID, "job"),
+		Version:         max(jobData.Version, 1),
+		CreatedAt:       jobData.Timestamp,
+		Description:     description,
+		Requirements:    jobData.Requirements,
+		TechnicalSkills: jobData.TechnicalSkills,
+		SoftSkills:      jobData.SoftSkills,
+	}
+	return utils.SaveJSONFile(jobVersionPath(version.JobID, version.Version), version, 0644)
+}
+
+func loadJobVersion(jobID string, version int) (*JobVersion, error) {
+	var v JobVersion
+	if err := utils.LoadJSONFile(jobVersionPath(jobID, version), &v); err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+func lockJobVersion(jobID string) func() {
+	mu, _ := jobVersionLocks.LoadOrStore(normalizeID(jobID, "job"), &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+// archiveCurrentJobVersion makes sure the job's current revision is in the
+// version history before it's replaced, and returns its version number. Jobs
+// created before versioning become version 1.
+func archiveCurrentJobVersion(jobID string) (int, error) {
+	current, err := LoadTextData("job_"+jobID, "job")
+	if err != nil {
+		return 0, fiber.NewError(404, "Job description not found")
+	}
+	current.Version = max(current.Version, 1)
+	if _, err := loadJobVersion(jobID, current.Version); err != nil {
+		if err := saveJobVersion(current, ""); err != nil {
+			return 0, err
+		}
+	}
+	return current.Version, nil
+}
+
+// listJobVersions returns every archived version of a job, oldest first
+func listJobVersions(jobID string) ([]JobVersion, error) {
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "job_versions", pathID(jobID, "job"), "v*.json"))
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]JobVersion, 0, len(paths))
+	for _, path := range paths {
+		var v JobVersion
+		if err := utils.LoadJSONFile(path, &v); err != nil {
+			log.Printf("Skipping unreadable job version %s: %v", path, err)
+			continue
+		}
+		versions = append(versions, v)
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i].Version < versions[j].Version })
+	return versions, nil
+}
+
+// GetJobVersions lists a job's version history
+func GetJobVersions(c *fiber.Ctx) error {
+	jobID := normalizeID(c.Params("id"), "job")
+	versions, err := listJobVersions(jobID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read job versions",
+		})
+	}
+	if len(versions) == 0 {
+		// Unedited jobs have a single, unarchived version
+		current, err := LoadTextData("job_"+jobID, "job")
+		if err != nil {
+			return c.Status(404).JSON(fiber.Map{
+				"error": "Job description not found",
+			})
+		}
+		versions = append(versions, JobVersion{
+			JobID:           jobID,
+			Version:         max(current.Version, 1),
+			CreatedAt:       current.Timestamp,
+			Requirements:    current.Requirements,
+			TechnicalSkills: current.TechnicalSkills,
+			SoftSkills:      current.SoftSkills,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"job_id":         jobID,
+		"latest_version": versions[len(versions)-1].Version,
+		"versions":       versions,
+	})
+}
+
+// GetJobDiff shows what changed between two versions of a job. It defaults
+// to the previous version against the latest.
+func GetJobDiff(c *fiber.Ctx) error {
+	jobID := normalizeID(c.Params("id"), "job")
+	versions, err := listJobVersions(jobID)
+	if err != nil || len(versions) < 2 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job has no earlier version to compare against",
+		})
+	}
+
+	to := c.QueryInt("to", versions[len(versions)-1].Version)
+	from := c.QueryInt("from", to-1)
+	older, err := loadJobVersion(jobID, from)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Version %d not found", from),
+		})
+	}
+	newer, err := loadJobVersion(jobID, to)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": fmt.Sprintf("Version %d not found", to),
+		})
+	}
+
+	return c.JSON(diffJobVersions(older, newer))
+}
+
+func diffJobVersions(older, newer *JobVersion) JobDiff {
+	a, b := older.Requirements, newer.Requirements
+	diff := JobDiff{JobID: newer.JobID, FromVersion: older.Version, ToVersion: newer.Version, Changes: []RequirementChange{}}
+
+	addList := func(field string, from, to []string) {
+		added, removed := diffLists(from, to)
+		if len(added) > 0 || len(removed) > 0 {
+			diff.Changes = append(diff.Changes, RequirementChange{Field: field, Added: added, Removed: removed})
+		}
+	}
+	addValue := func(field, from, to string) {
+		if !strings.EqualFold(strings.TrimSpace(from), strings.TrimSpace(to)) {
+			diff.Changes = append(diff.Changes, RequirementChange{Field: field, From: from, To: to})
+		}
+	}
+
+	addValue("title", a.Title, b.Title)
+	addList("skills", a.Skills, b.Skills)
+	addValue("experience.min_years", strconv.Itoa(a.Experience.MinYears), strconv.Itoa(b.Experience.MinYears))
+	addValue("experience.level", a.Experience.Level, b.Experience.Level)
+	addList("experience.areas", a.Experience.Areas, b.Experience.Areas)
+	addValue("education.degree", a.Education.Degree, b.Education.Degree)
+	addList("education.fields", a.Education.Fields, b.Education.Fields)
+	addList("education.qualifications", a.Education.Qualifications, b.Education.Qualifications)
+	addList("responsibilities", a.Responsibilities, b.Responsibilities)
+	addValue("salary", formatSalary(a.Salary), formatSalary(b.Salary))
+	addList("locations", a.Locations, b.Locations)
+	addValue("work_arrangement", a.WorkArrangement, b.WorkArrangement)
+	addValue("employment_type", a.EmploymentType, b.EmploymentType)
+	addValue("seniority", a.Seniority, b.Seniority)
+	addValue("department", a.Department, b.Department)
+	addValue("visa_sponsorship", formatOptionalBool(a.VisaSponsorship), formatOptionalBool(b.VisaSponsorship))
+	addValue("travel", formatTravel(a.Travel), formatTravel(b.Travel))
+	return diff
+}
+
+// diffLists compares two lists case-insensitively
+func diffLists(from, to []string) (added, removed []string) {
+	inFrom := make(map[string]bool)
+	for _, item := range from {
+		inFrom[strings.ToLower(strings.TrimSpace(item))] = true
+	}
+	inTo := make(map[string]bool)
+	for _, item := range to {
+		inTo[strings.ToLower(strings.TrimSpace(item))] = true
+	}
+	for _, item := range uniqueStrings(to) {
+		if !inFrom[strings.ToLower(strings.TrimSpace(item))] {
+			added = append(added, item)
+		}
+	}
+	for _, item := range uniqueStrings(from) {
+		if !inTo[strings.ToLower(strings.TrimSpace(item))] {
+			removed = append(removed, item)
+		}
+	}
+	return added, removed
+}
+
+func formatSalary(s *SalaryRange) string {
+	if s == nil {
+		return ""
+	}
+	return fmt.Sprintf("%.0f-%.0f %s %s", s.Min, s.Max, s.Currency, s.Period)
+}
+
+func formatOptionalBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+	return strconv.FormatBool(*b)
+}
+
+func formatTravel(t *TravelInfo) string {
+	switch {
+	case t == nil:
+		return ""
+	case t.Percent > 0:
+		return fmt.Sprintf("%d%%", t.Percent)
+	default:
+		return strconv.FormatBool(t.Required)
+	}
+}
+
+// queueJobRescore starts a background rescore of everyone scored against the
+// job. It returns an empty ID when nobody has been scored yet.
+func queueJobRescore(jobID string, fromVersion, toVersion int) (string, error) {
+	records, err := loadScoreRecordsForJob(jobID)
+	if err != nil || len(records) == 0 {
+		return "", err
+	}
+	task := &AnalysisTask{
+		Type:  "rescore_job",
+		JobID: jobID,
+		Params: map[string]string{
+			"from_version": strconv.Itoa(fromVersion),
+			"to_version":   strconv.Itoa(toVersion),
+		},
+	}
+	if err := tasks.enqueue(task); err != nil {
+		return "", err
+	}
+	return task.ID, nil
+}
+
+func runRescoreJobTask(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+	jobData, err := LoadTextData("job_"+normalizeID(task.JobID, "job"), "job")
+	if err != nil {
+		return nil, fmt.Errorf("job data not found: %v", err)
+	}
+	toVersion := max(jobData.Version, 1)
+	fromVersion, _ := strconv.Atoi(task.Params["from_version"])
+
+	records, err := loadScoreRecordsForJob(task.JobID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := RescoreReport{
+		JobID:       normalizeID(task.JobID, "job"),
+		FromVersion: fromVersion,
+		ToVersion:   toVersion,
+		Candidates:  []CandidateMovement{},
+		Skipped:     []string{},
+		Diff:        JobDiff{Changes: []RequirementChange{}},
+	}
+	if older, err := loadJobVersion(task.JobID, fromVersion); err == nil {
+		if newer, err := loadJobVersion(task.JobID, toVersion); err == nil {
+			report.Diff = diffJobVersions(older, newer)
+		}
+	}
+
+	blind := isBlindScreeningJob(task.JobID)
+	oldScores := make(map[string]float64)
+	newScores := make(map[string]float64)
+	for i, record := range records {
+		progress.report("rescoring", i, len(records), nil)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		resumeData, err := LoadTextData("resume_"+record.ResumeID, "resume")
+		if err != nil {
+			report.Skipped = append(report.Skipped, record.ResumeID)
+			continue
+		}
+
+		score := scoreResumeAgainstJob(resumeData, jobData, nil)
+		reapplyKnockoutAnswers(&score, resumeData, jobData.Requirements, record.KnockoutAnswers)
+		if err := saveScoreRecord(record.ResumeID, task.JobID, toVersion, score, record.KnockoutAnswers); err != nil {
+			log.Printf("Error saving score record: %v", err)
+		}
+		emitScoreCompleted(record.ResumeID, task.JobID, toVersion, score, record.KnockoutAnswers)
+
+		name := resumeData.Entities.Name
+		if blind {
+			name = candidatePseudonym(record.ResumeID)
+		}
+		oldScores[record.ResumeID] = record.Score.OverallScore
+		newScores[record.ResumeID] = score.OverallScore
+		report.Candidates = append(report.Candidates, CandidateMovement{
+			ResumeID:   record.ResumeID,
+			Name:       name,
+			OldScore:   record.Score.OverallScore,
+			NewScore:   score.OverallScore,
+			ScoreDelta: score.OverallScore - record.Score.OverallScore,
+		})
+	}
+
+	oldRanks, newRanks := rankByScore(oldScores), rankByScore(newScores)
+	for i := range report.Candidates {
+		candidate := &report.Candidates[i]
+		candidate.OldRank = oldRanks[candidate.ResumeID]
+		candidate.NewRank = newRanks[candidate.ResumeID]
+		switch {
+		case candidate.ScoreDelta >= movementTolerance:
+			candidate.Movement = MovedUp
+			report.MovedUp++
+		case candidate.ScoreDelta <= -movementTolerance:
+			candidate.Movement = MovedDown
+			report.MovedDown++
+		default:
+			candidate.Movement = Unchanged
+			report.Unchanged++
+		}
+	}
+	// Biggest movers first
+	sort.SliceStable(report.Candidates, func(i, j int) bool {
+		return math.Abs(report.Candidates[i].ScoreDelta) > math.Abs(report.Candidates[j].ScoreDelta)
+	})
+
+	report.RescoredAt = time.Now()
+	progress.report("rescored", len(records), len(records), nil)
+	if err := utils.SaveJSONFile(rescoreReportPath(task.JobID, toVersion), report, 0644); err != nil {
+		log.Printf("Error saving rescore report: %v", err)
+	}
+	return report, nil
+}
+
+// rankByScore ranks IDs by descending score, starting at 1
+func rankByScore(scores map[string]float64) map[string]int {
+	ids := sortedKeys(scores)
+	sort.SliceStable(ids, func(i, j int) bool { return scores[ids[i]] > scores[ids[j]] })
+	ranks := make(map[string]int, len(ids))
+	for i, id := range ids {
+		ranks[id] = i + 1
+	}
+	return ranks
+}
+
+// GetRescoreReport returns the rescore report produced when a version was created
+func GetRescoreReport(c *fiber.Ctx) error {
+	version, err := strconv.Atoi(c.Params("version"))
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid version",
+		})
+	}
+	var report RescoreReport
+	if err := utils.LoadJSONFile(rescoreReportPath(c.Params("id"), version), &report); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Rescore report not found",
+		})
+	}
+	return c.JSON(report)
+}
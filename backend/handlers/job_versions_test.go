@@ -0,0 +1,317 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDiffLists(t *testing.T) {
+	tests := []struct {
+		name           string
+		from, to       []string
+		added, removed []string
+	}{
+		{"added and removed", []string{"Go", "SQL"}, []string{"Go", "Kubernetes"}, []string{"Kubernetes"}, []string{"SQL"}},
+		{"case and spacing ignored", []string{"go", " SQL"}, []string{"Go", "sql"}, nil, nil},
+		{"duplicates reported once", nil, []string{"Go", "Go"}, []string{"Go"}, nil},
+		{"both empty", nil, nil, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := diffLists(tt.from, tt.to)
+			if strings.Join(added, ",") != strings.Join(tt.added, ",") || strings.Join(removed, ",") != strings.Join(tt.removed, ",") {
+				t.Errorf("diffLists = +%v -%v, want +%v -%v", added, removed, tt.added, tt.removed)
+			}
+		})
+	}
+}
+
+func TestDiffJobVersions(t *testing.T) {
+	older := &JobVersion{JobID: "job_1", Version: 1, Requirements: JobRequirements{
+		Title:           "Backend Engineer",
+		Skills:          []string{"Go", "SQL"},
+		Salary:          &SalaryRange{Min: 90000, Max: 110000, Currency: "USD", Period: "yearly"},
+		WorkArrangement: WorkOnsite,
+		VisaSponsorship: boolPtr(true),
+	}}
+	newer := &JobVersion{JobID: "job_1", Version: 2, Requirements: older.Requirements}
+	newer.Requirements.Title = "backend engineer "
+	newer.Requirements.Skills = []string{"Go", "Kubernetes"}
+	newer.Requirements.Experience.MinYears = 5
+	newer.Requirements.Salary = &SalaryRange{Min: 100000, Max: 120000, Currency: "USD", Period: "yearly"}
+	newer.Requirements.WorkArrangement = WorkHybrid
+	newer.Requirements.VisaSponsorship = nil
+	newer.Requirements.Travel = &TravelInfo{Required: true, Percent: 10}
+
+	diff := diffJobVersions(older, newer)
+	if diff.FromVersion != 1 || diff.ToVersion != 2 || diff.JobID != "job_1" {
+		t.Errorf("diff header = %+v", diff)
+	}
+	changes := make(map[string]RequirementChange)
+	for _, change := range diff.Changes {
+		changes[change.Field] = change
+	}
+
+	tests := []struct {
+		field string
+		want  RequirementChange
+	}{
+		{"skills", RequirementChange{Field: "skills", Added: []string{"Kubernetes"}, Removed: []string{"SQL"}}},
+		{"experience.min_years", RequirementChange{Field: "experience.min_years", From: "0", To: "5"}},
+		{"salary", RequirementChange{Field: "salary", From: "90000-110000 USD yearly", To: "100000-120000 USD yearly"}},
+		{"work_arrangement", RequirementChange{Field: "work_arrangement", From: WorkOnsite, To: WorkHybrid}},
+		{"visa_sponsorship", RequirementChange{Field: "visa_sponsorship", From: "true"}},
+		{"travel", RequirementChange{Field: "travel", To: "10%"}},
+	}
+	for _, tt := range tests {
+		got, ok := changes[tt.field]
+		if !ok {
+			t.Errorf("no change reported for %s", tt.field)
+			continue
+		}
+		if got.From != tt.want.From || got.To != tt.want.To ||
+			strings.Join(got.Added, ",") != strings.Join(tt.want.Added, ",") || strings.Join(got.Removed, ",") != strings.Join(tt.want.Removed, ",") {
+			t.Errorf("%s change = %+v, want %+v", tt.field, got, tt.want)
+		}
+	}
+	if _, ok := changes["title"]; ok {
+		t.Error("a case and whitespace edit to the title was reported as a change")
+	}
+	if len(diff.Changes) != len(tests) {
+		t.Errorf("got %d changes, want %d: %+v", len(diff.Changes), len(tests), diff.Changes)
+	}
+	if same := diffJobVersions(older, older); len(same.Changes) != 0 {
+		t.Errorf("identical versions produced changes: %+v", same.Changes)
+	}
+}
+
+func TestFormatTravel(t *testing.T) {
+	tests := []struct {
+		travel *TravelInfo
+		want   string
+	}{
+		{nil, ""},
+		{&TravelInfo{Required: true, Percent: 25}, "25%"},
+		{&TravelInfo{Required: true}, "true"},
+		{&TravelInfo{Required: false}, "false"},
+	}
+	for _, tt := range tests {
+		if got := formatTravel(tt.travel); got != tt.want {
+			t.Errorf("formatTravel(%+v) = %q, want %q", tt.travel, got, tt.want)
+		}
+	}
+}
+
+func TestRankByScore(t *testing.T) {
+	ranks := rankByScore(map[string]float64{"b": 70, "a": 70, "c": 90, "d": 10})
+	want := map[string]int{"c": 1, "a": 2, "b": 3, "d": 4}
+	for id, rank := range want {
+		if ranks[id] != rank {
+			t.Errorf("rank of %s = %d, want %d (ties break by ID)", id, ranks[id], rank)
+		}
+	}
+}
+
+func TestLockJobVersion(t *testing.T) {
+	unlock := lockJobVersion("3950")
+
+	acquired := make(chan struct{})
+	go func() {
+		defer lockJobVersion("job_3950")()
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("the same job was locked twice")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	otherJob := make(chan struct{})
+	go func() {
+		defer lockJobVersion("3951")()
+		close(otherJob)
+	}()
+	select {
+	case <-otherJob:
+	case <-time.After(time.Second):
+		t.Fatal("a lock on one job blocked another job")
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("the lock was not released")
+	}
+}
+
+func TestArchiveCurrentJobVersion(t *testing.T) {
+	saveTestText(t, "job", "3960", TextData{Requirements: JobRequirements{Title: "Unversioned"}})
+	saveTestText(t, "job", "3961", TextData{Version: 3, Requirements: JobRequirements{Title: "Edited"}})
+	if err := saveJobVersion(&TextData{ID: "3961", Version: 3, Requirements: JobRequirements{Title: "Edited"}}, "original text"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		jobID       string
+		wantVersion int
+		wantDesc    string
+		wantErr     bool
+	}{
+		{"unversioned job becomes version 1", "3960", 1, "", false},
+		{"existing archive is kept", "3961", 3, "original text", false},
+		{"unknown job", "3969", 0, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			version, err := archiveCurrentJobVersion(tt.jobID)
+			if (err != nil) != tt.wantErr || version != tt.wantVersion {
+				t.Fatalf("archiveCurrentJobVersion = %d, %v; want %d, error %v", version, err, tt.wantVersion, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			archived, err := loadJobVersion(tt.jobID, version)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if archived.Description != tt.wantDesc || archived.JobID != tt.jobID {
+				t.Errorf("archived version = %+v, want description %q", archived, tt.wantDesc)
+			}
+		})
+	}
+}
+
+func TestJobVersionEndpoints(t *testing.T) {
+	saveTestText(t, "job", "3970", TextData{Requirements: JobRequirements{Title: "Never edited"}})
+	saveTestText(t, "job", "3971", TextData{Version: 3, Requirements: JobRequirements{Title: "Edited"}})
+	for version, skills := range map[int][]string{1: {"Go"}, 2: {"Go", "SQL"}, 3: {"SQL"}} {
+		job := &TextData{ID: "3971", Version: version, Requirements: JobRequirements{Title: "Edited", Skills: skills}}
+		if err := saveJobVersion(job, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := fiber.New()
+	app.Get("/jobs/:id/versions", GetJobVersions)
+	app.Get("/jobs/:id/diff", GetJobDiff)
+
+	tests := []struct {
+		name     string
+		path     string
+		want     int
+		contains string
+	}{
+		{"history", "/jobs/3971/versions", 200, `"latest_version":3`},
+		{"unedited job has one version", "/jobs/3970/versions", 200, `"latest_version":1`},
+		{"unknown job", "/jobs/3979/versions", 404, ""},
+		{"latest against previous", "/jobs/3971/diff", 200, `"removed":["Go"]`},
+		{"chosen versions", "/jobs/3971/diff?from=1&to=3", 200, `"added":["SQL"],"removed":["Go"]`},
+		{"missing version", "/jobs/3971/diff?from=7&to=3", 404, "Version 7"},
+		{"nothing to compare", "/jobs/3970/diff", 404, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.want || !strings.Contains(string(body), tt.contains) {
+				t.Errorf("got %d %s, want %d containing %s", resp.StatusCode, body, tt.want, tt.contains)
+			}
+		})
+	}
+}
+
+func TestRunRescoreJobTask(t *testing.T) {
+	job := TextData{Version: 2, Requirements: JobRequirements{Title: "Platform Engineer", Skills: []string{"Go", "Kubernetes"}}}
+	saveTestText(t, "job", "3980", job)
+	for version, skills := range map[int][]string{1: {"Go"}, 2: {"Go", "Kubernetes"}} {
+		if err := saveJobVersion(&TextData{ID: "3980", Version: version, Requirements: JobRequirements{Skills: skills}}, ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := saveScreeningSettings(ScreeningSettings{JobID: "3980", BlindScreening: true}); err != nil {
+		t.Fatal(err)
+	}
+	saveTestText(t, "resume", "3981", TextData{Entities: ExtractedEntities{Name: "Jane Doe", Skills: []string{"Go", "Kubernetes"}}})
+	saveTestText(t, "resume", "3982", TextData{Entities: ExtractedEntities{Name: "John Roe", Skills: []string{"Python"}}})
+	for id, old := range map[string]float64{"3981": 10, "3982": 90, "3983": 50} {
+		if err := saveScoreRecord(id, "3980", 1, ScoreResponse{OverallScore: old}, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result, err := runRescoreJobTask(context.Background(), &AnalysisTask{JobID: "3980", Params: map[string]string{"from_version": "1"}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := result.(RescoreReport)
+
+	if report.FromVersion != 1 || report.ToVersion != 2 || strings.Join(report.Skipped, ",") != "3983" {
+		t.Errorf("report = v%d to v%d, skipped %v; want v1 to v2, skipping the missing resume", report.FromVersion, report.ToVersion, report.Skipped)
+	}
+	if len(report.Diff.Changes) != 1 || report.Diff.Changes[0].Field != "skills" {
+		t.Errorf("diff = %+v, want the added skill", report.Diff.Changes)
+	}
+	if report.MovedUp != 1 || report.MovedDown != 1 || report.Unchanged != 0 {
+		t.Errorf("moved up %d, down %d, unchanged %d; want 1, 1, 0", report.MovedUp, report.MovedDown, report.Unchanged)
+	}
+
+	movements := make(map[string]CandidateMovement)
+	for _, candidate := range report.Candidates {
+		movements[candidate.ResumeID] = candidate
+		if strings.Contains(candidate.Name, "Doe") || strings.Contains(candidate.Name, "Roe") {
+			t.Errorf("blind rescore report names %q", candidate.Name)
+		}
+	}
+	tests := []struct {
+		resumeID         string
+		movement         string
+		oldRank, newRank int
+	}{
+		{"3981", MovedUp, 2, 1},
+		{"3982", MovedDown, 1, 2},
+	}
+	for _, tt := range tests {
+		got := movements[tt.resumeID]
+		if got.Movement != tt.movement || got.OldRank != tt.oldRank || got.NewRank != tt.newRank {
+			t.Errorf("%s = %+v, want %s from rank %d to %d", tt.resumeID, got, tt.movement, tt.oldRank, tt.newRank)
+		}
+	}
+	if record, err := loadScoreRecord("3981", "3980"); err != nil || record.JobVersion != 2 {
+		t.Errorf("stored score is not against version 2: %+v, %v", record, err)
+	}
+
+	app := fiber.New()
+	app.Get("/jobs/:id/versions/:version/rescore", GetRescoreReport)
+	for path, want := range map[string]int{
+		"/jobs/3980/versions/2/rescore": 200,
+		"/jobs/3980/versions/9/rescore": 404,
+		"/jobs/3980/versions/x/rescore": 400,
+	} {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != want {
+			t.Errorf("GET %s = %d, want %d", path, resp.StatusCode, want)
+			continue
+		}
+		if want == 200 {
+			var stored RescoreReport
+			if err := json.NewDecoder(resp.Body).Decode(&stored); err != nil || len(stored.Candidates) != 2 {
+				t.Errorf("stored report = %+v, %v", stored, err)
+			}
+		}
+	}
+}
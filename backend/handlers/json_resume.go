@@ -0,0 +1,529 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const jsonResumeSchemaURL = "https://raw.githubusercontent.com/jsonresume/resume-schema/v1.0.0/schema.json"
+
+// JSON Resume dates are ISO 8601 truncated to year, month or day
+var jsonResumeDatePattern = regexp.MustCompile(`^\d{4}(-(0[1-9]|1[0-2])(-(0[1-9]|[12]\d|3[01]))?)?$`)
+
+// spacedDurationSeparator is durationSeparator with whitespace on both sides
+var spacedDurationSeparator = regexp.MustCompile(`\s+[-‐–—]\s+`)
+
+// JSONResume is the subset of the open JSON Resume schema (jsonresume.org)
+// that maps onto ExtractedEntities
+type JSONResume struct {
+	Schema       string                  `json:"$schema,omitempty"`
+	Basics       JSONResumeBasics        `json:"basics"`
+	Work         []JSONResumeWork        `json:"work"`
+	Education    []JSONResumeEducation   `json:"education"`
+	Skills       []JSONResumeSkill       `json:"skills"`
+	Projects     []JSONResumeProject     `json:"projects"`
+	Certificates []JSONResumeCertificate `json:"certificates"`
+	Meta         *JSONResumeMeta         `json:"meta,omitempty"`
+}
+
+type JSONResumeBasics struct {
+	Name     string              `json:"name,omitempty"`
+	Label    string              `json:"label,omitempty"`
+	Email    string              `json:"email,omitempty"`
+	Phone    string              `json:"phone,omitempty"`
+	URL      string              `json:"url,omitempty"`
+	Summary  string              `json:"summary,omitempty"`
+	Location *JSONResumeLocation `json:"location,omitempty"`
+	Profiles []JSONResumeProfile `json:"profiles,omitempty"`
+}
+
+type JSONResumeLocation struct {
+	Address     string `json:"address,omitempty"`
+	PostalCode  string `json:"postalCode,omitempty"`
+	City        string `json:"city,omitempty"`
+	CountryCode string `json:"countryCode,omitempty"`
+	Region      string `json:"region,omitempty"`
+}
+
+type JSONResumeProfile struct {
+	Network  string `json:"network,omitempty"`
+	Username string `json:"username,omitempty"`
+	URL      string `json:"url,omitempty"`
+}
+
+type JSONResumeWork struct {
+	Name       string   `json:"name,omitempty"`
+	Position   string   `json:"position,omitempty"`
+	Location   string   `json:"location,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	StartDate  string   `json:"startDate,omitempty"`
+	EndDate    string   `json:"endDate,omitempty"`
+	Summary    string   `json:"summary,omitempty"`
+	Highlights []string `json:"highlights,omitempty"`
+}
+
+type JSONResumeEducation struct {
+	Institution string   `json:"institution,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Area        string   `json:"area,omitempty"`
+	StudyType   string   `json:"studyType,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	Score       string   `json:"score,omitempty"`
+	Courses     []string `json:"courses,omitempty"`
+}
+
+type JSONResumeSkill struct {
+	Name     string   `json:"name,omitempty"`
+	Level    string   `json:"level,omitempty"`
+	Keywords []string `json:"keywords,omitempty"`
+}
+
+type JSONResumeProject struct {
+	Name        string   `json:"name,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Highlights  []string `json:"highlights,omitempty"`
+	Keywords    []string `json:"keywords,omitempty"`
+	StartDate   string   `json:"startDate,omitempty"`
+	EndDate     string   `json:"endDate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Roles       []string `json:"roles,omitempty"`
+}
+
+type JSONResumeCertificate struct {
+	Name   string `json:"name,omitempty"`
+	Date   string `json:"date,omitempty"`
+	Issuer string `json:"issuer,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+type JSONResumeMeta struct {
+	Canonical    string `json:"canonical,omitempty"`
+	Version      string `json:"version,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+}
+
+// parseJSONResume decodes and checks a resume.json document
+func parseJSONResume(content []byte) (*JSONResume, error) {
+	var resume JSONResume
+	if err := json.Unmarshal(content, &resume); err != nil {
+		return nil, fiber.NewError(400, "Invalid JSON Resume: "+err.Error())
+	}
+	if problems := validateJSONResume(&resume); len(problems) > 0 {
+		return nil, fiber.NewError(400, "Invalid JSON Resume: "+strings.Join(problems, "; "))
+	}
+	return &resume, nil
+}
+
+// validateJSONResume checks the formats the schema constrains; it returns
+// one message per problem
+func validateJSONResume(r *JSONResume) []string {
+	var problems []string
+	if r.Basics.Name == "" && len(r.Work) == 0 && len(r.Education) == 0 && len(r.Skills) == 0 {
+		problems = append(problems, "document has no basics.name, work, education or skills")
+	}
+	if r.Basics.Email != "" {
+		if _, err := mail.ParseAddress(r.Basics.Email); err != nil {
+			problems = append(problems, fmt.Sprintf("basics.email %q is not an email address", r.Basics.Email))
+		}
+	}
+	checkDate := func(field, value string) {
+		if value != "" && !jsonResumeDatePattern.MatchString(value) {
+			problems = append(problems, fmt.Sprintf("%s %q must be YYYY, YYYY-MM or YYYY-MM-DD", field, value))
+		}
+	}
+	for i, work := range r.Work {
+		checkDate(fmt.Sprintf("work[%d].startDate", i), work.StartDate)
+		checkDate(fmt.Sprintf("work[%d].endDate", i), work.EndDate)
+	}
+	for i, edu := range r.Education {
+		checkDate(fmt.Sprintf("education[%d].startDate", i), edu.StartDate)
+		checkDate(fmt.Sprintf("education[%d].endDate", i), edu.EndDate)
+	}
+	for i, project := range r.Projects {
+		checkDate(fmt.Sprintf("projects[%d].startDate", i), project.StartDate)
+		checkDate(fmt.Sprintf("projects[%d].endDate", i), project.EndDate)
+	}
+	for i, cert := range r.Certificates {
+		checkDate(fmt.Sprintf("certificates[%d].date", i), cert.Date)
+	}
+	return problems
+}
+
+// entitiesFromJSONResume maps a JSON Resume onto ExtractedEntities. Skill
+// entries with keywords are treated as categories and contribute their
+// keywords; entries without keywords contribute their name.
+func entitiesFromJSONResume(r *JSONResume) ExtractedEntities {
+	entities := ExtractedEntities{
+		Name:       strings.TrimSpace(r.Basics.Name),
+		Phone:      strings.TrimSpace(r.Basics.Phone),
+		Skills:     []string{},
+		Education:  []Education{},
+		Projects:   []Project{},
+		Experience: []Experience{},
+	}
+	if r.Basics.Email != "" {
+		entities.Email = []string{strings.TrimSpace(r.Basics.Email)}
+	}
+	if loc := r.Basics.Location; loc != nil {
+		var parts []string
+		for _, part := range []string{loc.City, loc.Region, loc.CountryCode} {
+			if part = strings.TrimSpace(part); part != "" {
+				parts = append(parts, part)
+			}
+		}
+		entities.Preferences.Location = strings.Join(parts, ", ")
+	}
+
+	for _, work := range r.Work {
+		entities.Experience = append(entities.Experience, Experience{
+			Title:            work.Position,
+			Company:          work.Name,
+			Location:         work.Location,
+			Duration:         durationFromDates(work.StartDate, work.EndDate),
+			Description:      work.Summary,
+			Responsibilities: work.Highlights,
+		})
+	}
+	for _, edu := range r.Education {
+		year := ""
+		if len(edu.EndDate) >= 4 {
+			year = edu.EndDate[:4]
+		}
+		entities.Education = append(entities.Education, Education{
+			Degree:         edu.StudyType,
+			Institution:    edu.Institution,
+			Specialization: edu.Area,
+			Year:           year,
+			GraduationDate: displayDate(edu.EndDate),
+		})
+	}
+	for _, skill := range r.Skills {
+		if len(skill.Keywords) > 0 {
+			entities.Skills = append(entities.Skills, skill.Keywords...)
+		} else if skill.Name != "" {
+			entities.Skills = append(entities.Skills, skill.Name)
+		}
+	}
+	for _, project := range r.Projects {
+		entities.Projects = append(entities.Projects, Project{
+			Name:         project.Name,
+			Description:  project.Description,
+			Skills:       project.Keywords,
+			Technologies: project.Keywords,
+			Achievements: project.Highlights,
+			Role:         strings.Join(project.Roles, ", "),
+			Duration:     durationFromDates(project.StartDate, project.EndDate),
+		})
+	}
+	for _, cert := range r.Certificates {
+		entities.Certificates = append(entities.Certificates, Certificate{
+			Name:   cert.Name,
+			Issuer: cert.Issuer,
+			Date:   displayDate(cert.Date),
+			URL:    cert.URL,
+		})
+	}
+	return entities
+}
+
+// jsonResumeFromEntities exports processed entities as a JSON Resume. Skills
+// are grouped into technical and soft categories.
+func jsonResumeFromEntities(entities ExtractedEntities, lastModified time.Time) JSONResume {
+	r := JSONResume{
+		Schema:       jsonResumeSchemaURL,
+		Work:         []JSONResumeWork{},
+		Education:    []JSONResumeEducation{},
+		Skills:       []JSONResumeSkill{},
+		Projects:     []JSONResumeProject{},
+		Certificates: []JSONResumeCertificate{},
+		Meta:         &JSONResumeMeta{Version: "v1.0.0"},
+	}
+	if !lastModified.IsZero() {
+		r.Meta.LastModified = lastModified.Format(time.RFC3339)
+	}
+	if entities.Name != "Not provided" {
+		r.Basics.Name = entities.Name
+	}
+	if len(entities.Email) > 0 {
+		r.Basics.Email = entities.Email[0]
+	}
+	if entities.Phone != "Not provided" {
+		r.Basics.Phone = entities.Phone
+	}
+	if len(entities.Experience) > 0 {
+		r.Basics.Label = entities.Experience[0].Title
+	}
+	if location := entities.Preferences.Location; location != "" {
+		parts := strings.Split(location, ",")
+		for i := range parts {
+			parts[i] = strings.TrimSpace(parts[i])
+		}
+		loc := &JSONResumeLocation{City: parts[0]}
+		// "City, Region, US" carries a trailing country code
+		if last := parts[len(parts)-1]; len(parts) > 2 && len(last) == 2 {
+			loc.CountryCode = strings.ToUpper(last)
+			parts = parts[:len(parts)-1]
+		}
+		loc.Region = strings.Join(parts[1:], ", ")
+		r.Basics.Location = loc
+	}
+
+	for _, exp := range entities.Experience {
+		start, end := datesFromDuration(exp.Duration)
+		r.Work = append(r.Work, JSONResumeWork{
+			Name:       exp.Company,
+			Position:   exp.Title,
+			Location:   exp.Location,
+			StartDate:  start,
+			EndDate:    end,
+			Summary:    exp.Description,
+			Highlights: append(append([]string{}, exp.Responsibilities...), exp.Achievements...),
+		})
+	}
+	for _, edu := range entities.Education {
+		end := isoDate(edu.GraduationDate)
+		if end == "" {
+			end = isoDate(edu.Year)
+		}
+		r.Education = append(r.Education, JSONResumeEducation{
+			Institution: edu.Institution,
+			Area:        edu.Specialization,
+			StudyType:   edu.Degree,
+			EndDate:     end,
+		})
+	}
+
+	var technical, soft []string
+	for _, skill := range entities.Skills {
+		if isSoftSkill(skill) {
+			soft = append(soft, skill)
+		} else {
+			technical = append(technical, skill)
+		}
+	}
+	if len(technical) > 0 {
+		r.Skills = append(r.Skills, JSONResumeSkill{Name: "Technical", Keywords: technical})
+	}
+	if len(soft) > 0 {
+		r.Skills = append(r.Skills, JSONResumeSkill{Name: "Soft skills", Keywords: soft})
+	}
+
+	for _, project := range entities.Projects {
+		start, end := datesFromDuration(project.Duration)
+		var roles []string
+		if project.Role != "" {
+			roles = []string{project.Role}
+		}
+		r.Projects = append(r.Projects, JSONResumeProject{
+			Name:        project.Name,
+			Description: project.Description,
+			Highlights:  project.Achievements,
+			Keywords:    uniqueStrings(append(append([]string{}, project.Technologies...), project.Skills...)),
+			StartDate:   start,
+			EndDate:     end,
+			Roles:       roles,
+		})
+	}
+	for _, cert := range entities.Certificates {
+		r.Certificates = append(r.Certificates, JSONResumeCertificate{
+			Name:   cert.Name,
+			Issuer: cert.Issuer,
+			Date:   isoDate(cert.Date),
+			URL:    cert.URL,
+		})
+	}
+	return r
+}
+
+// jsonResumeText renders a JSON Resume as plain text with standard section
+// headings, so imported resumes get the same text-based checks as uploads
+func jsonResumeText(r *JSONResume) string {
+	var b strings.Builder
+	line := func(parts ...string) {
+		var kept []string
+		for _, part := range parts {
+			if part = strings.TrimSpace(part); part != "" {
+				kept = append(kept, part)
+			}
+		}
+		if len(kept) > 0 {
+			b.WriteString(strings.Join(kept, " | ") + "\n")
+		}
+	}
+	bullets := func(items []string) {
+		for _, item := range items {
+			if item = strings.TrimSpace(item); item != "" {
+				b.WriteString("- " + item + "\n")
+			}
+		}
+	}
+
+	line(r.Basics.Name)
+	line(r.Basics.Label)
+	line(r.Basics.Email, r.Basics.Phone, r.Basics.URL)
+	if loc := r.Basics.Location; loc != nil {
+		line(loc.City, loc.Region, loc.CountryCode)
+	}
+	if r.Basics.Summary != "" {
+		b.WriteString("\nSUMMARY\n" + r.Basics.Summary + "\n")
+	}
+	if len(r.Work) > 0 {
+		b.WriteString("\nEXPERIENCE\n")
+		for _, work := range r.Work {
+			line(work.Position, work.Name, work.Location, durationFromDates(work.StartDate, work.EndDate))
+			line(work.Summary)
+			bullets(work.Highlights)
+		}
+	}
+	if len(r.Education) > 0 {
+		b.WriteString("\nEDUCATION\n")
+		for _, edu := range r.Education {
+			line(edu.StudyType, edu.Area, edu.Institution, durationFromDates(edu.StartDate, edu.EndDate))
+		}
+	}
+	if len(r.Skills) > 0 {
+		b.WriteString("\nSKILLS\n")
+		for _, skill := range r.Skills {
+			if len(skill.Keywords) > 0 {
+				line(skill.Name + ": " + strings.Join(skill.Keywords, ", "))
+			} else {
+				line(skill.Name)
+			}
+		}
+	}
+	if len(r.Projects) > 0 {
+		b.WriteString("\nPROJECTS\n")
+		for _, project := range r.Projects {
+			line(project.Name, durationFromDates(project.StartDate, project.EndDate))
+			line(project.Description)
+			bullets(project.Highlights)
+		}
+	}
+	if len(r.Certificates) > 0 {
+		b.WriteString("\nCERTIFICATIONS\n")
+		for _, cert := range r.Certificates {
+			line(cert.Name, cert.Issuer, displayDate(cert.Date))
+		}
+	}
+	return b.String()
+}
+
+// displayDate turns an ISO date into the "Jan 2006" form used in durations
+func displayDate(iso string) string {
+	for _, layout := range []string{"2006-01-02", "2006-01"} {
+		if t, err := time.Parse(layout, iso); err == nil {
+			return t.Format("Jan 2006")
+		}
+	}
+	return iso
+}
+
+// isoDate turns a parsed resume date into YYYY-MM, or YYYY when only the year
+// is known
+func isoDate(value string) string {
+	value = strings.TrimSpace(value)
+	if jsonResumeDatePattern.MatchString(value) {
+		return value
+	}
+	t := parseDate(value)
+	if t.IsZero() || strings.EqualFold(value, "present") {
+		if m := yearPattern.FindString(value); m != "" {
+			return m
+		}
+		return ""
+	}
+	return t.Format("2006-01")
+}
+
+func durationFromDates(start, end string) string {
+	switch {
+	case start == "" && end == "":
+		return ""
+	case end == "":
+		return displayDate(start) + " - Present"
+	case start == "":
+		return displayDate(end)
+	}
+	return displayDate(start) + " - " + displayDate(end)
+}
+
+// datesFromDuration splits a "Jan 2020 - Present" duration into ISO start and
+// end dates; an ongoing role has no end date. A spaced dash is tried first so
+// ISO dates on either side keep their own hyphens.
+func datesFromDuration(duration string) (string, string) {
+	duration = strings.TrimSpace(duration)
+	if jsonResumeDatePattern.MatchString(duration) {
+		return duration, ""
+	}
+	parts := spacedDurationSeparator.Split(duration, 2)
+	if len(parts) < 2 {
+		parts = durationSeparator.Split(duration, 2)
+	}
+	if len(parts) < 2 {
+		return isoDate(duration), ""
+	}
+	return isoDate(parts[0]), isoDate(parts[1])
+}
+
+// ImportJSONResume processes a resume.json body without LLM extraction
+func ImportJSONResume(c *fiber.Ctx) error {
+	body := c.Body()
+	if len(bytes.TrimSpace(body)) == 0 {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "A JSON Resume document is required",
+		})
+	}
+	if _, err := parseJSONResume(body); err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	uploadPath := filepath.Join("uploads", fmt.Sprintf("import-%d.json", time.Now().UnixNano()))
+	if err := os.WriteFile(uploadPath, body, 0644); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Unable to save the file",
+		})
+	}
+
+	result, err := processResumeFile(uploadPath, "resume.json", resumeProcessingOptions{
+		JobID: c.Query("job_id"),
+		Blind: c.QueryBool("blind"),
+	}, nil)
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
+
+// ExportJSONResume returns a processed resume in JSON Resume format
+func ExportJSONResume(c *fiber.Ctx) error {
+	resumeID := normalizeID(c.Params("id"), "resume")
+	resumeData, err := LoadTextData(resumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume not found",
+		})
+	}
+
+	document := jsonResumeFromEntities(resumeData.Entities, resumeData.Timestamp)
+	if c.QueryBool("download") {
+		c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="resume_%s.json"`, resumeID))
+	}
+	return c.JSON(document)
+}
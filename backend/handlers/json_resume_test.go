@@ -0,0 +1,152 @@
+package handlers
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestIsoDate(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"2020", "2020"},
+		{"2020-03", "2020-03"},
+		{"2020-03-15", "2020-03-15"},
+		{"Mar 2020", "2020-03"},
+		{"March 2020", "2020-03"},
+		{"  Mar 2020 ", "2020-03"},
+		{"Present", ""},
+		{"present", ""},
+		{"Spring 2019", "2019"},
+		{"", ""},
+		{"soon", ""},
+		{"2020-13", "2020"}, // only the year is usable
+	}
+	for _, tt := range tests {
+		if got := isoDate(tt.value); got != tt.want {
+			t.Errorf("isoDate(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestDatesFromDuration(t *testing.T) {
+	tests := []struct {
+		duration  string
+		wantStart string
+		wantEnd   string
+	}{
+		{"Jan 2020 - Present", "2020-01", ""},
+		{"Jan 2020 - Mar 2022", "2020-01", "2022-03"},
+		{"January 2020 – March 2022", "2020-01", "2022-03"},
+		{"Jan 2020 — Mar 2022", "2020-01", "2022-03"},
+		{"2018-2020", "2018", "2020"},
+		{"2018–2020", "2018", "2020"},
+		{"2019-06 - 2021-02", "2019-06", "2021-02"},
+		{"2019-06", "2019-06", ""},
+		{"Mar 2021", "2021-03", ""},
+		{"", "", ""},
+	}
+	for _, tt := range tests {
+		start, end := datesFromDuration(tt.duration)
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("datesFromDuration(%q) = (%q, %q), want (%q, %q)", tt.duration, start, end, tt.wantStart, tt.wantEnd)
+		}
+	}
+}
+
+// Dates survive import then export at the precision durations keep: year and
+// month. Days are dropped because durations read "Jan 2006".
+func TestDurationRoundTrip(t *testing.T) {
+	tests := []struct {
+		start, end         string
+		wantStart, wantEnd string
+	}{
+		{"2020-01", "", "2020-01", ""},
+		{"2020-01", "2022-03", "2020-01", "2022-03"},
+		{"2018", "2020", "2018", "2020"},
+		{"2018", "2020-06", "2018", "2020-06"},
+		{"2020-01-15", "2022-03-31", "2020-01", "2022-03"},
+		{"", "", "", ""},
+	}
+	for _, tt := range tests {
+		duration := durationFromDates(tt.start, tt.end)
+		start, end := datesFromDuration(duration)
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("(%q, %q) -> %q -> (%q, %q), want (%q, %q)",
+				tt.start, tt.end, duration, start, end, tt.wantStart, tt.wantEnd)
+		}
+		if start != "" && isoDate(displayDate(start)) != start {
+			t.Errorf("isoDate(displayDate(%q)) = %q", start, isoDate(displayDate(start)))
+		}
+	}
+}
+
+func TestJSONResumeRoundTrip(t *testing.T) {
+	imported := &JSONResume{
+		Basics: JSONResumeBasics{
+			Name:     "Ada Lovelace",
+			Email:    "ada@example.com",
+			Phone:    "+44 20 7946 0000",
+			Location: &JSONResumeLocation{City: "London", Region: "England", CountryCode: "GB"},
+		},
+		Work: []JSONResumeWork{
+			{Name: "Analytical Engines", Position: "Engineer", StartDate: "2020-01", Highlights: []string{"Wrote the first program"}},
+			{Name: "Difference Co", Position: "Analyst", StartDate: "2017", EndDate: "2019-12"},
+		},
+		Education: []JSONResumeEducation{
+			{Institution: "University of London", Area: "Mathematics", StudyType: "BSc", EndDate: "2016-06"},
+		},
+		Skills: []JSONResumeSkill{
+			{Name: "Languages", Keywords: []string{"Go", "Python"}},
+		},
+		Certificates: []JSONResumeCertificate{
+			{Name: "CKA", Issuer: "CNCF", Date: "2021-05"},
+		},
+	}
+
+	exported := jsonResumeFromEntities(entitiesFromJSONResume(imported), time.Time{})
+	if problems := validateJSONResume(&exported); len(problems) > 0 {
+		t.Fatalf("export does not validate: %v", problems)
+	}
+
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"name", exported.Basics.Name, imported.Basics.Name},
+		{"email", exported.Basics.Email, imported.Basics.Email},
+		{"location", *exported.Basics.Location, *imported.Basics.Location},
+		{"work[0] dates", [2]string{exported.Work[0].StartDate, exported.Work[0].EndDate}, [2]string{"2020-01", ""}},
+		{"work[1] dates", [2]string{exported.Work[1].StartDate, exported.Work[1].EndDate}, [2]string{"2017", "2019-12"}},
+		{"work[0] highlights", exported.Work[0].Highlights, imported.Work[0].Highlights},
+		{"education end", exported.Education[0].EndDate, "2016-06"},
+		{"skills", exported.Skills[0].Keywords, []string{"Go", "Python"}},
+		{"certificate date", exported.Certificates[0].Date, "2021-05"},
+	}
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestValidateJSONResume(t *testing.T) {
+	tests := []struct {
+		name   string
+		resume JSONResume
+		want   int
+	}{
+		{"minimal", JSONResume{Basics: JSONResumeBasics{Name: "Ada"}}, 0},
+		{"empty", JSONResume{}, 1},
+		{"bad email", JSONResume{Basics: JSONResumeBasics{Name: "Ada", Email: "ada at example"}}, 1},
+		{"display dates", JSONResume{Work: []JSONResumeWork{{StartDate: "Jan 2020", EndDate: "Present"}}}, 2},
+		{"bad certificate date", JSONResume{Basics: JSONResumeBasics{Name: "Ada"}, Certificates: []JSONResumeCertificate{{Date: "2021-13"}}}, 1},
+	}
+	for _, tt := range tests {
+		if got := validateJSONResume(&tt.resume); len(got) != tt.want {
+			t.Errorf("%s: got problems %q, want %d", tt.name, got, tt.want)
+		}
+	}
+}
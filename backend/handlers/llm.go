@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/generative-ai-go/genai"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+)
+
+// LLMProvider generates text from a prompt
+type LLMProvider interface {
+	Generate(ctx context.Context, prompt string) (string, error)
+	Close() error
+}
+
+// StreamingLLMProvider can also deliver text incrementally as it is generated
+type StreamingLLMProvider interface {
+	LLMProvider
+	GenerateStream(ctx context.Context, prompt string, onToken func(string)) (string, error)
+}
+
+// geminiProvider implements StreamingLLMProvider on top of the Gemini API
+type geminiProvider struct {
+	client *genai.Client
+	model  *genai.GenerativeModel
+}
+
+// newLLMProvider creates the configured LLM provider
+func newLLMProvider(ctx context.Context) (LLMProvider, error) {
+	client, err := genai.NewClient(ctx, option.WithAPIKey(getGeminiAPIKey()))
+	if err != nil {
+		return nil, err
+	}
+	return &geminiProvider{
+		client: client,
+		model:  client.GenerativeModel("gemini-pro"),
+	}, nil
+}
+
+func (p *geminiProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := p.model.GenerateContent(ctx, genai.Text(prompt))
+	if err != nil {
+		return "", err
+	}
+	return responseText(resp)
+}
+
+func (p *geminiProvider) GenerateStream(ctx context.Context, prompt string, onToken func(string)) (string, error) {
+	iter := p.model.GenerateContentStream(ctx, genai.Text(prompt))
+	var full strings.Builder
+	for {
+		resp, err := iter.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return full.String(), err
+		}
+		chunk, err := responseText(resp)
+		if err != nil {
+			continue
+		}
+		full.WriteString(chunk)
+		onToken(chunk)
+	}
+	if full.Len() == 0 {
+		return "", fmt.Errorf("no response from model")
+	}
+	return full.String(), nil
+}
+
+func (p *geminiProvider) Close() error {
+	return p.client.Close()
+}
+
+// responseText concatenates the text parts of the first candidate
+func responseText(resp *genai.GenerateContentResponse) (string, error) {
+	if resp == nil || len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
+		return "", fmt.Errorf("no response from model")
+	}
+	var text strings.Builder
+	for _, part := range resp.Candidates[0].Content.Parts {
+		if t, ok := part.(genai.Text); ok {
+			text.WriteString(string(t))
+		}
+	}
+	return text.String(), nil
+}
+
+// generateText runs a prompt, streaming tokens to onToken when both the
+// provider and the caller support it
+func generateText(ctx context.Context, llm LLMProvider, prompt string, onToken func(string)) (string, error) {
+	if streamer, ok := llm.(StreamingLLMProvider); ok && onToken != nil {
+		return streamer.GenerateStream(ctx, prompt, onToken)
+	}
+	return llm.Generate(ctx, prompt)
+}
@@ -10,24 +10,27 @@ import (
 )
 
 type PreprocessedData struct {
-	ProcessedText   string            `json:"processed_text"`
-	Text            string            `json:"text"`
-	Entities        ExtractedEntities `json:"entities"`
-	RawText         string            `json:"raw_text"`
-	RawJSON         string            `json:"raw_json"`
-	Name            string            `json:"name"`
-	Email           string            `json:"email"`
-	Phone           string            `json:"phone"`
-	Requirements    JobRequirements   `json:"requirements,omitempty"`
-	TechnicalSkills []string          `json:"technical_skills"`
-	SoftSkills      []string          `json:"soft_skills"`
-	Education       []Education       `json:"education"`
-	Experience      []Experience      `json:"experience"`
-	Projects        []Project         `json:"projects"`
-	SessionID       string            `json:"session_id"`
-	Filename        string            `json:"filename"`
-	ProcessedAt     time.Time         `json:"processed_at"`
-	ID              string            `json:"id"`
+	ProcessedText   string                 `json:"processed_text"`
+	Text            string                 `json:"text"`
+	Entities        ExtractedEntities      `json:"entities"`
+	RawText         string                 `json:"raw_text"`
+	RawJSON         string                 `json:"raw_json"`
+	Name            string                 `json:"name"`
+	Email           string                 `json:"email"`
+	Phone           string                 `json:"phone"`
+	Requirements    JobRequirements        `json:"requirements,omitempty"`
+	TechnicalSkills []string               `json:"technical_skills"`
+	SoftSkills      []string               `json:"soft_skills"`
+	Education       []Education            `json:"education"`
+	Experience      []Experience           `json:"experience"`
+	Projects        []Project              `json:"projects"`
+	SessionID       string                 `json:"session_id"`
+	Filename        string                 `json:"filename"`
+	ProcessedAt     time.Time              `json:"processed_at"`
+	ID              string                 `json:"id"`
+	CandidateID     string                 `json:"candidate_id,omitempty"`
+	Diagnostics     *ExtractionDiagnostics `json:"extraction_diagnostics,omitempty"`
+	IntegrityFlags  []IntegrityFlag        `json:"integrity_flags"`
 }
 
 // Add LogEntry type definition
@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// mailWithAttachments builds a multipart message; each attachment is a
+// Content-Disposition filename and the attachment body
+func mailWithAttachments(from, to, subject string, attachments ...[2]string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: %s\r\nMessage-Id: <m1@example.com>\r\nDate: Mon, 02 Jan 2006 15:04:05 +0000\r\n", from, to, subject)
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: multipart/mixed; boundary=XYZ\r\n\r\n")
+	b.WriteString("--XYZ\r\nContent-Type: text/plain\r\n\r\nPlease find my resume attached.\r\n")
+	for _, attachment := range attachments {
+		fmt.Fprintf(&b, "--XYZ\r\nContent-Type: application/octet-stream\r\nContent-Transfer-Encoding: base64\r\nContent-Disposition: attachment; filename=\"%s\"\r\n\r\n%s\r\n",
+			attachment[0], base64.StdEncoding.EncodeToString([]byte(attachment[1])))
+	}
+	b.WriteString("--XYZ--\r\n")
+	return []byte(b.String())
+}
+
+func TestParseMailMessage(t *testing.T) {
+	forwarded := "From: Jane Doe <jane@example.com>\r\nSubject: Application\r\nContent-Type: multipart/mixed; boundary=INNER\r\n\r\n" +
+		"--INNER\r\nContent-Type: application/pdf; name=\"inner.pdf\"\r\n\r\n%PDF-1.4\r\n--INNER--\r\n"
+	tests := []struct {
+		name         string
+		raw          string
+		wantFrom     string
+		wantSubject  string
+		wantAccepted []string
+		wantRejected []string
+	}{
+		{
+			name:         "attachments by extension",
+			raw:          string(mailWithAttachments("Jane Doe <Jane@Example.com>", "jobs@corp.test", "Backend role", [2]string{"cv.pdf", "%PDF-1.4"}, [2]string{"notes.txt", "hi"})),
+			wantFrom:     "jane@example.com",
+			wantSubject:  "Backend role",
+			wantAccepted: []string{"cv.pdf"},
+		},
+		{
+			name:         "encoded subject and filename",
+			raw:          string(mailWithAttachments("jane@example.com", "jobs@corp.test", "=?UTF-8?B?UsOpc3Vtw6k=?=", [2]string{"=?UTF-8?Q?R=C3=A9sum=C3=A9.pdf?=", "%PDF"})),
+			wantFrom:     "jane@example.com",
+			wantSubject:  "Résumé",
+			wantAccepted: []string{"Résumé.pdf"},
+		},
+		{
+			name:         "path in filename",
+			raw:          string(mailWithAttachments("jane@example.com", "jobs@corp.test", "cv", [2]string{`C:\Users\jane\cv.docx`, "PK"})),
+			wantFrom:     "jane@example.com",
+			wantSubject:  "cv",
+			wantAccepted: []string{"cv.docx"},
+		},
+		{
+			name:         "oversized attachment",
+			raw:          string(mailWithAttachments("jane@example.com", "jobs@corp.test", "cv", [2]string{"big.pdf", strings.Repeat("a", maxBulkFileBytes+1)})),
+			wantFrom:     "jane@example.com",
+			wantSubject:  "cv",
+			wantRejected: []string{"big.pdf"},
+		},
+		{
+			name: "forwarded message",
+			raw: "From: recruiter@corp.test\r\nSubject: Fwd: Application\r\nContent-Type: multipart/mixed; boundary=OUTER\r\n\r\n" +
+				"--OUTER\r\nContent-Type: message/rfc822\r\n\r\n" + forwarded + "\r\n--OUTER--\r\n",
+			wantFrom:     "recruiter@corp.test",
+			wantSubject:  "Fwd: Application",
+			wantAccepted: []string{"inner.pdf"},
+		},
+		{
+			name:         "named by media type",
+			raw:          "From: jane@example.com\r\nSubject: cv\r\nContent-Type: application/pdf\r\n\r\n%PDF-1.4",
+			wantFrom:     "jane@example.com",
+			wantSubject:  "cv",
+			wantAccepted: []string{"resume.pdf"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := parseMailMessage([]byte(tt.raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if msg.From != tt.wantFrom || msg.Subject != tt.wantSubject {
+				t.Errorf("from, subject = %q, %q, want %q, %q", msg.From, msg.Subject, tt.wantFrom, tt.wantSubject)
+			}
+			names := func(attachments []mailAttachment) []string {
+				var out []string
+				for _, a := range attachments {
+					out = append(out, a.Name)
+				}
+				return out
+			}
+			if got := names(msg.Attachments); strings.Join(got, ",") != strings.Join(tt.wantAccepted, ",") {
+				t.Errorf("attachments = %v, want %v", got, tt.wantAccepted)
+			}
+			if got := names(msg.Rejected); strings.Join(got, ",") != strings.Join(tt.wantRejected, ",") {
+				t.Errorf("rejected = %v, want %v", got, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestJobForMessage(t *testing.T) {
+	saveTestText(t, "job", "4400001", TextData{Requirements: JobRequirements{Title: "Data Engineer"}})
+	saveTestText(t, "job", "4400002", TextData{Requirements: JobRequirements{Title: "Senior Data Engineer"}})
+
+	tests := []struct {
+		name       string
+		msg        mailMessage
+		defaultJob string
+		wantJob    string
+		wantBy     string
+	}{
+		{"address tag", mailMessage{Recipients: []string{"jobs+job_4400001@corp.test"}, Subject: "Senior Data Engineer"}, "", "4400001", "address_tag"},
+		{"unknown tag falls through", mailMessage{Recipients: []string{"jobs+9999999@corp.test"}, Subject: "Job #4400002"}, "", "4400002", "subject_id"},
+		{"longest title", mailMessage{Subject: "Application: senior data engineer"}, "", "4400002", "subject_title"},
+		{"shorter title", mailMessage{Subject: "Data Engineer opening"}, "", "4400001", "subject_title"},
+		{"default job", mailMessage{Subject: "Hello"}, "4400001", "4400001", "default"},
+		{"no job", mailMessage{Subject: "Hello"}, "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			job, by := jobForMessage(&tt.msg, tt.defaultJob)
+			if job != tt.wantJob || by != tt.wantBy {
+				t.Errorf("jobForMessage = (%q, %q), want (%q, %q)", job, by, tt.wantJob, tt.wantBy)
+			}
+		})
+	}
+}
+
+func TestBlindIngestFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     IngestFile
+		position int
+		wantName string
+		wantMeta map[string]string
+	}{
+		{"renames by position", IngestFile{Name: "Jane_Doe_CV.PDF"}, 3, "resume-003.pdf", nil},
+		{"drops sender and subject", IngestFile{Name: "cv.docx", Metadata: map[string]string{
+			"from": "jane@example.com", "subject": "Jane Doe application", "message_id": "m1", "job_matched_by": "default",
+		}}, 12, "resume-012.docx", map[string]string{"message_id": "m1", "job_matched_by": "default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := blindIngestFile(tt.file, tt.position)
+			if got.Name != tt.wantName {
+				t.Errorf("name = %q, want %q", got.Name, tt.wantName)
+			}
+			if fmt.Sprint(got.Metadata) != fmt.Sprint(tt.wantMeta) {
+				t.Errorf("metadata = %v, want %v", got.Metadata, tt.wantMeta)
+			}
+			if tt.file.Metadata != nil && tt.file.Metadata["from"] == "" {
+				t.Error("blindIngestFile modified the caller's metadata")
+			}
+		})
+	}
+}
+
+func TestIngestMessageBlindJob(t *testing.T) {
+	saveTestText(t, "job", "4400010", TextData{ProcessedText: "Blind job"})
+	if err := saveScreeningSettings(ScreeningSettings{JobID: "4400010", BlindScreening: true}); err != nil {
+		t.Fatal(err)
+	}
+	saveTestText(t, "job", "4400011", TextData{ProcessedText: "Open job"})
+
+	tests := []struct {
+		name      string
+		to        string
+		attach    [][2]string
+		blind     bool
+		wantNames []string
+	}{
+		{"blind job", "jobs+4400010@corp.test", [][2]string{{"Jane_Doe.pdf", "%PDF"}, {"big.pdf", strings.Repeat("a", maxBulkFileBytes+1)}}, true,
+			[]string{"resume-001.pdf", "resume-002.pdf"}},
+		{"blind job without attachments", "jobs+4400010@corp.test", nil, true, []string{"(no attachment)"}},
+		{"open job", "jobs+4400011@corp.test", [][2]string{{"Jane_Doe.pdf", "%PDF"}}, false, []string{"Jane_Doe.pdf"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			batch := newIngestBatch(IngestSourceInbox, "maildir", "", false)
+			(&mailIngester{}).ingestMessage(batch, mailWithAttachments("jane@example.com", tt.to, "Jane Doe applying", tt.attach...))
+
+			var names []string
+			for _, file := range batch.Files {
+				names = append(names, file.Name)
+				_, hasFrom := file.Metadata["from"]
+				_, hasSubject := file.Metadata["subject"]
+				if (hasFrom || hasSubject) == tt.blind {
+					t.Errorf("%s: metadata %v, blind %v", file.Name, file.Metadata, tt.blind)
+				}
+				if file.TaskID == "" {
+					continue
+				}
+				task, err := loadTask(file.TaskID)
+				if err != nil {
+					t.Fatal(err)
+				}
+				leaked := strings.Contains(fmt.Sprint(task.Params), "jane@example.com") || strings.Contains(fmt.Sprint(task.Params), "Jane")
+				if leaked == tt.blind {
+					t.Errorf("task params %v, blind %v", task.Params, tt.blind)
+				}
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantNames, ",") {
+				t.Errorf("files = %v, want %v", names, tt.wantNames)
+			}
+		})
+	}
+}
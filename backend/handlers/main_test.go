@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"interviewme/utils"
+)
+
+// TestMain runs the tests in a scratch directory, since handlers keep their
+// data under relative paths such as processed_texts/ and uploads/
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "handlers-test")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		log.Fatal(err)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// saveTestText stores processed text the way uploads do, so handlers can
+// load it with LoadTextData
+func saveTestText(t *testing.T, textType, id string, data TextData) {
+	t.Helper()
+	data.Type, data.ID = textType, id
+	path := filepath.Join("processed_texts", textType, fmt.Sprintf("%s_%s.json", textType, id))
+	if err := utils.SaveJSONFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
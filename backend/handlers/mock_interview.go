@@ -0,0 +1,577 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Mock interview session states
+const (
+	MockSessionActive    = "active"
+	MockSessionCompleted = "completed"
+)
+
+const (
+	defaultMockQuestions = 8
+	maxMockQuestions     = 20
+	stepUpScore          = 8.0 // Answers at or above this raise the difficulty
+	stepDownScore        = 4.0 // Answers at or below this lower the difficulty
+	maxAnswerLength      = 4000
+)
+
+var difficultyLevels = []string{"easy", "medium", "hard"}
+
+// mockSessionLocks serializes updates to the same session
+var mockSessionLocks sync.Map
+
+// AnswerEvaluation is the grade given to one answer
+type AnswerEvaluation struct {
+	Score          float64  `json:"score"` // 0-10
+	Feedback       string   `json:"feedback"`
+	Strengths      []string `json:"strengths"`
+	Improvements   []string `json:"improvements"`
+	RubricCoverage []string `json:"rubric_coverage"`
+	Source         string   `json:"source"`
+}
+
+// MockInterviewTurn is one question/answer exchange in the transcript
+type MockInterviewTurn struct {
+	Question   InterviewQuestion `json:"question"`
+	Answer     string            `json:"answer"`
+	Evaluation AnswerEvaluation  `json:"evaluation"`
+	AskedAt    time.Time         `json:"asked_at"`
+	AnsweredAt time.Time         `json:"answered_at"`
+}
+
+// MockInterviewReport summarizes a finished session
+type MockInterviewReport struct {
+	OverallScore      float64            `json:"overall_score"`
+	QuestionsAnswered int                `json:"questions_answered"`
+	FinalDifficulty   string             `json:"final_difficulty"`
+	CompetencyScores  map[string]float64 `json:"competency_scores"`
+	CategoryScores    map[string]float64 `json:"category_scores"`
+	Strengths         []string           `json:"strengths"`
+	FocusAreas        []string           `json:"focus_areas"`
+	Summary           string             `json:"summary"`
+}
+
+// MockInterviewSession is a persisted mock interview with its full transcript
+type MockInterviewSession struct {
+	SessionID         string               `json:"session_id"`
+	ResumeID          string               `json:"resume_id"`
+	JobID             string               `json:"job_id"`
+	Status            string               `json:"status"`
+	MaxQuestions      int                  `json:"max_questions"`
+	CurrentDifficulty string               `json:"current_difficulty"`
+	CurrentQuestion   *InterviewQuestion   `json:"current_question,omitempty"`
+	CurrentAskedAt    time.Time            `json:"current_asked_at,omitempty"`
+	QuestionPool      []InterviewQuestion  `json:"question_pool"`
+	Transcript        []MockInterviewTurn  `json:"transcript"`
+	Report            *MockInterviewReport `json:"report,omitempty"`
+	Timestamp         string               `json:"timestamp"`
+	UpdatedAt         time.Time            `json:"updated_at"`
+}
+
+// mockQuestionView is what the candidate sees; the rubric stays hidden
+type mockQuestionView struct {
+	Number     int    `json:"number"`
+	ID         string `json:"id"`
+	Category   string `json:"category"`
+	Difficulty string `json:"difficulty"`
+	Competency string `json:"competency"`
+	Question   string `json:"question"`
+}
+
+// mockSessionView is a session as the candidate sees it: answered turns keep
+// their rubric for review, but the current question and the pool don't
+type mockSessionView struct {
+	*MockInterviewSession
+	CurrentQuestion *mockQuestionView   `json:"current_question,omitempty"`
+	QuestionPool    []InterviewQuestion `json:"question_pool,omitempty"`
+}
+
+// StartMockInterview creates a session for a resume/job pair and serves the
+// first question
+func StartMockInterview(c *fiber.Ctx) error {
+	var request struct {
+		ResumeID     string `json:"resume_id"`
+		JobID        string `json:"job_id"`
+		MaxQuestions int    `json:"max_questions"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.ResumeID == "" || request.JobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+
+	resumeID := normalizeID(request.ResumeID, "resume")
+	jobID := normalizeID(request.JobID, "job")
+	resumeData, err := LoadTextData(resumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume not found",
+		})
+	}
+	jobData, err := LoadTextData(jobID, "job")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description not found",
+		})
+	}
+
+	// Reuse the interview kit for this pair, generating one if needed
+	kit, err := loadInterviewKit(resumeID, jobID)
+	if err != nil {
+		kit = buildInterviewKit(context.Background(), resumeID, resumeData, jobID, jobData)
+		if err := utils.SaveJSONFile(interviewKitPath(resumeID, jobID), kit, 0644); err != nil {
+			log.Printf("Error saving interview kit: %v", err)
+		}
+	}
+
+	var pool []InterviewQuestion
+	pool = append(pool, kit.TechnicalQuestions...)
+	pool = append(pool, kit.PartialMatchProbes...)
+	pool = append(pool, kit.BehavioralQuestions...)
+	pool = append(pool, kit.ProjectFollowUps...)
+	if len(pool) == 0 {
+		return c.Status(422).JSON(fiber.Map{
+			"error": "No interview questions could be generated for this pair",
+		})
+	}
+
+	maxQuestions := request.MaxQuestions
+	if maxQuestions <= 0 {
+		maxQuestions = defaultMockQuestions
+	}
+	maxQuestions = min(min(maxQuestions, maxMockQuestions), len(pool))
+
+	session := &MockInterviewSession{
+		SessionID:         fmt.Sprintf("mock_%d", time.Now().UnixNano()),
+		ResumeID:          resumeID,
+		JobID:             jobID,
+		Status:            MockSessionActive,
+		MaxQuestions:      maxQuestions,
+		CurrentDifficulty: seniorityDifficulty(strings.ToLower(jobData.Requirements.Experience.Level), "medium"),
+		QuestionPool:      pool,
+		Transcript:        []MockInterviewTurn{},
+		Timestamp:         utils.GetTimestamp(),
+	}
+	session.advance()
+
+	if err := saveMockSession(session); err != nil {
+		log.Printf("Error saving mock interview session: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save session",
+		})
+	}
+
+	return c.Status(201).JSON(fiber.Map{
+		"session_id":    session.SessionID,
+		"status":        session.Status,
+		"max_questions": session.MaxQuestions,
+		"question":      session.questionView(),
+	})
+}
+
+// GetMockInterview returns a session with its transcript
+func GetMockInterview(c *fiber.Ctx) error {
+	session, err := loadMockSession(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+	return c.JSON(mockSessionView{MockInterviewSession: session, CurrentQuestion: session.questionView()})
+}
+
+// GetMockInterviewQuestion returns the question currently awaiting an answer
+func GetMockInterviewQuestion(c *fiber.Ctx) error {
+	session, err := loadMockSession(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+	if session.Status != MockSessionActive {
+		return c.Status(409).JSON(fiber.Map{
+			"error":  "Session is already completed",
+			"report": session.Report,
+		})
+	}
+	return c.JSON(fiber.Map{
+		"session_id": session.SessionID,
+		"question":   session.questionView(),
+	})
+}
+
+// AnswerMockInterview grades the answer to the current question, adapts the
+// difficulty and serves the next question
+func AnswerMockInterview(c *fiber.Ctx) error {
+	var request struct {
+		Answer string `json:"answer"`
+	}
+	if err := c.BodyParser(&request); err != nil || strings.TrimSpace(request.Answer) == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "answer is required",
+		})
+	}
+
+	sessionID := c.Params("id")
+	unlock := lockMockSession(sessionID)
+	defer unlock()
+
+	session, err := loadMockSession(sessionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+	if session.Status != MockSessionActive || session.CurrentQuestion == nil {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Session is already completed",
+		})
+	}
+
+	evaluation := evaluateAnswer(context.Background(), *session.CurrentQuestion, request.Answer)
+	session.Transcript = append(session.Transcript, MockInterviewTurn{
+		Question:   *session.CurrentQuestion,
+		Answer:     request.Answer,
+		Evaluation: evaluation,
+		AskedAt:    session.CurrentAskedAt,
+		AnsweredAt: time.Now(),
+	})
+	session.adaptDifficulty(evaluation.Score)
+	session.advance()
+
+	if err := saveMockSession(session); err != nil {
+		log.Printf("Error saving mock interview session: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save session",
+		})
+	}
+
+	response := fiber.Map{
+		"session_id": session.SessionID,
+		"status":     session.Status,
+		"evaluation": evaluation,
+	}
+	if session.Status == MockSessionActive {
+		response["next_question"] = session.questionView()
+	} else {
+		response["report"] = session.Report
+	}
+	return c.JSON(response)
+}
+
+// FinishMockInterview ends a session early and produces its report
+func FinishMockInterview(c *fiber.Ctx) error {
+	sessionID := c.Params("id")
+	unlock := lockMockSession(sessionID)
+	defer unlock()
+
+	session, err := loadMockSession(sessionID)
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+	if session.Status == MockSessionActive {
+		session.complete()
+		if err := saveMockSession(session); err != nil {
+			log.Printf("Error saving mock interview session: %v", err)
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to save session",
+			})
+		}
+	}
+	return c.JSON(session.Report)
+}
+
+// GetMockInterviewReport returns the final report of a completed session
+func GetMockInterviewReport(c *fiber.Ctx) error {
+	session, err := loadMockSession(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+	if session.Report == nil {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Session is still in progress",
+		})
+	}
+	return c.JSON(session.Report)
+}
+
+func mockSessionPath(sessionID string) string {
+	return filepath.Join("processed_texts", "sessions", safeID(sessionID)+".json")
+}
+
+func saveMockSession(session *MockInterviewSession) error {
+	session.UpdatedAt = time.Now()
+	return utils.SaveJSONFile(mockSessionPath(session.SessionID), session, 0644)
+}
+
+func loadMockSession(sessionID string) (*MockInterviewSession, error) {
+	if !strings.HasPrefix(sessionID, "mock_") {
+		return nil, fmt.Errorf("not a mock interview session: %s", sessionID)
+	}
+	var session MockInterviewSession
+	if err := utils.LoadJSONFile(mockSessionPath(sessionID), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func lockMockSession(sessionID string) func() {
+	mu, _ := mockSessionLocks.LoadOrStore(sessionID, &sync.Mutex{})
+	mu.(*sync.Mutex).Lock()
+	return mu.(*sync.Mutex).Unlock
+}
+
+func (s *MockInterviewSession) questionView() *mockQuestionView {
+	if s.CurrentQuestion == nil {
+		return nil
+	}
+	return &mockQuestionView{
+		Number:     len(s.Transcript) + 1,
+		ID:         s.CurrentQuestion.ID,
+		Category:   s.CurrentQuestion.Category,
+		Difficulty: s.CurrentQuestion.Difficulty,
+		Competency: s.CurrentQuestion.Competency,
+		Question:   s.CurrentQuestion.Question,
+	}
+}
+
+// adaptDifficulty moves the target difficulty one step based on the last score
+func (s *MockInterviewSession) adaptDifficulty(score float64) {
+	level := difficultyIndex(s.CurrentDifficulty)
+	switch {
+	case score >= stepUpScore && level < len(difficultyLevels)-1:
+		level++
+	case score <= stepDownScore && level > 0:
+		level--
+	}
+	s.CurrentDifficulty = difficultyLevels[level]
+}
+
+// advance picks the next question closest to the target difficulty,
+// preferring a different category than the last one, or completes the session
+func (s *MockInterviewSession) advance() {
+	s.CurrentQuestion = nil
+	if len(s.Transcript) >= s.MaxQuestions || len(s.QuestionPool) == 0 {
+		s.complete()
+		return
+	}
+
+	lastCategory := ""
+	if n := len(s.Transcript); n > 0 {
+		lastCategory = s.Transcript[n-1].Question.Category
+	}
+
+	target := difficultyIndex(s.CurrentDifficulty)
+	best, bestCost := 0, -1
+	for i, q := range s.QuestionPool {
+		cost := abs(difficultyIndex(q.Difficulty)-target) * 2
+		if q.Category == lastCategory {
+			cost++
+		}
+		if bestCost < 0 || cost < bestCost {
+			best, bestCost = i, cost
+		}
+	}
+
+	next := s.QuestionPool[best]
+	s.QuestionPool = append(s.QuestionPool[:best], s.QuestionPool[best+1:]...)
+	s.CurrentQuestion = &next
+	s.CurrentAskedAt = time.Now()
+}
+
+func (s *MockInterviewSession) complete() {
+	s.Status = MockSessionCompleted
+	s.CurrentQuestion = nil
+	s.Report = buildMockInterviewReport(s)
+}
+
+func difficultyIndex(difficulty string) int {
+	for i, level := range difficultyLevels {
+		if level == difficulty {
+			return i
+		}
+	}
+	return 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// evaluateAnswer grades an answer with the LLM, falling back to rubric
+// keyword coverage when the LLM is unavailable
+func evaluateAnswer(ctx context.Context, question InterviewQuestion, answer string) AnswerEvaluation {
+	evaluation, err := evaluateAnswerWithLLM(ctx, question, answer)
+	if err != nil {
+		log.Printf("Falling back to heuristic answer grading: %v", err)
+		return evaluateAnswerHeuristically(question, answer)
+	}
+	return *evaluation
+}
+
+func evaluateAnswerWithLLM(ctx context.Context, question InterviewQuestion, answer string) (*AnswerEvaluation, error) {
+	llm, err := newLLMProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer llm.Close()
+
+	prompt := fmt.Sprintf(`You are an interviewer grading a candidate's answer.
+
+Question (%s, %s difficulty, competency: %s): %s
+A good answer includes:
+- %s
+
+Candidate's answer: %s
+
+Return only JSON:
+{"score": 0-10, "feedback": "", "strengths": [""], "improvements": [""], "rubric_coverage": ["rubric points the answer covered"]}`,
+		question.Category, question.Difficulty, question.Competency, question.Question,
+		strings.Join(question.GoodAnswerIncludes, "\n- "),
+		sanitizeText(answer, maxAnswerLength))
+
+	text, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr := cleanJSONString(text)
+	if jsonStr == "" {
+		return nil, fmt.Errorf("no JSON in model response")
+	}
+
+	var evaluation AnswerEvaluation
+	if err := json.Unmarshal([]byte(jsonStr), &evaluation); err != nil {
+		return nil, err
+	}
+	evaluation.Score = clampScore(evaluation.Score)
+	evaluation.Source = "llm"
+	return &evaluation, nil
+}
+
+// evaluateAnswerHeuristically scores an answer by how many rubric points it
+// touches and how much detail it gives
+func evaluateAnswerHeuristically(question InterviewQuestion, answer string) AnswerEvaluation {
+	answerLower := strings.ToLower(answer)
+	evaluation := AnswerEvaluation{
+		Strengths:      []string{},
+		Improvements:   []string{},
+		RubricCoverage: []string{},
+		Source:         "heuristic",
+	}
+
+	for _, point := range question.GoodAnswerIncludes {
+		hits, total := 0, 0
+		for _, word := range strings.Fields(strings.ToLower(point)) {
+			word = strings.Trim(word, ".,()")
+			if len(word) < 4 || isStopWord(word) {
+				continue
+			}
+			total++
+			if strings.Contains(answerLower, word) {
+				hits++
+			}
+		}
+		if total > 0 && float64(hits)/float64(total) >= 0.3 {
+			evaluation.RubricCoverage = append(evaluation.RubricCoverage, point)
+			evaluation.Strengths = append(evaluation.Strengths, "Covered: "+point)
+		} else {
+			evaluation.Improvements = append(evaluation.Improvements, "Address: "+point)
+		}
+	}
+
+	coverage := 1.0
+	if len(question.GoodAnswerIncludes) > 0 {
+		coverage = float64(len(evaluation.RubricCoverage)) / float64(len(question.GoodAnswerIncludes))
+	}
+	detail := math.Min(float64(len(strings.Fields(answer)))/120.0, 1.0)
+	evaluation.Score = clampScore(coverage*7 + detail*3)
+
+	switch {
+	case evaluation.Score >= stepUpScore:
+		evaluation.Feedback = "Strong answer that covers most of what the interviewer is looking for."
+	case evaluation.Score > stepDownScore:
+		evaluation.Feedback = "Reasonable answer; add specifics on the missing points to make it convincing."
+	default:
+		evaluation.Feedback = "The answer misses most of the expected points; use a concrete example and explain your reasoning."
+	}
+	return evaluation
+}
+
+func clampScore(score float64) float64 {
+	return math.Max(0, math.Min(10, score))
+}
+
+// buildMockInterviewReport aggregates the transcript into a final report
+func buildMockInterviewReport(s *MockInterviewSession) *MockInterviewReport {
+	report := &MockInterviewReport{
+		QuestionsAnswered: len(s.Transcript),
+		FinalDifficulty:   s.CurrentDifficulty,
+		CompetencyScores:  make(map[string]float64),
+		CategoryScores:    make(map[string]float64),
+		Strengths:         []string{},
+		FocusAreas:        []string{},
+	}
+	if len(s.Transcript) == 0 {
+		report.Summary = "No questions were answered."
+		return report
+	}
+
+	byCompetency := make(map[string][]float64)
+	byCategory := make(map[string][]float64)
+	var scores []float64
+	for _, turn := range s.Transcript {
+		score := turn.Evaluation.Score
+		scores = append(scores, score)
+		byCompetency[turn.Question.Competency] = append(byCompetency[turn.Question.Competency], score)
+		byCategory[turn.Question.Category] = append(byCategory[turn.Question.Category], score)
+	}
+	report.OverallScore = mean(scores)
+	for competency, values := range byCompetency {
+		report.CompetencyScores[competency] = mean(values)
+	}
+	for category, values := range byCategory {
+		report.CategoryScores[category] = mean(values)
+	}
+
+	for _, competency := range sortedKeys(report.CompetencyScores) {
+		switch score := report.CompetencyScores[competency]; {
+		case score >= stepUpScore:
+			report.Strengths = append(report.Strengths, competency)
+		case score <= stepDownScore+1:
+			report.FocusAreas = append(report.FocusAreas, competency)
+		}
+	}
+	sort.SliceStable(report.FocusAreas, func(i, j int) bool {
+		return report.CompetencyScores[report.FocusAreas[i]] < report.CompetencyScores[report.FocusAreas[j]]
+	})
+
+	report.Summary = fmt.Sprintf("Answered %d questions with an average score of %.1f/10, finishing at %s difficulty.",
+		report.QuestionsAnswered, report.OverallScore, report.FinalDifficulty)
+	if len(report.FocusAreas) > 0 {
+		report.Summary += " Practice next: " + strings.Join(report.FocusAreas, ", ") + "."
+	}
+	return report
+}
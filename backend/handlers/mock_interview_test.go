@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func mockQuestion(id, category, difficulty string) InterviewQuestion {
+	return InterviewQuestion{
+		ID:                 id,
+		Category:           category,
+		Difficulty:         difficulty,
+		Competency:         category + " skills",
+		Question:           "Question " + id,
+		GoodAnswerIncludes: []string{"secret rubric for " + id},
+	}
+}
+
+func TestGetMockInterviewHidesRubric(t *testing.T) {
+	session := &MockInterviewSession{
+		SessionID:         "mock_3100",
+		Status:            MockSessionActive,
+		MaxQuestions:      3,
+		CurrentDifficulty: "medium",
+		QuestionPool:      []InterviewQuestion{mockQuestion("q1", "technical", "medium"), mockQuestion("q2", "behavioral", "hard")},
+		Transcript: []MockInterviewTurn{
+			{Question: mockQuestion("q0", "technical", "easy"), Answer: "answered"},
+		},
+	}
+	session.advance()
+	if err := saveMockSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/mock/:id", GetMockInterview)
+	resp, err := app.Test(httptest.NewRequest("GET", "/mock/mock_3100", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	var view map[string]json.RawMessage
+	if err := json.Unmarshal(body, &view); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		snippet string
+		want    bool
+	}{
+		{"current question text", "Question q1", true},
+		{"current rubric", "secret rubric for q1", false},
+		{"unasked question", "Question q2", false},
+		{"unasked rubric", "secret rubric for q2", false},
+		{"answered rubric kept for review", "secret rubric for q0", true},
+	}
+	for _, tt := range tests {
+		if got := strings.Contains(string(body), tt.snippet); got != tt.want {
+			t.Errorf("%s: response contains %q = %v, want %v", tt.name, tt.snippet, got, tt.want)
+		}
+	}
+	if _, ok := view["question_pool"]; ok {
+		t.Error("response has a question_pool")
+	}
+	if resp, _ := app.Test(httptest.NewRequest("GET", "/mock/../../etc", nil)); resp.StatusCode != 404 {
+		t.Errorf("non-session ID: status = %d, want 404", resp.StatusCode)
+	}
+}
+
+func TestMockInterviewAdaptsDifficulty(t *testing.T) {
+	tests := []struct {
+		from  string
+		score float64
+		want  string
+	}{
+		{"medium", 9, "hard"},
+		{"hard", 10, "hard"},
+		{"medium", 8, "hard"},
+		{"medium", 6, "medium"},
+		{"medium", 4, "easy"},
+		{"easy", 0, "easy"},
+		{"unknown", 9, "hard"},
+	}
+	for _, tt := range tests {
+		session := &MockInterviewSession{CurrentDifficulty: tt.from}
+		session.adaptDifficulty(tt.score)
+		if session.CurrentDifficulty != tt.want {
+			t.Errorf("%s after %.0f = %s, want %s", tt.from, tt.score, session.CurrentDifficulty, tt.want)
+		}
+	}
+}
+
+func TestMockInterviewAdvance(t *testing.T) {
+	pool := func() []InterviewQuestion {
+		return []InterviewQuestion{
+			mockQuestion("easy-tech", "technical", "easy"),
+			mockQuestion("hard-tech", "technical", "hard"),
+			mockQuestion("hard-behav", "behavioral", "hard"),
+		}
+	}
+	tests := []struct {
+		name         string
+		difficulty   string
+		lastCategory string
+		answered     int
+		want         string
+	}{
+		{"closest difficulty", "easy", "", 0, "easy-tech"},
+		{"prefers a new category", "hard", "technical", 1, "hard-behav"},
+		{"difficulty beats category", "easy", "technical", 1, "easy-tech"},
+		{"completes at the limit", "medium", "", 3, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			session := &MockInterviewSession{MaxQuestions: 3, CurrentDifficulty: tt.difficulty, QuestionPool: pool(), Status: MockSessionActive}
+			for i := 0; i < tt.answered; i++ {
+				session.Transcript = append(session.Transcript, MockInterviewTurn{Question: InterviewQuestion{Category: tt.lastCategory}})
+			}
+			session.advance()
+			got := ""
+			if session.CurrentQuestion != nil {
+				got = session.CurrentQuestion.ID
+			}
+			if got != tt.want {
+				t.Errorf("next question = %q, want %q", got, tt.want)
+			}
+			if tt.want == "" && (session.Status != MockSessionCompleted || session.Report == nil) {
+				t.Error("session at its limit should complete with a report")
+			}
+			if tt.want != "" && len(session.QuestionPool) != 2 {
+				t.Errorf("pool has %d questions, want the asked one removed", len(session.QuestionPool))
+			}
+		})
+	}
+}
+
+func TestEvaluateAnswerHeuristically(t *testing.T) {
+	question := InterviewQuestion{GoodAnswerIncludes: []string{
+		"Explains database indexing tradeoffs",
+		"Mentions query profiling",
+	}}
+	tests := []struct {
+		name     string
+		answer   string
+		covered  int
+		min, max float64
+	}{
+		{"covers both points", "I would start with query profiling, then weigh indexing tradeoffs for the database writes.", 2, 7, 8},
+		{"covers one point", "I would use profiling on the slow query.", 1, 3.5, 4},
+		{"covers nothing", "I am not sure.", 0, 0, 0.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			evaluation := evaluateAnswerHeuristically(question, tt.answer)
+			if len(evaluation.RubricCoverage) != tt.covered {
+				t.Errorf("covered %v, want %d points", evaluation.RubricCoverage, tt.covered)
+			}
+			if evaluation.Score < tt.min || evaluation.Score > tt.max {
+				t.Errorf("score = %.2f, want between %.1f and %.1f", evaluation.Score, tt.min, tt.max)
+			}
+			if len(evaluation.Strengths)+len(evaluation.Improvements) != len(question.GoodAnswerIncludes) {
+				t.Errorf("every rubric point should be a strength or an improvement: %+v", evaluation)
+			}
+		})
+	}
+}
+
+func TestBuildMockInterviewReport(t *testing.T) {
+	turn := func(competency string, score float64) MockInterviewTurn {
+		return MockInterviewTurn{
+			Question:   InterviewQuestion{Competency: competency, Category: "technical"},
+			Evaluation: AnswerEvaluation{Score: score},
+		}
+	}
+	session := &MockInterviewSession{
+		CurrentDifficulty: "hard",
+		Transcript:        []MockInterviewTurn{turn("Go", 9), turn("Go", 8), turn("SQL", 5), turn("Testing", 2)},
+	}
+
+	report := buildMockInterviewReport(session)
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"overall", report.OverallScore, 6.0},
+		{"answered", report.QuestionsAnswered, 4},
+		{"Go score", report.CompetencyScores["Go"], 8.5},
+		{"strengths", strings.Join(report.Strengths, ","), "Go"},
+		{"focus areas, weakest first", strings.Join(report.FocusAreas, ","), "Testing,SQL"},
+		{"category", report.CategoryScores["technical"], 6.0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+	if empty := buildMockInterviewReport(&MockInterviewSession{}); empty.Summary != "No questions were answered." {
+		t.Errorf("empty report summary = %q", empty.Summary)
+	}
+}
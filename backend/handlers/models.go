@@ -6,8 +6,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 	"time"
+
+	"interviewme/utils"
 )
 
 // TextData represents the processed text data structure
@@ -21,6 +24,9 @@ type TextData struct {
 	SoftSkills      []string          `json:"soft_skills,omitempty"`
 	TechnicalSkills []string          `json:"technical_skills,omitempty"`
 	RawJSON         string            `json:"raw_json,omitempty"`
+	Version         int               `json:"version,omitempty"`         // job descriptions only
+	IntegrityFlags  []IntegrityFlag   `json:"integrity_flags,omitempty"` // resumes only
+	Source          map[string]string `json:"source,omitempty"`          // resumes only: how the file arrived
 }
 
 // ExtractedEntities represents the entities extracted from text
@@ -32,6 +38,28 @@ type ExtractedEntities struct {
 	Education  []Education  `json:"education"`
 	Projects   []Project    `json:"projects"`
 	Experience []Experience `json:"experience"`
+
+	Certificates []Certificate        `json:"certificates,omitempty"`
+	Preferences  CandidatePreferences `json:"preferences"`
+}
+
+// Certificate is a certification or licence listed on a resume
+type Certificate struct {
+	Name   string `json:"name"`
+	Issuer string `json:"issuer,omitempty"`
+	Date   string `json:"date,omitempty"`
+	URL    string `json:"url,omitempty"`
+}
+
+// CandidatePreferences holds the logistics a resume states about the candidate
+type CandidatePreferences struct {
+	Location            string   `json:"location,omitempty"`
+	WillingToRelocate   *bool    `json:"willing_to_relocate,omitempty"`
+	WorkAuthorization   string   `json:"work_authorization,omitempty"`
+	RequiresSponsorship *bool    `json:"requires_sponsorship,omitempty"`
+	DesiredRoles        []string `json:"desired_roles,omitempty"`
+	WorkArrangements    []string `json:"work_arrangements,omitempty"` // remote, hybrid or onsite
+	EmploymentTypes     []string `json:"employment_types,omitempty"`
 }
 
 // Education represents educational background
@@ -87,6 +115,31 @@ type JobRequirements struct {
 		Qualifications []string `json:"qualifications"`
 	} `json:"education"`
 	Responsibilities []string `json:"responsibilities"`
+
+	// Structured posting details, used for filtering and knockouts
+	Title           string       `json:"title,omitempty"`
+	Salary          *SalaryRange `json:"salary,omitempty"`
+	Locations       []string     `json:"locations,omitempty"`
+	WorkArrangement string       `json:"work_arrangement,omitempty"` // remote, hybrid or onsite
+	EmploymentType  string       `json:"employment_type,omitempty"`
+	Seniority       string       `json:"seniority,omitempty"`
+	Department      string       `json:"department,omitempty"`
+	VisaSponsorship *bool        `json:"visa_sponsorship,omitempty"` // nil when the posting doesn't say
+	Travel          *TravelInfo  `json:"travel,omitempty"`
+}
+
+// SalaryRange is the advertised pay for a job
+type SalaryRange struct {
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Currency string  `json:"currency"`
+	Period   string  `json:"period"` // yearly, monthly or hourly
+}
+
+// TravelInfo describes a job's travel requirement
+type TravelInfo struct {
+	Required bool `json:"required"`
+	Percent  int  `json:"percent,omitempty"`
 }
 
 type Entities struct {
@@ -146,3 +199,34 @@ func LoadTextData(id string, textType string) (*TextData, error) {
 
 	return &data, nil
 }
+
+// updateResumeRecord applies update to a saved resume record and writes it back
+func updateResumeRecord(resumeID string, update func(*TextData)) error {
+	data, err := LoadTextData(resumeID, "resume")
+	if err != nil {
+		return err
+	}
+	update(data)
+	path := filepath.Join("processed_texts", "resume", fmt.Sprintf("resume_%s.json", pathID(resumeID, "resume")))
+	return utils.SaveJSONFile(path, data, 0644)
+}
+
+// unsafeIDChars matches anything that can't appear in a stored ID. IDs are
+// timestamps, optionally behind a type prefix such as "task_".
+var unsafeIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]`)
+
+// safeID confines an ID taken from a request to a single file name, so it
+// can't name a path outside the directory it is joined to
+func safeID(id string) string {
+	return unsafeIDChars.ReplaceAllString(id, "_")
+}
+
+// pathID is normalizeID for IDs that become part of a file path
+func pathID(id string, textType string) string {
+	return safeID(normalizeID(id, textType))
+}
+
+// normalizeID strips the type prefix and .json suffix, leaving the bare ID
+func normalizeID(id string, textType string) string {
+	return cleanID(strings.TrimSuffix(id, ".json"), textType)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPathID(t *testing.T) {
+	tests := []struct {
+		id, textType, want string
+	}{
+		{"1718000000", "job", "1718000000"},
+		{"job_1718000000", "job", "1718000000"},
+		{"resume_1718000000.json", "resume", "1718000000"},
+		{"", "job", ""},
+		{"../../.env", "job", "_______env"},
+		{"..", "resume", "__"},
+		{`..\..\secrets`, "job", "______secrets"},
+		{"12*", "job", "12_"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			if got := pathID(tt.id, tt.textType); got != tt.want {
+				t.Errorf("pathID(%q) = %q, want %q", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPathBuildersStayInTheirDirectory(t *testing.T) {
+	const hostile = "../../../etc/passwd"
+	paths := map[string]string{
+		"score":     scoreRecordPath(hostile, hostile),
+		"kit":       interviewKitPath(hostile, hostile),
+		"screening": screeningSettingsPath(hostile),
+		"vault":     redactionVaultPath(hostile),
+		"analysis":  analysisResultPath("projects", hostile, hostile),
+		"version":   jobVersionPath(hostile, 1),
+		"task":      taskPath(hostile),
+		"session":   mockSessionPath(hostile),
+		"candidate": candidatePath(hostile),
+	}
+	for name, path := range paths {
+		clean := filepath.Clean(path)
+		if !strings.HasPrefix(clean, "processed_texts"+string(filepath.Separator)) || strings.Contains(clean, "..") {
+			t.Errorf("%s path %q leaves processed_texts", name, path)
+		}
+	}
+}
@@ -17,6 +17,13 @@ func DisplayPDF(c *fiber.Ctx) error {
 		})
 	}
 
+	// Original files of anonymized resumes may carry photos or contact details
+	if isBlindUpload(filepath.Base(filename)) {
+		return c.Status(403).JSON(fiber.Map{
+			"error": "Original file is hidden while blind screening is enabled",
+		})
+	}
+
 	// Ensure the path is within uploads directory
 	filePath := filepath.Join("uploads", filename)
 	absPath, err := filepath.Abs(filePath)
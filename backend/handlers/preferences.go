@@ -0,0 +1,381 @@
+package handlers
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const headerLines = 8 // Contact details sit in the first few lines of a resume
+
+// Logistics fit levels
+const (
+	LogisticsGood          = "good"
+	LogisticsPartial       = "partial"
+	LogisticsPoor          = "poor"
+	LogisticsNoConstraints = "no_constraints"
+)
+
+// logisticsWeights is how much each factor counts toward the logistics score
+var logisticsWeights = map[string]float64{
+	"location":         0.4,
+	"work_arrangement": 0.3,
+	"visa_sponsorship": 0.2,
+	"desired_role":     0.1,
+}
+
+var (
+	prefLocationLine = regexp.MustCompile(`(?im)^\s*(?:location|address|based in|current location)\s*:\s*(.+)$`)
+	cityRegionSeg    = regexp.MustCompile(`^[A-Z][A-Za-z.'-]*(?: [A-Z][A-Za-z.'-]*)*, [A-Z][A-Za-z.'-]*(?: [A-Z][A-Za-z.'-]*)*$`)
+	contactSeparator = regexp.MustCompile(`\s*[|•·]\s*`)
+
+	relocateNo  = regexp.MustCompile(`(?i)(not (open|willing) to relocat|unable to relocat|cannot relocat|no relocation)`)
+	relocateYes = regexp.MustCompile(`(?i)((open|willing|happy|able|ready) to relocat|relocation: ?yes|will relocate)`)
+
+	// In free text, citizenship and residency only count when phrased as the
+	// candidate's own status, so "Citizens Bank" or "green card processing"
+	// don't match; the work authorization field is already about status
+	workAuthPattern  = regexp.MustCompile(`(?i)\b(u\.?s\.? citizen(ship)?|green card( holder)?|permanent resident|eu citizen(ship)?|authori[sz]ed to work in (?:the )?[A-Z][\w.]*(?: [A-Z][\w.]*)*|h-?1b( visa)?|tn visa|stem opt|opt ead|work permit|citizen of [A-Z][\w]*)`)
+	sponsorNeeded    = regexp.MustCompile(`(?i)(requires? (visa )?sponsorship|need(s|ing)? (visa )?sponsorship|h-?1b transfer|will require sponsorship)`)
+	sponsorNotNeeded = regexp.MustCompile(`(?i)(no sponsorship (is )?(required|needed)|(do|does) not (require|need) (visa )?sponsorship|without (the need for )?sponsorship|\b(u\.?s\.?|us|united states|eu|e\.u\.|uk|american|canadian|british|australian|indian) citizen(ship)?\b|\b(am|is) an? (\w+ )?citizen\b|\bcitizen of [A-Z]|\b(hold|holds|holding|have|has) an? (u\.?s\.? )?green card\b|\bgreen card holder\b|\b(am|is) an? (lawful |u\.?s\.? )?permanent resident\b|\bpermanent resident (status|of [A-Z]))`)
+	noSponsorStatus  = regexp.MustCompile(`(?i)\b(citizen(ship)?|green card|permanent resident)\b`)
+
+	desiredRoleLine    = regexp.MustCompile(`(?im)^\s*(?:desired (?:role|position)|target (?:role|position)|objective|seeking)\s*:\s*(.+)$`)
+	desiredRolePattern = regexp.MustCompile(`(?i)\b(?:seeking|looking for|pursuing|interested in)\s+(?:an?\s+|the\s+)?((?:[\w/+#.-]+\s){0,4}?(?:engineer|developer|manager|analyst|designer|scientist|architect|consultant|specialist|administrator|lead|researcher))\b`)
+	preferenceContext  = regexp.MustCompile(`(?i)\b(open to|prefer|preference|seeking|looking for|available for|interested in)\b`)
+	roleStopWords      = map[string]bool{"a": true, "an": true, "the": true, "of": true, "and": true, "role": true, "position": true}
+)
+
+// parsePreferences reads the "preferences" object of the entity extraction response
+func parsePreferences(raw map[string]interface{}) CandidatePreferences {
+	prefs := CandidatePreferences{
+		Location:          getString(raw, "location"),
+		WorkAuthorization: getString(raw, "work_authorization"),
+		DesiredRoles:      stringSlice(raw["desired_roles"]),
+		WorkArrangements:  stringSlice(raw["work_arrangements"]),
+		EmploymentTypes:   stringSlice(raw["employment_types"]),
+	}
+	if v, ok := raw["willing_to_relocate"].(bool); ok {
+		prefs.WillingToRelocate = &v
+	}
+	if v, ok := raw["requires_sponsorship"].(bool); ok {
+		prefs.RequiresSponsorship = &v
+	}
+	return prefs
+}
+
+func stringSlice(raw interface{}) []string {
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		if s, ok := item.(string); ok && strings.TrimSpace(s) != "" {
+			values = append(values, strings.TrimSpace(s))
+		}
+	}
+	return values
+}
+
+// fillCandidatePreferences normalizes the extracted preferences and fills
+// any gaps from the raw resume text
+func fillCandidatePreferences(prefs *CandidatePreferences, text string) {
+	prefs.Location = cleanLocation(prefs.Location)
+	if prefs.Location == "" {
+		prefs.Location = parseCandidateLocation(text)
+	}
+
+	if prefs.WillingToRelocate == nil {
+		switch {
+		case relocateNo.MatchString(text):
+			no := false
+			prefs.WillingToRelocate = &no
+		case relocateYes.MatchString(text):
+			yes := true
+			prefs.WillingToRelocate = &yes
+		}
+	}
+
+	prefs.WorkAuthorization = strings.TrimSpace(prefs.WorkAuthorization)
+	if prefs.WorkAuthorization == "" {
+		prefs.WorkAuthorization = strings.TrimSpace(workAuthPattern.FindString(text))
+	}
+	if prefs.RequiresSponsorship == nil {
+		switch {
+		case sponsorNeeded.MatchString(text):
+			yes := true
+			prefs.RequiresSponsorship = &yes
+		case sponsorNotNeeded.MatchString(text) || noSponsorStatus.MatchString(prefs.WorkAuthorization):
+			no := false
+			prefs.RequiresSponsorship = &no
+		}
+	}
+
+	if len(prefs.DesiredRoles) == 0 {
+		prefs.DesiredRoles = parseDesiredRoles(text)
+	}
+	prefs.DesiredRoles = uniqueStrings(prefs.DesiredRoles)
+
+	// Arrangements and employment types only count when stated as a preference,
+	// not when they describe a past job
+	var statements []string
+	for _, line := range strings.Split(text, "\n") {
+		if preferenceContext.MatchString(line) {
+			statements = append(statements, line)
+		}
+	}
+	stated := strings.Join(statements, "\n")
+
+	prefs.WorkArrangements = normalizeOptions(prefs.WorkArrangements, normalizeWorkArrangement)
+	if len(prefs.WorkArrangements) == 0 && stated != "" {
+		for _, pattern := range []*regexp.Regexp{remotePattern, hybridPattern, onsitePattern} {
+			if m := pattern.FindString(stated); m != "" {
+				prefs.WorkArrangements = append(prefs.WorkArrangements, normalizeWorkArrangement(m))
+			}
+		}
+	}
+	prefs.EmploymentTypes = normalizeOptions(prefs.EmploymentTypes, normalizeEmploymentType)
+	if len(prefs.EmploymentTypes) == 0 && stated != "" {
+		for _, kind := range employmentTypes {
+			if employmentRegs[kind].MatchString(stated) {
+				prefs.EmploymentTypes = append(prefs.EmploymentTypes, kind)
+			}
+		}
+	}
+}
+
+func normalizeOptions(options []string, normalize func(string) string) []string {
+	var normalized []string
+	seen := make(map[string]bool)
+	for _, option := range options {
+		if v := normalize(option); v != "" && !seen[v] {
+			seen[v] = true
+			normalized = append(normalized, v)
+		}
+	}
+	return normalized
+}
+
+// parseCandidateLocation looks for a labelled location, then a "City, Region"
+// segment in the contact lines at the top of the resume
+func parseCandidateLocation(text string) string {
+	if m := prefLocationLine.FindStringSubmatch(text); m != nil {
+		if location := cleanLocation(m[1]); location != "" {
+			return location
+		}
+	}
+	lines := strings.Split(text, "\n")
+	for _, line := range lines[:min(len(lines), headerLines)] {
+		for _, segment := range contactSeparator.Split(strings.TrimSpace(line), -1) {
+			if cityRegionSeg.MatchString(segment) {
+				return segment
+			}
+		}
+	}
+	return ""
+}
+
+func parseDesiredRoles(text string) []string {
+	var roles []string
+	for _, m := range desiredRoleLine.FindAllStringSubmatch(text, -1) {
+		if role := strings.Trim(strings.TrimSpace(m[1]), "."); role != "" && len(strings.Fields(role)) <= 6 {
+			roles = append(roles, role)
+		}
+	}
+	for _, m := range desiredRolePattern.FindAllStringSubmatch(text, -1) {
+		roles = append(roles, strings.TrimSpace(m[1]))
+	}
+	return roles
+}
+
+// LogisticsFit scores how well a candidate's stated logistics suit a job,
+// separately from skills fit
+type LogisticsFit struct {
+	Score   float64          `json:"score"` // 0-100
+	Level   string           `json:"level"`
+	Factors []KnockoutResult `json:"factors"`
+}
+
+// logisticsFit compares the candidate's location, arrangement, authorization
+// and desired roles with the job. Unknown factors count half.
+func logisticsFit(req JobRequirements, answers KnockoutAnswers) LogisticsFit {
+	fit := LogisticsFit{Factors: []KnockoutResult{}}
+	for _, result := range evaluateKnockouts(req, answers) {
+		if _, ok := logisticsWeights[result.Field]; ok {
+			fit.Factors = append(fit.Factors, result)
+		}
+	}
+
+	// A remote job suits any location, which evaluateKnockouts doesn't report
+	if req.WorkArrangement == WorkRemote {
+		fit.Factors = append(fit.Factors, KnockoutResult{
+			Field: "location", Requirement: WorkRemote, Candidate: answers.Location,
+			Status: KnockoutPass, Reason: "Role is remote",
+		})
+	}
+
+	if req.Title != "" && len(answers.DesiredRoles) > 0 {
+		result := KnockoutResult{Field: "desired_role", Requirement: req.Title, Candidate: strings.Join(answers.DesiredRoles, ", ")}
+		if roleMatches(req.Title, answers.DesiredRoles) {
+			result.Status, result.Reason = KnockoutPass, "Role matches what the candidate is seeking"
+		} else {
+			result.Status, result.Reason = KnockoutFail, "Candidate is seeking a different kind of role"
+		}
+		fit.Factors = append(fit.Factors, result)
+	}
+
+	if len(fit.Factors) == 0 {
+		fit.Score, fit.Level = maxScore, LogisticsNoConstraints
+		return fit
+	}
+
+	var total, weight float64
+	for _, factor := range fit.Factors {
+		w := logisticsWeights[factor.Field]
+		weight += w
+		switch factor.Status {
+		case KnockoutPass:
+			total += w
+		case KnockoutUnknown:
+			total += w * 0.5
+		}
+	}
+	// Round so float weight sums can't drop an exact boundary into the level below
+	fit.Score = math.Round(total/weight*maxScore*100) / 100
+	switch {
+	case fit.Score >= 80:
+		fit.Level = LogisticsGood
+	case fit.Score >= 50:
+		fit.Level = LogisticsPartial
+	default:
+		fit.Level = LogisticsPoor
+	}
+	return fit
+}
+
+// roleMatches reports whether any desired role shares at least half its
+// meaningful words with the job title, ignoring seniority
+func roleMatches(title string, desired []string) bool {
+	titleWords := roleWords(title)
+	for _, role := range desired {
+		words := roleWords(role)
+		if len(words) == 0 {
+			continue
+		}
+		shared := 0
+		for word := range words {
+			if titleWords[word] {
+				shared++
+			}
+		}
+		if float64(shared)/float64(len(words)) >= 0.5 {
+			return true
+		}
+	}
+	return false
+}
+
+func roleWords(role string) map[string]bool {
+	words := make(map[string]bool)
+	for _, word := range strings.FieldsFunc(strings.ToLower(role), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z') && !(r >= '0' && r <= '9') && r != '+' && r != '#'
+	}) {
+		if roleStopWords[word] || normalizeSeniority(word) != "" {
+			continue
+		}
+		words[word] = true
+	}
+	return words
+}
+
+// CandidateFilterEntry is one scored candidate in a job's filtered list
+type CandidateFilterEntry struct {
+	ResumeID     string       `json:"resume_id"`
+	Name         string       `json:"name"`
+	OverallScore float64      `json:"overall_score"`
+	LogisticsFit LogisticsFit `json:"logistics_fit"`
+	KnockedOut   bool         `json:"knocked_out"`
+	Knockouts    []string     `json:"failed_knockouts"`
+}
+
+// ListJobCandidates lists the candidates scored against a job, filtered on
+// skills score, logistics fit and knockouts independently
+func ListJobCandidates(c *fiber.Ctx) error {
+	jobID := normalizeID(c.Params("id"), "job")
+	minScore, err := queryFloat(c, "min_score")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	minLogistics, err := queryFloat(c, "min_logistics")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+	}
+	level := c.Query("logistics_level")
+	excludeKnockedOut := c.QueryBool("exclude_knocked_out", false)
+
+	records, err := loadScoreRecordsForJob(jobID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load scores",
+		})
+	}
+
+	blind := isBlindScreeningJob(jobID)
+	candidates := []CandidateFilterEntry{}
+	for _, record := range records {
+		score := record.Score
+		if score.OverallScore < minScore || score.LogisticsFit.Score < minLogistics {
+			continue
+		}
+		if (level != "" && score.LogisticsFit.Level != level) || (excludeKnockedOut && score.KnockedOut) {
+			continue
+		}
+
+		entry := CandidateFilterEntry{
+			ResumeID:     record.ResumeID,
+			Name:         score.ProcessedEntities.Name,
+			OverallScore: score.OverallScore,
+			LogisticsFit: score.LogisticsFit,
+			KnockedOut:   score.KnockedOut,
+			Knockouts:    []string{},
+		}
+		if blind {
+			entry.Name = candidatePseudonym(record.ResumeID)
+		}
+		for _, result := range score.Knockouts {
+			if result.Status == KnockoutFail {
+				entry.Knockouts = append(entry.Knockouts, result.Field)
+			}
+		}
+		candidates = append(candidates, entry)
+	}
+
+	if c.Query("sort") == "logistics" {
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].LogisticsFit.Score > candidates[j].LogisticsFit.Score
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"job_id":     jobID,
+		"count":      len(candidates),
+		"candidates": candidates,
+	})
+}
+
+func queryFloat(c *fiber.Ctx, name string) (float64, error) {
+	v := c.Query(name)
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, fiber.NewError(400, name+" must be a number")
+	}
+	return f, nil
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestParsePreferences(t *testing.T) {
+	raw := map[string]interface{}{
+		"location":             "Leeds, UK",
+		"willing_to_relocate":  true,
+		"requires_sponsorship": "no",
+		"desired_roles":        []interface{}{"Backend Engineer", " ", 7},
+		"work_arrangements":    "remote",
+	}
+	prefs := parsePreferences(raw)
+
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"location", prefs.Location, "Leeds, UK"},
+		{"willing to relocate", formatOptionalBool(prefs.WillingToRelocate), "true"},
+		{"non-boolean sponsorship is unknown", formatOptionalBool(prefs.RequiresSponsorship), ""},
+		{"roles keep only strings", strings.Join(prefs.DesiredRoles, ","), "Backend Engineer"},
+		{"non-list arrangements are dropped", len(prefs.WorkArrangements), 0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestFillCandidatePreferences(t *testing.T) {
+	tests := []struct {
+		name         string
+		prefs        CandidatePreferences
+		text         string
+		location     string
+		relocate     string
+		sponsorship  string
+		roles        []string
+		arrangements []string
+		types        []string
+	}{
+		{
+			name:     "contact line location",
+			text:     "Jane Doe\njane@example.com | Austin, TX | +1 555 123 4567\nExperience",
+			location: "Austin, TX",
+		},
+		{
+			name:     "labelled location",
+			text:     "Jane Doe\nLocation: Berlin, Germany",
+			location: "Berlin, Germany",
+		},
+		{
+			name:     "extracted remote is not a place",
+			prefs:    CandidatePreferences{Location: "Remote"},
+			text:     "Jane Doe\nLeeds, UK",
+			location: "Leeds, UK",
+		},
+		{
+			name:     "not willing to relocate",
+			text:     "Not willing to relocate.",
+			relocate: "false",
+		},
+		{
+			name:     "open to relocation",
+			text:     "Open to relocation within Europe.",
+			relocate: "true",
+		},
+		{
+			name:        "needs sponsorship",
+			text:        "Will require sponsorship for an H-1B transfer.",
+			sponsorship: "true",
+		},
+		{
+			name:        "citizen",
+			text:        "US citizen, available immediately.",
+			sponsorship: "false",
+		},
+		{
+			name: "employer named after citizens",
+			text: "Software Engineer, Citizens Bank, 2019 - 2022",
+		},
+		{
+			name:        "authorization field states a status",
+			prefs:       CandidatePreferences{WorkAuthorization: "Permanent resident"},
+			sponsorship: "false",
+		},
+		{
+			name:  "desired role line and phrase",
+			text:  "Objective: Platform Engineer\nLooking for a senior backend engineer role.",
+			roles: []string{"Platform Engineer", "senior backend engineer"},
+		},
+		{
+			name:         "stated preferences only",
+			text:         "Remote contractor at Acme, 2020 - 2022\nOpen to hybrid or on-site, full-time roles.",
+			arrangements: []string{WorkHybrid, WorkOnsite},
+			types:        []string{"full_time"},
+		},
+		{
+			name:         "extracted options are normalized",
+			prefs:        CandidatePreferences{WorkArrangements: []string{"Remote", "remote-first", "beach"}, EmploymentTypes: []string{"Freelance"}},
+			arrangements: []string{WorkRemote},
+			types:        []string{"contract"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := tt.prefs
+			fillCandidatePreferences(&prefs, tt.text)
+			got := []struct {
+				field     string
+				got, want string
+			}{
+				{"location", prefs.Location, tt.location},
+				{"willing to relocate", formatOptionalBool(prefs.WillingToRelocate), tt.relocate},
+				{"requires sponsorship", formatOptionalBool(prefs.RequiresSponsorship), tt.sponsorship},
+				{"desired roles", strings.Join(prefs.DesiredRoles, ","), strings.Join(tt.roles, ",")},
+				{"work arrangements", strings.Join(prefs.WorkArrangements, ","), strings.Join(tt.arrangements, ",")},
+				{"employment types", strings.Join(prefs.EmploymentTypes, ","), strings.Join(tt.types, ",")},
+			}
+			for _, g := range got {
+				if g.got != g.want {
+					t.Errorf("%s = %q, want %q", g.field, g.got, g.want)
+				}
+			}
+		})
+	}
+}
+
+func TestRoleMatches(t *testing.T) {
+	tests := []struct {
+		title   string
+		desired []string
+		want    bool
+	}{
+		{"Senior Backend Engineer", []string{"Backend Engineer"}, true},
+		{"Backend Engineer", []string{"Lead Backend Developer"}, true},
+		{"Data Scientist", []string{"Backend Engineer", "Data Scientist"}, true},
+		{"Product Designer", []string{"Backend Engineer"}, false},
+		{"Backend Engineer", []string{"Senior"}, false},
+		{"C# Developer", []string{"C++ Developer"}, true},
+		{"C# Engineer", []string{"C++ Developer"}, false},
+	}
+	for _, tt := range tests {
+		if got := roleMatches(tt.title, tt.desired); got != tt.want {
+			t.Errorf("roleMatches(%q, %q) = %v, want %v", tt.title, tt.desired, got, tt.want)
+		}
+	}
+}
+
+func TestLogisticsFit(t *testing.T) {
+	onsite := JobRequirements{
+		Title:           "Backend Engineer",
+		Locations:       []string{"Austin, TX"},
+		WorkArrangement: WorkOnsite,
+		VisaSponsorship: boolPtr(false),
+	}
+	tests := []struct {
+		name      string
+		req       JobRequirements
+		answers   KnockoutAnswers
+		wantScore float64
+		wantLevel string
+	}{
+		{"everything fits", onsite, KnockoutAnswers{
+			Location: "Austin, TX", RequiresSponsorship: boolPtr(false), WorkArrangements: []string{"onsite"}, DesiredRoles: []string{"Backend Engineer"},
+		}, 100, LogisticsGood},
+		{"nothing known counts half", onsite, KnockoutAnswers{}, 50, LogisticsPartial},
+		{"wrong place and role", onsite, KnockoutAnswers{
+			Location: "Denver, CO", WillingToRelocate: boolPtr(false), RequiresSponsorship: boolPtr(false), WorkArrangements: []string{"onsite"}, DesiredRoles: []string{"Designer"},
+		}, 50, LogisticsPartial},
+		{"needs sponsorship and remote only", onsite, KnockoutAnswers{
+			Location: "Denver, CO", WillingToRelocate: boolPtr(false), RequiresSponsorship: boolPtr(true), WorkArrangements: []string{"remote"},
+		}, 0, LogisticsPoor},
+		{"remote suits any location", JobRequirements{WorkArrangement: WorkRemote, Locations: []string{"Austin, TX"}}, KnockoutAnswers{
+			Location: "Denver, CO", WorkArrangements: []string{"remote"},
+		}, 100, LogisticsGood},
+		{"no constraints", JobRequirements{Title: "Backend Engineer"}, KnockoutAnswers{}, 100, LogisticsNoConstraints},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fit := logisticsFit(tt.req, tt.answers)
+			if math.Abs(fit.Score-tt.wantScore) > 0.01 || fit.Level != tt.wantLevel {
+				t.Errorf("logistics fit = %.2f %s, want %.0f %s (factors %+v)", fit.Score, fit.Level, tt.wantScore, tt.wantLevel, fit.Factors)
+			}
+			for _, factor := range fit.Factors {
+				if _, ok := logisticsWeights[factor.Field]; !ok {
+					t.Errorf("factor %q has no weight", factor.Field)
+				}
+			}
+		})
+	}
+}
+
+func TestListJobCandidates(t *testing.T) {
+	scored := func(overall, logistics float64, level string, knockedOut bool) ScoreResponse {
+		score := ScoreResponse{
+			OverallScore:      overall,
+			ProcessedEntities: ExtractedEntities{Name: "Candidate"},
+			LogisticsFit:      LogisticsFit{Score: logistics, Level: level},
+			KnockedOut:        knockedOut,
+		}
+		if knockedOut {
+			score.Knockouts = []KnockoutResult{{Field: "location", Status: KnockoutFail}, {Field: "salary", Status: KnockoutPass}}
+		}
+		return score
+	}
+	for id, score := range map[string]ScoreResponse{
+		"3801": scored(90, 40, LogisticsPoor, true),
+		"3802": scored(60, 100, LogisticsGood, false),
+		"3803": scored(75, 60, LogisticsPartial, false),
+	} {
+		if err := saveScoreRecord(id, "3800", 1, score, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveTestText(t, "job", "3800", TextData{Requirements: JobRequirements{Title: "Backend Engineer"}})
+
+	app := fiber.New()
+	app.Get("/jobs/:id/candidates", ListJobCandidates)
+
+	tests := []struct {
+		name  string
+		query string
+		want  int
+		ids   []string
+	}{
+		{"all", "", 200, []string{"3801", "3802", "3803"}},
+		{"skills floor", "?min_score=70", 200, []string{"3801", "3803"}},
+		{"logistics floor", "?min_logistics=50", 200, []string{"3802", "3803"}},
+		{"logistics level", "?logistics_level=good", 200, []string{"3802"}},
+		{"exclude knocked out", "?exclude_knocked_out=true", 200, []string{"3802", "3803"}},
+		{"sort by logistics", "?sort=logistics", 200, []string{"3802", "3803", "3801"}},
+		{"bad number", "?min_score=high", 400, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", "/jobs/3800/candidates"+tt.query, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.want {
+				t.Fatalf("status = %d, want %d: %s", resp.StatusCode, tt.want, body)
+			}
+			if tt.want != 200 {
+				return
+			}
+			var result struct {
+				Candidates []CandidateFilterEntry `json:"candidates"`
+			}
+			if err := json.Unmarshal(body, &result); err != nil {
+				t.Fatal(err)
+			}
+			var ids []string
+			for _, candidate := range result.Candidates {
+				ids = append(ids, candidate.ResumeID)
+				if candidate.KnockedOut && strings.Join(candidate.Knockouts, ",") != "location" {
+					t.Errorf("%s failed knockouts = %v, want only location", candidate.ResumeID, candidate.Knockouts)
+				}
+			}
+			if tt.query != "?sort=logistics" {
+				sort.Strings(ids)
+			}
+			if strings.Join(ids, ",") != strings.Join(tt.ids, ",") {
+				t.Errorf("candidates = %v, want %v", ids, tt.ids)
+			}
+		})
+	}
+}
@@ -1,17 +1,16 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"interviewme/utils"
@@ -51,43 +50,125 @@ func PreprocessResume(c *fiber.Ctx) error {
 		})
 	}
 
-	// Read the uploaded file
-	fileContent, err := os.ReadFile(uploadedFilePath)
+	result, err := processResumeFile(uploadedFilePath, file.Filename, resumeProcessingOptions{
+		JobID: c.FormValue("job_id"),
+		Blind: c.FormValue("blind") == "true",
+	}, nil)
 	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Could not read uploaded file",
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
 
-	// Create a buffer with the file content
-	buf := bytes.NewBuffer(fileContent)
+	// Log the response for debugging
+	log.Printf("Sending response: %+v", result)
 
-	fileExt := strings.ToLower(filepath.Ext(file.Filename))
-	var extractedText string
+	// Return file information and session ID
+	return c.JSON(fiber.Map{
+		"success": true,
+		"data":    result,
+	})
+}
 
-	// Extract text based on file type
-	switch fileExt {
-	case ".pdf":
-		cmd := exec.Command("pdftotext", "-", "-")
-		cmd.Stdin = bytes.NewReader(buf.Bytes())
-		var out bytes.Buffer
-		cmd.Stdout = &out
-		if err := cmd.Run(); err != nil {
-			return c.Status(500).JSON(fiber.Map{
-				"error": "Could not extract text from PDF",
-			})
+// resumeProcessingOptions controls optional steps of the resume pipeline
+type resumeProcessingOptions struct {
+	JobID string // Target job, used for its blind screening setting
+	Blind bool   // Force redaction regardless of the job setting
+
+	// How the file arrived, e.g. the sender and subject of an emailed
+	// application; "from" is dropped when screening blind
+	Source map[string]string
+}
+
+// errorStatus returns the HTTP status carried by a *fiber.Error, or 500
+func errorStatus(err error) int {
+	if fiberErr, ok := err.(*fiber.Error); ok {
+		return fiberErr.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// Last resume ID handed out, so uploads processed in the same second
+// (bulk and inbox ingestion) still get distinct IDs
+var resumeIDs struct {
+	sync.Mutex
+	last int64
+}
+
+// newResumeID returns a timestamp ID that no other resume uses
+func newResumeID() string {
+	resumeIDs.Lock()
+	defer resumeIDs.Unlock()
+
+	id := time.Now().Unix()
+	if id <= resumeIDs.last {
+		id = resumeIDs.last + 1
+	}
+	for {
+		if _, err := os.Stat(filepath.Join("processed_texts", "resume", fmt.Sprintf("resume_%d.json", id))); os.IsNotExist(err) {
+			break
 		}
-		extractedText = out.String()
-	case ".docx":
-		extractedText = string(buf.Bytes())
-	default:
-		return c.Status(400).JSON(fiber.Map{
-			"error": "Unsupported file format",
-		})
+		id++
+	}
+	resumeIDs.last = id
+	return strconv.FormatInt(id, 10)
+}
+
+// processResumeFile runs an uploaded resume through text extraction, entity
+// extraction and storage, reporting each stage as it completes.
+func processResumeFile(uploadedFilePath string, originalName string, opts resumeProcessingOptions, progress progressFunc) (*PreprocessedData, error) {
+	// Read the uploaded file
+	fileContent, err := os.ReadFile(uploadedFilePath)
+	if err != nil {
+		return nil, fiber.NewError(500, "Could not read uploaded file")
+	}
+
+	fileExt := strings.ToLower(filepath.Ext(originalName))
+	extractedText, diagnostics, err := extractResumeText(fileContent, fileExt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Generate a unique ID for the resume
+	resumeID := newResumeID()
+
+	// Redact identifying details before the text reaches the model when the
+	// target job uses blind screening (or the uploader asks for it)
+	jobID := opts.JobID
+	blind := opts.Blind || isBlindScreeningJob(jobID)
+	var redactionTokens map[string]string
+	if blind {
+		extractedText, redactionTokens = redactResumeText(extractedText)
+		// A JSON Resume names the candidate outright, even when the name
+		// doesn't look like one to the text heuristics
+		if fileExt == ".json" && redactionTokens[redactedNameToken] == "" {
+			if resume, err := parseJSONResume(fileContent); err == nil && strings.TrimSpace(resume.Basics.Name) != "" {
+				redactionTokens[redactedNameToken] = strings.TrimSpace(resume.Basics.Name)
+				extractedText = redactVaultValues(extractedText, redactionTokens)
+			}
+		}
+		redactDiagnostics(diagnostics, extractedText, redactionTokens)
+		vault := RedactionVault{
+			ResumeID:   resumeID,
+			JobID:      jobID,
+			UploadFile: filepath.Base(uploadedFilePath),
+			Tokens:     redactionTokens,
+			CreatedAt:  time.Now(),
+			UnmaskLog:  []UnmaskEvent{},
+		}
+		if err := saveRedactionVault(vault); err != nil {
+			log.Printf("Error saving redaction vault: %v", err)
+			return nil, fiber.NewError(500, "Failed to save redaction vault")
+		}
+	}
+
+	if err := saveExtractionDiagnostics(resumeID, diagnostics); err != nil {
+		log.Printf("Error saving extraction diagnostics: %v", err)
 	}
 
 	// Log the extracted text
 	log.Printf("Extracted text from resume: %s", extractedText)
+	progress.report("text_extracted", 1, 4, nil)
 
 	// Save extracted text
 	err = saveProcessedText("resume", extractedText)
@@ -98,37 +179,68 @@ func PreprocessResume(c *fiber.Ctx) error {
 	// Preprocess text
 	processedText := preprocessText(extractedText)
 
-	// Generate a unique ID for the resume
-	timestamp := time.Now().Unix()
-	resumeID := fmt.Sprintf("%d", timestamp)
 	filename := fmt.Sprintf("resume_%s.json", resumeID) // Update filename format
 	filePath := filepath.Join("processed_texts", "resume", filename)
 
 	// Ensure directory exists before saving
 	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
 		log.Printf("Error creating directory structure: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to create directory structure",
-		})
+		return nil, fiber.NewError(500, "Failed to create directory structure")
 	}
 
-	// Extract entities using Gemini API first
-	entities, err := extractEntitiesWithGemini(processedText)
-	if err != nil {
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Entity extraction failed: " + err.Error(),
-		})
+	// JSON Resume uploads are already structured; everything else goes
+	// through the model
+	var entities ExtractedEntities
+	if fileExt == ".json" {
+		resume, err := parseJSONResume(fileContent)
+		if err != nil {
+			return nil, err
+		}
+		entities = entitiesFromJSONResume(resume)
+		// These come from the raw upload, not the redacted text
+		if blind {
+			entities = redactEntityText(entities, redactionTokens)
+		}
+	} else if entities, err = extractEntitiesWithGemini(processedText); err != nil {
+		return nil, fiber.NewError(500, "Entity extraction failed: "+err.Error())
 	}
 
 	// Validate and clean extracted entities
 	validateExtractedEntities(&entities)
+	fillCandidatePreferences(&entities.Preferences, extractedText)
+	// Emailed applications carry the sender's address even when the
+	// resume itself doesn't list one
+	if from := opts.Source["from"]; from != "" && len(entities.Email) == 0 {
+		entities.Email = []string{from}
+	}
+	if blind {
+		entities = anonymizeEntities(entities, resumeID)
+	}
+	progress.report("entities_parsed", 2, 4, entities)
 
 	// Save processed text with entities
 	if err := SaveProcessedText("resume", processedText, resumeID, entities); err != nil {
 		log.Printf("Error saving resume text: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to save processed text",
-		})
+		return nil, fiber.NewError(500, "Failed to save processed text")
+	}
+
+	// Check the parsed resume for impossible timelines and hidden text, and
+	// record where the file came from
+	integrityFlags := validateResumeIntegrity(entities, extractedText, diagnostics)
+	source := opts.Source
+	if blind && source["from"] != "" {
+		source = make(map[string]string)
+		for key, value := range opts.Source {
+			if key != "from" {
+				source[key] = value
+			}
+		}
+	}
+	if err := updateResumeRecord(resumeID, func(data *TextData) {
+		data.IntegrityFlags = integrityFlags
+		data.Source = source
+	}); err != nil {
+		log.Printf("Error saving resume record: %v", err)
 	}
 
 	// Wait briefly to ensure file is written
@@ -137,18 +249,24 @@ func PreprocessResume(c *fiber.Ctx) error {
 	// Verify file exists after saving
 	if _, err := os.Stat(filePath); err != nil {
 		log.Printf("Error verifying saved file: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": fmt.Sprintf("Failed to verify saved file: %v", err),
-		})
+		return nil, fiber.NewError(500, fmt.Sprintf("Failed to verify saved file: %v", err))
+	}
+
+	// Group the upload with earlier resumes from the same person
+	var candidateID string
+	if candidate, err := linkResumeToCandidate(resumeID, originalName, time.Now(), resumeIdentity(resumeID, entities, processedText)); err != nil {
+		log.Printf("Error linking resume to candidate: %v", err)
+	} else {
+		candidateID = candidate.ID
 	}
 
+	progress.report("resume_saved", 3, 4, nil)
+
 	// After ensuring file exists, create session
 	sessionID, err := utils.SaveProcessingSession(filename, "")
 	if err != nil {
 		log.Printf("Error saving resume session: %v", err)
-		return c.Status(500).JSON(fiber.Map{
-			"error": "Failed to save resume session",
-		})
+		return nil, fiber.NewError(500, "Failed to save resume session")
 	}
 
 	// Update the result initialization to match PreprocessedData structure
@@ -166,6 +284,9 @@ func PreprocessResume(c *fiber.Ctx) error {
 		Filename:        filename,
 		ProcessedAt:     time.Now(),
 		ID:              resumeID,
+		CandidateID:     candidateID,
+		Diagnostics:     diagnostics,
+		IntegrityFlags:  integrityFlags,
 	}
 
 	// Ensure Name is never undefined
@@ -173,14 +294,25 @@ func PreprocessResume(c *fiber.Ctx) error {
 		result.Entities.Name = "Not provided"
 	}
 
-	// Log the response for debugging
-	log.Printf("Sending response: %+v", result)
+	progress.report("session_created", 4, 4, nil)
 
-	// Return file information and session ID
-	return c.JSON(fiber.Map{
-		"success": true,
-		"data":    result,
-	})
+	event := fiber.Map{
+		"resume_id":       resumeID,
+		"candidate_id":    candidateID,
+		"job_id":          jobID,
+		"blind":           blind,
+		"source":          source,
+		"integrity_flags": integrityFlags,
+	}
+	// Uploaded filenames are often the candidate's name
+	if blind {
+		event["integrity_flags"] = blindIntegrityFlags(integrityFlags)
+	} else {
+		event["filename"] = originalName
+	}
+	emitWebhookEvent(EventResumeProcessed, event)
+
+	return &result, nil
 }
 
 // preprocessText performs basic text preprocessing.
@@ -231,12 +363,12 @@ type Content struct {
 	Role  string   `json:"Role"`
 }
 
-type Candidate struct {
+type ContentCandidate struct {
 	Content *Content `json:"Content"`
 }
 
 type ContentResponse struct {
-	Candidates []Candidate `json:"Candidates"`
+	Candidates []ContentCandidate `json:"Candidates"`
 }
 
 // Update the GeminiContent struct to match the actual response
@@ -282,13 +414,15 @@ func extractEntitiesWithGemini(text string) (ExtractedEntities, error) {
 6. Education (with degree, institution, year, specialization)
 7. Projects (with name, description, technologies used, duration, role)
 8. Experience (with title, company, duration, location, responsibilities)
+9. Preferences the candidate states (current location, relocation, work authorization, desired roles, remote/hybrid/onsite, employment type)
 
 Provide the output in JSON format with the keys: 
-name, email, phone, technical_skills, soft_skills, education, projects, experience.
+name, email, phone, technical_skills, soft_skills, education, projects, experience, preferences.
 Each skill type should be an array of strings.
 Education should include degree, institution, year, location, specialization.
 Projects should be an array of objects with name, description, technologies, duration, role.
 Experience should be an array of objects with title, company, duration, location, description, responsibilities.
+Preferences should be an object with location, willing_to_relocate (true, false or null), work_authorization, requires_sponsorship (true, false or null), desired_roles, work_arrangements, employment_types. Leave out anything the text does not state.
 
 Text: ` + text
 
@@ -475,6 +609,10 @@ Text: ` + text
 		}
 	}
 
+	if prefs, ok := rawResponse["preferences"].(map[string]interface{}); ok {
+		entities.Preferences = parsePreferences(prefs)
+	}
+
 	// Log the processed entities
 	logData := map[string]any{
 		"entities":       entities,
@@ -545,7 +683,9 @@ func extractEducationWithModel(text string) []string {
 // PreprocessJobDescription handles job description preprocessing
 func PreprocessJobDescription(c *fiber.Ctx) error {
 	var data struct {
-		Description string `json:"description"`
+		Description    string `json:"description"`
+		BlindScreening bool   `json:"blind_screening"`
+		JobID          string `json:"job_id"` // set to save a new version of an existing job
 	}
 
 	if err := c.BodyParser(&data); err != nil {
@@ -557,14 +697,30 @@ func PreprocessJobDescription(c *fiber.Ctx) error {
 	// Preprocess text
 	processedText := preprocessText(data.Description)
 
-	// Generate a unique ID for the job description
-	jobID := fmt.Sprintf("%d", time.Now().Unix())
+	// Editing an existing job archives its current version and keeps the ID,
+	// so scores, screening settings and scorecards stay linked. The job stays
+	// locked until the new version is saved.
+	jobID := normalizeID(data.JobID, "job")
+	version, previousVersion := 1, 0
+	if jobID != "" {
+		defer lockJobVersion(jobID)()
+		var err error
+		if previousVersion, err = archiveCurrentJobVersion(jobID); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		version = previousVersion + 1
+	} else {
+		// Generate a unique ID for the job description
+		jobID = fmt.Sprintf("%d", time.Now().Unix())
 
-	// Create empty entities for job description
-	emptyEntities := ExtractedEntities{}
+		// Create empty entities for job description
+		emptyEntities := ExtractedEntities{}
 
-	if err := SaveProcessedText("job", processedText, jobID, emptyEntities); err != nil {
-		log.Printf("Error saving job description: %v", err)
+		if err := SaveProcessedText("job", processedText, jobID, emptyEntities); err != nil {
+			log.Printf("Error saving job description: %v", err)
+		}
 	}
 
 	// Initialize Gemini client
@@ -608,12 +764,21 @@ func PreprocessJobDescription(c *fiber.Ctx) error {
             "fields": ["field1", "field2", ...],
             "qualifications": ["qualification1", ...]
         },
+        "title": "job title",
         "responsibilities": ["responsibility1", "responsibility2", ...],
         "project_requirements": {
             "types": ["type1", "type2", ...],
             "skills": ["skill1", "skill2", ...],
             "experience": ["exp1", "exp2", ...]
-        }
+        },
+        "salary": {"min": number, "max": number, "currency": "ISO code", "period": "yearly/monthly/hourly"} or null,
+        "locations": ["city, country", ...],
+        "work_arrangement": "remote/hybrid/onsite" or "",
+        "employment_type": "full_time/part_time/contract/internship/temporary" or "",
+        "seniority": "intern/junior/mid/senior/lead/principal/executive" or "",
+        "department": "team or department name" or "",
+        "visa_sponsorship": true, false or null if not stated,
+        "travel": {"required": boolean, "percent": number} or null
     }
 
     Job Description: ` + data.Description
@@ -657,6 +822,9 @@ func PreprocessJobDescription(c *fiber.Ctx) error {
 		requirements.Education.Qualifications = []string{}
 	}
 
+	// Fill structured details the model missed and normalize its values
+	fillJobDetails(&requirements, data.Description)
+
 	// Categorize skills
 	technicalSkills := FilterTechnicalSkills(requirements.Skills)
 	softSkills := filterSoftSkills(requirements.Skills)
@@ -670,6 +838,7 @@ func PreprocessJobDescription(c *fiber.Ctx) error {
 		Requirements:    requirements,
 		SoftSkills:      softSkills,
 		TechnicalSkills: technicalSkills,
+		Version:         version,
 	}
 
 	// Save the complete job data
@@ -708,17 +877,57 @@ func PreprocessJobDescription(c *fiber.Ctx) error {
 		log.Printf("Error saving JSON log: %v", err)
 	}
 
+	lint := lintJobDescription(data.Description, &requirements)
+	lint.JobID = jobID
+	if err := saveLintReport(lint); err != nil {
+		log.Printf("Error saving lint report: %v", err)
+	}
+
+	if err := saveJobVersion(&jobData, data.Description); err != nil {
+		log.Printf("Error saving job version: %v", err)
+	}
+
+	// Candidates scored against the old version are rescored in the background
+	var rescoreTaskID string
+	if previousVersion > 0 {
+		if rescoreTaskID, err = queueJobRescore(jobID, previousVersion, version); err != nil {
+			log.Printf("Error queueing rescore: %v", err)
+		}
+	}
+
+	if data.BlindScreening {
+		settings := loadScreeningSettings(jobID)
+		settings.BlindScreening = true
+		if err := saveScreeningSettings(settings); err != nil {
+			log.Printf("Error saving screening settings: %v", err)
+		}
+	}
+
 	// After saving job file
 	sessionIDJob, err := utils.SaveProcessingSession("", jobID)
 	if err != nil {
 		log.Printf("Error saving job session: %v", err)
 	}
 
+	// An edit that omits the flag leaves a blind job blind
+	blind := isBlindScreeningJob(jobID)
+	emitWebhookEvent(EventJobProcessed, fiber.Map{
+		"job_id":          jobID,
+		"version":         version,
+		"requirements":    requirements,
+		"blind_screening": blind,
+		"rescore_task_id": rescoreTaskID,
+	})
+
 	return c.JSON(fiber.Map{
-		"requirements":   requirements,
-		"id":             jobID,
-		"session_id_job": sessionIDJob,
-		"filename":       filename,
+		"requirements":    requirements,
+		"id":              jobID,
+		"session_id_job":  sessionIDJob,
+		"filename":        filename,
+		"blind_screening": blind,
+		"lint":            lint,
+		"version":         version,
+		"rescore_task_id": rescoreTaskID,
 	})
 }
 
@@ -120,15 +120,34 @@ func AnalyzeProjects(c *fiber.Ctx) error {
 		})
 	}
 
-	// Initialize Gemini with safety checks
-	ctx := context.Background()
-	client, err := genai.NewClient(ctx, option.WithAPIKey(getGeminiAPIKey()))
+	analysisResults, err := analyzeResumeProjects(context.Background(), resumeData, jobData, nil)
 	if err != nil {
-		log.Printf("Failed to create Gemini client: %v", err)
+		log.Printf("Failed to analyze projects: %v", err)
 		return c.Status(500).JSON(fiber.Map{
 			"error": "Failed to initialize AI service",
 		})
 	}
+
+	// Log the analysis results before sending response
+	log.Printf("Analysis completed for resume_id: %s, job_id: %s", req.ResumeID, req.JobID)
+	log.Printf("Analyzed %d projects", len(analysisResults))
+	responseBytes, _ := json.MarshalIndent(ProjectAnalysisResponse{Projects: analysisResults}, "", "  ")
+	log.Printf("Response payload: %s", string(responseBytes))
+
+	return c.JSON(ProjectAnalysisResponse{
+		Projects: analysisResults,
+	})
+}
+
+// analyzeResumeProjects runs the Gemini project analysis for a resume/job pair,
+// reporting progress after each project, and stores the result for later use.
+func analyzeResumeProjects(ctx context.Context, resumeData *TextData, jobData *TextData, progress progressFunc) ([]ProjectAnalysis, error) {
+	// Initialize Gemini with safety checks
+	client, err := genai.NewClient(ctx, option.WithAPIKey(getGeminiAPIKey()))
+	if err != nil {
+		log.Printf("Failed to create Gemini client: %v", err)
+		return nil, err
+	}
 	defer client.Close()
 
 	model := client.GenerativeModel("gemini-pro")
@@ -143,11 +162,16 @@ func AnalyzeProjects(c *fiber.Ctx) error {
 
 	// Sort projects by relevance to job description before analysis
 	sortedProjects := preprocessProjects(resumeData.Entities.Projects, jobData.ProcessedText)
+	total := min(len(sortedProjects), maxProjects)
+	progress.report("projects_ranked", 0, total, nil)
 
 	for _, project := range sortedProjects {
 		if processedProjects >= maxProjects {
 			break // Limit the number of projects analyzed
 		}
+		if err := ctx.Err(); err != nil {
+			return analysisResults, err
+		}
 
 		// Clean and truncate project description
 		cleanDesc := sanitizeText(project.Description, maxPromptLength)
@@ -162,6 +186,7 @@ func AnalyzeProjects(c *fiber.Ctx) error {
 		analysis, err := analyzeProjectWithRetry(ctx, model, prompt, project)
 		if err != nil {
 			log.Printf("Error analyzing project: %v", err)
+			progress.report("project_skipped", processedProjects, total, nil)
 			continue
 		}
 
@@ -170,17 +195,14 @@ func AnalyzeProjects(c *fiber.Ctx) error {
 
 		analysisResults = append(analysisResults, analysis)
 		processedProjects++
+		progress.report("project_analyzed", processedProjects, total, analysis)
 	}
 
-	// Log the analysis results before sending response
-	log.Printf("Analysis completed for resume_id: %s, job_id: %s", req.ResumeID, req.JobID)
-	log.Printf("Analyzed %d projects", len(analysisResults))
-	responseBytes, _ := json.MarshalIndent(ProjectAnalysisResponse{Projects: analysisResults}, "", "  ")
-	log.Printf("Response payload: %s", string(responseBytes))
+	if err := saveAnalysisResult("projects", resumeData.ID, jobData.ID, ProjectAnalysisResponse{Projects: analysisResults}); err != nil {
+		log.Printf("Error saving project analysis: %v", err)
+	}
 
-	return c.JSON(ProjectAnalysisResponse{
-		Projects: analysisResults,
-	})
+	return analysisResults, nil
 }
 
 // New helper functions
@@ -0,0 +1,521 @@
+package handlers
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+//go:embed templates/candidate_report.html
+var candidateReportHTML string
+
+var (
+	hexColorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	// Inline logos are limited to raster images; SVG can carry script
+	dataLogoPattern = regexp.MustCompile(`^data:image/(png|jpeg|gif|webp);base64,[A-Za-z0-9+/]+=*$`)
+
+	candidateReportTemplate = template.Must(template.New("candidate_report").Funcs(template.FuncMap{
+		"join":  strings.Join,
+		"score": func(v float64) string { return fmt.Sprintf("%.1f", v) },
+		// html/template rewrites data: URLs to #ZgotmplZ unless they are
+		// marked safe, which only holds for logos that pass validLogoURL
+		"logoURL": func(logo string) template.URL {
+			if !validLogoURL(logo) {
+				return ""
+			}
+			return template.URL(logo)
+		},
+		"percentWidth": func(v float64) template.CSS {
+			return template.CSS(fmt.Sprintf("%.1f%%", math.Min(math.Max(v, 0), 100)))
+		},
+	}).Parse(candidateReportHTML))
+
+	defaultReportBranding = ReportBranding{
+		CompanyName:  "InterviewMe",
+		PrimaryColor: "#2563eb",
+		Footer:       "Confidential – prepared for the hiring team. Do not forward outside the hiring process.",
+	}
+)
+
+// ReportBranding is applied to every candidate report
+type ReportBranding struct {
+	CompanyName  string `json:"company_name"`
+	PrimaryColor string `json:"primary_color"`      // #rrggbb
+	LogoURL      string `json:"logo_url,omitempty"` // HTML reports only
+	Footer       string `json:"footer"`
+}
+
+// CandidateReport is everything a recruiter-facing report shows
+type CandidateReport struct {
+	Branding       ReportBranding
+	GeneratedAt    time.Time
+	ResumeID       string
+	JobID          string
+	JobTitle       string
+	JobVersion     int
+	ScoredAt       time.Time
+	CandidateName  string
+	Contact        []string
+	Blind          bool
+	Score          ScoreResponse
+	Breakdown      []ReportMetric
+	SkillRows      []ReportSkillRow
+	TotalYears     float64
+	OverallFit     string
+	Timeline       []ReportRole
+	Projects       []ProjectAnalysis
+	Questions      []ReportQuestionGroup
+	IntegrityFlags []IntegrityFlag
+}
+
+// ReportMetric is one bar of the score breakdown
+type ReportMetric struct {
+	Name  string
+	Value float64
+}
+
+// ReportSkillRow is one job skill and how the resume covers it
+type ReportSkillRow struct {
+	JobSkill    string
+	ResumeSkill string
+	Match       string
+	Class       string // exact, partial or missing
+}
+
+// ReportRole is one entry of the experience timeline
+type ReportRole struct {
+	Title    string
+	Company  string
+	Duration string
+	Summary  string
+	Skills   []string
+}
+
+// ReportQuestionGroup is one section of the interview kit
+type ReportQuestionGroup struct {
+	Title     string
+	Questions []InterviewQuestion
+}
+
+// validLogoURL reports whether a logo can be embedded in the HTML report
+func validLogoURL(logo string) bool {
+	return strings.HasPrefix(logo, "https://") || strings.HasPrefix(logo, "http://") ||
+		dataLogoPattern.MatchString(logo)
+}
+
+func reportBrandingPath() string {
+	return filepath.Join("processed_texts", "reports", "branding.json")
+}
+
+func loadReportBranding() ReportBranding {
+	branding := defaultReportBranding
+	if err := utils.LoadJSONFile(reportBrandingPath(), &branding); err != nil && !os.IsNotExist(err) {
+		log.Printf("Error reading report branding: %v", err)
+	}
+	return branding
+}
+
+// buildCandidateReport gathers the score, skill match, experience, projects
+// and interview kit for a resume against a job. Without a job the most recent
+// score is used; a resume not yet scored against the job is a conflict, since
+// a report only reads what scoring already saved.
+func buildCandidateReport(resumeID, jobID string) (*CandidateReport, error) {
+	resumeID = normalizeID(resumeID, "resume")
+	resumeData, err := LoadTextData(resumeID, "resume")
+	if err != nil {
+		return nil, fiber.NewError(404, "Resume not found")
+	}
+
+	var record *ScoreRecord
+	if jobID == "" {
+		if record, err = loadLatestScoreRecord(resumeID); err != nil {
+			return nil, fiber.NewError(404, "Resume has not been scored yet; pass job_id")
+		}
+		jobID = record.JobID
+	}
+	jobID = normalizeID(jobID, "job")
+	jobData, jobErr := LoadTextData(jobID, "job")
+	if record == nil {
+		if record, err = loadScoreRecord(resumeID, jobID); err != nil {
+			if jobErr != nil {
+				return nil, fiber.NewError(404, "Job description not found")
+			}
+			return nil, fiber.NewError(409, "Resume has not been scored against this job; score it first")
+		}
+	}
+
+	report := &CandidateReport{
+		Branding:       loadReportBranding(),
+		GeneratedAt:    time.Now(),
+		ResumeID:       resumeID,
+		JobID:          jobID,
+		JobTitle:       "Job " + jobID,
+		JobVersion:     max(record.JobVersion, 1),
+		ScoredAt:       record.ScoredAt,
+		Blind:          isBlindScreeningJob(jobID),
+		Score:          record.Score,
+		IntegrityFlags: resumeIntegrityFlags(resumeData),
+	}
+	if jobErr == nil && jobData.Requirements.Title != "" {
+		report.JobTitle = jobData.Requirements.Title
+	}
+
+	entities := resumeData.Entities
+	if report.Blind {
+		entities = anonymizeEntities(entities, resumeID)
+		report.Score = anonymizeScore(report.Score, resumeID)
+		report.IntegrityFlags = blindIntegrityFlags(report.IntegrityFlags)
+	}
+	report.CandidateName = provided(entities.Name)
+	if report.CandidateName == "" {
+		report.CandidateName = candidatePseudonym(resumeID)
+	}
+	if !report.Blind {
+		report.Contact = append(report.Contact, entities.Email...)
+		if phone := provided(entities.Phone); phone != "" {
+			report.Contact = append(report.Contact, phone)
+		}
+		if location := entities.Preferences.Location; location != "" {
+			report.Contact = append(report.Contact, location)
+		}
+	}
+
+	score := record.Score
+	report.Breakdown = []ReportMetric{
+		{Name: "Technical skills", Value: score.DetailedScores["technical_skills"]},
+		{Name: "Soft skills", Value: score.DetailedScores["soft_skills"]},
+		{Name: "Experience", Value: score.ExperienceMatch},
+		{Name: "Education", Value: score.EducationMatch},
+		{Name: "Qualifications", Value: score.DetailedScores["qualifications"]},
+		{Name: "Logistics fit", Value: score.LogisticsFit.Score},
+	}
+	for _, skill := range score.MatchedSkills.ExactMatches {
+		report.SkillRows = append(report.SkillRows, ReportSkillRow{JobSkill: skill, ResumeSkill: skill, Match: "Exact", Class: "exact"})
+	}
+	for _, partial := range score.MatchedSkills.PartialMatches {
+		report.SkillRows = append(report.SkillRows, ReportSkillRow{
+			JobSkill:    partial.JobSkill,
+			ResumeSkill: partial.ResumeSkill,
+			Match:       fmt.Sprintf("Partial (%.0f%%)", partial.Similarity*100),
+			Class:       "partial",
+		})
+	}
+	for _, skill := range score.MatchedSkills.MissingSkills {
+		report.SkillRows = append(report.SkillRows, ReportSkillRow{JobSkill: skill, Match: "Missing", Class: "missing"})
+	}
+
+	// Stored analyses add the model's fit summaries; without them the
+	// report falls back to what the resume itself says
+	var experience ExperienceResponse
+	hasExperience := loadAnalysisResult("experience", resumeID, jobID, &experience) == nil
+	report.TotalYears, report.OverallFit = experience.TotalYearsExperience, experience.OverallFit
+	for _, exp := range entities.Experience {
+		role := ReportRole{
+			Title:    provided(exp.Title),
+			Company:  provided(exp.Company),
+			Duration: provided(exp.Duration),
+			Summary:  provided(exp.Description),
+			Skills:   exp.Skills,
+		}
+		if hasExperience {
+			for _, analysed := range experience.Experiences {
+				if strings.EqualFold(analysed.Title, exp.Title) && normalizeEmployer(analysed.Company) == normalizeEmployer(exp.Company) {
+					if analysed.JobFitSummary != "" {
+						role.Summary = analysed.JobFitSummary
+					}
+					if len(analysed.RelevantSkills) > 0 {
+						role.Skills = analysed.RelevantSkills
+					}
+				}
+			}
+		}
+		if role.Title == "" && role.Company == "" {
+			continue
+		}
+		report.Timeline = append(report.Timeline, role)
+	}
+
+	var projects ProjectAnalysisResponse
+	if loadAnalysisResult("projects", resumeID, jobID, &projects) == nil && len(projects.Projects) > 0 {
+		report.Projects = projects.Projects
+	} else {
+		for _, project := range entities.Projects {
+			report.Projects = append(report.Projects, ProjectAnalysis{
+				Name:        project.Name,
+				Description: project.Description,
+				TechStack:   uniqueStrings(append(append([]string{}, project.Technologies...), project.Skills...)),
+			})
+		}
+	}
+
+	if kit, err := loadInterviewKit(resumeID, jobID); err == nil {
+		for _, group := range []ReportQuestionGroup{
+			{Title: "Technical", Questions: kit.TechnicalQuestions},
+			{Title: "Partial matches to probe", Questions: kit.PartialMatchProbes},
+			{Title: "Behavioral", Questions: kit.BehavioralQuestions},
+			{Title: "Project follow-ups", Questions: kit.ProjectFollowUps},
+		} {
+			if len(group.Questions) > 0 {
+				report.Questions = append(report.Questions, group)
+			}
+		}
+	}
+	return report, nil
+}
+
+// renderReportHTML renders the report with the embedded template
+func renderReportHTML(report *CandidateReport) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := candidateReportTemplate.Execute(&buf, report); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// renderReportPDF lays the same sections out as a PDF
+func renderReportPDF(report *CandidateReport) []byte {
+	brand := hexToRGB(report.Branding.PrimaryColor)
+	dark, muted, white := utils.RGB{0.12, 0.16, 0.2}, utils.RGB{0.45, 0.5, 0.55}, utils.RGB{1, 1, 1}
+	body := utils.PDFStyle{Size: 10, Color: dark}
+	bold := utils.PDFStyle{Size: 10, Bold: true, Color: dark}
+	small := utils.PDFStyle{Size: 9, Color: muted}
+
+	doc := utils.NewPDFDocument(report.CandidateName+" – "+report.JobTitle, report.Branding.Footer)
+	width := doc.ContentWidth()
+	heading := func(title string) {
+		doc.Space(14)
+		doc.EnsureSpace(60)
+		doc.Paragraph(title, utils.PDFStyle{Size: 13, Bold: true, Color: brand})
+		doc.Rule(brand)
+		doc.Space(2)
+	}
+
+	doc.Banner(report.CandidateName, 44, brand, utils.PDFStyle{Size: 18, Bold: true, Color: white})
+	doc.Space(6)
+	doc.Paragraph(report.Branding.CompanyName, bold)
+	meta := fmt.Sprintf("Candidate report for %s (job %s, version %d) · scored %s · generated %s",
+		report.JobTitle, report.JobID, report.JobVersion,
+		report.ScoredAt.Format("2 Jan 2006 15:04"), report.GeneratedAt.Format("2 Jan 2006 15:04"))
+	if report.Blind {
+		meta += " · blind screening"
+	}
+	doc.Paragraph(meta, small)
+	if len(report.Contact) > 0 {
+		doc.Paragraph(strings.Join(report.Contact, " · "), small)
+	}
+
+	heading("Score")
+	overall := fmt.Sprintf("%.1f / 100 overall", report.Score.OverallScore)
+	if report.Score.KnockedOut {
+		overall += " – knocked out"
+	}
+	doc.Paragraph(overall, utils.PDFStyle{Size: 16, Bold: true, Color: brand})
+	doc.Space(4)
+	for _, metric := range report.Breakdown {
+		doc.Bar(metric.Name, metric.Value, 100, brand, body)
+	}
+	if len(report.Score.Knockouts) > 0 {
+		doc.Space(6)
+		widths := []float64{width * 0.2, width * 0.3, width * 0.2, width * 0.3}
+		header := utils.RGB{0.94, 0.96, 0.97}
+		doc.TableRow([]string{"Knockout", "Requirement", "Candidate", "Result"}, widths, bold, &header)
+		for _, knockout := range report.Score.Knockouts {
+			result := knockout.Status
+			if knockout.Reason != "" {
+				result += " – " + knockout.Reason
+			}
+			doc.TableRow([]string{knockout.Field, knockout.Requirement, knockout.Candidate, result}, widths, body, nil)
+		}
+	}
+	if len(report.Score.Feedback) > 0 {
+		doc.Space(6)
+		for _, feedback := range report.Score.Feedback {
+			doc.Paragraph("• "+feedback, utils.PDFStyle{Size: 10, Color: dark, Indent: 8})
+		}
+	}
+	if len(report.IntegrityFlags) > 0 {
+		heading("Integrity flags")
+		for _, flag := range report.IntegrityFlags {
+			doc.Paragraph(fmt.Sprintf("• [%s] %s", flag.Severity, flag.Message), utils.PDFStyle{Size: 10, Color: dark, Indent: 8})
+		}
+	}
+
+	heading("Skill match")
+	if len(report.SkillRows) == 0 {
+		doc.Paragraph("The job lists no skills to match.", small)
+	} else {
+		widths := []float64{width * 0.4, width * 0.4, width * 0.2}
+		header := utils.RGB{0.94, 0.96, 0.97}
+		doc.TableRow([]string{"Job skill", "Candidate skill", "Match"}, widths, bold, &header)
+		for _, row := range report.SkillRows {
+			doc.TableRow([]string{row.JobSkill, row.ResumeSkill, row.Match}, widths, body, nil)
+		}
+	}
+
+	heading("Experience")
+	if report.TotalYears > 0 {
+		summary := fmt.Sprintf("%.1f years of experience", report.TotalYears)
+		if report.OverallFit != "" {
+			summary += " · " + report.OverallFit
+		}
+		doc.Paragraph(summary, small)
+		doc.Space(4)
+	}
+	if len(report.Timeline) == 0 {
+		doc.Paragraph("No experience was extracted from the resume.", small)
+	}
+	for _, role := range report.Timeline {
+		title := role.Title
+		if role.Company != "" {
+			title += " · " + role.Company
+		}
+		doc.EnsureSpace(40)
+		doc.Paragraph(title, bold)
+		if role.Duration != "" {
+			doc.Paragraph(role.Duration, small)
+		}
+		if role.Summary != "" {
+			doc.Paragraph(role.Summary, body)
+		}
+		if len(role.Skills) > 0 {
+			doc.Paragraph(strings.Join(role.Skills, ", "), small)
+		}
+		doc.Space(6)
+	}
+
+	heading("Projects")
+	if len(report.Projects) == 0 {
+		doc.Paragraph("No projects were found.", small)
+	}
+	for _, project := range report.Projects {
+		doc.EnsureSpace(40)
+		doc.Paragraph(project.Name, bold)
+		if project.Description != "" {
+			doc.Paragraph(project.Description, body)
+		}
+		if project.RelevanceToJob != "" {
+			doc.Paragraph(project.RelevanceToJob, small)
+		}
+		if len(project.TechStack) > 0 {
+			doc.Paragraph(strings.Join(project.TechStack, ", "), small)
+		}
+		doc.Space(6)
+	}
+
+	heading("Interview questions")
+	if len(report.Questions) == 0 {
+		doc.Paragraph("No interview kit has been generated for this job yet.", small)
+	}
+	for _, group := range report.Questions {
+		doc.EnsureSpace(40)
+		doc.Paragraph(group.Title, bold)
+		for i, question := range group.Questions {
+			doc.Paragraph(fmt.Sprintf("%d. %s", i+1, question.Question), utils.PDFStyle{Size: 10, Color: dark, Indent: 8})
+			if len(question.GoodAnswerIncludes) > 0 {
+				doc.Paragraph("Listen for: "+strings.Join(question.GoodAnswerIncludes, "; "), utils.PDFStyle{Size: 9, Color: muted, Indent: 20})
+			}
+		}
+		doc.Space(6)
+	}
+	return doc.Bytes()
+}
+
+// hexToRGB converts a validated #rrggbb colour
+func hexToRGB(color string) utils.RGB {
+	if !hexColorPattern.MatchString(color) {
+		color = defaultReportBranding.PrimaryColor
+	}
+	var rgb utils.RGB
+	for i := range rgb {
+		component, _ := strconv.ParseUint(color[1+2*i:3+2*i], 16, 8)
+		rgb[i] = float64(component) / 255
+	}
+	return rgb
+}
+
+// GetCandidateReport renders a resume's report against a job as HTML
+// (default) or PDF
+func GetCandidateReport(c *fiber.Ctx) error {
+	format := strings.ToLower(c.Query("format", "html"))
+	if format != "html" && format != "pdf" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "format must be html or pdf",
+		})
+	}
+
+	report, err := buildCandidateReport(c.Params("id"), c.Query("job_id"))
+	if err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	filename := fmt.Sprintf("report_%s_%s.%s", report.ResumeID, report.JobID, format)
+	disposition := "inline"
+	if c.QueryBool("download") {
+		disposition = "attachment"
+	}
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`%s; filename="%s"`, disposition, filename))
+
+	if format == "pdf" {
+		c.Set(fiber.HeaderContentType, "application/pdf")
+		return c.Send(renderReportPDF(report))
+	}
+	html, err := renderReportHTML(report)
+	if err != nil {
+		log.Printf("Error rendering report: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to render report",
+		})
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(html)
+}
+
+// GetReportBranding returns the branding applied to reports
+func GetReportBranding(c *fiber.Ctx) error {
+	return c.JSON(loadReportBranding())
+}
+
+// UpdateReportBranding changes the report branding; omitted fields keep
+// their current values
+func UpdateReportBranding(c *fiber.Ctx) error {
+	branding := loadReportBranding()
+	if err := c.BodyParser(&branding); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if !hexColorPattern.MatchString(branding.PrimaryColor) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "primary_color must be a #rrggbb colour",
+		})
+	}
+	if logo := branding.LogoURL; logo != "" && !validLogoURL(logo) {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "logo_url must be an http(s) URL or a base64 PNG, JPEG, GIF or WebP data URL",
+		})
+	}
+	if strings.TrimSpace(branding.CompanyName) == "" {
+		branding.CompanyName = defaultReportBranding.CompanyName
+	}
+	if err := utils.SaveJSONFile(reportBrandingPath(), branding, 0644); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save branding",
+		})
+	}
+	return c.JSON(branding)
+}
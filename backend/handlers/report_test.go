@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+func TestValidLogoURL(t *testing.T) {
+	tests := []struct {
+		logo string
+		want bool
+	}{
+		{"https://corp.test/logo.png", true},
+		{"http://corp.test/logo.png", true},
+		{"data:image/png;base64,iVBORw0KGgo=", true},
+		{"data:image/webp;base64,UklGRg==", true},
+		{"data:image/svg+xml;base64,PHN2Zz4=", false},
+		{"data:text/html;base64,PHNjcmlwdD4=", false},
+		{"data:image/png;base64,iVBO\"><script>", false},
+		{"javascript:alert(1)", false},
+		{"//corp.test/logo.png", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := validLogoURL(tt.logo); got != tt.want {
+			t.Errorf("validLogoURL(%q) = %v, want %v", tt.logo, got, tt.want)
+		}
+	}
+}
+
+func TestHexToRGB(t *testing.T) {
+	tests := []struct {
+		color string
+		want  utils.RGB
+	}{
+		{"#ff0000", utils.RGB{1, 0, 0}},
+		{"#FFFFFF", utils.RGB{1, 1, 1}},
+		{"#000000", utils.RGB{0, 0, 0}},
+		{"red", hexToRGB(defaultReportBranding.PrimaryColor)},
+		{"#fff", hexToRGB(defaultReportBranding.PrimaryColor)},
+	}
+	for _, tt := range tests {
+		if got := hexToRGB(tt.color); got != tt.want {
+			t.Errorf("hexToRGB(%q) = %v, want %v", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestCandidateReport(t *testing.T) {
+	entities := ExtractedEntities{
+		Name:        "Jane <b>Doe</b>",
+		Email:       []string{"jane@example.com"},
+		Phone:       "+1 555 123 4567",
+		Preferences: CandidatePreferences{Location: "Leeds, UK"},
+		Experience:  []Experience{{Title: "Engineer", Company: "Acme", Duration: "2019 - 2022"}},
+	}
+	score := ScoreResponse{
+		OverallScore:      72,
+		ProcessedEntities: entities,
+		Knockouts:         []KnockoutResult{{Field: "location", Requirement: "Remote (UK)", Candidate: "Leeds, UK", Status: "pass"}},
+	}
+	saveTestText(t, "resume", "4901", TextData{ProcessedText: "Jane Doe", Entities: entities})
+	for _, jobID := range []string{"4901", "4902"} {
+		saveTestText(t, "job", jobID, TextData{Requirements: JobRequirements{Title: "Backend Engineer"}})
+		if err := saveScoreRecord("4901", jobID, 1, score, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saveTestText(t, "job", "4903", TextData{Requirements: JobRequirements{Title: "Unscored"}})
+	if err := saveScreeningSettings(ScreeningSettings{JobID: "4902", BlindScreening: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/resumes/:id/report", GetCandidateReport)
+
+	tests := []struct {
+		name        string
+		path        string
+		want        int
+		contentType string
+		contains    []string
+		doesNotShow []string
+	}{
+		{"html report", "/resumes/4901/report?job_id=4901", 200, "text/html",
+			[]string{"Backend Engineer", "jane@example.com", "Leeds, UK", "Jane &lt;b&gt;Doe&lt;/b&gt;"}, []string{"<b>Doe</b>"}},
+		{"blind html report", "/resumes/4901/report?job_id=4902", 200, "text/html",
+			[]string{candidatePseudonym("4901"), "Remote (UK)"}, []string{"Jane", "jane@example.com", "555", "Leeds"}},
+		{"blind pdf report", "/resumes/4901/report?job_id=4902&format=pdf", 200, "application/pdf",
+			[]string{"%PDF-1.4", candidatePseudonym("4901")}, []string{"Jane", "jane@example.com", "Leeds"}},
+		{"latest score without a job", "/resumes/4901/report", 200, "text/html", []string{"Backend Engineer"}, nil},
+		{"not scored against the job", "/resumes/4901/report?job_id=4903", 409, "application/json", []string{"score it first"}, nil},
+		{"unknown job", "/resumes/4901/report?job_id=4999", 404, "application/json", nil, nil},
+		{"unknown resume", "/resumes/4999/report?job_id=4901", 404, "application/json", nil, nil},
+		{"bad format", "/resumes/4901/report?format=docx", 400, "application/json", nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode != tt.want || !strings.HasPrefix(resp.Header.Get("Content-Type"), tt.contentType) {
+				t.Fatalf("got %d %s, want %d %s: %.200s", resp.StatusCode, resp.Header.Get("Content-Type"), tt.want, tt.contentType, body)
+			}
+			for _, s := range tt.contains {
+				if !strings.Contains(string(body), s) {
+					t.Errorf("report does not contain %q", s)
+				}
+			}
+			for _, s := range tt.doesNotShow {
+				if strings.Contains(string(body), s) {
+					t.Errorf("report shows %q", s)
+				}
+			}
+		})
+	}
+}
+
+func TestUpdateReportBranding(t *testing.T) {
+	app := fiber.New()
+	app.Put("/reports/branding", UpdateReportBranding)
+
+	tests := []struct {
+		name, body string
+		want       int
+	}{
+		{"valid", `{"company_name":"Corp","primary_color":"#112233","logo_url":"https://corp.test/logo.png"}`, 200},
+		{"bad colour", `{"primary_color":"blue"}`, 400},
+		{"svg logo", `{"primary_color":"#112233","logo_url":"data:image/svg+xml;base64,PHN2Zz4="}`, 400},
+		{"script logo", `{"primary_color":"#112233","logo_url":"javascript:alert(1)"}`, 400},
+		{"keeps other fields", `{"footer":"Internal"}`, 200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("PUT", "/reports/branding", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, resp.StatusCode, tt.want)
+		}
+	}
+	if branding := loadReportBranding(); branding.CompanyName != "Corp" || branding.Footer != "Internal" || branding.PrimaryColor != "#112233" {
+		t.Errorf("branding = %+v, want the valid update plus the new footer", branding)
+	}
+}
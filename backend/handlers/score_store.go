@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"interviewme/utils"
+)
+
+// ScoreRecord is a persisted ScoreResponse for one resume/job pair
+type ScoreRecord struct {
+	ResumeID   string        `json:"resume_id"`
+	JobID      string        `json:"job_id"`
+	JobVersion int           `json:"job_version,omitempty"`
+	ScoredAt   time.Time     `json:"scored_at"`
+	Score      ScoreResponse `json:"score"`
+	// Screening answers the recruiter supplied, reapplied on every rescore
+	KnockoutAnswers *KnockoutAnswers `json:"knockout_answers,omitempty"`
+}
+
+func scoreRecordPath(resumeID, jobID string) string {
+	return filepath.Join("processed_texts", "scores",
+		fmt.Sprintf("score_%s_%s.json", pathID(resumeID, "resume"), pathID(jobID, "job")))
+}
+
+// saveScoreRecord stores the latest score of a resume against a job version,
+// with the screening answers that were applied to it. Callers that scored on
+// request announce it with emitScoreCompleted.
+func saveScoreRecord(resumeID, jobID string, jobVersion int, score ScoreResponse, answers *KnockoutAnswers) error {
+	record := newScoreRecord(resumeID, jobID, jobVersion, score, answers)
+	return utils.SaveJSONFile(scoreRecordPath(resumeID, jobID), record, 0644)
+}
+
+func newScoreRecord(resumeID, jobID string, jobVersion int, score ScoreResponse, answers *KnockoutAnswers) ScoreRecord {
+	return ScoreRecord{
+		ResumeID:        normalizeID(resumeID, "resume"),
+		JobID:           normalizeID(jobID, "job"),
+		JobVersion:      max(jobVersion, 1),
+		ScoredAt:        time.Now(),
+		Score:           score,
+		KnockoutAnswers: answers,
+	}
+}
+
+// emitScoreCompleted announces a score someone asked for. It is called where
+// scoring is requested, not from saveScoreRecord, so scores filled in while
+// serving a read don't fire events. Blind jobs get the anonymized score.
+func emitScoreCompleted(resumeID, jobID string, jobVersion int, score ScoreResponse, answers *KnockoutAnswers) {
+	record := newScoreRecord(resumeID, jobID, jobVersion, score, answers)
+	if isBlindScreeningJob(record.JobID) {
+		record.Score = anonymizeScore(record.Score, record.ResumeID)
+		record.KnockoutAnswers = nil
+	}
+	emitWebhookEvent(EventScoreCompleted, record)
+}
+
+// loadScoreRecord returns the stored score of a resume against a job
+func loadScoreRecord(resumeID, jobID string) (*ScoreRecord, error) {
+	var record ScoreRecord
+	if err := utils.LoadJSONFile(scoreRecordPath(resumeID, jobID), &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// savedKnockoutAnswers returns the screening answers stored with the last
+// score of a resume against a job, if any
+func savedKnockoutAnswers(resumeID, jobID string) *KnockoutAnswers {
+	if record, err := loadScoreRecord(resumeID, jobID); err == nil {
+		return record.KnockoutAnswers
+	}
+	return nil
+}
+
+// loadScoreRecordsForJob returns every stored score for a job, best first
+func loadScoreRecordsForJob(jobID string) ([]ScoreRecord, error) {
+	jobID = pathID(jobID, "job")
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "scores", fmt.Sprintf("score_*_%s.json", jobID)))
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]ScoreRecord, 0, len(paths))
+	for _, path := range paths {
+		var record ScoreRecord
+		if err := utils.LoadJSONFile(path, &record); err != nil {
+			log.Printf("Skipping unreadable score record %s: %v", path, err)
+			continue
+		}
+		if record.JobID == jobID {
+			records = append(records, record)
+		}
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].Score.OverallScore > records[j].Score.OverallScore
+	})
+	return records, nil
+}
+
+// loadLatestScoreRecord returns the most recent score of a resume against any job
+func loadLatestScoreRecord(resumeID string) (*ScoreRecord, error) {
+	resumeID = pathID(resumeID, "resume")
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "scores", fmt.Sprintf("score_%s_*.json", resumeID)))
+	if err != nil {
+		return nil, err
+	}
+
+	var latest *ScoreRecord
+	for _, path := range paths {
+		var record ScoreRecord
+		if err := utils.LoadJSONFile(path, &record); err != nil {
+			log.Printf("Skipping unreadable score record %s: %v", path, err)
+			continue
+		}
+		if record.ResumeID == resumeID && (latest == nil || record.ScoredAt.After(latest.ScoredAt)) {
+			latest = &record
+		}
+	}
+	if latest == nil {
+		return nil, fmt.Errorf("no scores for resume %s", resumeID)
+	}
+	return latest, nil
+}
@@ -0,0 +1,488 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Interviewer recommendations, weakest to strongest
+const (
+	RecommendStrongNo  = "strong_no"
+	RecommendNo        = "no"
+	RecommendYes       = "yes"
+	RecommendStrongYes = "strong_yes"
+)
+
+const (
+	minRating                 = 1
+	maxRating                 = 5
+	ratingDisagreementSpread  = 2    // Ratings this far apart on one competency need discussion
+	machineDisagreementPoints = 30.0 // Human/machine gaps of this many points (0-100) are flagged
+	maxResponsibilityName     = 80
+)
+
+var (
+	recommendationValues = map[string]float64{
+		RecommendStrongNo:  1,
+		RecommendNo:        2,
+		RecommendYes:       4,
+		RecommendStrongYes: 5,
+	}
+	competencySlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Competency is something interviewers rate a candidate on
+type Competency struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Source      string `json:"source"` // skill, soft_skill or responsibility
+	Description string `json:"description,omitempty"`
+}
+
+// CompetencyRating is one interviewer's rating of one competency
+type CompetencyRating struct {
+	CompetencyID string `json:"competency_id"`
+	Rating       int    `json:"rating"` // 1-5
+	Evidence     string `json:"evidence"`
+}
+
+// Scorecard is an interviewer's structured feedback on a candidate
+type Scorecard struct {
+	ResumeID       string             `json:"resume_id"`
+	JobID          string             `json:"job_id"`
+	Interviewer    string             `json:"interviewer"`
+	Stage          string             `json:"stage,omitempty"`
+	Ratings        []CompetencyRating `json:"ratings"`
+	Recommendation string             `json:"recommendation"`
+	Notes          string             `json:"notes,omitempty"`
+	SubmittedAt    time.Time          `json:"submitted_at"`
+}
+
+// CompetencySummary aggregates every rating of one competency
+type CompetencySummary struct {
+	Competency   Competency        `json:"competency"`
+	Ratings      map[string]int    `json:"ratings"` // interviewer -> rating
+	Evidence     map[string]string `json:"evidence"`
+	MeanRating   float64           `json:"mean_rating"`
+	Spread       int               `json:"spread"`
+	HumanScore   float64           `json:"human_score"`             // 0-100
+	MachineScore *float64          `json:"machine_score,omitempty"` // 0-100, skills only
+	Flags        []string          `json:"flags"`
+}
+
+// ScorecardSummary combines the panel's scorecards with the automated score
+type ScorecardSummary struct {
+	ResumeID                  string              `json:"resume_id"`
+	JobID                     string              `json:"job_id"`
+	Interviewers              []string            `json:"interviewers"`
+	Recommendations           map[string]string   `json:"recommendations"`
+	Competencies              []CompetencySummary `json:"competencies"`
+	HumanScore                float64             `json:"human_score"`             // 0-100
+	MachineScore              *float64            `json:"machine_score,omitempty"` // ScoreResponse.OverallScore
+	MachineScoredAt           *time.Time          `json:"machine_scored_at,omitempty"`
+	Unrated                   []string            `json:"unrated_competencies"`
+	InterviewerDisagreements  []string            `json:"interviewer_disagreements"`
+	HumanMachineDisagreements []string            `json:"human_machine_disagreements"`
+	HiringRecommendation      string              `json:"hiring_recommendation"`
+	Rationale                 []string            `json:"rationale"`
+}
+
+// GetJobCompetencies lists the competencies a job's scorecards are rated on
+func GetJobCompetencies(c *fiber.Ctx) error {
+	jobData, err := LoadTextData(normalizeID(c.Params("id"), "job"), "job")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description not found",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"job_id":       normalizeID(c.Params("id"), "job"),
+		"rating_scale": fiber.Map{"min": minRating, "max": maxRating},
+		"competencies": jobCompetencies(jobData),
+	})
+}
+
+// SubmitScorecard stores an interviewer's scorecard, replacing their
+// earlier submission for the same candidate
+func SubmitScorecard(c *fiber.Ctx) error {
+	var card Scorecard
+	if err := c.BodyParser(&card); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if card.Interviewer == "" {
+		card.Interviewer, _ = unmaskIdentity(c)
+	}
+	if card.ResumeID == "" || card.JobID == "" || card.Interviewer == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "resume_id, job_id and interviewer are required",
+		})
+	}
+	card.ResumeID = normalizeID(card.ResumeID, "resume")
+	card.JobID = normalizeID(card.JobID, "job")
+
+	jobData, err := LoadTextData(card.JobID, "job")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description not found",
+		})
+	}
+	if _, err := LoadTextData(card.ResumeID, "resume"); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume not found",
+		})
+	}
+
+	if err := validateScorecard(&card, jobCompetencies(jobData)); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	card.SubmittedAt = time.Now()
+	if err := utils.SaveJSONFile(scorecardPath(card.ResumeID, card.JobID, card.Interviewer), card, 0644); err != nil {
+		log.Printf("Error saving scorecard: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save scorecard",
+		})
+	}
+
+	return c.Status(201).JSON(card)
+}
+
+// ListScorecards returns every scorecard submitted for a candidate
+func ListScorecards(c *fiber.Ctx) error {
+	resumeID, jobID := c.Query("resume_id"), c.Query("job_id")
+	if resumeID == "" || jobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+
+	cards, err := loadScorecards(resumeID, jobID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load scorecards",
+		})
+	}
+	return c.JSON(cards)
+}
+
+// GetScorecardSummary aggregates scorecards into a hiring recommendation
+// alongside the automated score
+func GetScorecardSummary(c *fiber.Ctx) error {
+	resumeID := normalizeID(c.Query("resume_id"), "resume")
+	jobID := normalizeID(c.Query("job_id"), "job")
+	if resumeID == "" || jobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+
+	jobData, err := LoadTextData(jobID, "job")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Job description not found",
+		})
+	}
+
+	cards, err := loadScorecards(resumeID, jobID)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to load scorecards",
+		})
+	}
+	if len(cards) == 0 {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "No scorecards submitted for this candidate",
+		})
+	}
+
+	// The automated score is optional; summaries work without one
+	record, _ := loadScoreRecord(resumeID, jobID)
+
+	return c.JSON(summarizeScorecards(resumeID, jobID, jobCompetencies(jobData), cards, record))
+}
+
+// jobCompetencies derives competencies from the job's skills and responsibilities
+func jobCompetencies(jobData *TextData) []Competency {
+	var competencies []Competency
+	seen := make(map[string]bool)
+	add := func(name, source string) {
+		name = strings.TrimSpace(name)
+		id := competencySlug(name)
+		if id == "" || seen[id] {
+			return
+		}
+		seen[id] = true
+		competency := Competency{ID: id, Name: name, Source: source}
+		if runes := []rune(name); len(runes) > maxResponsibilityName {
+			competency.Name = string(runes[:maxResponsibilityName]) + "..."
+			competency.Description = name
+		}
+		competencies = append(competencies, competency)
+	}
+
+	for _, skill := range jobData.Requirements.Skills {
+		if isSoftSkill(skill) {
+			add(skill, "soft_skill")
+		} else {
+			add(skill, "skill")
+		}
+	}
+	for _, skill := range jobData.SoftSkills {
+		add(skill, "soft_skill")
+	}
+	for _, responsibility := range jobData.Requirements.Responsibilities {
+		add(responsibility, "responsibility")
+	}
+	return competencies
+}
+
+func competencySlug(name string) string {
+	slug := strings.Trim(competencySlugPattern.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if runes := []rune(slug); len(runes) > 48 {
+		slug = strings.TrimRight(string(runes[:48]), "_")
+	}
+	return slug
+}
+
+func validateScorecard(card *Scorecard, competencies []Competency) error {
+	if _, ok := recommendationValues[card.Recommendation]; !ok {
+		return fmt.Errorf("recommendation must be one of strong_no, no, yes, strong_yes")
+	}
+	if len(card.Ratings) == 0 {
+		return fmt.Errorf("at least one rating is required")
+	}
+
+	known := make(map[string]bool)
+	for _, competency := range competencies {
+		known[competency.ID] = true
+	}
+	rated := make(map[string]bool)
+	for _, rating := range card.Ratings {
+		if !known[rating.CompetencyID] {
+			return fmt.Errorf("unknown competency: %s", rating.CompetencyID)
+		}
+		if rated[rating.CompetencyID] {
+			return fmt.Errorf("competency rated twice: %s", rating.CompetencyID)
+		}
+		rated[rating.CompetencyID] = true
+		if rating.Rating < minRating || rating.Rating > maxRating {
+			return fmt.Errorf("rating for %s must be between %d and %d", rating.CompetencyID, minRating, maxRating)
+		}
+		if strings.TrimSpace(rating.Evidence) == "" {
+			return fmt.Errorf("evidence is required for %s", rating.CompetencyID)
+		}
+	}
+	return nil
+}
+
+func scorecardDir(resumeID, jobID string) string {
+	return filepath.Join("processed_texts", "scorecards",
+		fmt.Sprintf("%s_%s", pathID(resumeID, "resume"), pathID(jobID, "job")))
+}
+
+// normalizeInterviewer folds case and whitespace so one person always maps
+// to one scorecard
+func normalizeInterviewer(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// scorecardPath keys the file on a hash of the interviewer's name, so names
+// without ASCII letters still get a file of their own
+func scorecardPath(resumeID, jobID, interviewer string) string {
+	sum := sha256.Sum256([]byte(normalizeInterviewer(interviewer)))
+	return filepath.Join(scorecardDir(resumeID, jobID), fmt.Sprintf("scorecard_%s.json", hex.EncodeToString(sum[:8])))
+}
+
+func loadScorecards(resumeID, jobID string) ([]Scorecard, error) {
+	paths, err := filepath.Glob(filepath.Join(scorecardDir(resumeID, jobID), "scorecard_*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	cards := make([]Scorecard, 0, len(paths))
+	for _, path := range paths {
+		var card Scorecard
+		if err := utils.LoadJSONFile(path, &card); err != nil {
+			log.Printf("Skipping unreadable scorecard %s: %v", path, err)
+			continue
+		}
+		cards = append(cards, card)
+	}
+	sort.Slice(cards, func(i, j int) bool {
+		return cards[i].SubmittedAt.Before(cards[j].SubmittedAt)
+	})
+	return cards, nil
+}
+
+// ratingToScore maps a 1-5 rating onto the 0-100 scale used by ScoreResponse
+func ratingToScore(rating float64) float64 {
+	return (rating - minRating) / (maxRating - minRating) * 100
+}
+
+// machineSkillScore is the automated evidence for one skill competency
+func machineSkillScore(name string, matches SkillMatches) *float64 {
+	score := func(v float64) *float64 { return &v }
+	for _, skill := range matches.ExactMatches {
+		if strings.EqualFold(skill, name) {
+			return score(100)
+		}
+	}
+	for _, pm := range matches.PartialMatches {
+		if strings.EqualFold(pm.JobSkill, name) {
+			return score(pm.Similarity * 100)
+		}
+	}
+	for _, skill := range matches.MissingSkills {
+		if strings.EqualFold(skill, name) {
+			return score(0)
+		}
+	}
+	return nil
+}
+
+func summarizeScorecards(resumeID, jobID string, competencies []Competency, cards []Scorecard, record *ScoreRecord) ScorecardSummary {
+	summary := ScorecardSummary{
+		ResumeID:                  resumeID,
+		JobID:                     jobID,
+		Recommendations:           make(map[string]string),
+		Unrated:                   []string{},
+		InterviewerDisagreements:  []string{},
+		HumanMachineDisagreements: []string{},
+		Rationale:                 []string{},
+	}
+
+	byCompetency := make(map[string]*CompetencySummary)
+	for _, competency := range competencies {
+		byCompetency[competency.ID] = &CompetencySummary{
+			Competency: competency,
+			Ratings:    make(map[string]int),
+			Evidence:   make(map[string]string),
+			Flags:      []string{},
+		}
+	}
+
+	var voteValues []float64
+	for _, card := range cards {
+		summary.Interviewers = append(summary.Interviewers, card.Interviewer)
+		summary.Recommendations[card.Interviewer] = card.Recommendation
+		voteValues = append(voteValues, recommendationValues[card.Recommendation])
+		for _, rating := range card.Ratings {
+			// Ratings for competencies removed from the job since submission are ignored
+			if cs, ok := byCompetency[rating.CompetencyID]; ok {
+				cs.Ratings[card.Interviewer] = rating.Rating
+				cs.Evidence[card.Interviewer] = rating.Evidence
+			}
+		}
+	}
+
+	if record != nil {
+		overall := record.Score.OverallScore
+		summary.MachineScore = &overall
+		summary.MachineScoredAt = &record.ScoredAt
+	}
+
+	var humanScores []float64
+	for _, competency := range competencies {
+		cs := byCompetency[competency.ID]
+		if len(cs.Ratings) == 0 {
+			summary.Unrated = append(summary.Unrated, competency.Name)
+			continue
+		}
+
+		var ratings []float64
+		low, high := maxRating, minRating
+		for _, rating := range cs.Ratings {
+			ratings = append(ratings, float64(rating))
+			low, high = min(low, rating), max(high, rating)
+		}
+		cs.MeanRating = mean(ratings)
+		cs.Spread = high - low
+		cs.HumanScore = ratingToScore(cs.MeanRating)
+		humanScores = append(humanScores, cs.HumanScore)
+
+		if cs.Spread >= ratingDisagreementSpread {
+			cs.Flags = append(cs.Flags, "interviewer_disagreement")
+			summary.InterviewerDisagreements = append(summary.InterviewerDisagreements,
+				fmt.Sprintf("%s: ratings range from %d to %d", competency.Name, low, high))
+		}
+
+		if record != nil && competency.Source == "skill" {
+			cs.MachineScore = machineSkillScore(competency.Name, record.Score.MatchedSkills)
+			if cs.MachineScore != nil && math.Abs(cs.HumanScore-*cs.MachineScore) >= machineDisagreementPoints {
+				cs.Flags = append(cs.Flags, "human_machine_disagreement")
+				summary.HumanMachineDisagreements = append(summary.HumanMachineDisagreements,
+					fmt.Sprintf("%s: panel %.0f vs automated %.0f", competency.Name, cs.HumanScore, *cs.MachineScore))
+			}
+		}
+		summary.Competencies = append(summary.Competencies, *cs)
+	}
+
+	summary.HumanScore = mean(humanScores)
+	if summary.MachineScore != nil && len(humanScores) > 0 &&
+		math.Abs(summary.HumanScore-*summary.MachineScore) >= machineDisagreementPoints {
+		summary.HumanMachineDisagreements = append(summary.HumanMachineDisagreements,
+			fmt.Sprintf("Overall: panel %.0f vs automated %.0f", summary.HumanScore, *summary.MachineScore))
+	}
+
+	// Split votes (someone for, someone against) always need a debrief
+	splitVote := false
+	for _, v := range voteValues {
+		for _, w := range voteValues {
+			if v <= recommendationValues[RecommendNo] && w >= recommendationValues[RecommendYes] {
+				splitVote = true
+			}
+		}
+	}
+	if splitVote {
+		summary.InterviewerDisagreements = append(summary.InterviewerDisagreements, "Interviewers disagree on whether to hire")
+	}
+
+	summary.HiringRecommendation, summary.Rationale = hiringRecommendation(mean(voteValues), summary.HumanScore, splitVote, len(humanScores) > 0)
+	if len(summary.HumanMachineDisagreements) > 0 {
+		summary.Rationale = append(summary.Rationale, "The panel and the automated score disagree; review the flagged competencies")
+	}
+	if len(summary.Unrated) > 0 {
+		summary.Rationale = append(summary.Rationale, fmt.Sprintf("%d competencies were not rated by any interviewer", len(summary.Unrated)))
+	}
+	return summary
+}
+
+func hiringRecommendation(meanVote, humanScore float64, splitVote, hasRatings bool) (string, []string) {
+	rationale := []string{
+		fmt.Sprintf("Average interviewer recommendation %.1f/5", meanVote),
+	}
+	if hasRatings {
+		rationale = append(rationale, fmt.Sprintf("Average competency score %.0f/100", humanScore))
+	}
+
+	switch {
+	case splitVote:
+		return "discuss", append(rationale, "Split vote across the panel")
+	case meanVote >= 4.5 && (!hasRatings || humanScore >= 75):
+		return RecommendStrongYes, rationale
+	case meanVote >= 3.5 && (!hasRatings || humanScore >= 50):
+		return RecommendYes, rationale
+	case meanVote < 1.5:
+		return RecommendStrongNo, rationale
+	case meanVote < 3:
+		return RecommendNo, rationale
+	default:
+		return "discuss", append(rationale, "Votes and competency ratings point in different directions")
+	}
+}
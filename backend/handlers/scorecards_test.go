@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestJobCompetencies(t *testing.T) {
+	long := "Own the design, rollout and on-call support of the payments platform across three regions and two data centres"
+	job := &TextData{
+		Requirements: JobRequirements{
+			Skills:           []string{"Go", "C++", "Communication", "go "},
+			Responsibilities: []string{long, "  "},
+		},
+		SoftSkills: []string{"Teamwork", "communication"},
+	}
+	got := jobCompetencies(job)
+	want := []struct{ id, source string }{
+		{"go", "skill"},
+		{"c", "skill"},
+		{"communication", "soft_skill"},
+		{"teamwork", "soft_skill"},
+		{competencySlug(long), "responsibility"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("competencies = %+v, want %d", got, len(want))
+	}
+	for i, w := range want {
+		if got[i].ID != w.id || got[i].Source != w.source {
+			t.Errorf("competency %d = %s (%s), want %s (%s)", i, got[i].ID, got[i].Source, w.id, w.source)
+		}
+	}
+	if last := got[4]; len([]rune(last.Name)) != maxResponsibilityName+3 || last.Description != long {
+		t.Errorf("long responsibility = %+v, want a shortened name and the full description", last)
+	}
+	if slug := competencySlug(long); len(slug) > 48 || strings.HasSuffix(slug, "_") {
+		t.Errorf("competencySlug = %q, want at most 48 characters without a trailing underscore", slug)
+	}
+}
+
+func TestValidateScorecard(t *testing.T) {
+	competencies := []Competency{{ID: "go"}, {ID: "communication"}}
+	rating := func(id string, value int, evidence string) CompetencyRating {
+		return CompetencyRating{CompetencyID: id, Rating: value, Evidence: evidence}
+	}
+	tests := []struct {
+		name    string
+		card    Scorecard
+		wantErr string
+	}{
+		{"valid", Scorecard{Recommendation: RecommendYes, Ratings: []CompetencyRating{rating("go", 4, "Built a worker pool"), rating("communication", 3, "Clear")}}, ""},
+		{"bad recommendation", Scorecard{Recommendation: "maybe", Ratings: []CompetencyRating{rating("go", 4, "x")}}, "recommendation must be"},
+		{"no ratings", Scorecard{Recommendation: RecommendNo}, "at least one rating"},
+		{"unknown competency", Scorecard{Recommendation: RecommendNo, Ratings: []CompetencyRating{rating("rust", 4, "x")}}, "unknown competency: rust"},
+		{"rated twice", Scorecard{Recommendation: RecommendNo, Ratings: []CompetencyRating{rating("go", 4, "x"), rating("go", 2, "y")}}, "rated twice"},
+		{"rating too high", Scorecard{Recommendation: RecommendNo, Ratings: []CompetencyRating{rating("go", 6, "x")}}, "between 1 and 5"},
+		{"rating too low", Scorecard{Recommendation: RecommendNo, Ratings: []CompetencyRating{rating("go", 0, "x")}}, "between 1 and 5"},
+		{"no evidence", Scorecard{Recommendation: RecommendNo, Ratings: []CompetencyRating{rating("go", 3, "  ")}}, "evidence is required"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateScorecard(&tt.card, competencies)
+			if (err == nil) != (tt.wantErr == "") || (err != nil && !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("validateScorecard = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScorecardPathPerInterviewer(t *testing.T) {
+	tests := []struct {
+		a, b string
+		same bool
+	}{
+		{"Alice Smith", "  alice   SMITH ", true},
+		{"Alice Smith", "Alice Smyth", false},
+		{"李雷", "韩梅梅", false},
+	}
+	for _, tt := range tests {
+		if same := scorecardPath("1", "2", tt.a) == scorecardPath("1", "2", tt.b); same != tt.same {
+			t.Errorf("%q and %q share a scorecard = %v, want %v", tt.a, tt.b, same, tt.same)
+		}
+	}
+}
+
+func TestMachineSkillScore(t *testing.T) {
+	matches := SkillMatches{
+		ExactMatches:   []string{"Go"},
+		PartialMatches: []PartialMatch{{JobSkill: "Kafka", ResumeSkill: "RabbitMQ", Similarity: 0.6}},
+		MissingSkills:  []string{"Rust"},
+	}
+	tests := []struct {
+		skill string
+		want  float64
+		found bool
+	}{
+		{"go", 100, true},
+		{"KAFKA", 60, true},
+		{"Rust", 0, true},
+		{"Python", 0, false},
+	}
+	for _, tt := range tests {
+		got := machineSkillScore(tt.skill, matches)
+		if (got != nil) != tt.found || (got != nil && *got != tt.want) {
+			t.Errorf("machineSkillScore(%q) = %v, want %v (found %v)", tt.skill, got, tt.want, tt.found)
+		}
+	}
+}
+
+func TestHiringRecommendation(t *testing.T) {
+	tests := []struct {
+		name       string
+		meanVote   float64
+		humanScore float64
+		split      bool
+		hasRatings bool
+		want       string
+	}{
+		{"split vote", 3, 60, true, true, "discuss"},
+		{"strong yes", 5, 80, false, true, RecommendStrongYes},
+		{"strong votes, weak ratings", 5, 60, false, true, RecommendYes},
+		{"yes", 4, 55, false, true, RecommendYes},
+		{"yes without ratings", 4, 0, false, false, RecommendYes},
+		{"yes votes, poor ratings", 4, 30, false, true, "discuss"},
+		{"no", 2, 30, false, true, RecommendNo},
+		{"strong no", 1, 10, false, true, RecommendStrongNo},
+	}
+	for _, tt := range tests {
+		if got, _ := hiringRecommendation(tt.meanVote, tt.humanScore, tt.split, tt.hasRatings); got != tt.want {
+			t.Errorf("%s: hiringRecommendation = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSummarizeScorecards(t *testing.T) {
+	competencies := []Competency{
+		{ID: "go", Name: "Go", Source: "skill"},
+		{ID: "communication", Name: "Communication", Source: "soft_skill"},
+		{ID: "kafka", Name: "Kafka", Source: "skill"},
+	}
+	cards := []Scorecard{
+		{Interviewer: "alice", Recommendation: RecommendStrongYes, Ratings: []CompetencyRating{
+			{CompetencyID: "go", Rating: 5, Evidence: "a"}, {CompetencyID: "communication", Rating: 2, Evidence: "b"}, {CompetencyID: "removed", Rating: 1, Evidence: "c"},
+		}},
+		{Interviewer: "bob", Recommendation: RecommendNo, Ratings: []CompetencyRating{
+			{CompetencyID: "go", Rating: 5, Evidence: "d"}, {CompetencyID: "communication", Rating: 5, Evidence: "e"},
+		}},
+	}
+	record := &ScoreRecord{Score: ScoreResponse{OverallScore: 20, MatchedSkills: SkillMatches{MissingSkills: []string{"Go"}}}}
+
+	summary := summarizeScorecards("1", "2", competencies, cards, record)
+	tests := []struct {
+		field     string
+		got, want any
+	}{
+		{"recommendation", summary.HiringRecommendation, "discuss"},
+		{"unrated", strings.Join(summary.Unrated, ","), "Kafka"},
+		{"rated competencies", len(summary.Competencies), 2},
+		{"go human score", summary.Competencies[0].HumanScore, 100.0},
+		{"go flags", strings.Join(summary.Competencies[0].Flags, ","), "human_machine_disagreement"},
+		{"communication spread", summary.Competencies[1].Spread, 3},
+		{"communication flags", strings.Join(summary.Competencies[1].Flags, ","), "interviewer_disagreement"},
+		{"interviewer disagreements", len(summary.InterviewerDisagreements), 2},
+		{"human/machine disagreements", len(summary.HumanMachineDisagreements), 2},
+		{"machine score", *summary.MachineScore, 20.0},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %v, want %v", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestSubmitScorecard(t *testing.T) {
+	saveTestText(t, "job", "3201", TextData{Requirements: JobRequirements{Skills: []string{"Go"}}})
+	saveTestText(t, "resume", "3201", TextData{ProcessedText: "Go developer"})
+
+	app := fiber.New()
+	app.Post("/scorecards", SubmitScorecard)
+	app.Get("/scorecards/summary", GetScorecardSummary)
+
+	submit := func(body string) int {
+		req := httptest.NewRequest("POST", "/scorecards", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+	card := func(interviewer, recommendation string) string {
+		return `{"resume_id":"3201","job_id":"3201","interviewer":"` + interviewer + `","recommendation":"` + recommendation +
+			`","ratings":[{"competency_id":"go","rating":4,"evidence":"Explained channels"}]}`
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"first submission", card("Alice", RecommendNo), 201},
+		{"resubmission replaces it", card(" alice ", RecommendYes), 201},
+		{"second interviewer", card("Bob", RecommendYes), 201},
+		{"no interviewer", card("", RecommendYes), 400},
+		{"unknown job", strings.Replace(card("Bob", RecommendYes), `"job_id":"3201"`, `"job_id":"3299"`, 1), 404},
+		{"unknown resume", strings.Replace(card("Bob", RecommendYes), `"resume_id":"3201"`, `"resume_id":"3299"`, 1), 404},
+		{"invalid rating", strings.Replace(card("Bob", RecommendYes), `"rating":4`, `"rating":9`, 1), 400},
+	}
+	for _, tt := range tests {
+		if got := submit(tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	cards, err := loadScorecards("3201", "3201")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cards) != 2 || cards[0].Recommendation != RecommendYes {
+		t.Errorf("scorecards = %+v, want two with Alice's resubmission", cards)
+	}
+	if resp, _ := app.Test(httptest.NewRequest("GET", "/scorecards/summary?resume_id=3201&job_id=3201", nil)); resp.StatusCode != 200 {
+		t.Errorf("summary status = %d, want 200", resp.StatusCode)
+	}
+	if resp, _ := app.Test(httptest.NewRequest("GET", "/scorecards/summary?resume_id=3202&job_id=3201", nil)); resp.StatusCode != 404 {
+		t.Errorf("summary without scorecards = %d, want 404", resp.StatusCode)
+	}
+}
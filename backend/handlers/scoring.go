@@ -28,6 +28,10 @@ type ScoreResponse struct {
 	MatchedSkills      SkillMatches       `json:"matched_skills"`
 	SoftSkillsAnalysis SoftSkillsData     `json:"soft_skills_analysis"`
 	ProcessedEntities  ExtractedEntities  `json:"processed_entities"`
+	LogisticsFit       LogisticsFit       `json:"logistics_fit"`
+	Knockouts          []KnockoutResult   `json:"knockouts"`
+	KnockedOut         bool               `json:"knocked_out"`
+	IntegrityFlags     []IntegrityFlag    `json:"integrity_flags"`
 }
 
 // Add new type for skill matches
@@ -123,8 +127,9 @@ func cleanID(id string, prefix string) string {
 // ScoreResume handles the resume scoring endpoint
 func ScoreResume(c *fiber.Ctx) error {
 	var request struct {
-		ResumeID string `json:"resume_id"`
-		JobID    string `json:"job_id"`
+		ResumeID        string           `json:"resume_id"`
+		JobID           string           `json:"job_id"`
+		KnockoutAnswers *KnockoutAnswers `json:"knockout_answers"`
 	}
 
 	if err := c.BodyParser(&request); err != nil {
@@ -188,6 +193,36 @@ func ScoreResume(c *fiber.Ctx) error {
 
 	log.Printf("Job ID: %s", jobFileID)
 
+	scoreResponse := scoreResumeAgainstJob(resumeData, jobData, nil)
+
+	// Screening answers supplied with the request override what the resume
+	// implies; without new ones, answers given on an earlier score still apply
+	answers := request.KnockoutAnswers
+	if answers == nil {
+		answers = savedKnockoutAnswers(cleanedResumeID, cleanedJobID)
+	}
+	reapplyKnockoutAnswers(&scoreResponse, resumeData, jobData.Requirements, answers)
+
+	// Keep a record of every score so batch reports can be built later
+	if err := saveScoreRecord(cleanedResumeID, cleanedJobID, jobData.Version, scoreResponse, answers); err != nil {
+		log.Printf("Error saving score record: %v", err)
+	}
+	emitScoreCompleted(cleanedResumeID, cleanedJobID, jobData.Version, scoreResponse, answers)
+
+	// Blind screening jobs only ever display anonymized entities
+	if isBlindScreeningJob(cleanedJobID) {
+		scoreResponse = anonymizeScore(scoreResponse, cleanedResumeID)
+	}
+
+	// Log the final score response
+	log.Printf("Score Response: %+v", scoreResponse)
+
+	return c.JSON(scoreResponse)
+}
+
+// scoreResumeAgainstJob computes the full score breakdown for a resume/job pair,
+// reporting each scoring stage with its partial result
+func scoreResumeAgainstJob(resumeData *TextData, jobData *TextData, progress progressFunc) ScoreResponse {
 	// Calculate normalized scores (0-100 scale)
 	skillsScore := math.Min(safeFloat64(calculateSkillsMatch(
 		resumeData.Entities.Skills,
@@ -198,9 +233,13 @@ func ScoreResume(c *fiber.Ctx) error {
 	educationScore := math.Min(safeFloat64(calculateEducationMatch(
 		resumeData.Entities.Education,
 		jobData.Requirements.Education)*maxScore), maxScore)
+	progress.report("skills_scored", 1, 5, fiber.Map{"skills_score": skillsScore})
+	progress.report("experience_scored", 2, 5, fiber.Map{"experience_match": experienceScore})
+	progress.report("education_scored", 3, 5, fiber.Map{"education_match": educationScore})
 	technicalScore := math.Min(safeFloat64(calculateTechnicalSkillsScore(
 		resumeData,
 		jobData)*maxScore), maxScore)
+	progress.report("technical_skills_scored", 4, 5, fiber.Map{"technical_skills": technicalScore})
 
 	// Calculate overall score using technical skills instead of education (weighted average)
 	overallScore := math.Min(safeFloat64(
@@ -219,6 +258,12 @@ func ScoreResume(c *fiber.Ctx) error {
 		jobData.Requirements.Skills,
 	)
 
+	progress.report("skill_matches_analyzed", 5, 5, SkillMatches{
+		ExactMatches:   exactMatches,
+		PartialMatches: partialMatches,
+		MissingSkills:  missingSkills,
+	})
+
 	// Then initialize scoreResponse with the calculated matches
 	scoreResponse := ScoreResponse{
 		OverallScore: overallScore,
@@ -237,12 +282,11 @@ func ScoreResume(c *fiber.Ctx) error {
 			MissingSkills:  missingSkills,
 		},
 		ProcessedEntities: resumeData.Entities,
+		IntegrityFlags:    resumeIntegrityFlags(resumeData),
 	}
+	applyKnockouts(&scoreResponse, jobData.Requirements, knockoutAnswersFromResume(resumeData.Entities))
 
-	// Log the final score response
-	log.Printf("Score Response: %+v", scoreResponse)
-
-	return c.JSON(scoreResponse)
+	return scoreResponse
 }
 
 // Enhanced calculateSkillsMatch with semantic search
@@ -856,16 +900,35 @@ func sqrt(x float32) float32 {
 // Helper function for word similarity
 func wordSimilarity(word1, word2 string) float64 {
 	// Implement word2vec or WordNet similarity here
-	// For now, using simple Levenshtein distance
-	distance := levenshteinDistance(word1, word2)
-	maxLen := float64(max(len(word1), len(word2)))
-	return 1.0 - float64(distance)/maxLen
+	// For now, using simple Levenshtein distance over runes
+	maxLen := float64(max(len([]rune(word1)), len([]rune(word2))))
+	if maxLen == 0 {
+		return 1.0
+	}
+	return 1.0 - float64(levenshteinDistance(word1, word2))/maxLen
 }
 
-// Helper function for Levenshtein distance
+// levenshteinDistance counts the single-rune insertions, deletions and
+// substitutions that turn s1 into s2
 func levenshteinDistance(s1, s2 string) int {
-	// ...implement Levenshtein distance algorithm...
-	return 0 // Placeholder
+	r1, r2 := []rune(s1), []rune(s2)
+	prev := make([]int, len(r2)+1)
+	curr := make([]int, len(r2)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(r1); i++ {
+		curr[0] = i
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(r2)]
 }
 
 // Helper function for entity comparison
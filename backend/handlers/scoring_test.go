@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"math"
+	"testing"
+)
+
+func TestLevenshteinDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"go", "", 2},
+		{"", "go", 2},
+		{"golang", "golang", 0},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"José", "Jose", 1},
+		{"naïve", "naive", 1},
+		{"abc", "cba", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := levenshteinDistance(tt.a, tt.b); got != tt.want {
+				t.Errorf("levenshteinDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+			}
+			if got := levenshteinDistance(tt.b, tt.a); got != tt.want {
+				t.Errorf("levenshteinDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWordSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"python", "python", 1},
+		{"python", "pyhton", 1 - 2.0/6},
+		{"java", "rust", 0},
+		// Normalised by rune count, not bytes
+		{"José", "Jose", 0.75},
+	}
+	for _, tt := range tests {
+		t.Run(tt.a+"/"+tt.b, func(t *testing.T) {
+			if got := wordSimilarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("wordSimilarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+const taskPollInterval = time.Second
+
+// sseStream writes Server-Sent Events and cancels the pipeline once the
+// client goes away
+type sseStream struct {
+	mu     sync.Mutex
+	w      *bufio.Writer
+	cancel context.CancelFunc
+}
+
+func (s *sseStream) send(event string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("Error encoding %s event: %v", event, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload)
+	if err := s.w.Flush(); err != nil {
+		// Client disconnected; stop any remaining work
+		s.cancel()
+	}
+}
+
+// ping writes an SSE comment so idle streams notice a disconnected client
+func (s *sseStream) ping() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprint(s.w, ": ping\n\n")
+	if err := s.w.Flush(); err != nil {
+		s.cancel()
+	}
+}
+
+// progress forwards pipeline updates as "stage" or "token" events
+func (s *sseStream) progress() progressFunc {
+	return func(update ProgressUpdate) {
+		if update.Stage == stageToken {
+			s.send("token", update.Partial)
+			return
+		}
+		s.send("stage", update)
+	}
+}
+
+func (s *sseStream) fail(err error) {
+	s.send("error", fiber.Map{"error": err.Error()})
+}
+
+// streamEvents switches the response to an event stream and runs fn in it
+func streamEvents(c *fiber.Ctx, fn func(ctx context.Context, stream *sseStream)) error {
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		stream := &sseStream{w: w, cancel: cancel}
+		fn(ctx, stream)
+		stream.send("done", fiber.Map{"finished_at": time.Now()})
+	})
+	return nil
+}
+
+// streamPairIDs reads resume_id and job_id, copying them out of the request
+// buffer since the stream outlives the handler
+func streamPairIDs(c *fiber.Ctx) (string, string, error) {
+	resumeID := normalizeID(strings.Clone(c.Query("resume_id")), "resume")
+	jobID := normalizeID(strings.Clone(c.Query("job_id")), "job")
+	if resumeID == "" || jobID == "" {
+		return "", "", fmt.Errorf("both resume_id and job_id are required")
+	}
+	return resumeID, jobID, nil
+}
+
+// loadStreamPair loads the resume and job and reports it as the first stage
+func loadStreamPair(stream *sseStream, resumeID, jobID string) (*TextData, *TextData, bool) {
+	resumeData, jobData, err := loadTaskPair(&AnalysisTask{ResumeID: resumeID, JobID: jobID})
+	if err != nil {
+		stream.fail(err)
+		return nil, nil, false
+	}
+	stream.send("stage", ProgressUpdate{Stage: "data_loaded"})
+	return resumeData, jobData, true
+}
+
+// StreamPreprocessResume runs resume preprocessing and streams each stage
+func StreamPreprocessResume(c *fiber.Ctx) error {
+	file, err := c.FormFile("resume")
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "No file uploaded",
+		})
+	}
+
+	uploadedFilePath := filepath.Join("uploads", "upload-"+filepath.Base(file.Filename))
+	if _, err := os.Stat(uploadedFilePath); os.IsNotExist(err) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "File not found in uploads directory",
+		})
+	}
+
+	originalName := strings.Clone(file.Filename)
+	opts := resumeProcessingOptions{
+		JobID: strings.Clone(c.FormValue("job_id")),
+		Blind: c.FormValue("blind") == "true",
+	}
+
+	return streamEvents(c, func(ctx context.Context, stream *sseStream) {
+		result, err := processResumeFile(uploadedFilePath, originalName, opts, stream.progress())
+		if err != nil {
+			stream.fail(err)
+			return
+		}
+		stream.send("result", result)
+	})
+}
+
+// StreamScoreResume scores a resume and streams each scoring stage
+func StreamScoreResume(c *fiber.Ctx) error {
+	resumeID, jobID, err := streamPairIDs(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return streamEvents(c, func(ctx context.Context, stream *sseStream) {
+		resumeData, jobData, ok := loadStreamPair(stream, resumeID, jobID)
+		if !ok {
+			return
+		}
+
+		score := scoreResumeAgainstJob(resumeData, jobData, stream.progress())
+		answers := savedKnockoutAnswers(resumeID, jobID)
+		reapplyKnockoutAnswers(&score, resumeData, jobData.Requirements, answers)
+		if err := saveScoreRecord(resumeID, jobID, jobData.Version, score, answers); err != nil {
+			log.Printf("Error saving score record: %v", err)
+		}
+		emitScoreCompleted(resumeID, jobID, jobData.Version, score, answers)
+		if isBlindScreeningJob(jobID) {
+			score = anonymizeScore(score, resumeID)
+		}
+		stream.send("result", score)
+	})
+}
+
+// StreamAnalyzeProjects analyzes projects and streams each one as it finishes
+func StreamAnalyzeProjects(c *fiber.Ctx) error {
+	resumeID, jobID, err := streamPairIDs(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return streamEvents(c, func(ctx context.Context, stream *sseStream) {
+		resumeData, jobData, ok := loadStreamPair(stream, resumeID, jobID)
+		if !ok {
+			return
+		}
+
+		projects, err := analyzeResumeProjects(ctx, resumeData, jobData, stream.progress())
+		if err != nil {
+			stream.fail(err)
+			return
+		}
+		stream.send("result", ProjectAnalysisResponse{Projects: projects})
+	})
+}
+
+// StreamAnalyzeExperience analyzes experience, token-streaming the generated
+// descriptions and job fit summaries
+func StreamAnalyzeExperience(c *fiber.Ctx) error {
+	resumeID, jobID, err := streamPairIDs(c)
+	if err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	return streamEvents(c, func(ctx context.Context, stream *sseStream) {
+		resumeData, jobData, ok := loadStreamPair(stream, resumeID, jobID)
+		if !ok {
+			return
+		}
+
+		response, err := analyzeResumeExperience(ctx, resumeID, resumeData.Entities, jobID, jobData.Requirements, jobData.ProcessedText, stream.progress())
+		if err != nil {
+			stream.fail(err)
+			return
+		}
+		stream.send("result", response)
+	})
+}
+
+// StreamTaskEvents streams a background task's progress until it finishes
+func StreamTaskEvents(c *fiber.Ctx) error {
+	taskID := strings.Clone(c.Params("id"))
+	if _, err := loadTask(taskID); err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Task not found",
+		})
+	}
+
+	return streamEvents(c, func(ctx context.Context, stream *sseStream) {
+		ticker := time.NewTicker(taskPollInterval)
+		defer ticker.Stop()
+
+		var lastUpdate time.Time
+		for {
+			task, err := loadTask(taskID)
+			if err != nil {
+				stream.fail(err)
+				return
+			}
+			if task.UpdatedAt.After(lastUpdate) {
+				lastUpdate = task.UpdatedAt
+				stream.send("stage", task.Progress)
+			}
+
+			switch task.Status {
+			case TaskCompleted:
+				stream.send("result", task.Result)
+				return
+			case TaskFailed:
+				stream.send("error", fiber.Map{"error": task.Error})
+				return
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				stream.ping()
+			}
+		}
+	})
+}
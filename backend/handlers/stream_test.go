@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// sseEvents splits an event stream into event name and data pairs
+func sseEvents(body string) [][2]string {
+	var events [][2]string
+	for _, block := range strings.Split(body, "\n\n") {
+		var event, data string
+		for _, line := range strings.Split(block, "\n") {
+			if name, ok := strings.CutPrefix(line, "event: "); ok {
+				event = name
+			} else if payload, ok := strings.CutPrefix(line, "data: "); ok {
+				data = payload
+			}
+		}
+		if event != "" {
+			events = append(events, [2]string{event, data})
+		}
+	}
+	return events
+}
+
+func TestSSEStreamProgress(t *testing.T) {
+	var buf bytes.Buffer
+	stream := &sseStream{w: bufio.NewWriter(&buf), cancel: func() {}}
+	progress := stream.progress()
+	progress.report("skills_scored", 1, 3, nil)
+	progress.report(stageToken, 0, 0, TokenChunk{Index: 2, Field: "summary", Text: "Go"})
+	stream.ping()
+	stream.fail(errors.New("model unavailable"))
+
+	want := [][2]string{
+		{"stage", `{"stage":"skills_scored","completed":1,"total":3}`},
+		{"token", `{"index":2,"field":"summary","text":"Go"}`},
+		{"error", `{"error":"model unavailable"}`},
+	}
+	got := sseEvents(buf.String())
+	if len(got) != len(want) {
+		t.Fatalf("events = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("event %d = %v, want %v", i, got[i], want[i])
+		}
+	}
+	if !strings.Contains(buf.String(), "\n: ping\n\n") {
+		t.Error("stream has no ping comment")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("client went away") }
+
+func TestSSEStreamCancelsOnDisconnect(t *testing.T) {
+	tests := []struct {
+		name  string
+		write func(*sseStream)
+	}{
+		{"event", func(s *sseStream) { s.send("stage", ProgressUpdate{Stage: "x"}) }},
+		{"ping", func(s *sseStream) { s.ping() }},
+	}
+	for _, tt := range tests {
+		ctx, cancel := context.WithCancel(context.Background())
+		tt.write(&sseStream{w: bufio.NewWriter(failingWriter{}), cancel: cancel})
+		if ctx.Err() == nil {
+			t.Errorf("%s: a failed write did not cancel the stream", tt.name)
+		}
+	}
+}
+
+func TestStreamEndpoints(t *testing.T) {
+	finished := &AnalysisTask{Type: "score_resume"}
+	failed := &AnalysisTask{Type: "score_resume"}
+	q := &taskQueue{pending: make(chan string, 2)}
+	for _, task := range []*AnalysisTask{finished, failed} {
+		if err := q.enqueue(task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	q.finish(finished, fiber.Map{"overall_score": 81}, nil)
+	q.finish(failed, nil, errors.New("resume data not found"))
+
+	app := fiber.New()
+	app.Get("/stream/score", StreamScoreResume)
+	app.Get("/jobs/:id/events", StreamTaskEvents)
+
+	tests := []struct {
+		name       string
+		path       string
+		wantStatus int
+		wantEvents []string
+		wantData   string
+	}{
+		{"completed task", "/jobs/" + finished.ID + "/events", 200, []string{"stage", "result", "done"}, `"overall_score":81`},
+		{"failed task", "/jobs/" + failed.ID + "/events", 200, []string{"stage", "error", "done"}, "resume data not found"},
+		{"unknown task", "/jobs/task_0/events", 404, nil, ""},
+		{"missing IDs", "/stream/score?resume_id=2901", 400, nil, ""},
+		{"missing resume", "/stream/score?resume_id=2999&job_id=2999", 200, []string{"error", "done"}, "resume data not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := app.Test(httptest.NewRequest("GET", tt.path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.StatusCode != tt.wantStatus {
+				t.Fatalf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+			if tt.wantEvents == nil {
+				return
+			}
+			if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+				t.Errorf("content type = %q", ct)
+			}
+			body, _ := io.ReadAll(resp.Body)
+			var names []string
+			for _, event := range sseEvents(string(body)) {
+				names = append(names, event[0])
+			}
+			if strings.Join(names, ",") != strings.Join(tt.wantEvents, ",") {
+				t.Errorf("events = %v, want %v", names, tt.wantEvents)
+			}
+			if !strings.Contains(string(body), tt.wantData) {
+				t.Errorf("stream does not contain %q:\n%s", tt.wantData, body)
+			}
+		})
+	}
+}
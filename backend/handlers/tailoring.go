@@ -0,0 +1,627 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Suggestion review states
+const (
+	SuggestionPending  = "pending"
+	SuggestionAccepted = "accepted"
+	SuggestionRejected = "rejected"
+)
+
+const maxTailoringBullets = 40
+
+var (
+	numberClaimPattern = regexp.MustCompile(`(?i)[$€£]?\d[\d,.]*\s*(%|percent|k\b|m\b|x\b|\+)?`)
+	sentenceEndPattern = regexp.MustCompile(`([.!?])\s+`)
+	bulletPrefix       = "-•*·–— \t"
+	// Verbs that claim ownership or seniority the source may not support
+	seniorityClaimVerbs = []string{
+		"led", "lead", "managed", "owned", "spearheaded", "architected",
+		"directed", "headed", "mentored", "supervised", "founded", "launched",
+	}
+)
+
+// TailoringSuggestion is a suggested rewrite of one resume bullet
+type TailoringSuggestion struct {
+	ID              string   `json:"id"`
+	Section         string   `json:"section"` // experience or project
+	EntryIndex      int      `json:"entry_index"`
+	EntryLabel      string   `json:"entry_label"`
+	Field           string   `json:"field"` // responsibilities, achievements or description
+	BulletIndex     int      `json:"bullet_index"`
+	Original        string   `json:"original"`
+	Suggested       string   `json:"suggested"`
+	TargetKeywords  []string `json:"target_keywords"`
+	FabricationRisk bool     `json:"fabrication_risk"`
+	AddedClaims     []string `json:"added_claims"`
+	Status          string   `json:"status"`
+	FinalText       string   `json:"final_text,omitempty"` // candidate's edit of the suggestion
+}
+
+// TailoringSession holds the suggestions for one resume/job pair and their
+// accept/reject state
+type TailoringSession struct {
+	ID          string                `json:"id"`
+	ResumeID    string                `json:"resume_id"`
+	JobID       string                `json:"job_id"`
+	Source      string                `json:"source"`
+	Suggestions []TailoringSuggestion `json:"suggestions"`
+	CreatedAt   time.Time             `json:"created_at"`
+	UpdatedAt   time.Time             `json:"updated_at"`
+}
+
+// resumeBullet is one rewritable line of a resume
+type resumeBullet struct {
+	section     string
+	entryIndex  int
+	entryLabel  string
+	field       string
+	bulletIndex int
+	text        string
+}
+
+// CreateTailoringSuggestions generates bullet-level rewrite suggestions for a
+// resume targeted at a job
+func CreateTailoringSuggestions(c *fiber.Ctx) error {
+	var request struct {
+		ResumeID string `json:"resume_id"`
+		JobID    string `json:"job_id"`
+	}
+	if err := c.BodyParser(&request); err != nil || request.ResumeID == "" || request.JobID == "" {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Both resume_id and job_id are required",
+		})
+	}
+
+	resumeID := normalizeID(request.ResumeID, "resume")
+	jobID := normalizeID(request.JobID, "job")
+	resumeData, jobData, err := loadTaskPair(&AnalysisTask{ResumeID: resumeID, JobID: jobID})
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	session := buildTailoringSession(context.Background(), resumeID, resumeData, jobID, jobData)
+	if err := saveTailoringSession(session); err != nil {
+		log.Printf("Error saving tailoring session: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save tailoring suggestions",
+		})
+	}
+	return c.Status(201).JSON(session)
+}
+
+// GetTailoringSession returns the suggestions and their review state
+func GetTailoringSession(c *fiber.Ctx) error {
+	session, err := loadTailoringSession(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Tailoring session not found",
+		})
+	}
+	return c.JSON(session)
+}
+
+// ReviewTailoringSuggestion accepts or rejects a suggestion. Accepting one
+// flagged for fabrication risk requires confirm_risk.
+func ReviewTailoringSuggestion(c *fiber.Ctx) error {
+	var request struct {
+		Status      string `json:"status"`
+		FinalText   string `json:"final_text"`
+		ConfirmRisk bool   `json:"confirm_risk"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	switch request.Status {
+	case SuggestionAccepted, SuggestionRejected, SuggestionPending:
+	default:
+		return c.Status(400).JSON(fiber.Map{
+			"error": "status must be accepted, rejected or pending",
+		})
+	}
+
+	session, err := loadTailoringSession(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Tailoring session not found",
+		})
+	}
+
+	var suggestion *TailoringSuggestion
+	for i := range session.Suggestions {
+		if session.Suggestions[i].ID == c.Params("suggestionId") {
+			suggestion = &session.Suggestions[i]
+			break
+		}
+	}
+	if suggestion == nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Suggestion not found",
+		})
+	}
+
+	finalText := strings.TrimSpace(request.FinalText)
+	if request.Status == SuggestionAccepted {
+		// Edited text is checked against the original just like the suggestion
+		claims := suggestion.AddedClaims
+		if finalText != "" {
+			claims = nil
+			if resumeData, jobData, err := loadTaskPair(&AnalysisTask{ResumeID: session.ResumeID, JobID: session.JobID}); err == nil {
+				claims = detectAddedClaims(suggestion.Original, finalText, resumeData.Entities.Skills, jobData.Requirements.Skills)
+			}
+		}
+		if len(claims) > 0 && !request.ConfirmRisk {
+			return c.Status(409).JSON(fiber.Map{
+				"error":        "This rewrite adds claims not supported by the original; set confirm_risk to accept it",
+				"added_claims": claims,
+			})
+		}
+	}
+
+	suggestion.Status = request.Status
+	suggestion.FinalText = finalText
+	if err := saveTailoringSession(session); err != nil {
+		log.Printf("Error saving tailoring session: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save review",
+		})
+	}
+	return c.JSON(suggestion)
+}
+
+// ExportTailoredResume applies the accepted suggestions to the resume and
+// returns it as JSON entities or plain text (?format=text)
+func ExportTailoredResume(c *fiber.Ctx) error {
+	session, err := loadTailoringSession(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Tailoring session not found",
+		})
+	}
+	resumeData, err := LoadTextData(session.ResumeID, "resume")
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Resume not found",
+		})
+	}
+
+	tailored := applyTailoringSuggestions(resumeData.Entities, session.Suggestions)
+	if c.Query("format") == "text" {
+		c.Set("Content-Type", "text/plain; charset=utf-8")
+		c.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.txt"`, session.ID))
+		return c.SendString(renderResumeText(tailored))
+	}
+
+	accepted := 0
+	for _, s := range session.Suggestions {
+		if s.Status == SuggestionAccepted {
+			accepted++
+		}
+	}
+	return c.JSON(fiber.Map{
+		"resume_id":            session.ResumeID,
+		"job_id":               session.JobID,
+		"accepted_suggestions": accepted,
+		"entities":             tailored,
+	})
+}
+
+func tailoringSessionPath(sessionID string) string {
+	return filepath.Join("processed_texts", "tailoring", safeID(sessionID)+".json")
+}
+
+func saveTailoringSession(session *TailoringSession) error {
+	session.UpdatedAt = time.Now()
+	return utils.SaveJSONFile(tailoringSessionPath(session.ID), session, 0644)
+}
+
+func loadTailoringSession(sessionID string) (*TailoringSession, error) {
+	var session TailoringSession
+	if err := utils.LoadJSONFile(tailoringSessionPath(sessionID), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func buildTailoringSession(ctx context.Context, resumeID string, resumeData *TextData, jobID string, jobData *TextData) *TailoringSession {
+	session := &TailoringSession{
+		// Every run gets its own session so regenerating never discards
+		// decisions already made on an earlier one
+		ID:          fmt.Sprintf("tailor_%s_%s_%d", resumeID, jobID, time.Now().UnixNano()),
+		ResumeID:    resumeID,
+		JobID:       jobID,
+		Source:      "llm",
+		Suggestions: []TailoringSuggestion{},
+		CreatedAt:   time.Now(),
+	}
+
+	bullets := collectResumeBullets(resumeData.Entities)
+	if len(bullets) > maxTailoringBullets {
+		bullets = bullets[:maxTailoringBullets]
+	}
+
+	rewrites, err := generateTailoredBullets(ctx, bullets, jobData)
+	if err != nil {
+		log.Printf("Falling back to terminology-only tailoring: %v", err)
+		session.Source = "terminology"
+		rewrites = terminologyRewrites(bullets, resumeData.Entities.Skills, jobData.Requirements.Skills)
+	}
+
+	for i, bullet := range bullets {
+		rewrite, ok := rewrites[i]
+		if !ok || strings.TrimSpace(rewrite.Suggested) == "" || strings.TrimSpace(rewrite.Suggested) == bullet.text {
+			continue
+		}
+		claims := detectAddedClaims(bullet.text, rewrite.Suggested, resumeData.Entities.Skills, jobData.Requirements.Skills)
+		keywords := rewrite.Keywords
+		if len(keywords) == 0 {
+			keywords = keywordsIn(rewrite.Suggested, jobData.Requirements.Skills)
+		}
+		session.Suggestions = append(session.Suggestions, TailoringSuggestion{
+			ID:              fmt.Sprintf("s%d", len(session.Suggestions)+1),
+			Section:         bullet.section,
+			EntryIndex:      bullet.entryIndex,
+			EntryLabel:      bullet.entryLabel,
+			Field:           bullet.field,
+			BulletIndex:     bullet.bulletIndex,
+			Original:        bullet.text,
+			Suggested:       strings.TrimSpace(rewrite.Suggested),
+			TargetKeywords:  keywords,
+			FabricationRisk: len(claims) > 0,
+			AddedClaims:     claims,
+			Status:          SuggestionPending,
+		})
+	}
+	return session
+}
+
+// collectResumeBullets flattens experience and project entries into
+// individually rewritable bullets
+func collectResumeBullets(entities ExtractedEntities) []resumeBullet {
+	var bullets []resumeBullet
+	addAll := func(section string, entryIndex int, label, field string, lines []string) {
+		for i, line := range lines {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			bullets = append(bullets, resumeBullet{section, entryIndex, label, field, i, strings.TrimSpace(line)})
+		}
+	}
+
+	for i, exp := range entities.Experience {
+		label := fmt.Sprintf("%s at %s", exp.Title, exp.Company)
+		addAll("experience", i, label, "description", splitBullets(exp.Description))
+		addAll("experience", i, label, "responsibilities", exp.Responsibilities)
+		addAll("experience", i, label, "achievements", exp.Achievements)
+	}
+	for i, project := range entities.Projects {
+		addAll("project", i, project.Name, "description", splitBullets(project.Description))
+		addAll("project", i, project.Name, "achievements", project.Achievements)
+	}
+	return bullets
+}
+
+// splitBullets splits a description into lines, or into sentences when it is
+// a single paragraph
+func splitBullets(text string) []string {
+	var bullets []string
+	lines := strings.Split(text, "\n")
+	for _, line := range lines {
+		line = strings.TrimLeft(strings.TrimSpace(line), bulletPrefix)
+		if line == "" {
+			continue
+		}
+		if len(lines) > 1 {
+			bullets = append(bullets, line)
+			continue
+		}
+		for _, sentence := range strings.Split(sentenceEndPattern.ReplaceAllString(line, "$1\n"), "\n") {
+			if s := strings.TrimSpace(sentence); s != "" {
+				bullets = append(bullets, s)
+			}
+		}
+	}
+	return bullets
+}
+
+// joinBullets reverses splitBullets for a rewritten description
+func joinBullets(original string, bullets []string) string {
+	if strings.Contains(strings.TrimSpace(original), "\n") {
+		return strings.Join(bullets, "\n")
+	}
+	return strings.Join(bullets, " ")
+}
+
+type bulletRewrite struct {
+	Index     int      `json:"index"`
+	Suggested string   `json:"suggested"`
+	Keywords  []string `json:"keywords"`
+}
+
+func generateTailoredBullets(ctx context.Context, bullets []resumeBullet, jobData *TextData) (map[int]bulletRewrite, error) {
+	if len(bullets) == 0 {
+		return map[int]bulletRewrite{}, nil
+	}
+
+	llm, err := newLLMProvider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer llm.Close()
+
+	var lines []string
+	for i, bullet := range bullets {
+		lines = append(lines, fmt.Sprintf("%d. [%s] %s", i, bullet.entryLabel, bullet.text))
+	}
+
+	prompt := fmt.Sprintf(`Rewrite resume bullets to target this job.
+
+Job description: %s
+Job keywords: %s
+
+Bullets:
+%s
+
+Rules:
+- Use the job's terminology where the bullet already describes the same thing.
+- Do not add numbers, tools, technologies, team sizes or responsibilities that the bullet does not state.
+- Skip bullets that are already well targeted.
+
+Return only a JSON array: [{"index": 0, "suggested": "", "keywords": ["job keywords the rewrite targets"]}]`,
+		sanitizeText(jobData.ProcessedText, maxPromptLength),
+		strings.Join(jobData.Requirements.Skills, ", "),
+		strings.Join(lines, "\n"))
+
+	text, err := llm.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	jsonStr := cleanJSONArrayString(text)
+	if jsonStr == "" {
+		return nil, fmt.Errorf("no JSON array in model response")
+	}
+
+	var parsed []bulletRewrite
+	if err := json.Unmarshal([]byte(jsonStr), &parsed); err != nil {
+		return nil, err
+	}
+
+	rewrites := make(map[int]bulletRewrite)
+	for _, rewrite := range parsed {
+		if rewrite.Index >= 0 && rewrite.Index < len(bullets) {
+			rewrites[rewrite.Index] = rewrite
+		}
+	}
+	return rewrites, nil
+}
+
+// cleanJSONArrayString extracts the outermost JSON array from a model response
+func cleanJSONArrayString(s string) string {
+	start := strings.Index(s, "[")
+	end := strings.LastIndex(s, "]")
+	if start == -1 || end <= start {
+		return ""
+	}
+	return s[start : end+1]
+}
+
+// terminologyRewrites swaps the resume's wording for the job's wording where
+// the two only partially match, which never adds new claims
+func terminologyRewrites(bullets []resumeBullet, resumeSkills, jobSkills []string) map[int]bulletRewrite {
+	_, partialMatches, _ := analyzeSkillMatches(resumeSkills, jobSkills)
+	rewrites := make(map[int]bulletRewrite)
+	for i, bullet := range bullets {
+		suggested := bullet.text
+		var keywords []string
+		for _, pm := range partialMatches {
+			pattern, err := regexp.Compile(`(?i)\b` + regexp.QuoteMeta(pm.ResumeSkill) + `\b`)
+			if err != nil || !pattern.MatchString(suggested) || containsFold(suggested, pm.JobSkill) {
+				continue
+			}
+			suggested = pattern.ReplaceAllString(suggested, fmt.Sprintf("%s (%s)", pm.JobSkill, pm.ResumeSkill))
+			keywords = append(keywords, pm.JobSkill)
+		}
+		if len(keywords) > 0 {
+			rewrites[i] = bulletRewrite{Index: i, Suggested: suggested, Keywords: keywords}
+		}
+	}
+	return rewrites
+}
+
+// detectAddedClaims lists facts in the rewrite that the original does not
+// state: new numbers, job skills not in the original or the resume's skill
+// list, and seniority verbs
+func detectAddedClaims(original, rewrite string, resumeSkills, jobSkills []string) []string {
+	claims := []string{}
+	originalLower := strings.ToLower(original)
+
+	// Figures are compared whole, so "5" in the rewrite isn't excused by "15"
+	originalFigures := make(map[string]bool)
+	for _, number := range numberClaimPattern.FindAllString(original, -1) {
+		originalFigures[normalizeFigure(number)] = true
+	}
+	for _, number := range numberClaimPattern.FindAllString(rewrite, -1) {
+		digits := strings.TrimRight(strings.TrimSpace(number), ".,")
+		if digits != "" && !originalFigures[normalizeFigure(number)] {
+			claims = append(claims, fmt.Sprintf("new figure %q", digits))
+		}
+	}
+
+	for _, term := range keywordsIn(rewrite, jobSkills) {
+		if containsFold(original, term) {
+			continue
+		}
+		supported := false
+		for _, skill := range resumeSkills {
+			if strings.EqualFold(skill, term) {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			claims = append(claims, fmt.Sprintf("new skill %q", term))
+		}
+	}
+
+	originalWords := make(map[string]bool)
+	for _, word := range strings.Fields(originalLower) {
+		originalWords[strings.Trim(word, ".,;:()")] = true
+	}
+	for _, word := range strings.Fields(strings.ToLower(rewrite)) {
+		word = strings.Trim(word, ".,;:()")
+		for _, verb := range seniorityClaimVerbs {
+			if word == verb && !originalWords[verb] {
+				claims = append(claims, fmt.Sprintf("new responsibility %q", verb))
+			}
+		}
+	}
+	return claims
+}
+
+// normalizeFigure drops case, inner spaces and trailing punctuation so
+// "20 %" and "20%." compare equal
+func normalizeFigure(number string) string {
+	return strings.TrimRight(strings.Join(strings.Fields(strings.ToLower(number)), ""), ".,")
+}
+
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+func keywordsIn(text string, keywords []string) []string {
+	found := []string{}
+	for _, keyword := range keywords {
+		if containsFold(text, keyword) {
+			found = append(found, keyword)
+		}
+	}
+	return found
+}
+
+// applyTailoringSuggestions returns a copy of the entities with every
+// accepted suggestion applied
+func applyTailoringSuggestions(entities ExtractedEntities, suggestions []TailoringSuggestion) ExtractedEntities {
+	tailored := entities
+	tailored.Experience = append([]Experience(nil), entities.Experience...)
+	tailored.Projects = append([]Project(nil), entities.Projects...)
+
+	replace := func(lines []string, s TailoringSuggestion, text string) []string {
+		lines = append([]string(nil), lines...)
+		if s.BulletIndex < len(lines) && strings.TrimSpace(lines[s.BulletIndex]) == s.Original {
+			lines[s.BulletIndex] = text
+		}
+		return lines
+	}
+
+	for _, s := range suggestions {
+		if s.Status != SuggestionAccepted {
+			continue
+		}
+		text := s.Suggested
+		if s.FinalText != "" {
+			text = s.FinalText
+		}
+
+		switch s.Section {
+		case "experience":
+			if s.EntryIndex >= len(tailored.Experience) {
+				continue
+			}
+			exp := &tailored.Experience[s.EntryIndex]
+			switch s.Field {
+			case "description":
+				exp.Description = joinBullets(exp.Description, replace(splitBullets(exp.Description), s, text))
+			case "responsibilities":
+				exp.Responsibilities = replace(exp.Responsibilities, s, text)
+			case "achievements":
+				exp.Achievements = replace(exp.Achievements, s, text)
+			}
+		case "project":
+			if s.EntryIndex >= len(tailored.Projects) {
+				continue
+			}
+			project := &tailored.Projects[s.EntryIndex]
+			switch s.Field {
+			case "description":
+				project.Description = joinBullets(project.Description, replace(splitBullets(project.Description), s, text))
+			case "achievements":
+				project.Achievements = replace(project.Achievements, s, text)
+			}
+		}
+	}
+	return tailored
+}
+
+// renderResumeText lays out resume entities as plain text
+func renderResumeText(entities ExtractedEntities) string {
+	var b strings.Builder
+	writeList := func(lines []string) {
+		for _, line := range lines {
+			if strings.TrimSpace(line) != "" {
+				fmt.Fprintf(&b, "  - %s\n", strings.TrimSpace(line))
+			}
+		}
+	}
+
+	b.WriteString(entities.Name + "\n")
+	contact := append([]string(nil), entities.Email...)
+	if entities.Phone != "" {
+		contact = append(contact, entities.Phone)
+	}
+	if len(contact) > 0 {
+		b.WriteString(strings.Join(contact, " | ") + "\n")
+	}
+
+	if len(entities.Skills) > 0 {
+		b.WriteString("\nSKILLS\n" + strings.Join(entities.Skills, ", ") + "\n")
+	}
+
+	if len(entities.Experience) > 0 {
+		b.WriteString("\nEXPERIENCE\n")
+		for _, exp := range entities.Experience {
+			fmt.Fprintf(&b, "%s, %s (%s)\n", exp.Title, exp.Company, exp.Duration)
+			writeList(splitBullets(exp.Description))
+			writeList(exp.Responsibilities)
+			writeList(exp.Achievements)
+		}
+	}
+
+	if len(entities.Projects) > 0 {
+		b.WriteString("\nPROJECTS\n")
+		for _, project := range entities.Projects {
+			b.WriteString(project.Name)
+			if len(project.Technologies) > 0 {
+				fmt.Fprintf(&b, " [%s]", strings.Join(project.Technologies, ", "))
+			}
+			b.WriteString("\n")
+			writeList(splitBullets(project.Description))
+			writeList(project.Achievements)
+		}
+	}
+
+	if len(entities.Education) > 0 {
+		b.WriteString("\nEDUCATION\n")
+		for _, edu := range entities.Education {
+			fmt.Fprintf(&b, "%s, %s (%s)\n", edu.Degree, edu.Institution, edu.Year)
+		}
+	}
+	return b.String()
+}
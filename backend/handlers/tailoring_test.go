@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestDetectAddedClaims(t *testing.T) {
+	resumeSkills := []string{"Go", "PostgreSQL"}
+	jobSkills := []string{"Go", "Kubernetes", "PostgreSQL"}
+	tests := []struct {
+		name      string
+		original  string
+		rewrite   string
+		wantClaim []string
+	}{
+		{"rewording only", "Built billing services in Go", "Developed billing microservices in Go", nil},
+		{"same figure, different spacing", "Cut latency by 20 %.", "Reduced latency by 20%", nil},
+		{"new figure", "Cut latency", "Cut latency by 40%", []string{`new figure "40%"`}},
+		{"figure not excused by a longer one", "Served 15 teams", "Served 5 teams", []string{`new figure "5"`}},
+		{"unit suffix changed", "Handled 2k requests a day", "Handled 2M requests a day", []string{`new figure "2M"`}},
+		{"unit suffix case", "Saved $3M", "Saved $3m", nil},
+		{"skill from the resume list", "Built billing services", "Built billing services on PostgreSQL", nil},
+		{"unsupported job skill", "Built billing services", "Built billing services on Kubernetes", []string{`new skill "Kubernetes"`}},
+		{"seniority verb", "Worked on the billing team", "Led the billing team", []string{`new responsibility "led"`}},
+		{"seniority verb already there", "Led the billing team", "Led the billing team of engineers", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := detectAddedClaims(tt.original, tt.rewrite, resumeSkills, jobSkills)
+			if strings.Join(got, "|") != strings.Join(tt.wantClaim, "|") {
+				t.Errorf("claims = %q, want %q", got, tt.wantClaim)
+			}
+		})
+	}
+}
+
+func TestSplitAndJoinBullets(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"sentences of a paragraph", "Built the API. Cut costs by 20%! Why? Because.", []string{"Built the API.", "Cut costs by 20%!", "Why?", "Because."}},
+		{"lines keep their sentences", "- Built the API. Shipped it.\n• Ran on-call\n\n", []string{"Built the API. Shipped it.", "Ran on-call"}},
+		{"version numbers are not sentence ends", "Upgraded to Go 1.22 quickly", []string{"Upgraded to Go 1.22 quickly"}},
+		{"empty", "", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitBullets(tt.text)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("splitBullets = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if got := joinBullets("One. Two.", []string{"One.", "Two."}); got != "One. Two." {
+		t.Errorf("joinBullets for a paragraph = %q", got)
+	}
+	if got := joinBullets("- One\n- Two", []string{"One", "Two"}); got != "One\nTwo" {
+		t.Errorf("joinBullets for lines = %q", got)
+	}
+}
+
+func TestCleanJSONArrayString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"```json\n[{\"index\":0}]\n```", `[{"index":0}]`},
+		{`[1, [2]] trailing`, `[1, [2]]`},
+		{"no array here", ""},
+		{"] backwards [", ""},
+	}
+	for _, tt := range tests {
+		if got := cleanJSONArrayString(tt.in); got != tt.want {
+			t.Errorf("cleanJSONArrayString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func tailoringEntities() ExtractedEntities {
+	return ExtractedEntities{
+		Name:   "Jane Doe",
+		Skills: []string{"Go"},
+		Experience: []Experience{{
+			Title: "Engineer", Company: "Acme", Duration: "2019 - 2022",
+			Description:      "Built the API. Ran on-call.",
+			Responsibilities: []string{"Owned deploys", ""},
+		}},
+		Projects: []Project{{Name: "Ledger", Achievements: []string{"Shipped v1"}}},
+	}
+}
+
+func TestCollectResumeBullets(t *testing.T) {
+	var got []string
+	for _, b := range collectResumeBullets(tailoringEntities()) {
+		got = append(got, strings.Join([]string{b.section, b.field, b.entryLabel, b.text}, ":"))
+	}
+	want := []string{
+		"experience:description:Engineer at Acme:Built the API.",
+		"experience:description:Engineer at Acme:Ran on-call.",
+		"experience:responsibilities:Engineer at Acme:Owned deploys",
+		"project:achievements:Ledger:Shipped v1",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("bullets = %q, want %q", got, want)
+	}
+}
+
+func TestApplyTailoringSuggestions(t *testing.T) {
+	entities := tailoringEntities()
+	suggestions := []TailoringSuggestion{
+		{Section: "experience", Field: "description", BulletIndex: 1, Original: "Ran on-call.", Suggested: "Ran on-call for payments.", Status: SuggestionAccepted},
+		{Section: "experience", Field: "responsibilities", BulletIndex: 0, Original: "Owned deploys", Suggested: "Owned releases", FinalText: "Owned weekly releases", Status: SuggestionAccepted},
+		{Section: "project", Field: "achievements", BulletIndex: 0, Original: "Shipped v1", Suggested: "Shipped v2", Status: SuggestionRejected},
+		{Section: "project", Field: "achievements", BulletIndex: 0, Original: "Changed since", Suggested: "Stale", Status: SuggestionAccepted},
+		{Section: "experience", EntryIndex: 5, Field: "description", Suggested: "Out of range", Status: SuggestionAccepted},
+	}
+	tailored := applyTailoringSuggestions(entities, suggestions)
+
+	tests := []struct {
+		field     string
+		got, want string
+	}{
+		{"description", tailored.Experience[0].Description, "Built the API. Ran on-call for payments."},
+		{"edited responsibility", tailored.Experience[0].Responsibilities[0], "Owned weekly releases"},
+		{"rejected and stale suggestions", tailored.Projects[0].Achievements[0], "Shipped v1"},
+		{"original untouched", entities.Experience[0].Responsibilities[0], "Owned deploys"},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("%s = %q, want %q", tt.field, tt.got, tt.want)
+		}
+	}
+}
+
+func TestReviewTailoringSuggestion(t *testing.T) {
+	saveTestText(t, "resume", "3301", TextData{Entities: tailoringEntities()})
+	saveTestText(t, "job", "3301", TextData{Requirements: JobRequirements{Skills: []string{"Go", "Kubernetes"}}})
+	session := &TailoringSession{
+		ID: "tailor_3301_3301_1", ResumeID: "3301", JobID: "3301",
+		Suggestions: []TailoringSuggestion{
+			{ID: "s1", Section: "experience", Field: "responsibilities", Original: "Owned deploys", Suggested: "Owned deploys to Kubernetes",
+				FabricationRisk: true, AddedClaims: []string{`new skill "Kubernetes"`}, Status: SuggestionPending},
+			{ID: "s2", Section: "experience", Field: "description", BulletIndex: 0, Original: "Built the API.", Suggested: "Built the REST API.", Status: SuggestionPending},
+		},
+	}
+	if err := saveTailoringSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Put("/tailoring/:id/suggestions/:suggestionId", ReviewTailoringSuggestion)
+	app.Get("/tailoring/:id/export", ExportTailoredResume)
+	review := func(suggestion, body string) int {
+		req := httptest.NewRequest("PUT", "/tailoring/"+session.ID+"/suggestions/"+suggestion, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return resp.StatusCode
+	}
+
+	tests := []struct {
+		name       string
+		suggestion string
+		body       string
+		want       int
+	}{
+		{"risky without confirmation", "s1", `{"status":"accepted"}`, 409},
+		{"edit that still adds a skill", "s1", `{"status":"accepted","final_text":"Owned Kubernetes deploys"}`, 409},
+		{"edit that removes the claim", "s1", `{"status":"accepted","final_text":"Owned weekly deploys"}`, 200},
+		{"safe suggestion", "s2", `{"status":"accepted"}`, 200},
+		{"bad status", "s2", `{"status":"maybe"}`, 400},
+		{"unknown suggestion", "s9", `{"status":"rejected"}`, 404},
+	}
+	for _, tt := range tests {
+		if got := review(tt.suggestion, tt.body); got != tt.want {
+			t.Errorf("%s: status = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+
+	resp, err := app.Test(httptest.NewRequest("GET", "/tailoring/"+session.ID+"/export", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export struct {
+		Accepted int               `json:"accepted_suggestions"`
+		Entities ExtractedEntities `json:"entities"`
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.Accepted != 2 || export.Entities.Experience[0].Responsibilities[0] != "Owned weekly deploys" ||
+		export.Entities.Experience[0].Description != "Built the REST API. Ran on-call." {
+		t.Errorf("export = %s", body)
+	}
+
+	resp, _ = app.Test(httptest.NewRequest("GET", "/tailoring/"+session.ID+"/export?format=text", nil))
+	text, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(text), "  - Owned weekly deploys\n") || !strings.Contains(string(text), "EXPERIENCE\nEngineer, Acme (2019 - 2022)\n") {
+		t.Errorf("text export = %s", text)
+	}
+}
@@ -0,0 +1,419 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"interviewme/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// Task statuses
+const (
+	TaskQueued    = "queued"
+	TaskRunning   = "running"
+	TaskCompleted = "completed"
+	TaskFailed    = "failed"
+)
+
+const (
+	defaultTaskWorkers = 2
+	maxTaskAttempts    = 3
+	taskQueueSize      = 1024
+)
+
+// AnalysisTask is a persisted long-running analysis
+type AnalysisTask struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	ResumeID   string            `json:"resume_id,omitempty"`
+	JobID      string            `json:"job_id,omitempty"`
+	Params     map[string]string `json:"params,omitempty"`
+	Status     string            `json:"status"`
+	Progress   TaskProgress      `json:"progress"`
+	Result     json.RawMessage   `json:"result,omitempty"`
+	Error      string            `json:"error,omitempty"`
+	Attempts   int               `json:"attempts"`
+	CreatedAt  time.Time         `json:"created_at"`
+	StartedAt  *time.Time        `json:"started_at,omitempty"`
+	FinishedAt *time.Time        `json:"finished_at,omitempty"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// TaskProgress describes how far a task has come
+type TaskProgress struct {
+	Stage     string  `json:"stage"`
+	Completed int     `json:"completed"`
+	Total     int     `json:"total"`
+	Percent   float64 `json:"percent"`
+}
+
+// ProgressUpdate is emitted by analysis pipelines as they advance
+type ProgressUpdate struct {
+	Stage     string `json:"stage"`
+	Completed int    `json:"completed"`
+	Total     int    `json:"total"`
+	Partial   any    `json:"partial,omitempty"`
+}
+
+// TokenChunk is a piece of streamed LLM output for one field of a partial result
+type TokenChunk struct {
+	Index int    `json:"index"`
+	Field string `json:"field"`
+	Text  string `json:"text"`
+}
+
+// stageToken marks progress updates that carry a TokenChunk rather than a step
+const stageToken = "token"
+
+// progressFunc receives pipeline progress; a nil progressFunc ignores updates
+type progressFunc func(update ProgressUpdate)
+
+func (p progressFunc) report(stage string, completed, total int, partial any) {
+	if p == nil {
+		return
+	}
+	p(ProgressUpdate{Stage: stage, Completed: completed, Total: total, Partial: partial})
+}
+
+// taskRunner executes a task and returns its result
+type taskRunner func(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error)
+
+var taskRunners = map[string]taskRunner{
+	"analyze_projects":   runProjectAnalysisTask,
+	"analyze_experience": runExperienceAnalysisTask,
+	"score_resume":       runScoreTask,
+	"rescore_job":        runRescoreJobTask,
+	"preprocess_resume":  runPreprocessResumeTask,
+	"ats_application":    runATSApplicationTask,
+}
+
+// publicTaskTypes are the task types callers may create through POST /jobs.
+// The rest read files and settings from their params, so they are only
+// queued internally with params the server builds.
+var publicTaskTypes = map[string]bool{
+	"analyze_projects":   true,
+	"analyze_experience": true,
+	"score_resume":       true,
+}
+
+type taskQueue struct {
+	mu      sync.Mutex
+	pending chan string
+	started bool
+}
+
+var tasks = &taskQueue{pending: make(chan string, taskQueueSize)}
+
+func taskPath(id string) string {
+	return filepath.Join("processed_texts", "tasks", safeID(id)+".json")
+}
+
+func loadTask(id string) (*AnalysisTask, error) {
+	var task AnalysisTask
+	if err := utils.LoadJSONFile(taskPath(id), &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (q *taskQueue) save(task *AnalysisTask) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	task.UpdatedAt = time.Now()
+	return utils.SaveJSONFile(taskPath(task.ID), task, 0644)
+}
+
+// enqueue persists a new task and hands it to the workers
+func (q *taskQueue) enqueue(task *AnalysisTask) error {
+	task.ID = fmt.Sprintf("task_%d", time.Now().UnixNano())
+	task.Status = TaskQueued
+	task.CreatedAt = time.Now()
+	if err := q.save(task); err != nil {
+		return err
+	}
+	q.push(task.ID)
+	return nil
+}
+
+func (q *taskQueue) push(id string) {
+	select {
+	case q.pending <- id:
+	default:
+		// Queue is full; wait for a free slot without blocking the caller
+		go func() { q.pending <- id }()
+	}
+}
+
+// StartTaskWorkers starts the bounded worker pool and re-queues tasks that
+// were still queued or running when the server last stopped.
+func StartTaskWorkers() {
+	tasks.mu.Lock()
+	if tasks.started {
+		tasks.mu.Unlock()
+		return
+	}
+	tasks.started = true
+	tasks.mu.Unlock()
+
+	workers := defaultTaskWorkers
+	if raw := os.Getenv("ANALYSIS_WORKERS"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			workers = n
+		}
+	}
+
+	resumeUnfinishedTasks()
+
+	for i := 0; i < workers; i++ {
+		go tasks.work()
+	}
+	log.Printf("Started %d analysis workers", workers)
+}
+
+func resumeUnfinishedTasks() {
+	paths, err := filepath.Glob(filepath.Join("processed_texts", "tasks", "task_*.json"))
+	if err != nil {
+		return
+	}
+
+	var unfinished []*AnalysisTask
+	for _, path := range paths {
+		var task AnalysisTask
+		if err := utils.LoadJSONFile(path, &task); err != nil {
+			log.Printf("Skipping unreadable task %s: %v", path, err)
+			continue
+		}
+		if task.Status == TaskQueued || task.Status == TaskRunning {
+			unfinished = append(unfinished, &task)
+		}
+	}
+
+	// Resume in submission order
+	sort.Slice(unfinished, func(i, j int) bool {
+		return unfinished[i].CreatedAt.Before(unfinished[j].CreatedAt)
+	})
+	for _, task := range unfinished {
+		task.Status = TaskQueued
+		if err := tasks.save(task); err != nil {
+			log.Printf("Error re-queueing task %s: %v", task.ID, err)
+			continue
+		}
+		tasks.push(task.ID)
+	}
+	if len(unfinished) > 0 {
+		log.Printf("Resumed %d unfinished tasks", len(unfinished))
+	}
+}
+
+func (q *taskQueue) work() {
+	for id := range q.pending {
+		q.run(id)
+	}
+}
+
+func (q *taskQueue) run(id string) {
+	task, err := loadTask(id)
+	if err != nil {
+		log.Printf("Error loading task %s: %v", id, err)
+		return
+	}
+
+	runner, ok := taskRunners[task.Type]
+	if !ok {
+		q.finish(task, nil, fmt.Errorf("unknown task type %q", task.Type))
+		return
+	}
+
+	now := time.Now()
+	task.Status = TaskRunning
+	task.StartedAt = &now
+	task.Attempts++
+	task.Error = ""
+	if task.Attempts > maxTaskAttempts {
+		q.finish(task, nil, fmt.Errorf("gave up after %d attempts", maxTaskAttempts))
+		return
+	}
+	if err := q.save(task); err != nil {
+		log.Printf("Error saving task %s: %v", task.ID, err)
+	}
+
+	progress := func(update ProgressUpdate) {
+		// Token streams are only useful to live listeners
+		if update.Stage == stageToken {
+			return
+		}
+		task.Progress = TaskProgress{
+			Stage:     update.Stage,
+			Completed: update.Completed,
+			Total:     update.Total,
+		}
+		if update.Total > 0 {
+			task.Progress.Percent = float64(update.Completed) / float64(update.Total) * 100
+		}
+		if err := q.save(task); err != nil {
+			log.Printf("Error saving task progress %s: %v", task.ID, err)
+		}
+	}
+
+	result, err := runner(context.Background(), task, progress)
+	q.finish(task, result, err)
+}
+
+func (q *taskQueue) finish(task *AnalysisTask, result any, runErr error) {
+	now := time.Now()
+	task.FinishedAt = &now
+	if runErr != nil {
+		log.Printf("Task %s (%s) failed: %v", task.ID, task.Type, runErr)
+		task.Status = TaskFailed
+		task.Error = runErr.Error()
+		emitWebhookEvent(EventAnalysisFailed, fiber.Map{
+			"task_id":   task.ID,
+			"type":      task.Type,
+			"resume_id": task.ResumeID,
+			"job_id":    task.JobID,
+			"error":     task.Error,
+			"attempts":  task.Attempts,
+		})
+	} else {
+		task.Status = TaskCompleted
+		task.Progress.Stage = "done"
+		task.Progress.Completed = task.Progress.Total
+		task.Progress.Percent = 100
+		if data, err := json.Marshal(result); err == nil {
+			task.Result = data
+		} else {
+			task.Status = TaskFailed
+			task.Error = fmt.Sprintf("failed to encode result: %v", err)
+		}
+	}
+	if err := q.save(task); err != nil {
+		log.Printf("Error saving task %s: %v", task.ID, err)
+	}
+}
+
+// loadTaskPair loads the resume and job a task refers to
+func loadTaskPair(task *AnalysisTask) (*TextData, *TextData, error) {
+	resumeData, err := LoadTextData("resume_"+normalizeID(task.ResumeID, "resume"), "resume")
+	if err != nil {
+		return nil, nil, fmt.Errorf("resume data not found: %v", err)
+	}
+	jobData, err := LoadTextData("job_"+normalizeID(task.JobID, "job"), "job")
+	if err != nil {
+		return nil, nil, fmt.Errorf("job data not found: %v", err)
+	}
+	return resumeData, jobData, nil
+}
+
+func runProjectAnalysisTask(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+	resumeData, jobData, err := loadTaskPair(task)
+	if err != nil {
+		return nil, err
+	}
+	projects, err := analyzeResumeProjects(ctx, resumeData, jobData, progress)
+	if err != nil {
+		return nil, err
+	}
+	return ProjectAnalysisResponse{Projects: projects}, nil
+}
+
+func runExperienceAnalysisTask(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+	resumeData, jobData, err := loadTaskPair(task)
+	if err != nil {
+		return nil, err
+	}
+	return analyzeResumeExperience(ctx, task.ResumeID, resumeData.Entities, task.JobID, jobData.Requirements, jobData.ProcessedText, progress)
+}
+
+func runScoreTask(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+	resumeData, jobData, err := loadTaskPair(task)
+	if err != nil {
+		return nil, err
+	}
+	score := scoreResumeAgainstJob(resumeData, jobData, progress)
+	answers := savedKnockoutAnswers(task.ResumeID, task.JobID)
+	reapplyKnockoutAnswers(&score, resumeData, jobData.Requirements, answers)
+	if err := saveScoreRecord(task.ResumeID, task.JobID, jobData.Version, score, answers); err != nil {
+		log.Printf("Error saving score record: %v", err)
+	}
+	emitScoreCompleted(task.ResumeID, task.JobID, jobData.Version, score, answers)
+	if isBlindScreeningJob(task.JobID) {
+		score = anonymizeScore(score, task.ResumeID)
+	}
+	return score, nil
+}
+
+// saveAnalysisResult stores the latest analysis of a given kind for a resume/job pair
+func saveAnalysisResult(kind, resumeID, jobID string, result any) error {
+	return utils.SaveJSONFile(analysisResultPath(kind, resumeID, jobID), result, 0644)
+}
+
+// loadAnalysisResult reads a stored analysis into v
+func loadAnalysisResult(kind, resumeID, jobID string, v any) error {
+	return utils.LoadJSONFile(analysisResultPath(kind, resumeID, jobID), v)
+}
+
+func analysisResultPath(kind, resumeID, jobID string) string {
+	return filepath.Join("processed_texts", "analyses",
+		fmt.Sprintf("%s_%s_%s.json", kind, pathID(resumeID, "resume"), pathID(jobID, "job")))
+}
+
+// CreateTask queues a long-running analysis and returns its ID immediately
+func CreateTask(c *fiber.Ctx) error {
+	var request struct {
+		Type     string `json:"type"`
+		ResumeID string `json:"resume_id"`
+		JobID    string `json:"job_id"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if !publicTaskTypes[request.Type] {
+		types := sortedKeys(publicTaskTypes)
+		return c.Status(400).JSON(fiber.Map{
+			"error":       "Unknown task type",
+			"valid_types": types,
+		})
+	}
+
+	task := &AnalysisTask{
+		Type:     request.Type,
+		ResumeID: normalizeID(request.ResumeID, "resume"),
+		JobID:    normalizeID(request.JobID, "job"),
+	}
+	if err := tasks.enqueue(task); err != nil {
+		log.Printf("Error queueing task: %v", err)
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to queue task",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(fiber.Map{
+		"id":     task.ID,
+		"status": task.Status,
+		"url":    "/jobs/" + task.ID,
+	})
+}
+
+// GetTask returns a task's status, progress and (when finished) its result
+func GetTask(c *fiber.Ctx) error {
+	task, err := loadTask(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Task not found",
+		})
+	}
+	return c.JSON(task)
+}
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+func TestTaskQueueRun(t *testing.T) {
+	taskRunners["test_ok"] = func(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+		progress.report("halfway", 1, 2, nil)
+		progress.report(stageToken, 0, 0, TokenChunk{Text: "ignored"})
+		return fiber.Map{"resume_id": task.ResumeID}, nil
+	}
+	taskRunners["test_fail"] = func(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+		return nil, errors.New("model unavailable")
+	}
+	taskRunners["test_unencodable"] = func(ctx context.Context, task *AnalysisTask, progress progressFunc) (any, error) {
+		return func() {}, nil
+	}
+	t.Cleanup(func() {
+		delete(taskRunners, "test_ok")
+		delete(taskRunners, "test_fail")
+		delete(taskRunners, "test_unencodable")
+	})
+
+	tests := []struct {
+		name       string
+		task       AnalysisTask
+		wantStatus string
+		wantError  string
+		wantResult string
+	}{
+		{"completes", AnalysisTask{Type: "test_ok", ResumeID: "2801"}, TaskCompleted, "", `{"resume_id":"2801"}`},
+		{"records the runner's error", AnalysisTask{Type: "test_fail"}, TaskFailed, "model unavailable", ""},
+		{"unknown type", AnalysisTask{Type: "no_such_task"}, TaskFailed, `unknown task type "no_such_task"`, ""},
+		{"gives up after the last attempt", AnalysisTask{Type: "test_ok", Attempts: maxTaskAttempts}, TaskFailed, "gave up after 3 attempts", ""},
+		{"unencodable result", AnalysisTask{Type: "test_unencodable"}, TaskFailed, "failed to encode result", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &taskQueue{pending: make(chan string, 1)}
+			task := tt.task
+			if err := q.enqueue(&task); err != nil {
+				t.Fatal(err)
+			}
+			if queued, _ := loadTask(task.ID); queued.Status != TaskQueued {
+				t.Errorf("status before running = %s, want queued", queued.Status)
+			}
+			q.run(<-q.pending)
+
+			done, err := loadTask(task.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if done.Status != tt.wantStatus || !strings.HasPrefix(done.Error, tt.wantError) {
+				t.Errorf("status, error = %s, %q, want %s, %q", done.Status, done.Error, tt.wantStatus, tt.wantError)
+			}
+			var result bytes.Buffer
+			if len(done.Result) > 0 {
+				json.Compact(&result, done.Result)
+			}
+			if result.String() != tt.wantResult {
+				t.Errorf("result = %s, want %s", result.String(), tt.wantResult)
+			}
+			if done.FinishedAt == nil {
+				t.Error("finished task has no finish time")
+			}
+			if done.Status == TaskCompleted && (done.Progress.Stage != "done" || done.Progress.Percent != 100 || done.Progress.Completed != 2) {
+				t.Errorf("progress = %+v, want done at 100%%", done.Progress)
+			}
+		})
+	}
+}
+
+func TestResumeUnfinishedTasks(t *testing.T) {
+	q := &taskQueue{pending: make(chan string, 8)}
+	var ids []string
+	for _, status := range []string{TaskRunning, TaskCompleted, TaskQueued, TaskFailed} {
+		task := &AnalysisTask{Type: "score_resume"}
+		if err := q.enqueue(task); err != nil {
+			t.Fatal(err)
+		}
+		<-q.pending
+		task.Status = status
+		if err := q.save(task); err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, task.ID)
+	}
+
+	resumeUnfinishedTasks()
+	requeued := map[string]bool{}
+	for len(tasks.pending) > 0 {
+		requeued[<-tasks.pending] = true
+	}
+
+	tests := []struct {
+		id         string
+		wantQueued bool
+		wantStatus string
+	}{
+		{ids[0], true, TaskQueued},
+		{ids[1], false, TaskCompleted},
+		{ids[2], true, TaskQueued},
+		{ids[3], false, TaskFailed},
+	}
+	for _, tt := range tests {
+		task, err := loadTask(tt.id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if requeued[tt.id] != tt.wantQueued || task.Status != tt.wantStatus {
+			t.Errorf("%s: requeued %v with status %s, want %v and %s", tt.id, requeued[tt.id], task.Status, tt.wantQueued, tt.wantStatus)
+		}
+	}
+}
+
+func TestGetTask(t *testing.T) {
+	q := &taskQueue{pending: make(chan string, 1)}
+	task := &AnalysisTask{Type: "score_resume", ResumeID: "2802", JobID: "2803"}
+	if err := q.enqueue(task); err != nil {
+		t.Fatal(err)
+	}
+
+	app := fiber.New()
+	app.Get("/jobs/:id", GetTask)
+	tests := []struct {
+		id   string
+		want int
+	}{
+		{task.ID, 200},
+		{"task_0", 404},
+		{"..%2F..%2Fetc", 404},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/jobs/"+tt.id, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != tt.want {
+			t.Errorf("GET /jobs/%s = %d, want %d", tt.id, resp.StatusCode, tt.want)
+		}
+		if tt.want == 200 {
+			body, _ := io.ReadAll(resp.Body)
+			var got AnalysisTask
+			if err := json.Unmarshal(body, &got); err != nil || got.ResumeID != "2802" || got.Status != TaskQueued {
+				t.Errorf("task = %s", body)
+			}
+		}
+	}
+}
@@ -16,9 +16,9 @@ func UploadFile(c *fiber.Ctx) error {
 	}
 
 	// Check file extension
-	if filepath.Ext(file.Filename) != ".pdf" {
+	if ext := filepath.Ext(file.Filename); ext != ".pdf" && ext != ".json" {
 		return c.Status(400).JSON(fiber.Map{
-			"error": "Only PDF files are allowed",
+			"error": "Only PDF and JSON Resume files are allowed",
 		})
 	}
 
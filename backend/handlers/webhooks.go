@@ -0,0 +1,645 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+// Outbound webhook events
+const (
+	EventResumeProcessed = "resume.processed"
+	EventJobProcessed    = "job.processed"
+	EventScoreCompleted  = "score.completed"
+	EventAnalysisFailed  = "analysis.failed"
+)
+
+// Delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead" // retries exhausted, written to the dead-letter log
+)
+
+const (
+	defaultWebhookWorkers  = 2
+	webhookRequestTimeout  = 15 * time.Second
+	maxWebhookResponseBody = 64 << 10
+)
+
+var (
+	webhookEvents = []string{EventResumeProcessed, EventJobProcessed, EventScoreCompleted, EventAnalysisFailed}
+
+	// Seven backoffs of 5s doubling add up to about 11 minutes (up to 13
+	// with jitter) from the first attempt to the dead-letter log
+	webhookRetryPolicy = utils.RetryPolicy{Attempts: 8, BaseDelay: 5 * time.Second, MaxDelay: 10 * time.Minute}
+
+	webhookClient = &http.Client{Timeout: webhookRequestTimeout}
+)
+
+// WebhookSubscription is an endpoint that receives some or all events
+type WebhookSubscription struct {
+	ID          string    `json:"id"`
+	URL         string    `json:"url"`
+	Events      []string  `json:"events"` // empty means every event
+	Secret      string    `json:"secret,omitempty"`
+	Active      bool      `json:"active"`
+	Description string    `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookEvent is the signed body sent to subscribers
+type WebhookEvent struct {
+	ID        string    `json:"id"`
+	Event     string    `json:"event"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
+
+// WebhookDelivery is one event sent to one subscription, with every attempt
+type WebhookDelivery struct {
+	ID            string            `json:"id"`
+	WebhookID     string            `json:"webhook_id"`
+	EventID       string            `json:"event_id"`
+	Event         string            `json:"event"`
+	URL           string            `json:"url"`
+	Payload       json.RawMessage   `json:"payload"`
+	Status        string            `json:"status"`
+	Attempts      []DeliveryAttempt `json:"attempts"`
+	RetryFrom     int               `json:"retry_from,omitempty"` // first attempt of the current round of retries
+	NextAttemptAt *time.Time        `json:"next_attempt_at,omitempty"`
+	DeliveredAt   *time.Time        `json:"delivered_at,omitempty"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+}
+
+// DeliveryAttempt records one HTTP request of a delivery
+type DeliveryAttempt struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// DeadLetter is appended to the dead-letter log when a delivery gives up
+type DeadLetter struct {
+	DeliveryID string          `json:"delivery_id"`
+	WebhookID  string          `json:"webhook_id"`
+	Event      string          `json:"event"`
+	URL        string          `json:"url"`
+	Attempts   int             `json:"attempts"`
+	LastError  string          `json:"last_error"`
+	Payload    json.RawMessage `json:"payload"`
+	DeadAt     time.Time       `json:"dead_at"`
+}
+
+type webhookDispatcher struct {
+	mu      sync.Mutex // guards subscriptions and delivery records
+	pending chan string
+	started bool
+}
+
+var dispatcher = &webhookDispatcher{pending: make(chan string, taskQueueSize)}
+
+func webhooksDir() string {
+	return filepath.Join("processed_texts", "webhooks")
+}
+
+func deliveryPath(id string) string {
+	return filepath.Join(webhooksDir(), "deliveries", safeID(id)+".json")
+}
+
+func loadWebhookSubscriptions() ([]WebhookSubscription, error) {
+	subscriptions := []WebhookSubscription{}
+	err := utils.LoadJSONFile(filepath.Join(webhooksDir(), "subscriptions.json"), &subscriptions)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+func saveWebhookSubscriptions(subscriptions []WebhookSubscription) error {
+	return utils.SaveJSONFile(filepath.Join(webhooksDir(), "subscriptions.json"), subscriptions, 0600)
+}
+
+func loadDelivery(id string) (*WebhookDelivery, error) {
+	var delivery WebhookDelivery
+	if err := utils.LoadJSONFile(deliveryPath(id), &delivery); err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+func saveDelivery(delivery *WebhookDelivery) error {
+	delivery.UpdatedAt = time.Now()
+	return utils.SaveJSONFile(deliveryPath(delivery.ID), delivery, 0644)
+}
+
+func (s *WebhookSubscription) wants(event string) bool {
+	return s.Active && (len(s.Events) == 0 || slices.Contains(s.Events, event))
+}
+
+// emitWebhookEvent queues a delivery of the event to every subscription that
+// wants it. It never blocks on the network; failures are only logged so the
+// pipeline that emitted the event is unaffected.
+func emitWebhookEvent(event string, data any) {
+	dispatcher.mu.Lock()
+	subscriptions, err := loadWebhookSubscriptions()
+	dispatcher.mu.Unlock()
+	if err != nil {
+		log.Printf("Error reading webhook subscriptions: %v", err)
+		return
+	}
+
+	now := time.Now()
+	payload := WebhookEvent{ID: fmt.Sprintf("evt_%d", now.UnixNano()), Event: event, CreatedAt: now, Data: data}
+	var body []byte
+	for i, subscription := range subscriptions {
+		if !subscription.wants(event) {
+			continue
+		}
+		if body == nil {
+			if body, err = json.Marshal(payload); err != nil {
+				log.Printf("Error encoding %s webhook: %v", event, err)
+				return
+			}
+		}
+		delivery := &WebhookDelivery{
+			ID:        fmt.Sprintf("dlv_%d_%d", now.UnixNano(), i),
+			WebhookID: subscription.ID,
+			EventID:   payload.ID,
+			Event:     event,
+			URL:       subscription.URL,
+			Payload:   body,
+			Status:    DeliveryPending,
+			Attempts:  []DeliveryAttempt{},
+			CreatedAt: now,
+		}
+		if err := saveDelivery(delivery); err != nil {
+			log.Printf("Error saving webhook delivery: %v", err)
+			continue
+		}
+		dispatcher.push(delivery.ID)
+	}
+}
+
+func (d *webhookDispatcher) push(id string) {
+	select {
+	case d.pending <- id:
+	default:
+		go func() { d.pending <- id }()
+	}
+}
+
+// schedule pushes a delivery once its next attempt is due
+func (d *webhookDispatcher) schedule(id string, at time.Time) {
+	if wait := time.Until(at); wait > 0 {
+		time.AfterFunc(wait, func() { d.push(id) })
+		return
+	}
+	d.push(id)
+}
+
+// StartWebhookDispatcher starts the delivery workers and reschedules
+// deliveries that were still pending when the server last stopped
+func StartWebhookDispatcher() {
+	dispatcher.mu.Lock()
+	if dispatcher.started {
+		dispatcher.mu.Unlock()
+		return
+	}
+	dispatcher.started = true
+	dispatcher.mu.Unlock()
+
+	paths, _ := filepath.Glob(filepath.Join(webhooksDir(), "deliveries", "dlv_*.json"))
+	for _, path := range paths {
+		var delivery WebhookDelivery
+		if err := utils.LoadJSONFile(path, &delivery); err != nil || delivery.Status != DeliveryPending {
+			continue
+		}
+		next := time.Now()
+		if delivery.NextAttemptAt != nil {
+			next = *delivery.NextAttemptAt
+		}
+		dispatcher.schedule(delivery.ID, next)
+	}
+
+	for i := 0; i < defaultWebhookWorkers; i++ {
+		go func() {
+			for id := range dispatcher.pending {
+				dispatcher.deliver(id)
+			}
+		}()
+	}
+}
+
+// deliver makes one attempt at a pending delivery and schedules the next
+// one, or moves it to the dead-letter log when retrying is pointless
+func (d *webhookDispatcher) deliver(id string) {
+	d.mu.Lock()
+	delivery, err := loadDelivery(id)
+	if err != nil || delivery.Status != DeliveryPending {
+		d.mu.Unlock()
+		return
+	}
+	subscriptions, err := loadWebhookSubscriptions()
+	d.mu.Unlock()
+	if err != nil {
+		log.Printf("Error reading webhook subscriptions: %v", err)
+		return
+	}
+	var subscription *WebhookSubscription
+	for i := range subscriptions {
+		if subscriptions[i].ID == delivery.WebhookID {
+			subscription = &subscriptions[i]
+		}
+	}
+
+	attempt := DeliveryAttempt{At: time.Now()}
+	retryable := false
+	if subscription == nil || !subscription.Active {
+		attempt.Error = "Webhook was deleted or disabled"
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookRequestTimeout)
+		_, status, err := utils.DoWithRetry(ctx, webhookClient, utils.RetryPolicy{Attempts: 1}, maxWebhookResponseBody, func() (*http.Request, error) {
+			return utils.NewJSONRequest(delivery.URL, delivery.Payload, map[string]string{
+				"User-Agent":          "InterviewMe-Webhooks/1.0",
+				"X-Webhook-Event":     delivery.Event,
+				"X-Webhook-Delivery":  delivery.ID,
+				"X-Webhook-Signature": "sha256=" + utils.SignPayload(subscription.Secret, delivery.Payload),
+				// Unchanged across retries so receivers can drop repeats
+				"Idempotency-Key": delivery.EventID + "-" + delivery.WebhookID,
+			})
+		})
+		cancel()
+		attempt.StatusCode = status
+		if err != nil {
+			attempt.Error = err.Error()
+			retryable = status == 0 || utils.Retryable(status)
+		}
+	}
+	attempt.DurationMS = time.Since(attempt.At).Milliseconds()
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delivery.Attempts = append(delivery.Attempts, attempt)
+	delivery.NextAttemptAt = nil
+	switch {
+	case attempt.Error == "":
+		delivery.Status, delivery.DeliveredAt = DeliveryDelivered, &attempt.At
+	case retryable && len(delivery.Attempts)-delivery.RetryFrom < webhookRetryPolicy.Attempts:
+		next := time.Now().Add(utils.Backoff(webhookRetryPolicy, len(delivery.Attempts)-delivery.RetryFrom))
+		delivery.NextAttemptAt = &next
+		d.schedule(delivery.ID, next)
+	default:
+		delivery.Status = DeliveryDead
+		log.Printf("Webhook delivery %s (%s to %s) failed for good: %s", delivery.ID, delivery.Event, delivery.URL, attempt.Error)
+		if err := appendDeadLetter(delivery, attempt.Error); err != nil {
+			log.Printf("Error writing dead letter for %s: %v", delivery.ID, err)
+		}
+	}
+	if err := saveDelivery(delivery); err != nil {
+		log.Printf("Error saving webhook delivery %s: %v", delivery.ID, err)
+	}
+}
+
+func appendDeadLetter(delivery *WebhookDelivery, lastError string) error {
+	line, err := json.Marshal(DeadLetter{
+		DeliveryID: delivery.ID,
+		WebhookID:  delivery.WebhookID,
+		Event:      delivery.Event,
+		URL:        delivery.URL,
+		Attempts:   len(delivery.Attempts),
+		LastError:  lastError,
+		Payload:    delivery.Payload,
+		DeadAt:     time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(webhooksDir(), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(filepath.Join(webhooksDir(), "dead_letters.jsonl"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(line, '\n'))
+	return err
+}
+
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// validateWebhookFields checks a subscription's URL and event names
+func validateWebhookFields(rawURL string, events []string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return fiber.NewError(400, "url must be an absolute http(s) URL")
+	}
+	for _, event := range events {
+		if !slices.Contains(webhookEvents, event) {
+			return fiber.NewError(400, fmt.Sprintf("Unknown event %q; valid events are %s", event, strings.Join(webhookEvents, ", ")))
+		}
+	}
+	return nil
+}
+
+// redactedSubscription hides the signing secret in listings
+func redactedSubscription(subscription WebhookSubscription) WebhookSubscription {
+	subscription.Secret = ""
+	return subscription
+}
+
+// ListWebhooks lists subscriptions without their secrets
+func ListWebhooks(c *fiber.Ctx) error {
+	dispatcher.mu.Lock()
+	subscriptions, err := loadWebhookSubscriptions()
+	dispatcher.mu.Unlock()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read webhooks",
+		})
+	}
+	for i := range subscriptions {
+		subscriptions[i] = redactedSubscription(subscriptions[i])
+	}
+	return c.JSON(fiber.Map{
+		"webhooks": subscriptions,
+		"events":   webhookEvents,
+	})
+}
+
+// CreateWebhook adds a subscription. The signing secret is generated unless
+// given, and only returned here.
+func CreateWebhook(c *fiber.Ctx) error {
+	var request struct {
+		URL         string   `json:"url"`
+		Events      []string `json:"events"`
+		Secret      string   `json:"secret"`
+		Description string   `json:"description"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+	if err := validateWebhookFields(request.URL, request.Events); err != nil {
+		return c.Status(errorStatus(err)).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+	secret := request.Secret
+	if secret == "" {
+		var err error
+		if secret, err = newWebhookSecret(); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to generate a secret",
+			})
+		}
+	}
+
+	now := time.Now()
+	subscription := WebhookSubscription{
+		ID:          fmt.Sprintf("wh_%d", now.UnixNano()),
+		URL:         request.URL,
+		Events:      append([]string{}, uniqueStrings(request.Events)...),
+		Secret:      secret,
+		Active:      true,
+		Description: request.Description,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	subscriptions, err := loadWebhookSubscriptions()
+	if err == nil {
+		err = saveWebhookSubscriptions(append(subscriptions, subscription))
+	}
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save webhook",
+		})
+	}
+	return c.Status(201).JSON(subscription)
+}
+
+// UpdateWebhook changes a subscription's URL, events, description or active flag
+func UpdateWebhook(c *fiber.Ctx) error {
+	var request struct {
+		URL         *string   `json:"url"`
+		Events      *[]string `json:"events"`
+		Active      *bool     `json:"active"`
+		Description *string   `json:"description"`
+	}
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(400).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	subscriptions, err := loadWebhookSubscriptions()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read webhooks",
+		})
+	}
+	for i := range subscriptions {
+		subscription := &subscriptions[i]
+		if subscription.ID != c.Params("id") {
+			continue
+		}
+		if request.URL != nil {
+			subscription.URL = *request.URL
+		}
+		if request.Events != nil {
+			subscription.Events = append([]string{}, uniqueStrings(*request.Events)...)
+		}
+		if err := validateWebhookFields(subscription.URL, subscription.Events); err != nil {
+			return c.Status(errorStatus(err)).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if request.Active != nil {
+			subscription.Active = *request.Active
+		}
+		if request.Description != nil {
+			subscription.Description = *request.Description
+		}
+		subscription.UpdatedAt = time.Now()
+		if err := saveWebhookSubscriptions(subscriptions); err != nil {
+			return c.Status(500).JSON(fiber.Map{
+				"error": "Failed to save webhook",
+			})
+		}
+		return c.JSON(redactedSubscription(*subscription))
+	}
+	return c.Status(404).JSON(fiber.Map{
+		"error": "Webhook not found",
+	})
+}
+
+// DeleteWebhook removes a subscription; its pending deliveries are dropped
+// to the dead-letter log on their next attempt
+func DeleteWebhook(c *fiber.Ctx) error {
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	subscriptions, err := loadWebhookSubscriptions()
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read webhooks",
+		})
+	}
+	kept := subscriptions[:0]
+	for _, subscription := range subscriptions {
+		if subscription.ID != c.Params("id") {
+			kept = append(kept, subscription)
+		}
+	}
+	if len(kept) == len(subscriptions) {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Webhook not found",
+		})
+	}
+	if err := saveWebhookSubscriptions(kept); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save webhooks",
+		})
+	}
+	return c.JSON(fiber.Map{
+		"message": "Webhook deleted",
+	})
+}
+
+// ListWebhookDeliveries returns a subscription's delivery history, newest
+// first, optionally filtered by status or event
+func ListWebhookDeliveries(c *fiber.Ctx) error {
+	paths, err := filepath.Glob(filepath.Join(webhooksDir(), "deliveries", "dlv_*.json"))
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to list deliveries",
+		})
+	}
+	webhookID, status, event := c.Params("id"), c.Query("status"), c.Query("event")
+	deliveries := make([]WebhookDelivery, 0)
+	for _, path := range paths {
+		var delivery WebhookDelivery
+		if err := utils.LoadJSONFile(path, &delivery); err != nil {
+			continue
+		}
+		if delivery.WebhookID != webhookID ||
+			(status != "" && delivery.Status != status) ||
+			(event != "" && delivery.Event != event) {
+			continue
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.Slice(deliveries, func(i, j int) bool {
+		return deliveries[i].CreatedAt.After(deliveries[j].CreatedAt)
+	})
+	if limit := c.QueryInt("limit", 50); limit > 0 && len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+	return c.JSON(fiber.Map{
+		"count":      len(deliveries),
+		"deliveries": deliveries,
+	})
+}
+
+// GetWebhookDelivery returns one delivery with its payload and attempts
+func GetWebhookDelivery(c *fiber.Ctx) error {
+	delivery, err := loadDelivery(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Delivery not found",
+		})
+	}
+	return c.JSON(delivery)
+}
+
+// RedeliverWebhook sends a delivery again with a fresh set of retries,
+// keeping its attempt history
+func RedeliverWebhook(c *fiber.Ctx) error {
+	dispatcher.mu.Lock()
+	defer dispatcher.mu.Unlock()
+	delivery, err := loadDelivery(c.Params("id"))
+	if err != nil {
+		return c.Status(404).JSON(fiber.Map{
+			"error": "Delivery not found",
+		})
+	}
+	if delivery.Status == DeliveryPending {
+		return c.Status(409).JSON(fiber.Map{
+			"error": "Delivery is still being retried",
+		})
+	}
+	// Earlier attempts stay in the history but don't count against the new retries
+	delivery.Status, delivery.DeliveredAt, delivery.NextAttemptAt = DeliveryPending, nil, nil
+	delivery.RetryFrom = len(delivery.Attempts)
+	if err := saveDelivery(delivery); err != nil {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to save delivery",
+		})
+	}
+	dispatcher.push(delivery.ID)
+	return c.Status(202).JSON(delivery)
+}
+
+// ListDeadLetters returns deliveries that exhausted their retries, newest first
+func ListDeadLetters(c *fiber.Ctx) error {
+	letters := make([]DeadLetter, 0)
+	f, err := os.Open(filepath.Join(webhooksDir(), "dead_letters.jsonl"))
+	if err != nil && !os.IsNotExist(err) {
+		return c.Status(500).JSON(fiber.Map{
+			"error": "Failed to read dead letters",
+		})
+	}
+	if err == nil {
+		defer f.Close()
+		scanner := bufio.NewScanner(f)
+		scanner.Buffer(make([]byte, 0, 64<<10), 16<<20)
+		for scanner.Scan() {
+			var letter DeadLetter
+			if json.Unmarshal(scanner.Bytes(), &letter) == nil {
+				letters = append(letters, letter)
+			}
+		}
+	}
+	for i, j := 0, len(letters)-1; i < j; i, j = i+1, j-1 {
+		letters[i], letters[j] = letters[j], letters[i]
+	}
+	if limit := c.QueryInt("limit", 100); limit > 0 && len(letters) > limit {
+		letters = letters[:limit]
+	}
+	return c.JSON(fiber.Map{
+		"count":        len(letters),
+		"dead_letters": letters,
+	})
+}
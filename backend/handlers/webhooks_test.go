@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"interviewme/utils"
+)
+
+// webhookDeliveries returns the stored deliveries of one subscription
+func webhookDeliveries(t *testing.T, webhookID string) []WebhookDelivery {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(webhooksDir(), "deliveries", "dlv_*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var deliveries []WebhookDelivery
+	for _, path := range paths {
+		var delivery WebhookDelivery
+		if err := utils.LoadJSONFile(path, &delivery); err == nil && delivery.WebhookID == webhookID {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries
+}
+
+// useWebhookSubscriptions replaces the stored subscriptions for one test
+func useWebhookSubscriptions(t *testing.T, subscriptions ...WebhookSubscription) {
+	t.Helper()
+	if err := saveWebhookSubscriptions(subscriptions); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { saveWebhookSubscriptions([]WebhookSubscription{}) })
+}
+
+// payloadID returns the id field of a JSON body
+func payloadID(t *testing.T, body []byte) string {
+	t.Helper()
+	var payload WebhookEvent
+	if err := json.Unmarshal(body, &payload); err != nil {
+		t.Fatal(err)
+	}
+	return payload.ID
+}
+
+func TestWebhookSubscriptionWants(t *testing.T) {
+	tests := []struct {
+		name         string
+		subscription WebhookSubscription
+		event        string
+		want         bool
+	}{
+		{"every event", WebhookSubscription{Active: true}, EventJobProcessed, true},
+		{"listed event", WebhookSubscription{Active: true, Events: []string{EventScoreCompleted}}, EventScoreCompleted, true},
+		{"unlisted event", WebhookSubscription{Active: true, Events: []string{EventScoreCompleted}}, EventAnalysisFailed, false},
+		{"disabled", WebhookSubscription{Events: []string{EventScoreCompleted}}, EventScoreCompleted, false},
+	}
+	for _, tt := range tests {
+		if got := tt.subscription.wants(tt.event); got != tt.want {
+			t.Errorf("%s: wants(%q) = %v, want %v", tt.name, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestValidateWebhookFields(t *testing.T) {
+	tests := []struct {
+		url     string
+		events  []string
+		wantErr string
+	}{
+		{"https://example.com/hooks", nil, ""},
+		{"http://localhost:8080/hooks", []string{EventResumeProcessed, EventAnalysisFailed}, ""},
+		{"ftp://example.com/hooks", nil, "absolute http(s) URL"},
+		{"/hooks", nil, "absolute http(s) URL"},
+		{"https://", nil, "absolute http(s) URL"},
+		{"https://example.com/hooks", []string{"score.deleted"}, `Unknown event "score.deleted"`},
+	}
+	for _, tt := range tests {
+		err := validateWebhookFields(tt.url, tt.events)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("validateWebhookFields(%q, %v) = %v, want nil", tt.url, tt.events, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("validateWebhookFields(%q, %v) = %v, want %q", tt.url, tt.events, err, tt.wantErr)
+		case err != nil && errorStatus(err) != 400:
+			t.Errorf("validateWebhookFields(%q, %v) status = %d, want 400", tt.url, tt.events, errorStatus(err))
+		}
+	}
+}
+
+func TestEmitWebhookEvent(t *testing.T) {
+	useWebhookSubscriptions(t,
+		WebhookSubscription{ID: "wh_emit_all", URL: "https://example.com/all", Active: true},
+		WebhookSubscription{ID: "wh_emit_scores", URL: "https://example.com/scores", Events: []string{EventScoreCompleted}, Active: true},
+		WebhookSubscription{ID: "wh_emit_off", URL: "https://example.com/off", Active: false},
+	)
+
+	emitWebhookEvent(EventJobProcessed, map[string]string{"job_id": "4801"})
+
+	tests := []struct {
+		webhookID string
+		want      int
+	}{
+		{"wh_emit_all", 1},
+		{"wh_emit_scores", 0},
+		{"wh_emit_off", 0},
+	}
+	for _, tt := range tests {
+		deliveries := webhookDeliveries(t, tt.webhookID)
+		if len(deliveries) != tt.want {
+			t.Fatalf("%s deliveries = %d, want %d", tt.webhookID, len(deliveries), tt.want)
+		}
+		for _, delivery := range deliveries {
+			var payload WebhookEvent
+			if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+				t.Fatal(err)
+			}
+			if delivery.Status != DeliveryPending || delivery.Event != EventJobProcessed || payload.ID != delivery.EventID || payload.Event != EventJobProcessed {
+				t.Errorf("delivery = %s %s event %s, payload %+v", delivery.Status, delivery.Event, delivery.EventID, payload)
+			}
+		}
+	}
+}
+
+func TestEmitScoreCompleted(t *testing.T) {
+	useWebhookSubscriptions(t, WebhookSubscription{ID: "wh_emit_score", URL: "https://example.com/scores", Events: []string{EventScoreCompleted}, Active: true})
+	if err := saveScreeningSettings(ScreeningSettings{JobID: "4811", BlindScreening: true}); err != nil {
+		t.Fatal(err)
+	}
+	score := ScoreResponse{
+		OverallScore:      80,
+		ProcessedEntities: ExtractedEntities{Name: "Jane Doe", Email: []string{"jane@example.com"}, Phone: "+1 555 123 4567"},
+		Knockouts:         []KnockoutResult{{Field: "location", Status: KnockoutPass, Candidate: "Austin, TX"}},
+	}
+	answers := &KnockoutAnswers{Location: "Austin, TX"}
+
+	tests := []struct {
+		name      string
+		jobID     string
+		wantName  string
+		wantEmail int
+		answers   bool
+	}{
+		{"open job", "4810", "Jane Doe", 1, true},
+		{"blind job", "4811", candidatePseudonym("4812"), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := len(webhookDeliveries(t, "wh_emit_score"))
+			emitScoreCompleted("4812", tt.jobID, 1, score, answers)
+			deliveries := webhookDeliveries(t, "wh_emit_score")
+			if len(deliveries) != before+1 {
+				t.Fatalf("deliveries = %d, want %d", len(deliveries), before+1)
+			}
+			var record ScoreRecord
+			for _, delivery := range deliveries {
+				var payload struct {
+					Data ScoreRecord `json:"data"`
+				}
+				if err := json.Unmarshal(delivery.Payload, &payload); err != nil {
+					t.Fatal(err)
+				}
+				if payload.Data.JobID == tt.jobID {
+					record = payload.Data
+				}
+			}
+			entities := record.Score.ProcessedEntities
+			if entities.Name != tt.wantName || len(entities.Email) != tt.wantEmail || (record.KnockoutAnswers != nil) != tt.answers {
+				t.Errorf("payload name %q, emails %v, answers %+v", entities.Name, entities.Email, record.KnockoutAnswers)
+			}
+			if !tt.answers && (entities.Phone != "" || record.Score.Knockouts[0].Candidate != "") {
+				t.Errorf("blind payload leaks phone %q or knockout value %q", entities.Phone, record.Score.Knockouts[0].Candidate)
+			}
+		})
+	}
+}
+
+func TestWebhookDeliver(t *testing.T) {
+	var status int
+	var received *http.Request
+	var receivedBody []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		receivedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	useWebhookSubscriptions(t,
+		WebhookSubscription{ID: "wh_deliver", URL: server.URL, Secret: "s3cret", Active: true},
+		WebhookSubscription{ID: "wh_deliver_off", URL: server.URL, Secret: "s3cret", Active: false},
+	)
+	d := &webhookDispatcher{pending: make(chan string, 10)}
+
+	priorAttempts := func(n int) []DeliveryAttempt {
+		attempts := make([]DeliveryAttempt, n)
+		for i := range attempts {
+			attempts[i] = DeliveryAttempt{StatusCode: 500, Error: "server error"}
+		}
+		return attempts
+	}
+	tests := []struct {
+		name       string
+		webhookID  string
+		status     int
+		attempts   []DeliveryAttempt
+		retryFrom  int
+		wantStatus string
+		wantNext   bool
+		wantSent   bool
+		wantDead   bool
+	}{
+		{"delivered", "wh_deliver", 200, nil, 0, DeliveryDelivered, false, true, false},
+		{"server error retries", "wh_deliver", 503, nil, 0, DeliveryPending, true, true, false},
+		{"rate limited retries", "wh_deliver", 429, priorAttempts(3), 0, DeliveryPending, true, true, false},
+		{"client error gives up", "wh_deliver", 400, nil, 0, DeliveryDead, false, true, true},
+		{"retries exhausted", "wh_deliver", 500, priorAttempts(7), 0, DeliveryDead, false, true, true},
+		{"redelivery gets fresh retries", "wh_deliver", 500, priorAttempts(7), 7, DeliveryPending, true, true, false},
+		{"disabled webhook", "wh_deliver_off", 200, nil, 0, DeliveryDead, false, false, true},
+		{"deleted webhook", "wh_deliver_gone", 200, nil, 0, DeliveryDead, false, false, true},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, received, receivedBody = tt.status, nil, nil
+			delivery := &WebhookDelivery{
+				ID:        "dlv_test_" + string(rune('a'+i)),
+				WebhookID: tt.webhookID,
+				EventID:   "evt_test",
+				Event:     EventJobProcessed,
+				URL:       server.URL,
+				Payload:   json.RawMessage(`{"id":"evt_test","event":"job.processed"}`),
+				Status:    DeliveryPending,
+				Attempts:  append([]DeliveryAttempt{}, tt.attempts...),
+				RetryFrom: tt.retryFrom,
+				CreatedAt: time.Now(),
+			}
+			if err := saveDelivery(delivery); err != nil {
+				t.Fatal(err)
+			}
+
+			d.deliver(delivery.ID)
+
+			got, err := loadDelivery(delivery.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Status != tt.wantStatus || (got.NextAttemptAt != nil) != tt.wantNext || len(got.Attempts) != len(tt.attempts)+1 {
+				t.Errorf("delivery = %s, next %v, %d attempts; want %s, next %v, %d attempts",
+					got.Status, got.NextAttemptAt, len(got.Attempts), tt.wantStatus, tt.wantNext, len(tt.attempts)+1)
+			}
+			if (got.DeliveredAt != nil) != (tt.wantStatus == DeliveryDelivered) {
+				t.Errorf("delivered at = %v", got.DeliveredAt)
+			}
+			if (received != nil) != tt.wantSent {
+				t.Fatalf("request sent = %v, want %v", received != nil, tt.wantSent)
+			}
+			if received != nil {
+				// Receivers verify the signature against the body exactly as sent
+				headers := []struct {
+					name, got, want string
+				}{
+					{"signature", received.Header.Get("X-Webhook-Signature"), "sha256=" + utils.SignPayload("s3cret", receivedBody)},
+					{"event", received.Header.Get("X-Webhook-Event"), EventJobProcessed},
+					{"delivery", received.Header.Get("X-Webhook-Delivery"), delivery.ID},
+					{"idempotency key", received.Header.Get("Idempotency-Key"), "evt_test-" + tt.webhookID},
+					{"event id", payloadID(t, receivedBody), "evt_test"},
+				}
+				for _, h := range headers {
+					if h.got != h.want {
+						t.Errorf("%s = %q, want %q", h.name, h.got, h.want)
+					}
+				}
+			}
+
+			letters, _ := os.ReadFile(filepath.Join(webhooksDir(), "dead_letters.jsonl"))
+			if dead := strings.Contains(string(letters), `"delivery_id":"`+delivery.ID+`"`); dead != tt.wantDead {
+				t.Errorf("dead letter written = %v, want %v", dead, tt.wantDead)
+			}
+		})
+	}
+
+	// A delivery that is no longer pending is left alone
+	status, received = 200, nil
+	d.deliver("dlv_test_a")
+	if received != nil {
+		t.Error("delivered delivery was sent again")
+	}
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	useWebhookSubscriptions(t)
+	app := fiber.New()
+	app.Get("/webhooks", ListWebhooks)
+	app.Post("/webhooks", CreateWebhook)
+	app.Get("/webhooks/dead-letters", ListDeadLetters)
+	app.Get("/webhooks/deliveries/:id", GetWebhookDelivery)
+	app.Post("/webhooks/deliveries/:id/redeliver", RedeliverWebhook)
+	app.Patch("/webhooks/:id", UpdateWebhook)
+	app.Delete("/webhooks/:id", DeleteWebhook)
+	app.Get("/webhooks/:id/deliveries", ListWebhookDeliveries)
+
+	call := func(method, path, body string) (int, []byte) {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(resp.Body)
+		return resp.StatusCode, data
+	}
+
+	status, body := call("POST", "/webhooks", `{"url":"https://example.com/hooks","events":["score.completed","score.completed"]}`)
+	if status != 201 {
+		t.Fatalf("create status = %d: %s", status, body)
+	}
+	var created WebhookSubscription
+	if err := json.Unmarshal(body, &created); err != nil {
+		t.Fatal(err)
+	}
+	if len(created.Secret) != 64 || !created.Active || strings.Join(created.Events, ",") != EventScoreCompleted {
+		t.Errorf("created = %+v, want a generated secret and one event", created)
+	}
+
+	now := time.Now()
+	for _, delivery := range []WebhookDelivery{
+		{ID: "dlv_ep_old", WebhookID: created.ID, Event: EventScoreCompleted, Status: DeliveryDead, Attempts: make([]DeliveryAttempt, 8), CreatedAt: now.Add(-time.Hour)},
+		{ID: "dlv_ep_new", WebhookID: created.ID, Event: EventScoreCompleted, Status: DeliveryDelivered, Attempts: make([]DeliveryAttempt, 1), CreatedAt: now},
+		{ID: "dlv_ep_pending", WebhookID: created.ID, Event: EventJobProcessed, Status: DeliveryPending, CreatedAt: now.Add(-time.Minute)},
+		{ID: "dlv_ep_other", WebhookID: "wh_other", Event: EventScoreCompleted, Status: DeliveryDead, CreatedAt: now},
+	} {
+		if err := saveDelivery(&delivery); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		body       string
+		wantStatus int
+		want       []string // substrings of the response
+		wantNot    []string
+	}{
+		{"create rejects bad url", "POST", "/webhooks", `{"url":"example.com"}`, 400, []string{"absolute http(s) URL"}, nil},
+		{"create rejects unknown event", "POST", "/webhooks", `{"url":"https://example.com","events":["nope"]}`, 400, []string{"Unknown event"}, nil},
+		{"list hides secrets", "GET", "/webhooks", "", 200, []string{created.ID, EventAnalysisFailed}, []string{created.Secret}},
+		{"update", "PATCH", "/webhooks/" + created.ID, `{"active":false,"description":"paused"}`, 200, []string{`"active":false`, "paused"}, []string{created.Secret}},
+		{"update rejects unknown event", "PATCH", "/webhooks/" + created.ID, `{"events":["nope"]}`, 400, []string{"Unknown event"}, nil},
+		{"update unknown webhook", "PATCH", "/webhooks/wh_missing", `{"active":true}`, 404, nil, nil},
+		{"deliveries newest first", "GET", "/webhooks/" + created.ID + "/deliveries", "", 200, []string{`"count":3`, `"id":"dlv_ep_new"`}, []string{"dlv_ep_other"}},
+		{"deliveries by status", "GET", "/webhooks/" + created.ID + "/deliveries?status=dead", "", 200, []string{`"count":1`, "dlv_ep_old"}, nil},
+		{"deliveries by event", "GET", "/webhooks/" + created.ID + "/deliveries?event=job.processed", "", 200, []string{`"count":1`, "dlv_ep_pending"}, nil},
+		{"deliveries limit", "GET", "/webhooks/" + created.ID + "/deliveries?limit=1", "", 200, []string{`"count":1`, "dlv_ep_new"}, nil},
+		{"get delivery", "GET", "/webhooks/deliveries/dlv_ep_old", "", 200, []string{`"status":"dead"`}, nil},
+		{"get unknown delivery", "GET", "/webhooks/deliveries/dlv_missing", "", 404, nil, nil},
+		{"redeliver pending", "POST", "/webhooks/deliveries/dlv_ep_pending/redeliver", "", 409, nil, nil},
+		{"redeliver dead", "POST", "/webhooks/deliveries/dlv_ep_old/redeliver", "", 202, []string{`"status":"pending"`, `"retry_from":8`}, nil},
+		{"redeliver unknown", "POST", "/webhooks/deliveries/dlv_missing/redeliver", "", 404, nil, nil},
+		{"delete", "DELETE", "/webhooks/" + created.ID, "", 200, nil, nil},
+		{"delete again", "DELETE", "/webhooks/" + created.ID, "", 404, nil, nil},
+		{"list after delete", "GET", "/webhooks", "", 200, []string{`"webhooks":[]`}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := call(tt.method, tt.path, tt.body)
+			if status != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", status, tt.wantStatus, body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(body), want) {
+					t.Errorf("response missing %s: %s", want, body)
+				}
+			}
+			for _, unwanted := range tt.wantNot {
+				if strings.Contains(string(body), unwanted) {
+					t.Errorf("response contains %s: %s", unwanted, body)
+				}
+			}
+		})
+	}
+}
+
+func TestListDeadLetters(t *testing.T) {
+	for _, id := range []string{"dlv_dl_first", "dlv_dl_second"} {
+		if err := appendDeadLetter(&WebhookDelivery{ID: id, WebhookID: "wh_dl", Event: EventAnalysisFailed, Payload: json.RawMessage(`{}`)}, "gave up"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	app := fiber.New()
+	app.Get("/webhooks/dead-letters", ListDeadLetters)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"dlv_dl_second", "dlv_dl_first"}},
+		{"?limit=1", []string{"dlv_dl_second"}},
+	}
+	for _, tt := range tests {
+		resp, err := app.Test(httptest.NewRequest("GET", "/webhooks/dead-letters"+tt.query, nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var result struct {
+			DeadLetters []DeadLetter `json:"dead_letters"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			t.Fatal(err)
+		}
+		if len(result.DeadLetters) < len(tt.want) {
+			t.Fatalf("%q: dead letters = %d, want at least %d", tt.query, len(result.DeadLetters), len(tt.want))
+		}
+		for i, id := range tt.want {
+			if letter := result.DeadLetters[i]; letter.DeliveryID != id || letter.LastError != "gave up" {
+				t.Errorf("%q: dead letter %d = %+v, want %s", tt.query, i, letter, id)
+			}
+		}
+		if tt.query == "?limit=1" && len(result.DeadLetters) != 1 {
+			t.Errorf("limit=1 returned %d dead letters", len(result.DeadLetters))
+		}
+	}
+}
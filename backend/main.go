@@ -19,15 +19,23 @@ func main() {
 		panic(" jamalu Error loading .env file")
 	}
 
-	app := fiber.New()
+	// Stream request bodies so bulk resume archives fit; LimitRequestBody
+	// keeps the default limit on every other route
+	app := fiber.New(fiber.Config{
+		StreamRequestBody:            true,
+		DisablePreParseMultipartForm: true,
+	})
 
 	// Add logger middleware
 	app.Use(logger.New())
 
+	// Enforce the request body limit for each route
+	app.Use(handlers.LimitRequestBody)
+
 	// Add CORS middleware
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: "http://localhost:3000",
-		AllowHeaders: "Origin, Content-Type, Accept",
+		AllowHeaders: "Origin, Content-Type, Accept, X-Unmask-Key",
 	}))
 
 	// Add logging middleware
@@ -41,7 +49,25 @@ func main() {
 		log.Fatalf("Failed to create uploads directory: %v", err)
 	}
 
+	// Start background analysis workers, resuming unfinished tasks
+	handlers.StartTaskWorkers()
+
+	// Ingest resumes dropped into INBOX_DIR, when configured
+	handlers.StartInboxWatcher()
+
+	// Ingest emailed applications from MAIL_INGEST_URL, when configured
+	handlers.StartMailIngestion()
+
+	// Deliver outbound webhooks, resuming pending retries
+	handlers.StartWebhookDispatcher()
+
 	app.Post("/upload", handlers.UploadFile)
+	app.Post("/upload/bulk", handlers.BulkUpload)
+	app.Get("/upload/batches", handlers.ListIngestBatches)
+	app.Get("/upload/batches/:id", handlers.GetIngestBatch)
+	app.Get("/upload/inbox", handlers.GetInboxStatus)
+	app.Get("/upload/email", handlers.GetMailIngestStatus)
+	app.Post("/upload/email/poll", handlers.PollMailbox)
 	app.Post("/preprocess", handlers.PreprocessResume)
 	app.Post("/preprocess-job", handlers.PreprocessJobDescription)
 	app.Post("/score-resume", handlers.ScoreResume)
@@ -59,6 +85,121 @@ func main() {
 	// Experience analysis endpoint
 	app.Get("api/experience/analyze", handlers.AnalyzeExperience)
 
+	// Job description listing and candidate filters
+	app.Get("/job-descriptions", handlers.ListJobDescriptions)
+	app.Get("/job-descriptions/:id/candidates", handlers.ListJobCandidates)
+	app.Get("/job-descriptions/:id/candidates/export", handlers.ExportJobCandidates)
+
+	// Job description versions
+	app.Get("/job-descriptions/:id/versions", handlers.GetJobVersions)
+	app.Get("/job-descriptions/:id/versions/:version/rescore", handlers.GetRescoreReport)
+	app.Get("/job-descriptions/:id/diff", handlers.GetJobDiff)
+
+	// Job description linting
+	app.Post("/job-descriptions/lint", handlers.LintJobDescription)
+	app.Get("/job-descriptions/:id/lint", handlers.GetJobLintReport)
+
+	// Blind screening routes
+	app.Get("/job-descriptions/:id/screening", handlers.GetScreeningSettings)
+	app.Put("/job-descriptions/:id/screening", handlers.UpdateScreeningSettings)
+	app.Post("/job-descriptions/:id/shortlist", handlers.ShortlistCandidate)
+	app.Get("/resumes/:id/unmask", handlers.UnmaskCandidate)
+
+	// Resume integrity routes
+	app.Get("/resumes/:id/integrity", handlers.GetResumeIntegrity)
+
+	// JSON Resume routes
+	app.Post("/resumes/import/json-resume", handlers.ImportJSONResume)
+	app.Get("/resumes/:id/json-resume", handlers.ExportJSONResume)
+
+	// Bias audit routes
+	app.Post("/audit/bias", handlers.RunBiasAudit)
+	app.Get("/audit/:id", handlers.GetBiasAudit)
+	app.Get("/audit/:id/export", handlers.ExportBiasAudit)
+
+	// Background analysis routes
+	app.Post("/jobs", handlers.CreateTask)
+	app.Get("/jobs/:id", handlers.GetTask)
+	app.Get("/jobs/:id/events", handlers.StreamTaskEvents)
+
+	// Server-Sent Events streaming routes
+	app.Post("/stream/preprocess", handlers.StreamPreprocessResume)
+	app.Get("/stream/score-resume", handlers.StreamScoreResume)
+	app.Get("/stream/analyze-projects", handlers.StreamAnalyzeProjects)
+	app.Get("/stream/analyze-experience", handlers.StreamAnalyzeExperience)
+
+	// Interview kit routes
+	app.Post("/interview/questions", handlers.GenerateInterviewQuestions)
+	app.Get("/interview/questions", handlers.GetInterviewQuestions)
+
+	// Mock interview routes
+	app.Post("/mock-interviews", handlers.StartMockInterview)
+	app.Get("/mock-interviews/:id", handlers.GetMockInterview)
+	app.Get("/mock-interviews/:id/question", handlers.GetMockInterviewQuestion)
+	app.Post("/mock-interviews/:id/answers", handlers.AnswerMockInterview)
+	app.Post("/mock-interviews/:id/finish", handlers.FinishMockInterview)
+	app.Get("/mock-interviews/:id/report", handlers.GetMockInterviewReport)
+
+	// Scorecard routes
+	app.Get("/job-descriptions/:id/competencies", handlers.GetJobCompetencies)
+	app.Post("/scorecards", handlers.SubmitScorecard)
+	app.Get("/scorecards", handlers.ListScorecards)
+	app.Get("/scorecards/summary", handlers.GetScorecardSummary)
+
+	// Resume tailoring routes
+	app.Post("/tailoring", handlers.CreateTailoringSuggestions)
+	app.Get("/tailoring/:id", handlers.GetTailoringSession)
+	app.Patch("/tailoring/:id/suggestions/:suggestionId", handlers.ReviewTailoringSuggestion)
+	app.Get("/tailoring/:id/export", handlers.ExportTailoredResume)
+
+	// ATS optimization report
+	app.Get("/ats-report", handlers.GetATSReport)
+
+	// Cover letter routes
+	app.Post("/cover-letter", handlers.GenerateCoverLetter)
+	app.Get("/cover-letter", handlers.GetCoverLetter)
+
+	// Candidate routes
+	app.Get("/candidates", handlers.ListCandidates)
+	app.Post("/candidates/reindex", handlers.ReindexCandidates)
+	app.Get("/candidates/:id", handlers.GetCandidate)
+	app.Get("/candidates/:id/diff", handlers.GetCandidateDiff)
+	app.Get("/candidates/:id/timeline", handlers.GetCandidateTimeline)
+	app.Get("/candidates/:id/duplicates", handlers.GetCandidateDuplicates)
+	app.Post("/candidates/merge", handlers.MergeCandidates)
+	app.Post("/candidates/:id/unmerge", handlers.UnmergeCandidate)
+	app.Get("/candidates/:id/hr-open", handlers.ExportCandidateHROpen)
+
+	// ATS connector
+	app.Post("/integrations/ats/webhook", handlers.ReceiveATSWebhook)
+	app.Get("/integrations/ats/applications", handlers.ListATSApplications)
+	app.Get("/integrations/ats/applications/:id", handlers.GetATSApplication)
+	app.Post("/integrations/ats/applications/:id/retry", handlers.RetryATSApplication)
+	app.Get("/integrations/ats/job-map", handlers.GetATSJobMap)
+	app.Put("/integrations/ats/job-map", handlers.UpdateATSJobMap)
+
+	// Outbound webhooks
+	app.Get("/webhooks", handlers.ListWebhooks)
+	app.Post("/webhooks", handlers.CreateWebhook)
+	app.Get("/webhooks/dead-letters", handlers.ListDeadLetters)
+	app.Get("/webhooks/deliveries/:id", handlers.GetWebhookDelivery)
+	app.Post("/webhooks/deliveries/:id/redeliver", handlers.RedeliverWebhook)
+	app.Patch("/webhooks/:id", handlers.UpdateWebhook)
+	app.Delete("/webhooks/:id", handlers.DeleteWebhook)
+	app.Get("/webhooks/:id/deliveries", handlers.ListWebhookDeliveries)
+
+	// HR Open Standards export
+	app.Get("/hr-open/candidates", handlers.ExportHROpenBulk)
+
+	// Candidate reports
+	app.Get("/resumes/:id/report", handlers.GetCandidateReport)
+	app.Get("/reports/branding", handlers.GetReportBranding)
+	app.Put("/reports/branding", handlers.UpdateReportBranding)
+
+	// Candidate dedup review queue
+	app.Get("/dedup/reviews", handlers.ListDedupReviews)
+	app.Post("/dedup/reviews/:id/resolve", handlers.ResolveDedupReview)
+
 	port := ":8080"
 	println("Server running on port", port)
 	app.Listen(port)
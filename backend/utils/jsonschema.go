@@ -0,0 +1,282 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// ValidateJSONSchema checks doc against a JSON Schema and returns one message
+// per violation. It covers the keywords our export schemas use: type,
+// required, properties, additionalProperties, items, enum, pattern,
+// minLength, minItems, minimum, maximum, allOf, anyOf, oneOf and local $ref.
+func ValidateJSONSchema(schema []byte, doc any) ([]string, error) {
+	var root map[string]any
+	if err := json.Unmarshal(schema, &root); err != nil {
+		return nil, fmt.Errorf("invalid schema: %v", err)
+	}
+	return validateDocument(&schemaValidator{documents: map[string]map[string]any{"": root}}, "", doc)
+}
+
+// ValidateJSONSchemaFile checks doc against the schema in a file. A $ref to
+// another file is read relative to the file that holds the reference, so a
+// vendored copy of a multi-file schema works as published.
+func ValidateJSONSchemaFile(path string, doc any) ([]string, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	v := &schemaValidator{documents: make(map[string]map[string]any)}
+	if _, err := v.document(path); err != nil {
+		return nil, err
+	}
+	return validateDocument(v, path, doc)
+}
+
+func validateDocument(v *schemaValidator, base string, doc any) ([]string, error) {
+	// Round-trip the document so Go values compare like decoded JSON
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	var value any
+	if err := json.Unmarshal(raw, &value); err != nil {
+		return nil, err
+	}
+
+	v.patterns = make(map[string]*regexp.Regexp)
+	v.validate(v.documents[base], base, value, "$")
+	if v.loadErr != nil {
+		return nil, v.loadErr
+	}
+	return v.errors, nil
+}
+
+type schemaValidator struct {
+	// documents holds each schema file by absolute path; "" is an in-memory schema
+	documents map[string]map[string]any
+	patterns  map[string]*regexp.Regexp
+	errors    []string
+	loadErr   error
+}
+
+func (v *schemaValidator) fail(path, format string, args ...any) {
+	v.errors = append(v.errors, path+": "+fmt.Sprintf(format, args...))
+}
+
+func (v *schemaValidator) document(path string) (map[string]any, error) {
+	if doc, ok := v.documents[path]; ok {
+		return doc, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading schema: %v", err)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return nil, fmt.Errorf("invalid schema %s: %v", filepath.Base(path), err)
+	}
+	v.documents[path] = doc
+	return doc, nil
+}
+
+// resolve follows $ref chains and returns the schema they point at along
+// with the file it lives in
+func (v *schemaValidator) resolve(schema map[string]any, base string) (map[string]any, string) {
+	for depth := 0; depth < 32; depth++ {
+		ref, ok := schema["$ref"].(string)
+		if !ok {
+			return schema, base
+		}
+		file, pointer, _ := strings.Cut(ref, "#")
+		if file != "" {
+			if base == "" || filepath.IsAbs(file) || strings.Contains(file, "://") {
+				// In-memory schemas and remote references can't be followed
+				return schema, base
+			}
+			base = filepath.Join(filepath.Dir(base), filepath.FromSlash(file))
+		}
+		root, err := v.document(base)
+		if err != nil {
+			if v.loadErr == nil {
+				v.loadErr = err
+			}
+			return map[string]any{}, base
+		}
+		var node any = root
+		for _, part := range strings.Split(strings.Trim(pointer, "/"), "/") {
+			if part == "" {
+				continue
+			}
+			m, ok := node.(map[string]any)
+			if !ok {
+				return map[string]any{}, base
+			}
+			node = m[strings.ReplaceAll(strings.ReplaceAll(part, "~1", "/"), "~0", "~")]
+		}
+		next, ok := node.(map[string]any)
+		if !ok {
+			return map[string]any{}, base
+		}
+		schema = next
+	}
+	return schema, base
+}
+
+// matches reports whether value satisfies schema without recording errors
+func (v *schemaValidator) matches(schema map[string]any, base string, value any) bool {
+	errors := v.errors
+	v.errors = nil
+	v.validate(schema, base, value, "")
+	ok := len(v.errors) == 0
+	v.errors = errors
+	return ok
+}
+
+func (v *schemaValidator) validate(schema map[string]any, base string, value any, path string) {
+	schema, base = v.resolve(schema, base)
+
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			if sub, ok := sub.(map[string]any); ok {
+				v.validate(sub, base, value, path)
+			}
+		}
+	}
+	for _, keyword := range []string{"anyOf", "oneOf"} {
+		options, ok := schema[keyword].([]any)
+		if !ok {
+			continue
+		}
+		matched := 0
+		for _, sub := range options {
+			if sub, ok := sub.(map[string]any); ok && v.matches(sub, base, value) {
+				matched++
+			}
+		}
+		switch {
+		case matched == 0:
+			v.fail(path, "matches none of the %s schemas", keyword)
+		case keyword == "oneOf" && matched > 1:
+			v.fail(path, "matches %d of the oneOf schemas, not exactly one", matched)
+		}
+	}
+
+	if expected, ok := schema["type"]; ok && !matchesType(expected, value) {
+		v.fail(path, "expected %v, got %s", expected, jsonType(value))
+		return
+	}
+	if options, ok := schema["enum"].([]any); ok {
+		found := false
+		for _, option := range options {
+			if fmt.Sprint(option) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			v.fail(path, "%v is not one of %v", value, options)
+		}
+	}
+
+	switch typed := value.(type) {
+	case string:
+		if limit, ok := schema["minLength"].(float64); ok && float64(len([]rune(typed))) < limit {
+			v.fail(path, "shorter than %d characters", int(limit))
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			re, ok := v.patterns[pattern]
+			if !ok {
+				var err error
+				if re, err = regexp.Compile(pattern); err != nil {
+					v.fail(path, "schema pattern %s is invalid: %v", pattern, err)
+					break
+				}
+				v.patterns[pattern] = re
+			}
+			if !re.MatchString(typed) {
+				v.fail(path, "%q does not match %s", typed, pattern)
+			}
+		}
+	case float64:
+		if limit, ok := schema["minimum"].(float64); ok && typed < limit {
+			v.fail(path, "%v is below the minimum %v", typed, limit)
+		}
+		if limit, ok := schema["maximum"].(float64); ok && typed > limit {
+			v.fail(path, "%v is above the maximum %v", typed, limit)
+		}
+	case []any:
+		if limit, ok := schema["minItems"].(float64); ok && float64(len(typed)) < limit {
+			v.fail(path, "needs at least %d items", int(limit))
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range typed {
+				v.validate(items, base, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case map[string]any:
+		if required, ok := schema["required"].([]any); ok {
+			for _, name := range required {
+				if _, present := typed[fmt.Sprint(name)]; !present {
+					v.fail(path, "missing required property %q", name)
+				}
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if propSchema, ok := properties[key].(map[string]any); ok {
+				v.validate(propSchema, base, typed[key], path+"."+key)
+			} else if allowed, ok := schema["additionalProperties"].(bool); ok && !allowed {
+				v.fail(path, "unexpected property %q", key)
+			}
+		}
+	}
+}
+
+func matchesType(expected any, value any) bool {
+	if list, ok := expected.([]any); ok {
+		for _, option := range list {
+			if matchesType(option, value) {
+				return true
+			}
+		}
+		return false
+	}
+	actual := jsonType(value)
+	switch expected {
+	case actual:
+		return true
+	case "integer":
+		f, ok := value.(float64)
+		return ok && f == math.Trunc(f)
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return "unknown"
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestValidateJSONSchemaKeywords(t *testing.T) {
+	schema := []byte(`{
+		"type": "object",
+		"required": ["id"],
+		"additionalProperties": false,
+		"properties": {
+			"id": {"$ref": "#/definitions/ID"},
+			"kind": {"enum": ["a", "b"]},
+			"code": {"type": "string", "pattern": "^[A-Z]{3}$"},
+			"name": {"type": "string", "minLength": 2},
+			"tags": {"type": "array", "minItems": 1, "items": {"type": "string"}},
+			"score": {"type": "number", "minimum": 0, "maximum": 100},
+			"count": {"type": "integer"},
+			"either": {"anyOf": [{"type": "string"}, {"type": "integer"}]},
+			"one": {"oneOf": [{"type": "number"}, {"type": "integer"}]},
+			"both": {"allOf": [{"type": "string"}, {"minLength": 3}]}
+		},
+		"definitions": {
+			"ID": {"type": "string", "minLength": 1}
+		}
+	}`)
+
+	tests := []struct {
+		name string
+		doc  string
+		want []string // substrings, one per expected violation
+	}{
+		{"valid", `{"id": "x", "kind": "a", "code": "ABC", "name": "Al", "tags": ["t"], "score": 50, "count": 3, "either": 1, "one": 1.5, "both": "abc"}`, nil},
+		{"required", `{}`, []string{`missing required property "id"`}},
+		{"additionalProperties", `{"id": "x", "extra": 1}`, []string{`unexpected property "extra"`}},
+		{"local ref", `{"id": ""}`, []string{"$.id: shorter than 1 characters"}},
+		{"type", `{"id": 7}`, []string{"$.id: expected string, got number"}},
+		{"enum", `{"id": "x", "kind": "c"}`, []string{"is not one of"}},
+		{"pattern", `{"id": "x", "code": "abc"}`, []string{"does not match"}},
+		{"minLength counts runes", `{"id": "x", "name": "é"}`, []string{"shorter than 2 characters"}},
+		{"minItems", `{"id": "x", "tags": []}`, []string{"needs at least 1 items"}},
+		{"items", `{"id": "x", "tags": [1]}`, []string{"$.tags[0]: expected string"}},
+		{"minimum", `{"id": "x", "score": -1}`, []string{"below the minimum"}},
+		{"maximum", `{"id": "x", "score": 101}`, []string{"above the maximum"}},
+		{"integer", `{"id": "x", "count": 1.5}`, []string{"expected integer"}},
+		{"anyOf", `{"id": "x", "either": true}`, []string{"none of the anyOf"}},
+		{"oneOf matches two", `{"id": "x", "one": 2}`, []string{"not exactly one"}},
+		{"allOf", `{"id": "x", "both": "ab"}`, []string{"$.both: shorter than 3 characters"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc any
+			if err := json.Unmarshal([]byte(tt.doc), &doc); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ValidateJSONSchema(schema, doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got violations %q, want %d", got, len(tt.want))
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(got[i], want) {
+					t.Errorf("violation %q does not mention %q", got[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateJSONSchemaInvalidSchema(t *testing.T) {
+	if _, err := ValidateJSONSchema([]byte(`{`), map[string]any{}); err == nil {
+		t.Fatal("expected an error for a malformed schema")
+	}
+}
+
+func TestValidateJSONSchemaFileFollowsRelativeRefs(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("recruiting/Candidate.json", `{
+		"type": "object",
+		"required": ["documentId"],
+		"properties": {"documentId": {"$ref": "../common/Identifier.json"}}
+	}`)
+	write("common/Identifier.json", `{
+		"type": "object",
+		"required": ["value"],
+		"properties": {"value": {"$ref": "#/definitions/NonEmpty"}},
+		"definitions": {"NonEmpty": {"type": "string", "minLength": 1}}
+	}`)
+
+	tests := []struct {
+		name string
+		doc  any
+		want int
+	}{
+		{"valid", map[string]any{"documentId": map[string]any{"value": "c1"}}, 0},
+		{"ref into another file", map[string]any{"documentId": map[string]any{}}, 1},
+		{"local ref inside the referenced file", map[string]any{"documentId": map[string]any{"value": ""}}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ValidateJSONSchemaFile(filepath.Join(dir, "recruiting", "Candidate.json"), tt.doc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(got) != tt.want {
+				t.Errorf("got violations %q, want %d", got, tt.want)
+			}
+		})
+	}
+
+	write("recruiting/Broken.json", `{"properties": {"x": {"$ref": "missing.json"}}}`)
+	if _, err := ValidateJSONSchemaFile(filepath.Join(dir, "recruiting", "Broken.json"), map[string]any{"x": 1}); err == nil {
+		t.Error("expected an error for a reference to a missing file")
+	}
+}
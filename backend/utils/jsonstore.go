@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// SaveJSONFile marshals v with indentation and writes it to path, creating
+// the parent directory if needed.
+func SaveJSONFile(path string, v any, perm os.FileMode) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling data: %v", err)
+	}
+
+	// Write to a temp file first so readers never see a half-written record
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, perm); err != nil {
+		return fmt.Errorf("error writing file: %v", err)
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// LoadJSONFile reads the JSON document at path into v.
+func LoadJSONFile(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
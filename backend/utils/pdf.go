@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// A4 in points, with the margins every page uses
+const (
+	pdfPageWidth  = 595.0
+	pdfPageHeight = 842.0
+	pdfMargin     = 50.0
+	pdfLineFactor = 1.35 // line height as a multiple of the font size
+)
+
+// RGB is a colour with components from 0 to 1
+type RGB [3]float64
+
+// PDFStyle describes how a run of text is drawn
+type PDFStyle struct {
+	Size   float64
+	Bold   bool
+	Color  RGB
+	Indent float64
+}
+
+// PDFDocument builds a simple text PDF: wrapped paragraphs, table rows,
+// filled bars and rules on A4 pages, using the standard Helvetica fonts so
+// nothing has to be embedded. Text outside Windows-1252 is replaced by "?".
+type PDFDocument struct {
+	title  string
+	footer string
+	pages  []*bytes.Buffer
+	y      float64 // distance of the cursor from the top of the page
+}
+
+// NewPDFDocument starts a document; footer is printed on every page next to
+// the page number
+func NewPDFDocument(title, footer string) *PDFDocument {
+	d := &PDFDocument{title: title, footer: footer}
+	d.AddPage()
+	return d
+}
+
+// ContentWidth is the usable width between the margins
+func (d *PDFDocument) ContentWidth() float64 {
+	return pdfPageWidth - 2*pdfMargin
+}
+
+// AddPage starts a new page with the cursor at the top margin
+func (d *PDFDocument) AddPage() {
+	d.pages = append(d.pages, &bytes.Buffer{})
+	d.y = pdfMargin
+}
+
+// ensureSpace breaks the page when fewer than h points are left
+func (d *PDFDocument) ensureSpace(h float64) {
+	if d.y+h > pdfPageHeight-pdfMargin {
+		d.AddPage()
+	}
+}
+
+func (d *PDFDocument) page() *bytes.Buffer {
+	return d.pages[len(d.pages)-1]
+}
+
+// Space moves the cursor down
+func (d *PDFDocument) Space(h float64) {
+	d.y += h
+}
+
+// Paragraph draws wrapped text at the cursor and moves below it
+func (d *PDFDocument) Paragraph(text string, style PDFStyle) {
+	lineHeight := style.Size * pdfLineFactor
+	for _, line := range wrapPDFText(text, style.Size, style.Bold, d.ContentWidth()-style.Indent) {
+		d.ensureSpace(lineHeight)
+		d.text(pdfMargin+style.Indent, d.y+style.Size, line, style)
+		d.y += lineHeight
+	}
+}
+
+// TableRow draws one row of cells with the given widths, wrapping each cell
+// and optionally filling the row background
+func (d *PDFDocument) TableRow(cells []string, widths []float64, style PDFStyle, fill *RGB) {
+	const padding = 4.0
+	lineHeight := style.Size * pdfLineFactor
+	wrapped := make([][]string, len(cells))
+	rows := 1
+	for i, cell := range cells {
+		if i >= len(widths) {
+			break
+		}
+		wrapped[i] = wrapPDFText(cell, style.Size, style.Bold, widths[i]-2*padding)
+		rows = max(rows, len(wrapped[i]))
+	}
+	height := float64(rows)*lineHeight + padding
+	d.ensureSpace(height)
+
+	if fill != nil {
+		d.rect(pdfMargin, d.y, sum(widths), height, *fill)
+	}
+	x := pdfMargin
+	for i, lines := range wrapped {
+		for j, line := range lines {
+			d.text(x+padding, d.y+padding/2+style.Size+float64(j)*lineHeight, line, style)
+		}
+		if i < len(widths) {
+			x += widths[i]
+		}
+	}
+	d.y += height
+}
+
+// Bar draws a labelled horizontal bar filled to value/maximum
+func (d *PDFDocument) Bar(label string, value, maximum float64, color RGB, style PDFStyle) {
+	const labelWidth, valueWidth, height = 170.0, 50.0, 10.0
+	lineHeight := style.Size * pdfLineFactor
+	d.ensureSpace(lineHeight + 4)
+	d.text(pdfMargin, d.y+style.Size, label, style)
+
+	track := d.ContentWidth() - labelWidth - valueWidth
+	top := d.y + (lineHeight-height)/2
+	d.rect(pdfMargin+labelWidth, top, track, height, RGB{0.92, 0.92, 0.92})
+	if maximum > 0 && value > 0 {
+		d.rect(pdfMargin+labelWidth, top, track*min(value/maximum, 1), height, color)
+	}
+	d.text(pdfMargin+labelWidth+track+8, d.y+style.Size, fmt.Sprintf("%.1f", value), style)
+	d.y += lineHeight + 4
+}
+
+// Banner draws a full-width coloured band with a line of text in it
+func (d *PDFDocument) Banner(text string, height float64, fill RGB, style PDFStyle) {
+	d.ensureSpace(height)
+	d.rect(pdfMargin, d.y, d.ContentWidth(), height, fill)
+	d.text(pdfMargin+12, d.y+(height+style.Size*0.7)/2, text, style)
+	d.y += height
+}
+
+// Rule draws a thin horizontal line across the content width
+func (d *PDFDocument) Rule(color RGB) {
+	d.ensureSpace(6)
+	d.rect(pdfMargin, d.y+2, d.ContentWidth(), 0.75, color)
+	d.y += 6
+}
+
+// EnsureSpace starts a new page unless h points are left, so a heading isn't
+// stranded at the bottom of a page
+func (d *PDFDocument) EnsureSpace(h float64) {
+	d.ensureSpace(h)
+}
+
+func (d *PDFDocument) text(x, baseline float64, s string, style PDFStyle) {
+	font := "F1"
+	if style.Bold {
+		font = "F2"
+	}
+	fmt.Fprintf(d.page(), "BT %.3f %.3f %.3f rg /%s %.1f Tf %.2f %.2f Td (%s) Tj ET\n",
+		style.Color[0], style.Color[1], style.Color[2], font, style.Size, x, pdfPageHeight-baseline, escapePDFText(s))
+}
+
+func (d *PDFDocument) rect(x, top, w, h float64, fill RGB) {
+	fmt.Fprintf(d.page(), "%.3f %.3f %.3f rg %.2f %.2f %.2f %.2f re f\n",
+		fill[0], fill[1], fill[2], x, pdfPageHeight-top-h, w, h)
+}
+
+// Bytes serialises the document, adding the footer and page numbers
+func (d *PDFDocument) Bytes() []byte {
+	footerStyle := PDFStyle{Size: 8, Color: RGB{0.45, 0.45, 0.45}}
+	for i, page := range d.pages {
+		label := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+		fmt.Fprintf(page, "BT %.3f %.3f %.3f rg /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n",
+			footerStyle.Color[0], footerStyle.Color[1], footerStyle.Color[2],
+			pdfPageWidth-pdfMargin-pdfTextWidth(label, 8, false), pdfMargin/2, escapePDFText(label))
+		if d.footer != "" {
+			fmt.Fprintf(page, "BT %.3f %.3f %.3f rg /F1 8 Tf %.2f %.2f Td (%s) Tj ET\n",
+				footerStyle.Color[0], footerStyle.Color[1], footerStyle.Color[2],
+				pdfMargin, pdfMargin/2, escapePDFText(d.footer))
+		}
+	}
+
+	var out bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	// Objects 1-5 are fixed; each page then takes a page and a content object
+	const firstPage = 6
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	object(fmt.Sprintf("<< /Title (%s) /Producer (InterviewMe) /CreationDate (D:%s) >>",
+		escapePDFText(d.title), time.Now().UTC().Format("20060102150405Z")))
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.0f %.0f] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPage+2*i+1))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.Bytes()
+}
+
+// wrapPDFText breaks text into lines no wider than width, honouring newlines
+// and splitting words that are too long on their own
+func wrapPDFText(text string, size float64, bold bool, width float64) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			for pdfTextWidth(word, size, bold) > width && len([]rune(word)) > 1 {
+				if line != "" {
+					lines = append(lines, line)
+					line = ""
+				}
+				runes := []rune(word)
+				cut := len(runes) - 1
+				for cut > 1 && pdfTextWidth(string(runes[:cut]), size, bold) > width {
+					cut--
+				}
+				lines = append(lines, string(runes[:cut]))
+				word = string(runes[cut:])
+			}
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if pdfTextWidth(candidate, size, bold) > width && line != "" {
+				lines = append(lines, line)
+				candidate = word
+			}
+			line = candidate
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfTextWidth measures text in points using the Helvetica metrics
+func pdfTextWidth(text string, size float64, bold bool) float64 {
+	widths := helveticaWidths
+	if bold {
+		widths = helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range text {
+		if r >= 32 && r <= 126 {
+			total += widths[r-32]
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Runes Windows-1252 places outside Latin-1
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, '„': 0x84, '…': 0x85, '•': 0x95, '–': 0x96, '—': 0x97,
+	'‘': 0x91, '’': 0x92, '“': 0x93, '”': 0x94, '™': 0x99,
+}
+
+func escapePDFText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		var c byte
+		switch {
+		case r == '\t':
+			c = ' '
+		case r >= 32 && r <= 126, r >= 0xA0 && r <= 0xFF:
+			c = byte(r)
+		default:
+			if mapped, ok := winAnsiExtras[r]; ok {
+				c = mapped
+			} else {
+				c = '?'
+			}
+		}
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		if c >= 0x80 {
+			fmt.Fprintf(&b, "\\%03o", c)
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+func sum(values []float64) float64 {
+	total := 0.0
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Advance widths of ASCII 32-126 in 1/1000 em, from the standard AFM files
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
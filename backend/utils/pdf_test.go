@@ -0,0 +1,110 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestWrapPDFText(t *testing.T) {
+	tests := []struct {
+		name  string
+		text  string
+		width float64
+		want  []string
+	}{
+		{"fits", "short line", 200, []string{"short line"}},
+		{"wraps at words", "alpha beta gamma delta", 60, []string{"alpha beta", "gamma delta"}},
+		{"keeps newlines", "one\ntwo", 200, []string{"one", "two"}},
+		{"keeps empty lines", "one\n\ntwo", 200, []string{"one", "", "two"}},
+		{"splits long words", "abcdefghijklmnop", 40, []string{"abcdefg", "hijklmno", "p"}},
+		{"empty", "", 200, []string{""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := wrapPDFText(tt.text, 10, false, tt.width)
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("wrapPDFText(%q, %.0f) = %q, want %q", tt.text, tt.width, got, tt.want)
+			}
+			for _, line := range got {
+				if pdfTextWidth(line, 10, false) > tt.width {
+					t.Errorf("line %q is wider than %.0f", line, tt.width)
+				}
+			}
+		})
+	}
+}
+
+func TestEscapePDFText(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`a (b) \c`, `a \(b\) \\c`},
+		{"tab\there", "tab here"},
+		{"café", `caf\351`},
+		{"“quoted” – €5", `\223quoted\224 \226 \2005`},
+		{"日本", "??"},
+	}
+	for _, tt := range tests {
+		if got := escapePDFText(tt.in); got != tt.want {
+			t.Errorf("escapePDFText(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestPDFDocumentStructure(t *testing.T) {
+	tests := []struct {
+		name       string
+		paragraphs int
+		wantPages  int
+	}{
+		{"single page", 3, 1},
+		{"breaks pages", 120, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := NewPDFDocument("Report (draft)", "Confidential")
+			for i := 0; i < tt.paragraphs; i++ {
+				doc.Paragraph(fmt.Sprintf("Paragraph %d", i), PDFStyle{Size: 10})
+			}
+			doc.TableRow([]string{"Skill", "Match"}, []float64{200, 100}, PDFStyle{Size: 9, Bold: true}, &RGB{0.9, 0.9, 0.9})
+			doc.Bar("Overall", 72, 100, RGB{0, 0, 1}, PDFStyle{Size: 9})
+			out := doc.Bytes()
+
+			if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+				t.Fatal("missing PDF header or trailer")
+			}
+			if got := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(out); got == nil || string(got[1]) != strconv.Itoa(tt.wantPages) {
+				t.Errorf("page count = %s, want %d", got, tt.wantPages)
+			}
+			if !bytes.Contains(out, []byte(fmt.Sprintf("(Page %d of %d)", tt.wantPages, tt.wantPages))) {
+				t.Error("last page is not numbered")
+			}
+			if !bytes.Contains(out, []byte(`/Title (Report \(draft\))`)) {
+				t.Error("title is not escaped into the info dictionary")
+			}
+
+			// Every xref entry must point at the start of its object
+			m := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+			if m == nil {
+				t.Fatal("no startxref")
+			}
+			xref, _ := strconv.Atoi(string(m[1]))
+			lines := strings.Split(string(out[xref:]), "\n")
+			if lines[0] != "xref" {
+				t.Fatalf("startxref points at %q", lines[0])
+			}
+			count, _ := strconv.Atoi(strings.Fields(lines[1])[1])
+			for n := 1; n < count; n++ {
+				offset, _ := strconv.Atoi(strings.Fields(lines[2+n])[0])
+				if want := fmt.Sprintf("%d 0 obj", n); !bytes.HasPrefix(out[offset:], []byte(want)) {
+					t.Errorf("xref entry %d points at %q", n, out[offset:offset+10])
+				}
+			}
+		})
+	}
+}
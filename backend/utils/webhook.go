@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SignPayload returns the hex HMAC-SHA256 of body under secret
+func SignPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySignature checks a hex HMAC-SHA256 signature in constant time. The
+// "sha256=" and "sha256 " prefixes ATS vendors put in front are accepted.
+func VerifySignature(secret string, body []byte, signature string) bool {
+	if secret == "" {
+		return false
+	}
+	signature = strings.TrimSpace(signature)
+	for _, prefix := range []string{"sha256=", "sha256 "} {
+		if len(signature) > len(prefix) && strings.EqualFold(signature[:len(prefix)], prefix) {
+			signature = strings.TrimSpace(signature[len(prefix):])
+			break
+		}
+	}
+	given, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(given, mac.Sum(nil))
+}
+
+// RetryPolicy controls how often and how patiently a request is retried
+type RetryPolicy struct {
+	Attempts  int           // total tries, including the first
+	BaseDelay time.Duration // doubled after every failure
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy suits calls to third-party APIs from background work
+var DefaultRetryPolicy = RetryPolicy{Attempts: 4, BaseDelay: 500 * time.Millisecond, MaxDelay: 30 * time.Second}
+
+// HTTPStatusError is returned when the final attempt got a non-2xx response
+type HTTPStatusError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.StatusCode, e.Body)
+}
+
+// Retryable reports whether a status is worth retrying: throttling and server errors
+func Retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= 500
+}
+
+// DoWithRetry sends a request until it gets a 2xx response, a status that
+// retrying cannot fix, or runs out of attempts. build is called for every
+// attempt so each gets a fresh body; it should set the same Idempotency-Key
+// on each so the receiver can drop duplicates. Retry-After is honoured. At
+// most limit bytes of the final response body are returned.
+func DoWithRetry(ctx context.Context, client *http.Client, policy RetryPolicy, limit int64, build func() (*http.Request, error)) ([]byte, int, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	attempts := policy.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		req, err := build()
+		if err != nil {
+			return nil, 0, err
+		}
+		req = req.WithContext(ctx)
+
+		resp, err := client.Do(req)
+		wait := Backoff(policy, attempt)
+		if err != nil {
+			lastErr = err
+		} else {
+			body, readErr := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+			resp.Body.Close()
+			switch {
+			case readErr != nil:
+				lastErr = readErr
+			case int64(len(body)) > limit:
+				return nil, resp.StatusCode, fmt.Errorf("response is larger than %d bytes", limit)
+			case resp.StatusCode >= 200 && resp.StatusCode < 300:
+				return body, resp.StatusCode, nil
+			default:
+				lastErr = &HTTPStatusError{StatusCode: resp.StatusCode, Body: truncate(string(body), 200)}
+				if !Retryable(resp.StatusCode) {
+					return nil, resp.StatusCode, lastErr
+				}
+				if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds >= 0 {
+					wait = time.Duration(seconds) * time.Second
+					if policy.MaxDelay > 0 && wait > policy.MaxDelay {
+						wait = policy.MaxDelay
+					}
+				}
+			}
+		}
+
+		if attempt == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return nil, 0, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+
+	status := 0
+	if statusErr, ok := lastErr.(*HTTPStatusError); ok {
+		status = statusErr.StatusCode
+	}
+	return nil, status, fmt.Errorf("gave up after %d attempts: %w", attempts, lastErr)
+}
+
+// Backoff returns the wait before retrying after the given attempt:
+// exponential, with up to 25% jitter so retries from many workers spread out
+func Backoff(policy RetryPolicy, attempt int) time.Duration {
+	delay := policy.BaseDelay << (attempt - 1)
+	if policy.MaxDelay > 0 && (delay > policy.MaxDelay || delay <= 0) {
+		delay = policy.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/4+1))
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// NewJSONRequest builds a POST with a JSON body and the given headers
+func NewJSONRequest(url string, body []byte, headers map[string]string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	return req, nil
+}
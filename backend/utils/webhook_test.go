@@ -0,0 +1,127 @@
+package utils
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestVerifySignature(t *testing.T) {
+	body := []byte(`{"event":"application.created","id":"a1"}`)
+	valid := SignPayload("s3cret", body)
+
+	tests := []struct {
+		name      string
+		secret    string
+		body      []byte
+		signature string
+		want      bool
+	}{
+		{"bare hex", "s3cret", body, valid, true},
+		{"sha256= prefix", "s3cret", body, "sha256=" + valid, true},
+		{"upper-case prefix", "s3cret", body, "SHA256=" + valid, true},
+		{"sha256 space prefix", "s3cret", body, "sha256 " + valid, true},
+		{"surrounding whitespace", "s3cret", body, "  sha256=" + valid + "\n", true},
+		{"upper-case hex", "s3cret", body, strings.ToUpper(valid), true},
+		{"wrong secret", "other", body, valid, false},
+		{"tampered body", "s3cret", []byte(`{"event":"application.created","id":"a2"}`), valid, false},
+		{"empty secret", "", body, SignPayload("", body), false},
+		{"empty signature", "s3cret", body, "", false},
+		{"prefix only", "s3cret", body, "sha256=", false},
+		{"not hex", "s3cret", body, "sha256=zz" + valid[2:], false},
+		{"truncated", "s3cret", body, valid[:len(valid)-2], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := VerifySignature(tt.secret, tt.body, tt.signature); got != tt.want {
+				t.Errorf("VerifySignature() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := RetryPolicy{Attempts: 8, BaseDelay: 5 * time.Second, MaxDelay: 10 * time.Minute}
+	tests := []struct {
+		attempt int
+		base    time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{7, 320 * time.Second},
+		{8, 10 * time.Minute},
+		{80, 10 * time.Minute}, // shift overflow falls back to the cap
+	}
+	for _, tt := range tests {
+		got := Backoff(policy, tt.attempt)
+		if got < tt.base || got > tt.base+tt.base/4 {
+			t.Errorf("Backoff(attempt %d) = %v, want %v plus up to 25%% jitter", tt.attempt, got, tt.base)
+		}
+	}
+	if got := Backoff(RetryPolicy{}, 1); got != 0 {
+		t.Errorf("Backoff with no base delay = %v, want 0", got)
+	}
+}
+
+func TestDoWithRetry(t *testing.T) {
+	policy := RetryPolicy{Attempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	tests := []struct {
+		name      string
+		statuses  []int
+		wantCalls int32
+		wantErr   bool
+		wantCode  int
+	}{
+		{"first try succeeds", []int{200}, 1, false, 200},
+		{"retries server errors", []int{503, 502, 201}, 3, false, 201},
+		{"retries throttling", []int{429, 200}, 2, false, 200},
+		{"client errors are final", []int{400, 200}, 1, true, 400},
+		{"gives up after the last attempt", []int{500, 500, 500, 200}, 3, true, 500},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls atomic.Int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := calls.Add(1)
+				if r.Header.Get("Idempotency-Key") != "k1" {
+					t.Errorf("attempt %d lost the idempotency key", n)
+				}
+				w.WriteHeader(tt.statuses[n-1])
+				w.Write([]byte("ok"))
+			}))
+			defer server.Close()
+
+			_, status, err := DoWithRetry(context.Background(), server.Client(), policy, 1024, func() (*http.Request, error) {
+				return NewJSONRequest(server.URL, []byte(`{}`), map[string]string{"Idempotency-Key": "k1"})
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if status != tt.wantCode {
+				t.Errorf("status = %d, want %d", status, tt.wantCode)
+			}
+			if calls.Load() != tt.wantCalls {
+				t.Errorf("made %d calls, want %d", calls.Load(), tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestDoWithRetryLimitsResponseBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(strings.Repeat("x", 100)))
+	}))
+	defer server.Close()
+
+	_, _, err := DoWithRetry(context.Background(), server.Client(), RetryPolicy{Attempts: 1}, 10, func() (*http.Request, error) {
+		return NewJSONRequest(server.URL, nil, nil)
+	})
+	if err == nil {
+		t.Fatal("expected an error for a response over the limit")
+	}
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestXLSXColumn(t *testing.T) {
+	tests := []struct {
+		index int
+		want  string
+	}{
+		{0, "A"},
+		{25, "Z"},
+		{26, "AA"},
+		{51, "AZ"},
+		{52, "BA"},
+		{701, "ZZ"},
+		{702, "AAA"},
+	}
+	for _, tt := range tests {
+		if got := xlsxColumn(tt.index); got != tt.want {
+			t.Errorf("xlsxColumn(%d) = %q, want %q", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXSheetName(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Candidates 42", "Candidates 42"},
+		{"Q1/Q2 [draft]: *?", "Q1Q2 draft"},
+		{"  ", "Sheet1"},
+		{strings.Repeat("é", 40), strings.Repeat("é", 31)},
+	}
+	for _, tt := range tests {
+		if got := xlsxSheetName(tt.name); got != tt.want {
+			t.Errorf("xlsxSheetName(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestXMLEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{`<a href="x">&</a>`, "&lt;a href=&#34;x&#34;&gt;&amp;&lt;/a&gt;"},
+		{"line\nbreak", "line&#xA;break"},
+	}
+	for _, tt := range tests {
+		if got := xmlEscape(tt.in); got != tt.want {
+			t.Errorf("xmlEscape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	xw, err := NewXLSXWriter(&buf, "Candidates <1>")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := [][]any{
+		{"name", "score", "knocked_out"},
+		{"Jane <Doe>", 72.5, false},
+		{"", 3, true, nil, "=SUM(A1)"},
+	}
+	for i, row := range rows {
+		if err := xw.WriteRow(row, i == 0); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a zip: %v", err)
+	}
+	parts := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[f.Name] = string(body)
+		if err := xml.Unmarshal(body, new(any)); err != nil && strings.HasSuffix(f.Name, ".xml") {
+			t.Errorf("%s is not well-formed XML: %v", f.Name, err)
+		}
+	}
+	for _, name := range []string{"[Content_Types].xml", "_rels/.rels", "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/styles.xml", "xl/worksheets/sheet1.xml"} {
+		if _, ok := parts[name]; !ok {
+			t.Errorf("workbook has no %s", name)
+		}
+	}
+
+	sheet := parts["xl/worksheets/sheet1.xml"]
+	tests := []struct {
+		name, cell string
+	}{
+		{"bold header", `<c r="A1" s="1" t="inlineStr"><is><t xml:space="preserve">name</t></is></c>`},
+		{"escaped text", `<c r="A2" t="inlineStr"><is><t xml:space="preserve">Jane &lt;Doe&gt;</t></is></c>`},
+		{"float", `<c r="B2"><v>72.5</v></c>`},
+		{"false", `<c r="C2" t="b"><v>0</v></c>`},
+		{"int after an empty cell", `<c r="B3"><v>3</v></c>`},
+		{"true", `<c r="C3" t="b"><v>1</v></c>`},
+		{"formula text stays text", `<c r="E3" t="inlineStr"><is><t xml:space="preserve">=SUM(A1)</t></is></c>`},
+	}
+	for _, tt := range tests {
+		if !strings.Contains(sheet, tt.cell) {
+			t.Errorf("%s: sheet does not contain %s", tt.name, tt.cell)
+		}
+	}
+	for _, skipped := range []string{`r="A3"`, `r="D3"`} {
+		if strings.Contains(sheet, skipped) {
+			t.Errorf("sheet has a cell for the empty value at %s", skipped)
+		}
+	}
+	if !strings.Contains(parts["xl/workbook.xml"], `name="Candidates &lt;1&gt;"`) {
+		t.Error("sheet name is not escaped in the workbook")
+	}
+}